@@ -0,0 +1,93 @@
+// If you are AI: This file implements per-app CIDR allow/deny lists for the
+// publish and play directions.
+
+package access
+
+import (
+	"fmt"
+	"net"
+)
+
+// Direction is the side of a stream a client is on.
+type Direction int
+
+const (
+	// Publish is RTMP / RTMPS ingest.
+	Publish Direction = iota
+	// Play is HTTP-FLV, WS-FLV, HLS and DASH playback.
+	Play
+)
+
+// Rule restricts one app ("" or "*" means every app). A client is refused
+// when its address is in a matching deny list, or when some matching rule
+// has an allow list for the direction and the address is in none of them.
+type Rule struct {
+	App          string
+	PublishAllow []string
+	PublishDeny  []string
+	PlayAllow    []string
+	PlayDeny     []string
+}
+
+// rule is a Rule with parsed networks, indexed by Direction.
+type rule struct {
+	app   string
+	allow [2][]*net.IPNet
+	deny  [2][]*net.IPNet
+}
+
+// parseRule parses the CIDR lists of r.
+func parseRule(r Rule) (rule, error) {
+	out := rule{app: r.App}
+	if out.app == "*" {
+		out.app = ""
+	}
+	lists := []struct {
+		dst  *[]*net.IPNet
+		src  []string
+		name string
+	}{
+		{&out.allow[Publish], r.PublishAllow, "publish_allow"},
+		{&out.deny[Publish], r.PublishDeny, "publish_deny"},
+		{&out.allow[Play], r.PlayAllow, "play_allow"},
+		{&out.deny[Play], r.PlayDeny, "play_deny"},
+	}
+	for _, l := range lists {
+		for _, c := range l.src {
+			_, n, err := net.ParseCIDR(c)
+			if err != nil {
+				return rule{}, fmt.Errorf("app %q %s %q: %w", r.App, l.name, c, err)
+			}
+			*l.dst = append(*l.dst, n)
+		}
+	}
+	return out, nil
+}
+
+// permits reports whether ip may use app in direction d under rules.
+func permits(rules []rule, app string, d Direction, ip net.IP) bool {
+	restricted, allowed := false, false
+	for _, r := range rules {
+		if r.app != "" && r.app != app {
+			continue
+		}
+		if contains(r.deny[d], ip) {
+			return false
+		}
+		if len(r.allow[d]) > 0 {
+			restricted = true
+			allowed = allowed || contains(r.allow[d], ip)
+		}
+	}
+	return !restricted || allowed
+}
+
+// contains reports whether any of nets contains ip.
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+// If you are AI: This file implements the connection guard shared by the
+// RTMP and HTTP listeners: per-IP concurrent connection caps, a per-IP
+// token bucket on new connections, the app ACLs, and rejection counters.
+// Loopback clients are never limited, since relays, the packager and edge
+// pulls connect back through the public listeners.
+
+package access
+
+import (
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Rejection reasons, as exported in /metrics.
+const (
+	ReasonPublishDenied    = "publish_denied"
+	ReasonPlayDenied       = "play_denied"
+	ReasonConnLimit        = "conn_limit"
+	ReasonRateLimit        = "rate_limit"
+	ReasonHandshakeTimeout = "handshake_timeout"
+	ReasonPublishPolicy    = "publish_policy"
+	ReasonBadName          = "bad_name"
+	ReasonCapacity         = "capacity"
+)
+
+// reasons lists every rejection reason so counters exist from the start.
+var reasons = []string{ReasonPublishDenied, ReasonPlayDenied, ReasonConnLimit, ReasonRateLimit,
+	ReasonHandshakeTimeout, ReasonPublishPolicy, ReasonBadName, ReasonCapacity}
+
+// bucketIdle is how long an untouched, refilled bucket is kept.
+const bucketIdle = time.Minute
+
+// Limits bounds what one client IP may do. Zero values mean unlimited.
+type Limits struct {
+	MaxConnsPerIP int     // concurrent connections across all listeners
+	ConnectRate   float64 // new connections per second
+	ConnectBurst  int     // bucket size; defaults to ConnectRate (at least 1)
+}
+
+// bucket is one IP's connect-rate token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Guard admits or refuses clients. A nil *Guard admits everyone. It is
+// safe for concurrent use.
+type Guard struct {
+	rules  []rule
+	limits Limits
+
+	mu        sync.Mutex
+	conns     map[string]int
+	buckets   map[string]*bucket
+	lastPrune time.Time
+
+	rejected map[string]*atomic.Uint64
+}
+
+// New builds a guard from ACL rules and limits.
+func New(rules []Rule, limits Limits) (*Guard, error) {
+	g := &Guard{
+		limits:   limits,
+		conns:    make(map[string]int),
+		buckets:  make(map[string]*bucket),
+		rejected: make(map[string]*atomic.Uint64, len(reasons)),
+	}
+	if g.limits.ConnectRate > 0 && g.limits.ConnectBurst <= 0 {
+		g.limits.ConnectBurst = int(math.Max(1, math.Ceil(g.limits.ConnectRate)))
+	}
+	for _, r := range rules {
+		pr, err := parseRule(r)
+		if err != nil {
+			return nil, err
+		}
+		g.rules = append(g.rules, pr)
+	}
+	for _, r := range reasons {
+		g.rejected[r] = new(atomic.Uint64)
+	}
+	return g, nil
+}
+
+// Allow reports whether the client at addr ("host:port") may publish or
+// play app, counting a rejection when it may not.
+func (g *Guard) Allow(app string, d Direction, addr string) bool {
+	if g == nil || len(g.rules) == 0 {
+		return true
+	}
+	ip := hostIP(addr)
+	if ip == nil || ip.IsLoopback() || permits(g.rules, app, d, ip) {
+		return true
+	}
+	if d == Publish {
+		g.Reject(ReasonPublishDenied)
+	} else {
+		g.Reject(ReasonPlayDenied)
+	}
+	return false
+}
+
+// Admit takes a connection slot and a connect token for the client at addr.
+// On success it returns a release func to call when the connection ends;
+// otherwise it returns the rejection reason, already counted.
+func (g *Guard) Admit(addr string) (release func(), reason string) {
+	ip := hostIP(addr)
+	if g == nil || ip == nil || ip.IsLoopback() {
+		return func() {}, ""
+	}
+	host := ip.String()
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.prune(now)
+	if g.limits.MaxConnsPerIP > 0 && g.conns[host] >= g.limits.MaxConnsPerIP {
+		g.Reject(ReasonConnLimit)
+		return nil, ReasonConnLimit
+	}
+	if g.limits.ConnectRate > 0 {
+		b := g.buckets[host]
+		if b == nil {
+			b = &bucket{tokens: float64(g.limits.ConnectBurst), last: now}
+			g.buckets[host] = b
+		}
+		b.tokens = math.Min(float64(g.limits.ConnectBurst), b.tokens+now.Sub(b.last).Seconds()*g.limits.ConnectRate)
+		b.last = now
+		if b.tokens < 1 {
+			g.Reject(ReasonRateLimit)
+			return nil, ReasonRateLimit
+		}
+		b.tokens--
+	}
+	g.conns[host]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			if g.conns[host]--; g.conns[host] <= 0 {
+				delete(g.conns, host)
+			}
+		})
+	}, ""
+}
+
+// prune drops buckets idle long enough to have refilled. Called with mu held.
+func (g *Guard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < bucketIdle {
+		return
+	}
+	g.lastPrune = now
+	for host, b := range g.buckets {
+		if now.Sub(b.last) >= bucketIdle {
+			delete(g.buckets, host)
+		}
+	}
+}
+
+// Reject counts a rejection for reason.
+func (g *Guard) Reject(reason string) {
+	if g == nil {
+		return
+	}
+	if c, ok := g.rejected[reason]; ok {
+		c.Add(1)
+	}
+}
+
+// Rejections returns the cumulative rejection count by reason.
+func (g *Guard) Rejections() map[string]uint64 {
+	out := make(map[string]uint64, len(reasons))
+	if g == nil {
+		return out
+	}
+	for r, c := range g.rejected {
+		out[r] = c.Load()
+	}
+	return out
+}
+
+// hostIP returns the IP of a "host:port" (or bare host) address, or nil.
+func hostIP(addr string) net.IP {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(addr)
+}
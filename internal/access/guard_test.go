@@ -0,0 +1,105 @@
+// If you are AI: Unit tests for the ACL rules, per-IP limits and rejection
+// counters of the connection guard.
+
+package access
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAllow checks deny-over-allow, per-app matching and the loopback
+// exemption.
+func TestAllow(t *testing.T) {
+	g, err := New([]Rule{
+		{App: "*", PlayDeny: []string{"203.0.113.0/24"}},
+		{App: "live", PublishAllow: []string{"10.0.0.0/8"}, PublishDeny: []string{"10.9.0.0/16"}},
+	}, Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		app  string
+		d    Direction
+		addr string
+		want bool
+	}{
+		{"live", Publish, "10.1.2.3:5000", true},
+		{"live", Publish, "10.9.1.1:5000", false},  // deny wins
+		{"live", Publish, "192.0.2.1:5000", false}, // not in allow
+		{"other", Publish, "192.0.2.1:5000", true}, // rule is for live only
+		{"other", Play, "203.0.113.5:80", false},   // wildcard app
+		{"live", Play, "198.51.100.1:80", true},
+		{"live", Publish, "127.0.0.1:5000", true}, // loopback is exempt
+	}
+	for _, tc := range cases {
+		if got := g.Allow(tc.app, tc.d, tc.addr); got != tc.want {
+			t.Errorf("Allow(%s, %d, %s) = %v, want %v", tc.app, tc.d, tc.addr, got, tc.want)
+		}
+	}
+	r := g.Rejections()
+	if r[ReasonPublishDenied] != 2 || r[ReasonPlayDenied] != 1 {
+		t.Errorf("rejections = %v", r)
+	}
+	if _, err := New([]Rule{{PlayAllow: []string{"bogus"}}}, Limits{}); err == nil {
+		t.Error("expected error for an invalid CIDR")
+	}
+}
+
+// TestAdmitLimits checks the per-IP connection cap and connect rate.
+func TestAdmitLimits(t *testing.T) {
+	g, _ := New(nil, Limits{MaxConnsPerIP: 2})
+	r1, _ := g.Admit("192.0.2.1:1")
+	_, _ = g.Admit("192.0.2.1:2")
+	if _, reason := g.Admit("192.0.2.1:3"); reason != ReasonConnLimit {
+		t.Fatalf("third connection: reason %q, want %q", reason, ReasonConnLimit)
+	}
+	if _, reason := g.Admit("192.0.2.2:1"); reason != "" {
+		t.Fatalf("other IP refused: %q", reason)
+	}
+	r1()
+	r1() // release is idempotent
+	if _, reason := g.Admit("192.0.2.1:4"); reason != "" {
+		t.Fatalf("after release: %q", reason)
+	}
+	if _, reason := g.Admit("192.0.2.1:5"); reason != ReasonConnLimit {
+		t.Fatalf("double release freed two slots")
+	}
+
+	g, _ = New(nil, Limits{ConnectRate: 1, ConnectBurst: 2})
+	for i := 0; i < 2; i++ {
+		if _, reason := g.Admit("192.0.2.1:1"); reason != "" {
+			t.Fatalf("burst connection %d refused: %q", i, reason)
+		}
+	}
+	if _, reason := g.Admit("192.0.2.1:1"); reason != ReasonRateLimit {
+		t.Fatalf("reason %q, want %q", reason, ReasonRateLimit)
+	}
+	g.buckets["192.0.2.1"].last = time.Now().Add(-time.Second)
+	if _, reason := g.Admit("192.0.2.1:1"); reason != "" {
+		t.Fatalf("refilled bucket refused: %q", reason)
+	}
+	for i := 0; i < 10; i++ {
+		if _, reason := g.Admit("127.0.0.1:1"); reason != "" {
+			t.Fatal("loopback must not be limited")
+		}
+	}
+	if r := g.Rejections(); r[ReasonRateLimit] != 1 || r[ReasonConnLimit] != 0 {
+		t.Errorf("rejections = %v", r)
+	}
+}
+
+// TestNilGuard checks that a nil guard admits everything.
+func TestNilGuard(t *testing.T) {
+	var g *Guard
+	if !g.Allow("live", Publish, "192.0.2.1:1") {
+		t.Error("nil guard refused a publisher")
+	}
+	if release, reason := g.Admit("192.0.2.1:1"); reason != "" || release == nil {
+		t.Error("nil guard refused a connection")
+	}
+	g.Reject(ReasonRateLimit)
+	if len(g.Rejections()) != 0 {
+		t.Error("nil guard counted a rejection")
+	}
+}
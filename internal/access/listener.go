@@ -0,0 +1,79 @@
+// If you are AI: This file applies the guard's connection limits to a
+// listener. Admission happens on a connection's first Read, after any PROXY
+// header, so the limits see the real client address and a slow peer never
+// blocks the accept loop.
+
+package access
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// ErrRefused is wrapped by the Read error of a connection the guard refused.
+// Refusals are counted in metrics, so servers need not log them.
+var ErrRefused = errors.New("refused by access limits")
+
+// Wrap returns a listener whose connections are admitted by g. Refused
+// connections fail their first Read and are closed by the server. A nil
+// receiver returns ln unchanged.
+func (g *Guard) Wrap(ln net.Listener) net.Listener {
+	if g == nil || (g.limits.MaxConnsPerIP <= 0 && g.limits.ConnectRate <= 0) {
+		return ln
+	}
+	return &listener{Listener: ln, guard: g}
+}
+
+// listener wraps accepted connections in *conn.
+type listener struct {
+	net.Listener
+	guard *Guard
+}
+
+// Accept returns the next connection, not yet admitted.
+func (l *listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, guard: l.guard}, nil
+}
+
+// conn holds a guard slot from its first Read until Close.
+type conn struct {
+	net.Conn
+	guard *Guard
+
+	once    sync.Once
+	release func()
+	err     error
+}
+
+// admit asks the guard for a slot.
+func (c *conn) admit() {
+	addr := c.Conn.RemoteAddr().String()
+	var reason string
+	if c.release, reason = c.guard.Admit(addr); reason != "" {
+		c.err = fmt.Errorf("%s from %s: %w", reason, addr, ErrRefused)
+	}
+}
+
+// Read admits the connection on first use, then reads.
+func (c *conn) Read(b []byte) (int, error) {
+	c.once.Do(c.admit)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.Conn.Read(b)
+}
+
+// Close releases the slot, if one was taken, and closes the connection.
+func (c *conn) Close() error {
+	c.once.Do(func() {}) // never admit after close
+	if c.release != nil {
+		c.release()
+	}
+	return c.Conn.Close()
+}
@@ -0,0 +1,61 @@
+// If you are AI: This file applies the guard's connection limits to the
+// HTTP/3 (QUIC) listener. Each QUIC connection takes one slot, counted
+// with the TCP connections of the same IP, and holds it until it closes.
+
+package access
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"github.com/quic-go/quic-go"
+)
+
+// h3ExcessiveLoad is the HTTP/3 error code a refused connection is closed
+// with (H3_EXCESSIVE_LOAD, RFC 9114).
+const h3ExcessiveLoad = 0x0107
+
+// QUICListener is the listener http3.Server.ServeListener takes.
+type QUICListener interface {
+	Accept(context.Context) (*quic.Conn, error)
+	Addr() net.Addr
+	io.Closer
+}
+
+// WrapQUIC returns a listener whose connections are admitted by g, as Wrap
+// does for TCP. Refused connections are closed and never returned. A nil
+// receiver returns ln unchanged.
+func (g *Guard) WrapQUIC(ln QUICListener) QUICListener {
+	if g == nil || (g.limits.MaxConnsPerIP <= 0 && g.limits.ConnectRate <= 0) {
+		return ln
+	}
+	return &quicListener{QUICListener: ln, guard: g}
+}
+
+// quicListener admits connections as they are accepted.
+type quicListener struct {
+	QUICListener
+	guard *Guard
+}
+
+// Accept returns the next admitted connection. Its slot is released when
+// the connection's context ends.
+func (l *quicListener) Accept(ctx context.Context) (*quic.Conn, error) {
+	for {
+		c, err := l.QUICListener.Accept(ctx)
+		if err != nil {
+			return nil, err
+		}
+		release, reason := l.guard.Admit(c.RemoteAddr().String())
+		if reason != "" {
+			_ = c.CloseWithError(h3ExcessiveLoad, reason)
+			continue
+		}
+		go func() {
+			<-c.Context().Done()
+			release()
+		}()
+		return c, nil
+	}
+}
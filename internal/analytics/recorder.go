@@ -0,0 +1,202 @@
+// If you are AI: This file implements the viewer analytics recorder. The
+// session table feeds it publisher and viewer starts and ends; it rolls
+// them up per broadcast (publish to unpublish) into peak concurrency,
+// unique viewers and watch time, and exports each finished broadcast.
+// Streams idle for the retention window are forgotten.
+
+package analytics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// retention is how long a finished broadcast's report stays available
+// after the stream goes idle.
+const retention = 24 * time.Hour
+
+// Recorder aggregates viewer sessions per stream and broadcast. A nil
+// *Recorder records nothing.
+type Recorder struct {
+	mu      sync.Mutex
+	streams map[bus.StreamKey]*stream
+	dir     string           // export directory; "" disables export
+	now     func() time.Time // replaced in tests
+	exports sync.WaitGroup
+}
+
+// stream is the state of one stream key across broadcasts.
+type stream struct {
+	viewers map[string]*viewer // open viewer sessions by session ID
+	live    *broadcast         // nil while nobody publishes
+	last    *Report            // the most recent finished broadcast
+}
+
+// viewer is one open viewer session.
+type viewer struct {
+	protocol string
+	identity string    // hash of the subject, or of IP and user agent
+	since    time.Time // start of watch time in the current broadcast
+}
+
+// New returns a recorder that writes a JSON and a CSV report to dir when
+// a broadcast ends. An empty dir keeps reports in memory only.
+func New(dir string) *Recorder {
+	return &Recorder{streams: make(map[bus.StreamKey]*stream), dir: dir, now: time.Now}
+}
+
+// PublishStarted begins a broadcast on key. Viewers already waiting on
+// the stream are counted from now.
+func (r *Recorder) PublishStarted(key bus.StreamKey) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	s := r.streamLocked(key)
+	s.live = newBroadcast(now)
+	for _, v := range s.viewers {
+		v.since = now
+		s.live.join(v, now)
+	}
+}
+
+// PublishStopped ends the broadcast on key, keeps its report for the API
+// and exports it.
+func (r *Recorder) PublishStopped(key bus.StreamKey) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	s := r.streams[key]
+	if s == nil || s.live == nil {
+		r.mu.Unlock()
+		return
+	}
+	now := r.now()
+	for _, v := range s.viewers {
+		s.live.leave(v, now)
+	}
+	rep := s.live.report(key, now, nil)
+	rep.Live, rep.Ended = false, &now
+	s.last, s.live = &rep, nil
+	r.pruneLocked(now)
+	r.mu.Unlock()
+	if r.dir != "" {
+		r.exports.Add(1)
+		go func() {
+			defer r.exports.Done()
+			if err := export(r.dir, rep); err != nil {
+				log.Printf("analytics %s: export failed: %v", key, err)
+			}
+		}()
+	}
+}
+
+// ViewerJoined records viewer session id on key from remoteAddr with
+// userAgent, admitted with the play key subject ("" if anonymous).
+func (r *Recorder) ViewerJoined(key bus.StreamKey, id, protocol, remoteAddr, userAgent, subject string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	v := &viewer{protocol: protocol, identity: identity(remoteAddr, userAgent, subject), since: now}
+	s := r.streamLocked(key)
+	s.viewers[id] = v
+	if s.live != nil {
+		s.live.join(v, now)
+	}
+}
+
+// ViewerLeft ends viewer session id on key; its watch time runs to end.
+func (r *Recorder) ViewerLeft(key bus.StreamKey, id string, end time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.streams[key]
+	if s == nil || s.viewers[id] == nil {
+		return
+	}
+	v := s.viewers[id]
+	delete(s.viewers, id)
+	if s.live != nil {
+		s.live.leave(v, end)
+	}
+	if len(s.viewers) == 0 && s.live == nil && s.last == nil {
+		delete(r.streams, key)
+	}
+}
+
+// Report returns the live broadcast on key, or else the last finished
+// one.
+func (r *Recorder) Report(key bus.StreamKey) (Report, bool) {
+	if r == nil {
+		return Report{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.streams[key]
+	switch {
+	case s == nil:
+		return Report{}, false
+	case s.live != nil:
+		return s.live.report(key, r.now(), s.viewers), true
+	case s.last != nil && r.now().Sub(*s.last.Ended) <= retention:
+		return *s.last, true
+	}
+	return Report{}, false
+}
+
+// Wait blocks until pending exports are written.
+func (r *Recorder) Wait() {
+	if r != nil {
+		r.exports.Wait()
+	}
+}
+
+// streamLocked returns key's state, creating it. Caller must hold r.mu.
+func (r *Recorder) streamLocked(key bus.StreamKey) *stream {
+	s := r.streams[key]
+	if s == nil {
+		s = &stream{viewers: make(map[string]*viewer)}
+		r.streams[key] = s
+	}
+	return s
+}
+
+// pruneLocked forgets streams with no viewers and no broadcast whose last
+// report ended more than retention before now. Caller must hold r.mu.
+func (r *Recorder) pruneLocked(now time.Time) {
+	for key, s := range r.streams {
+		if len(s.viewers) == 0 && s.live == nil && (s.last == nil || now.Sub(*s.last.Ended) > retention) {
+			delete(r.streams, key)
+		}
+	}
+}
+
+// identity returns a short hash of the viewer's subject or, for anonymous
+// playback, of the client IP and user agent, so unique viewers can be
+// counted without keeping keys or addresses.
+func identity(remoteAddr, userAgent, subject string) string {
+	if subject != "" {
+		sum := sha256.Sum256([]byte("subject\x00" + subject))
+		return hex.EncodeToString(sum[:8])
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	sum := sha256.Sum256([]byte(host + "\x00" + userAgent))
+	return hex.EncodeToString(sum[:8])
+}
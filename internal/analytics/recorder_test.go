@@ -0,0 +1,132 @@
+// If you are AI: Unit tests for the analytics recorder: peak, unique
+// viewers and watch time per broadcast, and the export on unpublish.
+
+package analytics
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// TestBroadcastRollup runs one broadcast with a waiting viewer, a viewer
+// who reconnects and a second protocol, then checks the report.
+func TestBroadcastRollup(t *testing.T) {
+	start := time.Unix(1000, 0)
+	now := start
+	dir := t.TempDir()
+	r := New(dir)
+	r.now = func() time.Time { return now }
+	key := bus.NewStreamKey("live", "show")
+
+	r.ViewerJoined(key, "early", "httpflv", "10.0.0.1:1", "vlc", "") // waits for the publisher
+	now = now.Add(time.Minute)
+	r.PublishStarted(key)
+	r.ViewerJoined(key, "a1", "hls", "10.0.0.2:1", "safari", "")
+	now = now.Add(2 * time.Minute)
+	r.ViewerLeft(key, "a1", now)
+	r.ViewerJoined(key, "a2", "hls", "10.0.0.2:2", "safari", "") // same viewer again
+	now = now.Add(time.Minute)
+
+	live, ok := r.Report(key)
+	if !ok || !live.Live || live.Viewers != 2 || live.WatchMinutes != 6 {
+		t.Fatalf("live report = %+v", live)
+	}
+	r.PublishStopped(key)
+	r.ViewerLeft(key, "early", now.Add(time.Hour)) // after the broadcast: not counted
+	r.ViewerLeft(key, "a2", now)
+	r.Wait()
+
+	rep, ok := r.Report(key)
+	if !ok || rep.Live || rep.Ended == nil {
+		t.Fatalf("final report = %+v", rep)
+	}
+	if rep.PeakViewers != 2 || rep.UniqueViewers != 2 || rep.Sessions != 3 || rep.WatchMinutes != 6 || rep.AvgWatchMinutes != 2 {
+		t.Errorf("totals = %+v", rep)
+	}
+	hls := rep.Protocols["hls"]
+	if hls.Sessions != 2 || hls.UniqueViewers != 1 || hls.PeakViewers != 1 || hls.WatchMinutes != 3 {
+		t.Errorf("hls = %+v", hls)
+	}
+	if flv := rep.Protocols["httpflv"]; flv.WatchMinutes != 3 {
+		t.Errorf("httpflv = %+v", flv)
+	}
+
+	base := filepath.Join(dir, "live_show_"+start.Add(time.Minute).UTC().Format("20060102T150405Z"))
+	data, err := os.ReadFile(base + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported Report
+	if err := json.Unmarshal(data, &exported); err != nil || exported.Sessions != 3 {
+		t.Errorf("exported JSON = %s (%v)", data, err)
+	}
+	csv, err := os.ReadFile(base + ".csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(csv)), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[1], ",all,2,2,3,6.00,2.00") {
+		t.Errorf("exported CSV:\n%s", csv)
+	}
+}
+
+// TestNoBroadcast checks that viewers alone produce no report and that a
+// nil recorder is inert.
+func TestNoBroadcast(t *testing.T) {
+	r := New("")
+	key := bus.NewStreamKey("live", "x")
+	r.ViewerJoined(key, "v", "wsflv", "10.0.0.1:1", "", "")
+	r.PublishStopped(key)
+	r.ViewerLeft(key, "v", time.Now())
+	if _, ok := r.Report(key); ok {
+		t.Fatal("report without a broadcast")
+	}
+	var nilRec *Recorder
+	nilRec.PublishStarted(key)
+	nilRec.ViewerJoined(key, "v", "wsflv", "", "", "")
+	if _, ok := nilRec.Report(key); ok {
+		t.Fatal("nil recorder reported")
+	}
+}
+
+// TestSubjectIdentity counts viewers by play key when they have one, and
+// by IP and user agent otherwise.
+func TestSubjectIdentity(t *testing.T) {
+	r := New("")
+	key := bus.NewStreamKey("live", "keys")
+	r.PublishStarted(key)
+	r.ViewerJoined(key, "k1", "hls", "10.0.0.1:1", "safari", "alice")
+	r.ViewerJoined(key, "k2", "hls", "192.0.2.7:1", "chrome", "alice") // alice on another device
+	r.ViewerJoined(key, "k3", "hls", "10.0.0.1:2", "safari", "bob")    // behind alice's NAT
+	r.ViewerJoined(key, "n1", "hls", "10.0.0.9:1", "vlc", "")
+	r.ViewerJoined(key, "n2", "hls", "10.0.0.9:2", "vlc", "")
+	if rep, _ := r.Report(key); rep.UniqueViewers != 3 {
+		t.Errorf("unique viewers = %d, want 3", rep.UniqueViewers)
+	}
+}
+
+// TestRetention forgets a stream's last report once it has been idle for
+// the retention window.
+func TestRetention(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := New("")
+	r.now = func() time.Time { return now }
+	old, fresh := bus.NewStreamKey("live", "old"), bus.NewStreamKey("live", "fresh")
+	r.PublishStarted(old)
+	r.PublishStopped(old)
+	now = now.Add(retention + time.Minute)
+	if _, ok := r.Report(old); ok {
+		t.Fatal("expired report still served")
+	}
+	r.PublishStarted(fresh)
+	r.PublishStopped(fresh) // prunes
+	if len(r.streams) != 1 || r.streams[fresh] == nil {
+		t.Errorf("streams = %v", r.streams)
+	}
+}
@@ -0,0 +1,215 @@
+// If you are AI: This file holds the per-broadcast tallies and the report
+// built from them, plus its JSON and CSV export.
+
+package analytics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// Report is the viewer summary of one broadcast. Watch time of viewers
+// still connected counts up to the time of the report.
+type Report struct {
+	App             string                    `json:"app"`
+	Name            string                    `json:"name"`
+	Live            bool                      `json:"live"`
+	Started         time.Time                 `json:"started"`
+	Ended           *time.Time                `json:"ended,omitempty"`
+	Viewers         int                       `json:"viewers"` // connected now
+	PeakViewers     int                       `json:"peak_viewers"`
+	PeakAt          *time.Time                `json:"peak_at,omitempty"`
+	UniqueViewers   int                       `json:"unique_viewers"` // by play key, else IP and user agent
+	Sessions        int                       `json:"sessions"`
+	WatchMinutes    float64                   `json:"watch_minutes"`
+	AvgWatchMinutes float64                   `json:"avg_watch_minutes"` // per session
+	Protocols       map[string]ProtocolReport `json:"protocols"`
+}
+
+// ProtocolReport is the share of one output protocol in a Report.
+type ProtocolReport struct {
+	PeakViewers     int     `json:"peak_viewers"`
+	UniqueViewers   int     `json:"unique_viewers"`
+	Sessions        int     `json:"sessions"`
+	WatchMinutes    float64 `json:"watch_minutes"`
+	AvgWatchMinutes float64 `json:"avg_watch_minutes"`
+}
+
+// tally counts viewers for a broadcast or one of its protocols.
+type tally struct {
+	concurrent int
+	peak       int
+	sessions   int
+	uniques    map[string]struct{}
+	watch      time.Duration // of viewers that have left
+}
+
+// newTally returns an empty tally.
+func newTally() *tally { return &tally{uniques: make(map[string]struct{})} }
+
+// join counts one more viewer and reports whether it set a new peak.
+func (t *tally) join(identity string) bool {
+	t.concurrent++
+	t.sessions++
+	t.uniques[identity] = struct{}{}
+	if t.concurrent > t.peak {
+		t.peak = t.concurrent
+		return true
+	}
+	return false
+}
+
+// protocolReport summarises t, adding open watch time.
+func (t *tally) protocolReport(open time.Duration) ProtocolReport {
+	p := ProtocolReport{
+		PeakViewers:   t.peak,
+		UniqueViewers: len(t.uniques),
+		Sessions:      t.sessions,
+		WatchMinutes:  (t.watch + open).Minutes(),
+	}
+	if t.sessions > 0 {
+		p.AvgWatchMinutes = p.WatchMinutes / float64(t.sessions)
+	}
+	return p
+}
+
+// broadcast is one publish-to-unpublish span of a stream.
+type broadcast struct {
+	started time.Time
+	peakAt  time.Time
+	total   *tally
+	byProto map[string]*tally
+}
+
+// newBroadcast starts a broadcast at now.
+func newBroadcast(now time.Time) *broadcast {
+	return &broadcast{started: now, total: newTally(), byProto: make(map[string]*tally)}
+}
+
+// join counts v from at.
+func (b *broadcast) join(v *viewer, at time.Time) {
+	if b.total.join(v.identity) {
+		b.peakAt = at
+	}
+	p := b.byProto[v.protocol]
+	if p == nil {
+		p = newTally()
+		b.byProto[v.protocol] = p
+	}
+	p.join(v.identity)
+}
+
+// leave adds v's watch time up to end.
+func (b *broadcast) leave(v *viewer, end time.Time) {
+	d := max(end.Sub(v.since), 0)
+	b.total.concurrent--
+	b.total.watch += d
+	p := b.byProto[v.protocol]
+	p.concurrent--
+	p.watch += d
+}
+
+// report summarises b as of now; viewers are the stream's open sessions,
+// whose watch time so far is included.
+func (b *broadcast) report(key bus.StreamKey, now time.Time, viewers map[string]*viewer) Report {
+	open := make(map[string]time.Duration)
+	var openTotal time.Duration
+	for _, v := range viewers {
+		d := max(now.Sub(v.since), 0)
+		open[v.protocol] += d
+		openTotal += d
+	}
+	t := b.total.protocolReport(openTotal)
+	rep := Report{
+		App:             key.App,
+		Name:            key.Name,
+		Live:            true,
+		Started:         b.started,
+		Viewers:         b.total.concurrent,
+		PeakViewers:     t.PeakViewers,
+		UniqueViewers:   t.UniqueViewers,
+		Sessions:        t.Sessions,
+		WatchMinutes:    t.WatchMinutes,
+		AvgWatchMinutes: t.AvgWatchMinutes,
+		Protocols:       make(map[string]ProtocolReport, len(b.byProto)),
+	}
+	if t.PeakViewers > 0 {
+		peakAt := b.peakAt
+		rep.PeakAt = &peakAt
+	}
+	for proto, p := range b.byProto {
+		rep.Protocols[proto] = p.protocolReport(open[proto])
+	}
+	return rep
+}
+
+// csvHeader is the first row of a CSV report.
+var csvHeader = []string{"app", "name", "started", "ended", "protocol", "peak_viewers",
+	"unique_viewers", "sessions", "watch_minutes", "avg_watch_minutes"}
+
+// WriteCSV writes rep as CSV: a row for all protocols ("all") and one per
+// protocol.
+func WriteCSV(w io.Writer, rep Report) error {
+	ended := ""
+	if rep.Ended != nil {
+		ended = rep.Ended.UTC().Format(time.RFC3339)
+	}
+	row := func(proto string, p ProtocolReport) []string {
+		return []string{rep.App, rep.Name, rep.Started.UTC().Format(time.RFC3339), ended, proto,
+			strconv.Itoa(p.PeakViewers), strconv.Itoa(p.UniqueViewers), strconv.Itoa(p.Sessions),
+			strconv.FormatFloat(p.WatchMinutes, 'f', 2, 64), strconv.FormatFloat(p.AvgWatchMinutes, 'f', 2, 64)}
+	}
+	cw := csv.NewWriter(w)
+	_ = cw.Write(csvHeader)
+	_ = cw.Write(row("all", ProtocolReport{
+		PeakViewers:     rep.PeakViewers,
+		UniqueViewers:   rep.UniqueViewers,
+		Sessions:        rep.Sessions,
+		WatchMinutes:    rep.WatchMinutes,
+		AvgWatchMinutes: rep.AvgWatchMinutes,
+	}))
+	protos := make([]string, 0, len(rep.Protocols))
+	for proto := range rep.Protocols {
+		protos = append(protos, proto)
+	}
+	sort.Strings(protos)
+	for _, proto := range protos {
+		_ = cw.Write(row(proto, rep.Protocols[proto]))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// export writes rep to dir as {app}_{name}_{started}.json and .csv.
+// Stream names are validated, so they are safe in a file name.
+func export(dir string, rep Report) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	base := filepath.Join(dir, fmt.Sprintf("%s_%s_%s", rep.App, rep.Name, rep.Started.UTC().Format("20060102T150405Z")))
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(base+".json", data, 0o644); err != nil {
+		return err
+	}
+	f, err := os.Create(base + ".csv")
+	if err != nil {
+		return err
+	}
+	if err := WriteCSV(f, rep); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,189 @@
+// If you are AI: This file implements bearer-token authentication with roles
+// for the admin surface (/api/*, /debug/pprof/*) plus the audit log for
+// mutating admin calls.
+
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Role is an admin privilege level. Higher roles include the lower ones.
+type Role int
+
+const (
+	// RoleNone grants nothing; it is the role of unauthenticated callers.
+	RoleNone Role = iota
+	// RoleRead may call read-only endpoints.
+	RoleRead
+	// RoleOperate may additionally restart and toggle relays.
+	RoleOperate
+	// RoleAdmin may additionally change configuration and profile the process.
+	RoleAdmin
+)
+
+// ParseRole converts "read", "operate" or "admin" into a Role.
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "read":
+		return RoleRead, nil
+	case "operate":
+		return RoleOperate, nil
+	case "admin":
+		return RoleAdmin, nil
+	}
+	return RoleNone, fmt.Errorf("unknown role %q (want read, operate or admin)", s)
+}
+
+// String returns the config spelling of r.
+func (r Role) String() string {
+	switch r {
+	case RoleRead:
+		return "read"
+	case RoleOperate:
+		return "operate"
+	case RoleAdmin:
+		return "admin"
+	}
+	return "none"
+}
+
+// AdminToken is one configured bearer token. Name identifies the holder in
+// audit lines and never the secret itself.
+type AdminToken struct {
+	Name  string
+	Token string
+	Role  Role
+}
+
+// AdminPolicy maps bearer tokens to roles. A nil *AdminPolicy means admin
+// auth is disabled and every caller is treated as RoleAdmin, which keeps
+// configs without an admin section backward compatible.
+type AdminPolicy struct {
+	tokens []AdminToken
+}
+
+// NewAdminPolicy builds a policy from tokens, skipping blank secrets.
+// Returns nil when no tokens remain.
+func NewAdminPolicy(tokens []AdminToken) *AdminPolicy {
+	p := &AdminPolicy{}
+	for _, t := range tokens {
+		if strings.TrimSpace(t.Token) == "" {
+			continue
+		}
+		p.tokens = append(p.tokens, t)
+	}
+	if len(p.tokens) == 0 {
+		return nil
+	}
+	return p
+}
+
+// Authenticate returns the name and role of the caller presenting r's bearer
+// token. Unknown or missing tokens yield ("", RoleNone).
+func (p *AdminPolicy) Authenticate(r *http.Request) (string, Role) {
+	if p == nil {
+		return "anonymous", RoleAdmin
+	}
+	got := []byte(BearerToken(r))
+	if len(got) == 0 {
+		return "", RoleNone
+	}
+	for _, t := range p.tokens {
+		if subtle.ConstantTimeCompare(got, []byte(t.Token)) == 1 {
+			return t.Name, t.Role
+		}
+	}
+	return "", RoleNone
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer <token>"
+// header, or returns "".
+func BearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(h) < len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(h[len(prefix):])
+}
+
+// Admin returns middleware that requires RoleRead for GET/HEAD requests and
+// write for every other method. Mutating calls are audit-logged with the
+// caller, method, path, remote address and resulting status, whether or not
+// auth is enabled.
+func Admin(p *AdminPolicy, write Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		need := write
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			need = RoleRead
+		}
+		serveWithRole(p, need, next, w, r)
+	})
+}
+
+// RequireRole returns middleware that requires role for every method.
+func RequireRole(p *AdminPolicy, role Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWithRole(p, role, next, w, r)
+	})
+}
+
+// serveWithRole checks the caller's role and serves or rejects the request.
+// 401 means no valid token, 403 means a valid token with too small a role.
+func serveWithRole(p *AdminPolicy, need Role, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	name, role := p.Authenticate(r)
+	mutating := r.Method != http.MethodGet && r.Method != http.MethodHead
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	switch {
+	case role == RoleNone:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="nonchalant"`)
+		http.Error(rec, "unauthorized", http.StatusUnauthorized)
+	case role < need:
+		http.Error(rec, "forbidden: requires "+need.String()+" role", http.StatusForbidden)
+	default:
+		next.ServeHTTP(rec, r)
+	}
+	if mutating {
+		if name == "" {
+			name = "-"
+		}
+		log.Printf("audit: user=%s role=%s %s %s from %s -> %d",
+			name, role, r.Method, r.URL.Path, r.RemoteAddr, rec.status)
+	}
+}
+
+// statusRecorder captures the response status for the audit log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status before forwarding it.
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (s *statusRecorder) Unwrap() http.ResponseWriter { return s.ResponseWriter }
+
+// BearerGate returns middleware that requires a bearer token accepted by ks
+// (used for the optional /metrics scrape token). A nil ks is a pass-through.
+func BearerGate(ks *KeySet, next http.Handler) http.Handler {
+	if ks == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ks.Allow(BearerToken(r)) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="nonchalant"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,92 @@
+// If you are AI: Unit tests for admin bearer-token roles and middleware.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseRole covers the config spellings and rejects unknown roles.
+func TestParseRole(t *testing.T) {
+	for _, r := range []Role{RoleRead, RoleOperate, RoleAdmin} {
+		got, err := ParseRole(r.String())
+		if err != nil || got != r {
+			t.Errorf("ParseRole(%q) = %v, %v", r.String(), got, err)
+		}
+	}
+	if _, err := ParseRole("root"); err == nil {
+		t.Error("expected error for unknown role")
+	}
+}
+
+// TestAdminMiddleware checks 401/403/200 by role and method.
+func TestAdminMiddleware(t *testing.T) {
+	p := NewAdminPolicy([]AdminToken{
+		{Name: "grafana", Token: "r-secret", Role: RoleRead},
+		{Name: "oncall", Token: "o-secret", Role: RoleOperate},
+	})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := Admin(p, RoleOperate, ok)
+
+	cases := []struct {
+		method, token string
+		want          int
+	}{
+		{http.MethodGet, "", http.StatusUnauthorized},
+		{http.MethodGet, "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "r-secret", http.StatusOK},
+		{http.MethodPost, "r-secret", http.StatusForbidden},
+		{http.MethodPost, "o-secret", http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/api/relay/restart", nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Errorf("%s with %q: got %d, want %d", c.method, c.token, w.Code, c.want)
+		}
+	}
+
+	admin := RequireRole(p, RoleAdmin, ok)
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+	req.Header.Set("Authorization", "Bearer o-secret")
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("pprof with operate token: got %d, want 403", w.Code)
+	}
+}
+
+// TestAdminDisabled: a nil policy leaves everything open.
+func TestAdminDisabled(t *testing.T) {
+	if NewAdminPolicy([]AdminToken{{Name: "x", Token: " ", Role: RoleAdmin}}) != nil {
+		t.Fatal("blank tokens should produce a nil policy")
+	}
+	h := RequireRole(nil, RoleAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/relay/targets", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("nil policy: got %d, want 200", w.Code)
+	}
+}
+
+// TestBearerGate covers the /metrics scrape token.
+func TestBearerGate(t *testing.T) {
+	h := BearerGate(NewKeySet([]string{"scrape"}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "nope": http.StatusUnauthorized, "scrape": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("token %q: got %d, want %d", token, w.Code, want)
+		}
+	}
+}
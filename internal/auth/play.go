@@ -1,9 +1,16 @@
 // If you are AI: This file provides an HTTP middleware that enforces play-side
 // pre-shared-key authentication for HTTP-FLV / WS-FLV / HLS / DASH endpoints.
+// The key a request was admitted with is its subject for viewer analytics.
 
 package auth
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
+
+// subjectKey is the request context key of the admitted play key.
+type subjectKey struct{}
 
 // Gate returns an http.Handler that enforces ks against the "key" query
 // parameter before delegating to next. If ks is nil the gate is a pass-through
@@ -14,10 +21,18 @@ func Gate(ks *KeySet, next http.Handler) http.Handler {
 		return next
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !ks.Allow(r.URL.Query().Get("key")) {
+		key := r.URL.Query().Get("key")
+		if !ks.Allow(key) {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), subjectKey{}, key)))
 	})
 }
+
+// Subject returns the play key Gate admitted r with, or "" when playback
+// is anonymous.
+func Subject(r *http.Request) string {
+	key, _ := r.Context().Value(subjectKey{}).(string)
+	return key
+}
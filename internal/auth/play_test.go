@@ -0,0 +1,35 @@
+// If you are AI: Unit tests for the play-key gate and the subject it
+// passes on.
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestGateSubject admits a configured key and exposes it as the subject;
+// anonymous playback has none, even with a key in the URL.
+func TestGateSubject(t *testing.T) {
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = Subject(r) })
+
+	h := Gate(NewKeySet([]string{"alice", "bob"}), next)
+	for url, want := range map[string]int{"/live/a.flv": http.StatusUnauthorized, "/live/a.flv?key=eve": http.StatusUnauthorized, "/live/a.flv?key=bob": http.StatusOK} {
+		got = ""
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		if w.Code != want {
+			t.Errorf("%s: got %d, want %d", url, w.Code, want)
+		}
+		if want == http.StatusOK && got != "bob" {
+			t.Errorf("subject = %q, want bob", got)
+		}
+	}
+
+	Gate(nil, next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/live/a.flv?key=bob", nil))
+	if got != "" {
+		t.Errorf("anonymous subject = %q", got)
+	}
+}
@@ -0,0 +1,201 @@
+// If you are AI: This file implements the capacity limiter: global and
+// per-app caps on publishing streams, subscribers and egress bitrate, checked
+// against live bus state when a publish or playback starts.
+
+package capacity
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// Refusal reasons returned by AllowPlay.
+const (
+	ReasonStreamSubscribers = "max_subscribers_per_stream"
+	ReasonSubscribers       = "max_subscribers"
+	ReasonEgress            = "max_egress_kbps"
+)
+
+// sampleInterval is how often egress meters are turned into rates.
+const sampleInterval = time.Second
+
+// Limits caps one scope (the whole server or one app). Zero means unlimited.
+type Limits struct {
+	MaxStreams              int // streams with a publisher
+	MaxSubscribersPerStream int // HTTP-FLV / WS-FLV viewers of one stream
+	MaxSubscribers          int // HTTP-FLV / WS-FLV viewers in the scope
+	MaxEgressKbps           int // media bytes sent to viewers, kbit/s
+}
+
+// Limiter enforces global and per-app Limits. Per-app limits are extra caps
+// on that app's share; an app's MaxSubscribersPerStream replaces the global
+// one. A nil *Limiter allows everything.
+type Limiter struct {
+	registry *bus.Registry
+	global   Limits
+	apps     map[string]Limits
+
+	mu       sync.Mutex
+	total    *Meter
+	meters   map[string]*Meter // by app
+	reserved map[string]int    // publish slots claimed but not yet attached, by app
+
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// New returns a limiter over reg. Call Start to begin metering egress.
+func New(reg *bus.Registry, global Limits, apps map[string]Limits) *Limiter {
+	return &Limiter{
+		registry: reg,
+		global:   global,
+		apps:     apps,
+		total:    &Meter{},
+		meters:   make(map[string]*Meter),
+		reserved: make(map[string]int),
+	}
+}
+
+// ReservePublish claims a slot for one more publishing stream in app. The
+// slot counts against the caps until release is called, which the caller
+// does once its publisher is attached to the bus (or it gives up), so
+// concurrent publishes cannot all pass the same check.
+func (l *Limiter) ReservePublish(app string) (release func(), ok bool) {
+	if l == nil {
+		return func() {}, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.snapshot()
+	total := 0
+	for _, n := range l.reserved {
+		total += n
+	}
+	if !under(u.Streams+total, l.global.MaxStreams) ||
+		!under(u.Apps[app].Streams+l.reserved[app], l.apps[app].MaxStreams) {
+		return nil, false
+	}
+	l.reserved[app]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.reserved[app]--; l.reserved[app] == 0 {
+				delete(l.reserved, app)
+			}
+		})
+	}, true
+}
+
+// AllowPlay checks a playback request for app/name and returns "" or the
+// refusal reason. subscribes is true for requests that attach a bus
+// subscriber (HTTP-FLV, WS-FLV); other requests only face the egress caps.
+func (l *Limiter) AllowPlay(app, name string, subscribes bool) string {
+	if l == nil {
+		return ""
+	}
+	if !under(int(l.total.Kbps()), l.global.MaxEgressKbps) ||
+		!under(int(l.appKbps(app)), l.apps[app].MaxEgressKbps) {
+		return ReasonEgress
+	}
+	if !subscribes {
+		return ""
+	}
+	perStream := l.global.MaxSubscribersPerStream
+	if n := l.apps[app].MaxSubscribersPerStream; n > 0 {
+		perStream = n
+	}
+	if s := l.registry.Get(bus.NewStreamKey(app, name)); s != nil && !under(s.SubscriberCount(), perStream) {
+		return ReasonStreamSubscribers
+	}
+	u := l.snapshot()
+	if !under(u.Subscribers, l.global.MaxSubscribers) || !under(u.Apps[app].Subscribers, l.apps[app].MaxSubscribers) {
+		return ReasonSubscribers
+	}
+	return ""
+}
+
+// Meter returns the egress meter for playback of app/name; bytes added to
+// it also count toward the global total. Only configured apps and apps of
+// existing streams get a meter of their own, so requests for made-up
+// names cannot grow the table; the rest count toward the total alone.
+func (l *Limiter) Meter(app, name string) *Meter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if m, ok := l.meters[app]; ok {
+		return m
+	}
+	m := &Meter{parent: l.total}
+	if _, ok := l.apps[app]; ok || l.registry.Get(bus.NewStreamKey(app, name)) != nil {
+		l.meters[app] = m
+	}
+	return m
+}
+
+// appKbps returns app's egress rate, 0 when it has no meter.
+func (l *Limiter) appKbps(app string) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if m, ok := l.meters[app]; ok {
+		return m.Kbps()
+	}
+	return 0
+}
+
+// Start samples the egress meters every second until Stop.
+func (l *Limiter) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.stopped = make(chan struct{})
+	go func() {
+		defer close(l.stopped)
+		tick := time.NewTicker(sampleInterval)
+		defer tick.Stop()
+		last := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-tick.C:
+				l.sample(now.Sub(last))
+				last = now
+			}
+		}
+	}()
+}
+
+// Stop ends the sampling loop, if running, and waits for it.
+func (l *Limiter) Stop() {
+	if l == nil || l.cancel == nil {
+		return
+	}
+	l.cancel()
+	<-l.stopped
+}
+
+// sample updates every meter's rate over elapsed and drops the meters of
+// unconfigured apps that sent nothing and have no streams left.
+func (l *Limiter) sample(elapsed time.Duration) {
+	live := make(map[string]bool)
+	for _, key := range l.registry.List() {
+		live[key.App] = true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total.sample(elapsed)
+	for app, m := range l.meters {
+		m.sample(elapsed)
+		if _, ok := l.apps[app]; !ok && !live[app] && m.Kbps() == 0 {
+			delete(l.meters, app)
+		}
+	}
+}
+
+// under reports whether n is below limit (0 = unlimited).
+func under(n, limit int) bool {
+	return limit <= 0 || n < limit
+}
@@ -0,0 +1,158 @@
+// If you are AI: Unit tests for the capacity limiter against a real bus
+// registry.
+
+package capacity
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// publish creates app/name with a publisher and n subscribers.
+func publish(reg *bus.Registry, app, name string, n int) {
+	s, _ := reg.GetOrCreate(bus.NewStreamKey(app, name))
+	s.AttachPublisher(1)
+	for i := 0; i < n; i++ {
+		s.AttachSubscriber(0, bus.BackpressureDropOldest)
+	}
+}
+
+// canPublish reports whether l would admit a publish in app, giving the slot
+// straight back.
+func canPublish(l *Limiter, app string) bool {
+	release, ok := l.ReservePublish(app)
+	if ok {
+		release()
+	}
+	return ok
+}
+
+// TestLimits checks stream, subscriber and per-app caps.
+func TestLimits(t *testing.T) {
+	reg := bus.NewRegistry()
+	l := New(reg, Limits{MaxStreams: 3, MaxSubscribersPerStream: 2, MaxSubscribers: 5},
+		map[string]Limits{"vip": {MaxStreams: 1, MaxSubscribersPerStream: 5}})
+
+	publish(reg, "live", "a", 2)
+	publish(reg, "vip", "b", 2)
+	if !canPublish(l, "live") {
+		t.Error("third stream refused under max_streams 3")
+	}
+	if canPublish(l, "vip") {
+		t.Error("second vip stream allowed over the app's max_streams 1")
+	}
+	if got := l.AllowPlay("live", "a", true); got != ReasonStreamSubscribers {
+		t.Errorf("full stream: %q, want %q", got, ReasonStreamSubscribers)
+	}
+	if got := l.AllowPlay("live", "a", false); got != "" {
+		t.Errorf("segment request on a full stream: %q, want allowed", got)
+	}
+	if got := l.AllowPlay("vip", "b", true); got != "" {
+		t.Errorf("vip stream under its own per-stream cap: %q", got)
+	}
+	publish(reg, "live", "c", 1)
+	if canPublish(l, "live") {
+		t.Error("fourth stream allowed over max_streams 3")
+	}
+
+	u := l.Usage()
+	if u.Streams != 3 || u.Subscribers != 5 || u.BusiestStream != 2 || u.MaxStreams != 3 {
+		t.Errorf("usage = %+v", u.Usage)
+	}
+	if v := u.Apps["vip"]; v.Streams != 1 || v.MaxStreams != 1 || v.MaxSubscribersPerStream != 5 {
+		t.Errorf("vip usage = %+v", v)
+	}
+	if got := l.AllowPlay("live", "c", true); got != ReasonSubscribers {
+		t.Errorf("server full: %q, want %q", got, ReasonSubscribers)
+	}
+}
+
+// TestReservePublish checks that concurrent publishes cannot overshoot
+// max_streams and that a released slot is free again.
+func TestReservePublish(t *testing.T) {
+	l := New(bus.NewRegistry(), Limits{MaxStreams: 3}, nil)
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		releases []func()
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if release, ok := l.ReservePublish("live"); ok {
+				mu.Lock()
+				releases = append(releases, release)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(releases) != 3 {
+		t.Fatalf("%d concurrent publishes reserved a slot, want 3", len(releases))
+	}
+	releases[0]()
+	releases[0]() // a second release is a no-op
+	if _, ok := l.ReservePublish("live"); !ok {
+		t.Error("released slot still taken")
+	}
+	if _, ok := l.ReservePublish("other"); ok {
+		t.Error("reservation allowed with max_streams already claimed")
+	}
+}
+
+// TestEgress checks that metered bytes become a rate and trip the ceiling.
+func TestEgress(t *testing.T) {
+	l := New(bus.NewRegistry(), Limits{MaxEgressKbps: 1000}, map[string]Limits{"live": {MaxEgressKbps: 100}})
+	l.Meter("live", "x").Add(25_000) // 200 kbit in one second
+	l.sample(time.Second)
+	if got := l.AllowPlay("live", "x", false); got != ReasonEgress {
+		t.Errorf("app over its ceiling: %q, want %q", got, ReasonEgress)
+	}
+	if got := l.AllowPlay("other", "x", false); got != "" {
+		t.Errorf("other app under the global ceiling: %q", got)
+	}
+	if k := l.Usage().EgressKbps; k != 200 {
+		t.Errorf("global egress = %v kbit/s, want 200", k)
+	}
+
+	var nilLimiter *Limiter
+	if !canPublish(nilLimiter, "live") || nilLimiter.AllowPlay("live", "x", true) != "" {
+		t.Error("nil limiter must allow everything")
+	}
+}
+
+// TestMetersBounded keeps meters only for configured apps and apps with
+// streams, and forgets the latter once they go idle.
+func TestMetersBounded(t *testing.T) {
+	reg := bus.NewRegistry()
+	l := New(reg, Limits{}, map[string]Limits{"live": {MaxEgressKbps: 100}})
+	for i := 0; i < 100; i++ {
+		app := fmt.Sprintf("junk%d", i)
+		l.AllowPlay(app, "x", true)
+		l.Meter(app, "x").Add(10)
+	}
+	if len(l.meters) != 0 {
+		t.Fatalf("%d meters for apps without streams", len(l.meters))
+	}
+	if k := l.total.bytes.Load(); k != 1000 {
+		t.Errorf("global total = %d bytes, want 1000", k)
+	}
+
+	reg.GetOrCreate(bus.NewStreamKey("tv", "x"))
+	l.Meter("tv", "x").Add(10)
+	l.Meter("live", "y")
+	if len(l.meters) != 2 {
+		t.Fatalf("meters = %v, want tv and live", l.meters)
+	}
+	reg.Remove(bus.NewStreamKey("tv", "x"))
+	l.sample(time.Second) // tv sent bytes in this interval
+	l.sample(time.Second)
+	if _, ok := l.meters["tv"]; ok || len(l.meters) != 1 {
+		t.Errorf("meters after tv went idle = %v, want live only", l.meters)
+	}
+}
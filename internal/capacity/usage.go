@@ -0,0 +1,109 @@
+// If you are AI: This file implements the egress meter and the usage report
+// shown on /api/server.
+
+package capacity
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Meter counts media bytes sent to viewers. Add is a single atomic add, so
+// it is cheap enough to call on every write.
+type Meter struct {
+	parent *Meter
+	bytes  atomic.Uint64
+	last   uint64        // bytes at the previous sample; sampler only
+	kbps   atomic.Uint64 // float64 bits of the last sampled rate
+}
+
+// Add records n bytes sent.
+func (m *Meter) Add(n int) {
+	m.bytes.Add(uint64(n))
+	if m.parent != nil {
+		m.parent.bytes.Add(uint64(n))
+	}
+}
+
+// Kbps returns the egress rate over the last sample interval.
+func (m *Meter) Kbps() float64 {
+	return math.Float64frombits(m.kbps.Load())
+}
+
+// sample turns the bytes added since the previous sample into a rate.
+func (m *Meter) sample(elapsed time.Duration) {
+	b := m.bytes.Load()
+	rate := float64(b-m.last) * 8 / 1000 / elapsed.Seconds()
+	m.last = b
+	m.kbps.Store(math.Float64bits(rate))
+}
+
+// Usage is the current load of one scope next to its limits (0 = unlimited).
+type Usage struct {
+	Streams                 int     `json:"streams"`
+	MaxStreams              int     `json:"max_streams,omitempty"`
+	Subscribers             int     `json:"subscribers"`
+	MaxSubscribers          int     `json:"max_subscribers,omitempty"`
+	BusiestStream           int     `json:"busiest_stream_subscribers"`
+	MaxSubscribersPerStream int     `json:"max_subscribers_per_stream,omitempty"`
+	EgressKbps              float64 `json:"egress_kbps"`
+	MaxEgressKbps           int     `json:"max_egress_kbps,omitempty"`
+}
+
+// Report is the global Usage plus one Usage per app that has streams or a
+// limit.
+type Report struct {
+	Usage
+	Apps map[string]Usage `json:"apps,omitempty"`
+}
+
+// Usage reports current load against every limit.
+func (l *Limiter) Usage() Report {
+	r := l.snapshot()
+	r.Usage = withLimits(r.Usage, l.global)
+	r.EgressKbps = l.total.Kbps()
+	for app := range l.apps {
+		if _, ok := r.Apps[app]; !ok {
+			r.Apps[app] = Usage{}
+		}
+	}
+	for app, u := range r.Apps {
+		u = withLimits(u, l.apps[app])
+		u.EgressKbps = l.appKbps(app)
+		r.Apps[app] = u
+	}
+	return r
+}
+
+// withLimits copies lim into u's limit fields.
+func withLimits(u Usage, lim Limits) Usage {
+	u.MaxStreams = lim.MaxStreams
+	u.MaxSubscribers = lim.MaxSubscribers
+	u.MaxSubscribersPerStream = lim.MaxSubscribersPerStream
+	u.MaxEgressKbps = lim.MaxEgressKbps
+	return u
+}
+
+// snapshot counts publishing streams and subscribers, globally and by app.
+func (l *Limiter) snapshot() Report {
+	r := Report{Apps: make(map[string]Usage)}
+	for _, key := range l.registry.List() {
+		s := l.registry.Get(key)
+		if s == nil {
+			continue
+		}
+		u := r.Apps[key.App]
+		subs := s.SubscriberCount()
+		u.Subscribers += subs
+		r.Subscribers += subs
+		u.BusiestStream = max(u.BusiestStream, subs)
+		r.BusiestStream = max(r.BusiestStream, subs)
+		if s.HasPublisher() {
+			u.Streams++
+			r.Streams++
+		}
+		r.Apps[key.App] = u
+	}
+	return r
+}
@@ -0,0 +1,198 @@
+// If you are AI: This file implements the TLS certificate store shared by the
+// HTTPS and RTMPS listeners. Certificates are chosen by SNI and reloaded from
+// disk when their files change, so renewals need no restart.
+
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pair names a PEM certificate chain and its private key on disk.
+type Pair struct {
+	CertFile string
+	KeyFile  string
+}
+
+// stamp identifies one version of a Pair's files.
+type stamp struct {
+	certMod, keyMod   time.Time
+	certSize, keySize int64
+}
+
+// entry is one loaded certificate and the file versions it came from.
+type entry struct {
+	pair  Pair
+	cert  *tls.Certificate
+	stamp stamp
+}
+
+// Store serves certificates by SNI server name, falling back to the default
+// pair. It is safe for concurrent use.
+type Store struct {
+	mu     sync.RWMutex
+	def    *entry
+	byName map[string]*entry // lower-cased server name or "*.domain"
+
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// NewStore loads the default pair and every SNI pair. Any unreadable or
+// mismatched pair is an error, so a bad config fails at startup rather than
+// on the first handshake.
+func NewStore(def Pair, sni map[string]Pair) (*Store, error) {
+	s := &Store{byName: make(map[string]*entry, len(sni))}
+	var err error
+	if s.def, err = load(def); err != nil {
+		return nil, err
+	}
+	for name, p := range sni {
+		e, err := load(p)
+		if err != nil {
+			return nil, fmt.Errorf("sni %q: %w", name, err)
+		}
+		s.byName[strings.ToLower(name)] = e
+	}
+	return s, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate: an exact server name
+// match wins, then a "*.domain" wildcard, then the default pair.
+func (s *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if e, ok := s.byName[name]; ok {
+		return e.cert, nil
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if e, ok := s.byName["*"+name[i:]]; ok {
+			return e.cert, nil
+		}
+	}
+	return s.def.cert, nil
+}
+
+// TLSConfig returns a server config that takes certificates from s.
+// NextProtos defaults to HTTP/1.1 only, so HTTP-FLV can keep hijacking the
+// connection; callers that offer HTTP/2 override it. RTMPS ignores ALPN.
+func (s *Store) TLSConfig(minVersion uint16) *tls.Config {
+	return &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: s.GetCertificate,
+		NextProtos:     []string{"http/1.1"},
+	}
+}
+
+// Watch polls the certificate files every interval and reloads any pair
+// whose files changed. A pair that fails to load keeps serving its previous
+// certificate. Call Stop to end the loop.
+func (s *Store) Watch(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.stopped = make(chan struct{})
+	go func() {
+		defer close(s.stopped)
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				s.Reload()
+			}
+		}
+	}()
+}
+
+// Stop ends the Watch loop, if running, and waits for it.
+func (s *Store) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.stopped
+}
+
+// Reload re-reads every pair whose files changed since they were last loaded
+// and returns how many were replaced.
+func (s *Store) Reload() int {
+	s.mu.RLock()
+	entries := map[string]*entry{"": s.def}
+	for name, e := range s.byName {
+		entries[name] = e
+	}
+	s.mu.RUnlock()
+
+	n := 0
+	for name, old := range entries {
+		st, err := statPair(old.pair)
+		if err != nil || st == old.stamp {
+			continue
+		}
+		e, err := load(old.pair)
+		if err != nil {
+			// Often a renewal caught halfway through writing; the next poll
+			// sees the finished files.
+			log.Printf("tls: reload %s: %v (keeping previous certificate)", old.pair.CertFile, err)
+			continue
+		}
+		s.mu.Lock()
+		if name == "" {
+			s.def = e
+		} else {
+			s.byName[name] = e
+		}
+		s.mu.Unlock()
+		log.Printf("tls: reloaded %s", old.pair.CertFile)
+		n++
+	}
+	return n
+}
+
+// load reads and parses a pair along with the file versions it read.
+func load(p Pair) (*entry, error) {
+	st, err := statPair(p)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(p.CertFile, p.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", p.CertFile, err)
+	}
+	return &entry{pair: p, cert: &cert, stamp: st}, nil
+}
+
+// statPair returns the modification times and sizes of a pair's files.
+func statPair(p Pair) (stamp, error) {
+	ci, err := os.Stat(p.CertFile)
+	if err != nil {
+		return stamp{}, err
+	}
+	ki, err := os.Stat(p.KeyFile)
+	if err != nil {
+		return stamp{}, err
+	}
+	return stamp{certMod: ci.ModTime(), keyMod: ki.ModTime(), certSize: ci.Size(), keySize: ki.Size()}, nil
+}
+
+// ParseVersion converts a config min_version ("1.2" or "1.3"; "" means 1.2)
+// into a tls.Version constant.
+func ParseVersion(v string) (uint16, error) {
+	switch v {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q (want 1.2 or 1.3)", v)
+}
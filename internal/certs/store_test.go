@@ -0,0 +1,143 @@
+// If you are AI: Unit tests for the certificate store: SNI selection and
+// reloading certificates when their files change.
+
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePair writes a self-signed certificate for cn into dir and returns
+// its Pair.
+func writePair(t *testing.T, dir, cn string) Pair {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Pair{CertFile: filepath.Join(dir, cn+".crt"), KeyFile: filepath.Join(dir, cn+".key")}
+	if err := os.WriteFile(p.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// commonName returns the subject CN of the leaf in c.
+func commonName(t *testing.T, c *tls.Certificate) string {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(c.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+// TestGetCertificateSNI checks exact, wildcard and default selection.
+func TestGetCertificateSNI(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(writePair(t, dir, "default.test"), map[string]Pair{
+		"live.example.com": writePair(t, dir, "live.example.com"),
+		"*.cdn.example":    writePair(t, dir, "wild.cdn.example"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"live.example.com":  "live.example.com",
+		"LIVE.example.com.": "live.example.com",
+		"eu.cdn.example":    "wild.cdn.example",
+		"other.test":        "default.test",
+		"":                  "default.test",
+	}
+	for sni, want := range cases {
+		c, err := s.GetCertificate(&tls.ClientHelloInfo{ServerName: sni})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := commonName(t, c); got != want {
+			t.Errorf("SNI %q served %q, want %q", sni, got, want)
+		}
+	}
+}
+
+// TestReload checks that a rewritten pair is picked up and that a broken
+// rewrite keeps the previous certificate.
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	p := writePair(t, dir, "old.test")
+	s, err := NewStore(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Reload(); n != 0 {
+		t.Fatalf("Reload with unchanged files replaced %d pairs", n)
+	}
+
+	// Replace the files in place with a new certificate.
+	fresh := writePair(t, dir, "renewed.test")
+	for src, dst := range map[string]string{fresh.CertFile: p.CertFile, fresh.KeyFile: p.KeyFile} {
+		if err := os.Rename(src, dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.Reload(); n != 1 {
+		t.Fatalf("Reload after rewrite replaced %d pairs, want 1", n)
+	}
+	c, _ := s.GetCertificate(&tls.ClientHelloInfo{})
+	if got := commonName(t, c); got != "renewed.test" {
+		t.Fatalf("served %q after reload, want renewed.test", got)
+	}
+
+	// A half-written certificate must not replace the working one.
+	if err := os.WriteFile(p.CertFile, []byte("-----BEGIN CERTIFICATE-----\ngarbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.Reload(); n != 0 {
+		t.Fatalf("broken rewrite replaced %d pairs", n)
+	}
+	c, _ = s.GetCertificate(&tls.ClientHelloInfo{})
+	if got := commonName(t, c); got != "renewed.test" {
+		t.Fatalf("served %q after broken rewrite, want renewed.test", got)
+	}
+}
+
+// TestParseVersion covers the accepted spellings.
+func TestParseVersion(t *testing.T) {
+	for in, want := range map[string]uint16{"": tls.VersionTLS12, "1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13} {
+		if got, err := ParseVersion(in); err != nil || got != want {
+			t.Errorf("ParseVersion(%q) = %x, %v", in, got, err)
+		}
+	}
+	if _, err := ParseVersion("1.0"); err == nil {
+		t.Error("ParseVersion(1.0) should fail")
+	}
+}
@@ -0,0 +1,51 @@
+// If you are AI: This file defines the access section: per-app CIDR allow /
+// deny lists, per-IP connection and connect-rate limits, and the handshake
+// deadline that drops slowloris clients.
+
+package config
+
+import (
+	"fmt"
+	"net"
+)
+
+// AccessConfig restricts who may connect and how much. Rules apply per app
+// and direction; limits apply per client IP across every listener.
+// HandshakeTimeoutSeconds bounds the RTMP handshake plus connect command
+// and the time an HTTP client has to send its request headers. Loopback
+// clients are exempt from rules and limits.
+type AccessConfig struct {
+	Rules                   []AccessRule `yaml:"rules,omitempty"`
+	MaxConnsPerIP           int          `yaml:"max_conns_per_ip,omitempty"`          // 0 = unlimited
+	ConnectRate             float64      `yaml:"connect_rate,omitempty"`              // New connections per second per IP (0 = unlimited)
+	ConnectBurst            int          `yaml:"connect_burst,omitempty"`             // Token bucket size (default connect_rate)
+	HandshakeTimeoutSeconds int          `yaml:"handshake_timeout_seconds,omitempty"` // Default 10
+}
+
+// AccessRule holds the CIDR lists for one app ("*" or empty = every app).
+// A deny match refuses the client; a non-empty allow list refuses
+// everyone it does not match.
+type AccessRule struct {
+	App          string   `yaml:"app,omitempty"`
+	PublishAllow []string `yaml:"publish_allow,omitempty"`
+	PublishDeny  []string `yaml:"publish_deny,omitempty"`
+	PlayAllow    []string `yaml:"play_allow,omitempty"`
+	PlayDeny     []string `yaml:"play_deny,omitempty"`
+}
+
+// Validate checks that limits are non-negative and every CIDR parses.
+func (a *AccessConfig) Validate() error {
+	if a.MaxConnsPerIP < 0 || a.ConnectRate < 0 || a.ConnectBurst < 0 || a.HandshakeTimeoutSeconds < 0 {
+		return fmt.Errorf("limits and timeouts must not be negative")
+	}
+	for i, r := range a.Rules {
+		for _, list := range [][]string{r.PublishAllow, r.PublishDeny, r.PlayAllow, r.PlayDeny} {
+			for _, c := range list {
+				if _, _, err := net.ParseCIDR(c); err != nil {
+					return fmt.Errorf("rules[%d] (app %q): %w", i, r.App, err)
+				}
+			}
+		}
+	}
+	return nil
+}
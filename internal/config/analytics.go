@@ -0,0 +1,22 @@
+// If you are AI: This file defines the analytics section: where finished
+// broadcasts' viewer reports are written.
+
+package config
+
+import "fmt"
+
+// AnalyticsConfig exports a viewer report (peak and unique viewers, watch
+// time per protocol) as JSON and CSV files in ExportDir whenever a
+// broadcast ends. Without the section reports are kept in memory and
+// served by /api/analytics only.
+type AnalyticsConfig struct {
+	ExportDir string `yaml:"export_dir"` // Created if missing
+}
+
+// Validate checks that an export directory is set.
+func (c *AnalyticsConfig) Validate() error {
+	if c.ExportDir == "" {
+		return fmt.Errorf("export_dir is required")
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// If you are AI: This file defines the capacity section: global and per-app
+// caps on publishing streams, viewers and egress bitrate.
+
+package config
+
+import "fmt"
+
+// CapacityConfig caps load so one popular stream cannot starve the rest.
+// The top-level limits apply to the whole server; Apps adds caps for one
+// app's share (its max_subscribers_per_stream replaces the global one).
+// Zero means unlimited. Refused viewers get 503 with a Retry-After of
+// RetryAfterSeconds; refused publishers get NetStream.Publish.Rejected.
+type CapacityConfig struct {
+	CapacityLimits    `yaml:",inline"`
+	Apps              map[string]CapacityLimits `yaml:"apps,omitempty"`
+	RetryAfterSeconds int                       `yaml:"retry_after_seconds,omitempty"` // Default 10
+}
+
+// CapacityLimits is one set of caps.
+type CapacityLimits struct {
+	MaxStreams              int `yaml:"max_streams,omitempty"`                // Streams with a publisher
+	MaxSubscribersPerStream int `yaml:"max_subscribers_per_stream,omitempty"` // HTTP-FLV / WS-FLV viewers of one stream
+	MaxSubscribers          int `yaml:"max_subscribers,omitempty"`            // HTTP-FLV / WS-FLV viewers in total
+	MaxEgressKbps           int `yaml:"max_egress_kbps,omitempty"`            // Media sent to viewers, kbit/s
+}
+
+// Validate checks that no limit is negative.
+func (c *CapacityConfig) Validate() error {
+	if c.RetryAfterSeconds < 0 {
+		return fmt.Errorf("retry_after_seconds must not be negative")
+	}
+	if err := c.CapacityLimits.validate(); err != nil {
+		return err
+	}
+	for app, l := range c.Apps {
+		if err := l.validate(); err != nil {
+			return fmt.Errorf("apps[%q]: %w", app, err)
+		}
+	}
+	return nil
+}
+
+// validate checks one set of caps.
+func (l CapacityLimits) validate() error {
+	if l.MaxStreams < 0 || l.MaxSubscribersPerStream < 0 || l.MaxSubscribers < 0 || l.MaxEgressKbps < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+// If you are AI: This file defines the captions section: per-app decoding
+// of CEA-608 / CEA-708 captions from the H.264 SEI of published streams.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// CaptionsConfig decodes the closed captions of streams published to one
+// app; the "*" key covers apps without their own entry. Decoded captions
+// reach FLV viewers as onCaptionInfo script tags and HLS / DASH players
+// as a WebVTT subtitle rendition named Name in Language.
+type CaptionsConfig struct {
+	Language string `yaml:"language,omitempty"` // BCP 47 tag; default "en"
+	Name     string `yaml:"name,omitempty"`     // Rendition name; default "Captions"
+}
+
+// languageTag matches a BCP 47 language tag.
+var languageTag = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`)
+
+// Validate checks the language tag and that the name fits a quoted
+// playlist attribute.
+func (c CaptionsConfig) Validate() error {
+	if c.Language != "" && !languageTag.MatchString(c.Language) {
+		return fmt.Errorf("language %q is not a BCP 47 tag", c.Language)
+	}
+	if strings.ContainsAny(c.Name, "\"<>&\r\n") {
+		return fmt.Errorf("name must not contain quotes, markup or line breaks")
+	}
+	return nil
+}
@@ -14,11 +14,24 @@ import (
 // Config holds the complete server configuration.
 // All fields must have explicit defaults or be required.
 type Config struct {
-	Server    ServerConfig     `yaml:"server"`
-	Auth      AuthConfig       `yaml:"auth,omitempty"`
-	HLS       HLSConfig        `yaml:"hls,omitempty"`
-	Relays    []RelayConfig    `yaml:"relays,omitempty"`
-	Transcode *TranscodeConfig `yaml:"transcode,omitempty"`
+	Server        ServerConfig                    `yaml:"server"`
+	Auth          AuthConfig                      `yaml:"auth,omitempty"`
+	HLS           HLSConfig                       `yaml:"hls,omitempty"`
+	Relays        []RelayConfig                   `yaml:"relays,omitempty"`
+	Edge          *EdgeConfig                     `yaml:"edge,omitempty"`
+	Cluster       *ClusterConfig                  `yaml:"cluster,omitempty"`
+	Admin         AdminConfig                     `yaml:"admin,omitempty"`
+	TLS           *TLSConfig                      `yaml:"tls,omitempty"`
+	Proxy         *ProxyConfig                    `yaml:"proxy_protocol,omitempty"`
+	Access        AccessConfig                    `yaml:"access,omitempty"`
+	Capacity      *CapacityConfig                 `yaml:"capacity,omitempty"`
+	PublishPolicy map[string]PublishPolicyConfig  `yaml:"publish_policy,omitempty"`
+	StreamNames   map[string]string               `yaml:"stream_names,omitempty"` // app ("*" = rest) -> name regexp
+	Transcode     *TranscodeConfig                `yaml:"transcode,omitempty"`
+	Analytics     *AnalyticsConfig                `yaml:"analytics,omitempty"`
+	Watchdog      map[string]IngestWatchdogConfig `yaml:"ingest_watchdog,omitempty"`
+	Timestamps    map[string]TimestampConfig      `yaml:"timestamps,omitempty"`
+	Captions      map[string]CaptionsConfig       `yaml:"captions,omitempty"`
 }
 
 // HLSConfig tunes the native HLS / DASH packager.
@@ -59,19 +72,131 @@ type AuthConfig struct {
 }
 
 // ServerConfig defines HTTP server settings.
+// Layout "combined" (the default, and the historical behaviour) serves
+// health, API, metrics and pprof next to media on HTTPPort. Layout "split"
+// moves them to a dedicated admin listener on HealthPort bound to AdminBind,
+// leaving HTTPPort with media and /healthz only.
 type ServerConfig struct {
-	HealthPort int `yaml:"health_port"` // Port for health endpoint
-	HTTPPort   int `yaml:"http_port"`   // Port for future HTTP services
-	RTMPPort   int `yaml:"rtmp_port"`   // Port for future RTMP service
+	HealthPort int    `yaml:"health_port"`          // Admin listener port (split layout)
+	HTTPPort   int    `yaml:"http_port"`            // Public media port
+	RTMPPort   int    `yaml:"rtmp_port"`            // RTMP ingest port
+	Layout     string `yaml:"layout,omitempty"`     // "combined" (default) or "split"
+	AdminBind  string `yaml:"admin_bind,omitempty"` // Admin listener address, e.g. 127.0.0.1 (default all)
 }
 
 // RelayConfig defines a relay task configuration.
+// Push relays may list several Targets to simulcast one stream to many
+// remotes; RemoteURL/Reconnect remain as the single-destination shorthand.
+// Pull relays may list ordered RemoteURLs (primary first) for failover.
 type RelayConfig struct {
-	App       string `yaml:"app"`                 // Application name
-	Name      string `yaml:"name"`                // Stream name
-	Mode      string `yaml:"mode"`                // "pull" or "push"
-	RemoteURL string `yaml:"remote_url"`          // Remote RTMP URL
-	Reconnect bool   `yaml:"reconnect,omitempty"` // Enable reconnect on failure
+	App             string       `yaml:"app"`                        // Application name
+	Name            string       `yaml:"name"`                       // Stream name
+	Mode            string       `yaml:"mode"`                       // "pull" or "push"
+	RemoteURL       string       `yaml:"remote_url,omitempty"`       // Remote RTMP URL
+	RemoteURLs      []string     `yaml:"remote_urls,omitempty"`      // Pull only: primary, then fallbacks
+	FailbackSeconds int          `yaml:"failback_seconds,omitempty"` // Pull only: primary stable period before returning (default 30)
+	StallSeconds    int          `yaml:"stall_seconds,omitempty"`    // Pull only: no-media timeout before failing over (default 10)
+	Reconnect       bool         `yaml:"reconnect,omitempty"`        // Enable reconnect on failure
+	Targets         []PushTarget `yaml:"targets,omitempty"`          // Push only: simulcast destinations
+}
+
+// PushTarget is one destination of a push relay. Each target runs its own
+// ffmpeg with its own reconnect/backoff loop, so a failing destination never
+// affects its siblings or local viewers. ID must be unique per stream and is
+// how the API addresses the target.
+type PushTarget struct {
+	ID                string `yaml:"id"`                            // Unique per (app, name)
+	URL               string `yaml:"url"`                           // Remote RTMP URL
+	Reconnect         bool   `yaml:"reconnect,omitempty"`           // Retry after ffmpeg exits
+	MaxBackoffSeconds int    `yaml:"max_backoff_seconds,omitempty"` // Retry backoff ceiling (default 5)
+	Disabled          bool   `yaml:"disabled,omitempty"`            // Configured but not started
+}
+
+// EdgeConfig turns the server into an edge: a viewer asking for a stream
+// that is not live locally triggers a pull from an origin, and the pull is
+// torn down once the last viewer has been gone for IdleTTLSeconds.
+// Origin and Origins values are URL templates in which "{app}" and "{name}"
+// are substituted, e.g. "http://origin:8081/{app}/{name}.flv". Origins maps
+// an app to its own origin and takes precedence over Origin.
+type EdgeConfig struct {
+	Origin         string            `yaml:"origin,omitempty"`           // Default origin URL template
+	Origins        map[string]string `yaml:"origins,omitempty"`          // Per-app origin URL templates
+	IdleTTLSeconds int               `yaml:"idle_ttl_seconds,omitempty"` // Keep-alive after the last viewer (default 30)
+	WaitSeconds    int               `yaml:"wait_seconds,omitempty"`     // Max wait for the first frame (default 10)
+}
+
+// ClusterConfig joins this node to a static set of peers. Nodes exchange
+// heartbeats listing the streams published on them, so a viewer who reaches
+// the wrong node is redirected (Mode "redirect", the default) or served
+// through an internal pull from the owning node (Mode "proxy").
+// AdvertiseURL is the base URL peers and viewers use to reach this node's
+// HTTP port, e.g. "http://10.0.0.1:8081"; Peers lists the other nodes' base
+// URLs. Secret is required and must match on every node.
+type ClusterConfig struct {
+	NodeID             string   `yaml:"node_id"`                        // Unique per node
+	AdvertiseURL       string   `yaml:"advertise_url"`                  // Base URL of this node
+	Peers              []string `yaml:"peers"`                          // Base URLs of the other nodes
+	Secret             string   `yaml:"secret,omitempty"`               // Shared heartbeat secret
+	Mode               string   `yaml:"mode,omitempty"`                 // "redirect" (default) or "proxy"
+	HeartbeatSeconds   int      `yaml:"heartbeat_seconds,omitempty"`    // Heartbeat interval (default 2)
+	PeerTimeoutSeconds int      `yaml:"peer_timeout_seconds,omitempty"` // Peer considered down after (default 3 heartbeats)
+}
+
+// AdminConfig protects the admin surface. When Tokens is empty /api/* and
+// /debug/pprof/* stay open as before. Roles are "read" (GET endpoints),
+// "operate" (restart / enable / disable relays) and "admin" (change relay
+// targets, pprof). MetricsToken, when set, must be presented as a bearer
+// token to scrape /metrics.
+type AdminConfig struct {
+	Tokens       []AdminToken `yaml:"tokens,omitempty"`
+	MetricsToken string       `yaml:"metrics_token,omitempty"`
+}
+
+// AdminToken is one bearer token for the admin API.
+type AdminToken struct {
+	Name  string `yaml:"name"`  // Shown in audit log lines
+	Token string `yaml:"token"` // Bearer secret
+	Role  string `yaml:"role"`  // "read", "operate" or "admin"
+}
+
+// TLSConfig enables HTTPS and RTMPS. The plain HTTP and RTMP listeners keep
+// running (relays and the packager loop back through them); HTTPSPort serves
+// the same routes as http_port over TLS and RTMPSPort accepts RTMP over TLS.
+// SNI maps a server name, or a "*.domain" wildcard, to its own certificate;
+// other names get CertFile. Certificate files are polled every
+// ReloadSeconds and swapped in when they change, without a restart.
+// HTTP2 adds h2 to ALPN on https_port; HTTP3Port serves HTTP/3 over QUIC
+// (UDP, so it may share the https_port number) and is advertised to HTTPS
+// clients with Alt-Svc.
+type TLSConfig struct {
+	CertFile      string             `yaml:"cert_file"`                // PEM certificate chain
+	KeyFile       string             `yaml:"key_file"`                 // PEM private key
+	MinVersion    string             `yaml:"min_version,omitempty"`    // "1.2" (default) or "1.3"
+	SNI           map[string]TLSPair `yaml:"sni,omitempty"`            // Per-server-name certificates
+	HTTPSPort     int                `yaml:"https_port,omitempty"`     // HTTPS listener (0 = off)
+	RTMPSPort     int                `yaml:"rtmps_port,omitempty"`     // RTMPS listener (0 = off)
+	HTTP2         bool               `yaml:"http2,omitempty"`          // Offer HTTP/2 on https_port
+	HTTP3Port     int                `yaml:"http3_port,omitempty"`     // HTTP/3 (QUIC, UDP) listener (0 = off)
+	ReloadSeconds int                `yaml:"reload_seconds,omitempty"` // Certificate poll interval (default 10)
+}
+
+// TLSPair is a certificate and key used for one SNI server name.
+type TLSPair struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// ProxyConfig accepts HAProxy PROXY protocol v1/v2 headers from a TCP load
+// balancer so sessions, the API and logs see the real client address. Only
+// peers in TrustedCIDRs may send a header, and for them it is optional
+// (direct health checks keep working). RTMP covers the RTMP and RTMPS
+// listeners, HTTP the HTTP and HTTPS ones; the admin listener never
+// accepts headers.
+type ProxyConfig struct {
+	RTMP                 bool     `yaml:"rtmp,omitempty"`                   // RTMP / RTMPS listeners
+	HTTP                 bool     `yaml:"http,omitempty"`                   // HTTP / HTTPS listeners
+	TrustedCIDRs         []string `yaml:"trusted_cidrs"`                    // Load balancer source ranges
+	HeaderTimeoutSeconds int      `yaml:"header_timeout_seconds,omitempty"` // Max wait for the header (default 5)
 }
 
 // TranscodeConfig defines transcoding configuration.
@@ -123,4 +248,10 @@ func (c *Config) setDefaults() {
 	if c.Server.RTMPPort == 0 {
 		c.Server.RTMPPort = 1935
 	}
+	if c.Server.Layout == "" {
+		c.Server.Layout = "combined"
+	}
+	if c.Access.HandshakeTimeoutSeconds == 0 {
+		c.Access.HandshakeTimeoutSeconds = 10
+	}
 }
@@ -0,0 +1,20 @@
+// If you are AI: This file validates the stream_names section: per-app
+// regular expressions that stream names must match.
+
+package config
+
+import (
+	"fmt"
+
+	"nonchalant/internal/core/bus"
+)
+
+// validateStreamNames checks that every stream_names pattern compiles.
+// Patterns match the whole name and narrow the built-in rules (letters,
+// digits, '_', '-', '.', at most 128 bytes, no ".."), which always apply.
+func validateStreamNames(patterns map[string]string) error {
+	if _, err := bus.NewNamePolicy(patterns); err != nil {
+		return fmt.Errorf("stream_names: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// If you are AI: This file defines the publish_policy section: per-app
+// limits on the codecs, resolution, frame rate and bitrate a publisher
+// may send.
+
+package config
+
+import (
+	"fmt"
+
+	"nonchalant/internal/core/protocol/flv"
+)
+
+// PublishPolicyConfig restricts what publishers to one app may send; the
+// "*" key covers apps without their own entry. Codecs, size and frame rate
+// are read from the AVC sequence header and onMetaData, and a violation
+// gets NetStream.Publish.Rejected and a disconnect. Bitrate is averaged
+// over BitrateWindowSeconds and may stay over the cap for
+// BitrateGraceSeconds before the publisher is disconnected. Zero or empty
+// fields are not checked.
+type PublishPolicyConfig struct {
+	VideoCodecs          []string `yaml:"video_codecs,omitempty"` // e.g. ["h264"]
+	AudioCodecs          []string `yaml:"audio_codecs,omitempty"` // e.g. ["aac"]
+	MaxWidth             int      `yaml:"max_width,omitempty"`
+	MaxHeight            int      `yaml:"max_height,omitempty"`
+	MaxFPS               float64  `yaml:"max_fps,omitempty"`
+	MaxBitrateKbps       int      `yaml:"max_bitrate_kbps,omitempty"`
+	BitrateWindowSeconds int      `yaml:"bitrate_window_seconds,omitempty"` // Default 5
+	BitrateGraceSeconds  int      `yaml:"bitrate_grace_seconds,omitempty"`  // Default 10
+}
+
+// Validate checks codec names and that no limit is negative.
+func (p PublishPolicyConfig) Validate() error {
+	if p.MaxWidth < 0 || p.MaxHeight < 0 || p.MaxFPS < 0 || p.MaxBitrateKbps < 0 ||
+		p.BitrateWindowSeconds < 0 || p.BitrateGraceSeconds < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	for _, c := range append(append([]string{}, p.VideoCodecs...), p.AudioCodecs...) {
+		if !flv.KnownCodec(c) {
+			return fmt.Errorf("unknown codec %q", c)
+		}
+	}
+	return nil
+}
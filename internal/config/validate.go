@@ -4,6 +4,8 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 )
 
 // Validate checks that all configuration values are within acceptable ranges.
@@ -15,6 +17,195 @@ func (c *Config) Validate() error {
 	if err := c.HLS.Validate(); err != nil {
 		return fmt.Errorf("hls config: %w", err)
 	}
+	if c.Cluster != nil {
+		if err := c.Cluster.Validate(); err != nil {
+			return fmt.Errorf("cluster config: %w", err)
+		}
+	}
+	if err := c.Admin.Validate(); err != nil {
+		return fmt.Errorf("admin config: %w", err)
+	}
+	if c.Edge != nil {
+		if err := c.Edge.Validate(); err != nil {
+			return fmt.Errorf("edge config: %w", err)
+		}
+	}
+	if c.TLS != nil {
+		if err := c.TLS.Validate(&c.Server); err != nil {
+			return fmt.Errorf("tls config: %w", err)
+		}
+	}
+	if c.Proxy != nil {
+		if err := c.Proxy.Validate(); err != nil {
+			return fmt.Errorf("proxy_protocol config: %w", err)
+		}
+	}
+	if err := c.Access.Validate(); err != nil {
+		return fmt.Errorf("access config: %w", err)
+	}
+	if c.Capacity != nil {
+		if err := c.Capacity.Validate(); err != nil {
+			return fmt.Errorf("capacity config: %w", err)
+		}
+	}
+	if c.Analytics != nil {
+		if err := c.Analytics.Validate(); err != nil {
+			return fmt.Errorf("analytics config: %w", err)
+		}
+	}
+	for app, w := range c.Watchdog {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("ingest_watchdog[%q]: %w", app, err)
+		}
+	}
+	for app, ts := range c.Timestamps {
+		if err := ts.Validate(); err != nil {
+			return fmt.Errorf("timestamps[%q]: %w", app, err)
+		}
+	}
+	for app, cc := range c.Captions {
+		if err := cc.Validate(); err != nil {
+			return fmt.Errorf("captions[%q]: %w", app, err)
+		}
+	}
+	for app, p := range c.PublishPolicy {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("publish_policy[%q]: %w", app, err)
+		}
+	}
+	return validateStreamNames(c.StreamNames)
+}
+
+// Validate checks PROXY protocol configuration: at least one listener
+// group and a non-empty list of valid CIDRs, since trusting every source
+// would let any client spoof its address.
+func (p *ProxyConfig) Validate() error {
+	if !p.RTMP && !p.HTTP {
+		return fmt.Errorf("enable rtmp and/or http")
+	}
+	if len(p.TrustedCIDRs) == 0 {
+		return fmt.Errorf("trusted_cidrs is required")
+	}
+	for i, c := range p.TrustedCIDRs {
+		if _, _, err := net.ParseCIDR(c); err != nil {
+			return fmt.Errorf("trusted_cidrs[%d]: %w", i, err)
+		}
+	}
+	if p.HeaderTimeoutSeconds < 0 {
+		return fmt.Errorf("header_timeout_seconds must not be negative")
+	}
+	return nil
+}
+
+// Validate checks TLS configuration: a default certificate, at least one
+// TLS listener on a port no other listener uses, and a known min_version.
+func (t *TLSConfig) Validate(s *ServerConfig) error {
+	if t.CertFile == "" || t.KeyFile == "" {
+		return fmt.Errorf("cert_file and key_file are required")
+	}
+	for name, p := range t.SNI {
+		if name == "" || p.CertFile == "" || p.KeyFile == "" {
+			return fmt.Errorf("sni %q: server name, cert_file and key_file are required", name)
+		}
+	}
+	if t.MinVersion != "" && t.MinVersion != "1.2" && t.MinVersion != "1.3" {
+		return fmt.Errorf("min_version must be \"1.2\" or \"1.3\", got %q", t.MinVersion)
+	}
+	if t.HTTPSPort == 0 && t.RTMPSPort == 0 {
+		return fmt.Errorf("https_port or rtmps_port is required")
+	}
+	used := map[int]string{s.HTTPPort: "http_port", s.RTMPPort: "rtmp_port"}
+	if s.Layout == "split" {
+		used[s.HealthPort] = "health_port"
+	}
+	for _, p := range []struct {
+		name string
+		port int
+	}{{"https_port", t.HTTPSPort}, {"rtmps_port", t.RTMPSPort}} {
+		if p.port == 0 {
+			continue
+		}
+		if p.port < 0 || p.port > 65535 {
+			return fmt.Errorf("%s must be between 1 and 65535, got %d", p.name, p.port)
+		}
+		if other, dup := used[p.port]; dup {
+			return fmt.Errorf("%s and %s must be different, both are %d", p.name, other, p.port)
+		}
+		used[p.port] = p.name
+	}
+	if (t.HTTP2 || t.HTTP3Port != 0) && t.HTTPSPort == 0 {
+		return fmt.Errorf("http2 and http3_port require https_port")
+	}
+	if t.HTTP3Port < 0 || t.HTTP3Port > 65535 {
+		return fmt.Errorf("http3_port must be between 1 and 65535, got %d", t.HTTP3Port)
+	}
+	if t.ReloadSeconds < 0 {
+		return fmt.Errorf("reload_seconds must not be negative")
+	}
+	return nil
+}
+
+// Validate checks cluster configuration.
+func (c *ClusterConfig) Validate() error {
+	if c.NodeID == "" {
+		return fmt.Errorf("node_id is required")
+	}
+	if c.AdvertiseURL == "" {
+		return fmt.Errorf("advertise_url is required")
+	}
+	if c.Secret == "" {
+		return fmt.Errorf("secret is required")
+	}
+	for i, p := range c.Peers {
+		u, err := url.Parse(p)
+		if p == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return fmt.Errorf("peers[%d] %q must be an http(s) base URL", i, p)
+		}
+	}
+	if c.Mode != "" && c.Mode != "redirect" && c.Mode != "proxy" {
+		return fmt.Errorf("mode must be \"redirect\" or \"proxy\", got %q", c.Mode)
+	}
+	if c.HeartbeatSeconds < 0 || c.PeerTimeoutSeconds < 0 {
+		return fmt.Errorf("heartbeat_seconds and peer_timeout_seconds must not be negative")
+	}
+	return nil
+}
+
+// Validate checks admin tokens: each needs a name, a secret and a known role,
+// and names must be unique so audit lines are unambiguous.
+func (a *AdminConfig) Validate() error {
+	seen := make(map[string]struct{}, len(a.Tokens))
+	for i, t := range a.Tokens {
+		if t.Name == "" || t.Token == "" {
+			return fmt.Errorf("tokens[%d]: name and token are required", i)
+		}
+		switch t.Role {
+		case "read", "operate", "admin":
+		default:
+			return fmt.Errorf("tokens[%d] %q: role must be read, operate or admin", i, t.Name)
+		}
+		if _, dup := seen[t.Name]; dup {
+			return fmt.Errorf("tokens[%d]: duplicate name %q", i, t.Name)
+		}
+		seen[t.Name] = struct{}{}
+	}
+	return nil
+}
+
+// Validate checks edge configuration: at least one origin, no empty
+// templates, and non-negative timers.
+func (e *EdgeConfig) Validate() error {
+	if e.Origin == "" && len(e.Origins) == 0 {
+		return fmt.Errorf("origin or origins is required")
+	}
+	for app, tmpl := range e.Origins {
+		if app == "" || tmpl == "" {
+			return fmt.Errorf("origins: app and URL template must be non-empty")
+		}
+	}
+	if e.IdleTTLSeconds < 0 || e.WaitSeconds < 0 {
+		return fmt.Errorf("idle_ttl_seconds and wait_seconds must not be negative")
+	}
 	return nil
 }
 
@@ -84,5 +275,8 @@ func (s *ServerConfig) Validate() error {
 	if s.HTTPPort == s.RTMPPort {
 		return fmt.Errorf("http_port and rtmp_port must be different, both are %d", s.HTTPPort)
 	}
+	if s.Layout != "" && s.Layout != "combined" && s.Layout != "split" {
+		return fmt.Errorf("layout must be \"combined\" or \"split\", got %q", s.Layout)
+	}
 	return nil
 }
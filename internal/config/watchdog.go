@@ -0,0 +1,54 @@
+// If you are AI: This file defines the ingest_watchdog section (per-app
+// thresholds for stalls, missing keyframes, audio-only publishes,
+// timestamp anomalies and bitrate collapse) and the timestamps section
+// that normalizes publisher timestamps.
+
+package config
+
+import "fmt"
+
+// IngestWatchdogConfig watches publishers to one app; the "*" key covers
+// apps without their own entry. Each tripped check emits an ingest_alert
+// event and counts in nonchalant_ingest_alerts_total; with Disconnect the
+// publisher is also dropped so the encoder reconnects. Zero fields are
+// not checked.
+type IngestWatchdogConfig struct {
+	NoMediaSeconds       int  `yaml:"no_media_seconds,omitempty"`       // No audio or video
+	NoKeyframeSeconds    int  `yaml:"no_keyframe_seconds,omitempty"`    // Video without a keyframe
+	NoVideoSeconds       int  `yaml:"no_video_seconds,omitempty"`       // Video gone, audio continues
+	TimestampJumpSeconds int  `yaml:"timestamp_jump_seconds,omitempty"` // Forward jump on one track
+	MinBitrateKbps       int  `yaml:"min_bitrate_kbps,omitempty"`
+	BitrateWindowSeconds int  `yaml:"bitrate_window_seconds,omitempty"` // Default 5
+	Disconnect           bool `yaml:"disconnect,omitempty"`
+}
+
+// Validate checks that no threshold is negative.
+func (w IngestWatchdogConfig) Validate() error {
+	if w.NoMediaSeconds < 0 || w.NoKeyframeSeconds < 0 || w.NoVideoSeconds < 0 ||
+		w.TimestampJumpSeconds < 0 || w.MinBitrateKbps < 0 || w.BitrateWindowSeconds < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	return nil
+}
+
+// TimestampConfig normalizes publisher timestamps for one app; the "*"
+// key covers apps without their own entry. Every configured app gets
+// monotonic DTS per track, 32-bit wrap handling and smoothed jumps.
+type TimestampConfig struct {
+	MaxJumpMs    int `yaml:"max_jump_ms,omitempty"`   // Larger steps are smoothed; default 3000
+	InterleaveMs int `yaml:"interleave_ms,omitempty"` // Re-interleave window; 0 = off
+}
+
+// maxInterleaveMs caps the re-interleave window, which delays every frame.
+const maxInterleaveMs = 2000
+
+// Validate checks the thresholds.
+func (t TimestampConfig) Validate() error {
+	if t.MaxJumpMs < 0 || t.InterleaveMs < 0 {
+		return fmt.Errorf("thresholds must not be negative")
+	}
+	if t.InterleaveMs > maxInterleaveMs {
+		return fmt.Errorf("interleave_ms must be at most %d", maxInterleaveMs)
+	}
+	return nil
+}
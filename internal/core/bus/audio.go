@@ -0,0 +1,48 @@
+// If you are AI: This file holds a stream's audio codec descriptor. The
+// bus does not parse payloads, so the publisher sets it from the audio
+// tags it forwards; outputs use it to decide whether the stream has
+// audio they can serve, for codecs with and without a sequence header.
+
+package bus
+
+// AudioCodec describes a stream's audio track.
+type AudioCodec struct {
+	Name       string `json:"name"` // flv codec name: "aac", "mp3", "pcma", "opus", ...
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Channels   int    `json:"channels,omitempty"`
+	NeedsInit  bool   `json:"needs_init"` // frames are undecodable without a sequence header
+}
+
+// SetAudioCodec records the publisher's audio codec. A change of codec
+// drops the cached audio sequence header, which belongs to the old one;
+// the publisher calls it before forwarding the new codec's first tag.
+func (s *Stream) SetAudioCodec(c AudioCodec) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.audioCodec == nil || s.audioCodec.Name != c.Name || !c.NeedsInit {
+		s.initAudio = nil
+	}
+	s.audioCodec = &c
+}
+
+// AudioCodec returns the stream's audio codec and false before the
+// publisher has sent audio.
+func (s *Stream) AudioCodec() (AudioCodec, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.audioCodec == nil {
+		return AudioCodec{}, false
+	}
+	return *s.audioCodec, true
+}
+
+// HasAudio reports whether a subscriber joining now can decode the
+// stream's audio: the codec is known and, if it needs one, its sequence
+// header is cached. Subscribers use this to set the FLV header's
+// has-audio flag — claiming audio when none is present makes ffmpeg's
+// analyzer hang in find_stream_info.
+func (s *Stream) HasAudio() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.audioCodec != nil && (!s.audioCodec.NeedsInit || s.initAudio != nil)
+}
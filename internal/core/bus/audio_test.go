@@ -0,0 +1,51 @@
+// If you are AI: Tests for the audio codec descriptor and how it decides
+// whether a stream has audio viewers can decode.
+
+package bus
+
+import "testing"
+
+// TestHasAudio needs a sequence header only for codecs that have one.
+func TestHasAudio(t *testing.T) {
+	s := NewStream(NewStreamKey("live", "a"))
+	s.AttachPublisher(1)
+	if s.HasAudio() {
+		t.Fatal("audio before any codec")
+	}
+	s.SetAudioCodec(AudioCodec{Name: "pcma", SampleRate: 8000, Channels: 1})
+	if !s.HasAudio() {
+		t.Fatal("G.711 needs no sequence header")
+	}
+	s.SetAudioCodec(AudioCodec{Name: "aac", NeedsInit: true})
+	if s.HasAudio() {
+		t.Fatal("AAC without its sequence header")
+	}
+	s.Publish(&MediaMessage{Type: MessageTypeAudio, Payload: []byte{0xAF, 0, 0x11, 0x90}, IsInit: true})
+	if !s.HasAudio() {
+		t.Fatal("AAC with its sequence header")
+	}
+	s.DetachPublisher()
+	if _, ok := s.AudioCodec(); ok || s.HasAudio() {
+		t.Fatal("codec kept after the publisher left")
+	}
+}
+
+// TestSetAudioCodecDropsInit forgets the old codec's sequence header when
+// the codec changes, and keeps it when only its parameters do.
+func TestSetAudioCodecDropsInit(t *testing.T) {
+	s := NewStream(NewStreamKey("live", "a"))
+	s.SetAudioCodec(AudioCodec{Name: "aac", NeedsInit: true})
+	s.Publish(&MediaMessage{Type: MessageTypeAudio, Payload: []byte{0xAF, 0, 0x11, 0x90}, IsInit: true})
+	s.SetAudioCodec(AudioCodec{Name: "aac", SampleRate: 48000, Channels: 2, NeedsInit: true})
+	if !s.HasAudioInit() {
+		t.Fatal("sequence header dropped for the same codec")
+	}
+	s.SetAudioCodec(AudioCodec{Name: "mp3", SampleRate: 44100, Channels: 2})
+	if s.HasAudioInit() {
+		t.Fatal("AAC sequence header kept for MP3")
+	}
+	sub, _ := s.AttachSubscriber(0, BackpressureDropOldest)
+	if msg, ok := sub.Read(); ok {
+		t.Fatalf("stale init replayed: %+v", msg)
+	}
+}
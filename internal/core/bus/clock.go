@@ -0,0 +1,50 @@
+// If you are AI: This file maps a stream's relative media timestamps to
+// wall-clock time. The anchor comes from the encoder (onFI, SEI picture
+// timing) when it sends one, else from the server's receive time.
+
+package bus
+
+import "time"
+
+// Clock sources.
+const (
+	ClockServer = "server" // receive time of the first frame
+	ClockOnFI   = "onfi"   // encoder onFI script data
+	ClockSEI    = "sei"    // H.264 picture timing SEI
+)
+
+// Clock anchors a stream's timeline: media Timestamp (ms) was captured at
+// Wall.
+type Clock struct {
+	Timestamp uint32    `json:"timestamp"`
+	Wall      time.Time `json:"wall"`
+	Source    string    `json:"source"`
+}
+
+// At returns the wall-clock time of media timestamp ts. Timestamps within
+// ~24 days of the anchor map either way, across a 32-bit wrap.
+func (c Clock) At(ts uint32) time.Time {
+	return c.Wall.Add(time.Duration(int32(ts-c.Timestamp)) * time.Millisecond)
+}
+
+// SetClock anchors the stream's timeline, replacing any earlier anchor.
+// Publishers call it when the encoder reports its own time.
+func (s *Stream) SetClock(c Clock) { s.clock.Store(&c) }
+
+// Clock returns the stream's wall-clock anchor and false before the first
+// frame.
+func (s *Stream) Clock() (Clock, bool) {
+	c := s.clock.Load()
+	if c == nil {
+		return Clock{}, false
+	}
+	return *c, true
+}
+
+// anchorClock sets a server-time anchor from the first frame unless one is
+// already set.
+func (s *Stream) anchorClock(msg *MediaMessage) {
+	if s.clock.Load() == nil {
+		s.clock.CompareAndSwap(nil, &Clock{Timestamp: msg.Timestamp, Wall: time.Unix(0, msg.Received), Source: ClockServer})
+	}
+}
@@ -0,0 +1,125 @@
+// If you are AI: This file carries timed metadata (onCuePoint, onTextData,
+// onCaptionInfo, ...) on a stream. Messages injected through the API are queued and published
+// by the publisher's own goroutine at the next frame, so the shared log
+// keeps a single producer and the metadata lands at the live point. The
+// stream also remembers recent timed metadata for the HLS / DASH packager.
+
+package bus
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNotLive is returned when metadata is injected into a stream without
+// a publisher.
+var ErrNotLive = errors.New("stream has no publisher")
+
+// maxCues is how many timed metadata messages of each name a stream
+// remembers, so frequent captions do not push out ad splices.
+const maxCues = 64
+
+// maxRecentCues bounds the recent cues of all names together, since a
+// publisher may send script data under any name.
+const maxRecentCues = 4 * maxCues
+
+// Cue is one timed metadata message. ID increases per stream.
+type Cue struct {
+	ID        uint64
+	Timestamp uint32
+	Payload   []byte // AMF0 script data: name, then value
+}
+
+// cueLog holds injected metadata waiting for the publisher and the recent
+// cues.
+type cueLog struct {
+	mu      sync.Mutex
+	queued  [][]byte
+	pending atomic.Bool // queued is non-empty; checked without the lock
+	recent  []Cue
+	nextID  uint64
+}
+
+// InjectMetadata queues an AMF0 script data payload to be published with
+// the stream's next frame. Returns ErrNotLive without a publisher.
+func (s *Stream) InjectMetadata(payload []byte) error {
+	if !s.HasPublisher() {
+		return ErrNotLive
+	}
+	s.cues.mu.Lock()
+	defer s.cues.mu.Unlock()
+	s.cues.queued = append(s.cues.queued, append([]byte(nil), payload...))
+	s.cues.pending.Store(true)
+	return nil
+}
+
+// Cues returns the recent timed metadata, oldest first.
+func (s *Stream) Cues() []Cue {
+	s.cues.mu.Lock()
+	defer s.cues.mu.Unlock()
+	return append([]Cue(nil), s.cues.recent...)
+}
+
+// publishQueued publishes injected metadata at ts. Called from Publish on
+// the publisher's goroutine.
+func (s *Stream) publishQueued(ts uint32) {
+	if !s.cues.pending.Load() {
+		return
+	}
+	s.cues.mu.Lock()
+	queued := s.cues.queued
+	s.cues.queued = nil
+	s.cues.pending.Store(false)
+	s.cues.mu.Unlock()
+	for _, p := range queued {
+		s.Publish(&MediaMessage{Type: MessageTypeMetadata, Timestamp: ts, Payload: p})
+	}
+}
+
+// recordCue remembers a published timed metadata message, forgetting the
+// oldest of its name past maxCues and the oldest of all past
+// maxRecentCues.
+func (s *Stream) recordCue(msg *MediaMessage) {
+	s.cues.mu.Lock()
+	defer s.cues.mu.Unlock()
+	s.cues.nextID++
+	cue := Cue{ID: s.cues.nextID, Timestamp: msg.Timestamp, Payload: append([]byte(nil), msg.Payload...)}
+	name, n, oldest := cueName(msg.Payload), 0, -1
+	for i, c := range s.cues.recent {
+		if bytes.Equal(cueName(c.Payload), name) {
+			if n++; oldest < 0 {
+				oldest = i
+			}
+		}
+	}
+	switch {
+	case n >= maxCues:
+		s.cues.recent = append(s.cues.recent[:oldest], s.cues.recent[oldest+1:]...)
+	case len(s.cues.recent) >= maxRecentCues:
+		s.cues.recent = append(s.cues.recent[:0], s.cues.recent[1:]...)
+	}
+	s.cues.recent = append(s.cues.recent, cue)
+}
+
+// cueName returns the encoded AMF0 name string a script payload starts
+// with, nil if it has none.
+func cueName(p []byte) []byte {
+	if len(p) < 3 || p[0] != 0x02 {
+		return nil
+	}
+	n := 3 + (int(p[1])<<8 | int(p[2]))
+	if n > len(p) {
+		return nil
+	}
+	return p[3:n]
+}
+
+// dropQueued discards metadata injected for a publisher that has left.
+func (s *Stream) dropQueued() {
+	s.cues.mu.Lock()
+	defer s.cues.mu.Unlock()
+	s.cues.queued = nil
+	s.cues.pending.Store(false)
+}
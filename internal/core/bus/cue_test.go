@@ -0,0 +1,69 @@
+// If you are AI: Tests for timed metadata: injection waits for the
+// publisher's next frame and takes its timestamp.
+
+package bus
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestInjectMetadata publishes injected metadata ahead of the next frame
+// and remembers it as a cue.
+func TestInjectMetadata(t *testing.T) {
+	stream := NewStream(NewStreamKey("live", "cue"))
+	if err := stream.InjectMetadata([]byte("cue")); !errors.Is(err, ErrNotLive) {
+		t.Fatalf("inject without publisher: %v, want ErrNotLive", err)
+	}
+	stream.AttachPublisher(1)
+	sub, _ := stream.AttachSubscriber(0, BackpressureDropOldest)
+	if err := stream.InjectMetadata([]byte("cue")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sub.Read(); ok {
+		t.Fatal("injected metadata published before a frame")
+	}
+
+	stream.Publish(&MediaMessage{Type: MessageTypeVideo, Timestamp: 4000, Payload: []byte{0x27, 1}})
+	first, ok := sub.Read()
+	if !ok || first.Type != MessageTypeMetadata || first.IsInit || first.Timestamp != 4000 || string(first.Payload) != "cue" {
+		t.Fatalf("first message = %+v, want metadata at 4000", first)
+	}
+	if next, ok := sub.Read(); !ok || next.Type != MessageTypeVideo {
+		t.Fatalf("second message = %+v, want the frame", next)
+	}
+	if _, ok := sub.Read(); ok {
+		t.Fatal("metadata published twice")
+	}
+
+	cues := stream.Cues()
+	if len(cues) != 1 || cues[0].ID != 1 || cues[0].Timestamp != 4000 || string(cues[0].Payload) != "cue" {
+		t.Fatalf("cues = %+v", cues)
+	}
+	if last, _ := stream.LastTimestamp(); last != 4000 {
+		t.Errorf("LastTimestamp = %d", last)
+	}
+}
+
+// TestCueLogPerName keeps the last maxCues of each name, so a burst of
+// one kind leaves the others, and maxRecentCues in all.
+func TestCueLogPerName(t *testing.T) {
+	stream := NewStream(NewStreamKey("live", "cues"))
+	named := func(name string) []byte { return append([]byte{0x02, 0, byte(len(name))}, name...) }
+	stream.recordCue(&MediaMessage{Timestamp: 1, Payload: named("onSCTE35")})
+	for i := 0; i < maxCues+10; i++ {
+		stream.recordCue(&MediaMessage{Timestamp: uint32(2 + i), Payload: named("onCaptionInfo")})
+	}
+	cues := stream.Cues()
+	if len(cues) != maxCues+1 || string(cueName(cues[0].Payload)) != "onSCTE35" || cues[1].Timestamp != 12 {
+		t.Errorf("%d cues, first %q at %d", len(cues), cueName(cues[0].Payload), cues[1].Timestamp)
+	}
+
+	for i := 0; i < maxRecentCues; i++ { // one each of many names
+		stream.recordCue(&MediaMessage{Timestamp: uint32(100 + i), Payload: named(fmt.Sprintf("on%d", i))})
+	}
+	if cues := stream.Cues(); len(cues) != maxRecentCues || cues[0].Timestamp != 100 {
+		t.Errorf("%d cues, first at %d", len(cues), cues[0].Timestamp)
+	}
+}
@@ -0,0 +1,86 @@
+// If you are AI: This file measures a stream's ingest on the publish path:
+// bitrate and frame rate over ~1 s of media time, GOP length, and the gap
+// between the latest audio and video timestamps. The publisher goroutine is
+// the only writer; readers see atomically published snapshots.
+
+package bus
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// IngestStats is a snapshot of what the publisher is sending.
+type IngestStats struct {
+	BitrateKbps float64 `json:"bitrate_kbps"`
+	FPS         float64 `json:"fps"`
+	GOPFrames   int     `json:"gop_frames"`  // frames from one keyframe to the next
+	GOPSeconds  float64 `json:"gop_seconds"` // time from one keyframe to the next
+	AVDriftMs   int64   `json:"av_drift_ms"` // latest video minus latest audio timestamp
+}
+
+// ingestWindow is the media time (ms) one bitrate / fps sample covers.
+const ingestWindow = 1000
+
+// ingestMeter accumulates IngestStats. Fields above the atomics are owned
+// by the publishing goroutine.
+type ingestMeter struct {
+	start, lastKey        uint32
+	started, haveKey      bool
+	bytes, frames, gop    int
+	lastVideo, lastAudio  uint32
+	haveVideo, haveAudio  bool
+	bitrate, fps, gopSecs atomic.Uint64 // float64 bits
+	gopFrames, drift      atomic.Int64
+}
+
+// observe accounts one non-init message.
+func (m *ingestMeter) observe(msg *MediaMessage) {
+	ts := msg.Timestamp
+	// Restart the window on the first message and on timestamp jumps;
+	// audio and video interleave slightly out of order, which is fine.
+	elapsed := int64(ts) - int64(m.start)
+	if !m.started || elapsed < -ingestWindow || elapsed > 5*ingestWindow {
+		m.start, m.started, m.bytes, m.frames, elapsed = ts, true, 0, 0, 0
+	}
+	if elapsed >= ingestWindow {
+		m.bitrate.Store(math.Float64bits(float64(m.bytes) * 8 / float64(elapsed)))
+		m.fps.Store(math.Float64bits(float64(m.frames) * 1000 / float64(elapsed)))
+		m.start, m.bytes, m.frames = ts, 0, 0
+	}
+	m.bytes += len(msg.Payload)
+
+	switch msg.Type {
+	case MessageTypeVideo:
+		m.frames++
+		if len(msg.Payload) > 0 && (msg.Payload[0]>>4)&0x07 == 1 { // keyframe
+			if m.haveKey && ts > m.lastKey {
+				m.gopFrames.Store(int64(m.gop))
+				m.gopSecs.Store(math.Float64bits(float64(ts-m.lastKey) / 1000))
+			}
+			m.lastKey, m.haveKey, m.gop = ts, true, 0
+		}
+		m.gop++
+		m.lastVideo, m.haveVideo = ts, true
+	case MessageTypeAudio:
+		m.lastAudio, m.haveAudio = ts, true
+	default:
+		return
+	}
+	if m.haveVideo && m.haveAudio {
+		m.drift.Store(int64(m.lastVideo) - int64(m.lastAudio))
+	}
+}
+
+// IngestStats returns the latest ingest measurements. Values are zero until
+// a second of media (or, for GOP, two keyframes) has been published.
+func (s *Stream) IngestStats() IngestStats {
+	m := &s.ingest
+	return IngestStats{
+		BitrateKbps: math.Float64frombits(m.bitrate.Load()),
+		FPS:         math.Float64frombits(m.fps.Load()),
+		GOPFrames:   int(m.gopFrames.Load()),
+		GOPSeconds:  math.Float64frombits(m.gopSecs.Load()),
+		AVDriftMs:   m.drift.Load(),
+	}
+}
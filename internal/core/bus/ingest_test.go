@@ -0,0 +1,40 @@
+// If you are AI: This file contains unit tests for ingest measurements.
+
+package bus
+
+import "testing"
+
+// TestIngestStats publishes 3 s of 25 fps video (a keyframe every 50
+// frames, 1000 bytes each) and 50 audio frames/s trailing video by 20 ms.
+func TestIngestStats(t *testing.T) {
+	s := NewStream(NewStreamKey("live", "x"))
+	for i := 0; i <= 75; i++ {
+		ts := uint32(1000 + i*40)
+		frame := byte(0x27)
+		if i%50 == 0 {
+			frame = 0x17
+		}
+		payload := make([]byte, 1000)
+		payload[0] = frame
+		s.Publish(&MediaMessage{Type: MessageTypeVideo, Timestamp: ts, Payload: payload})
+		s.Publish(&MediaMessage{Type: MessageTypeAudio, Timestamp: ts - 20, Payload: make([]byte, 0)})
+		s.Publish(&MediaMessage{Type: MessageTypeAudio, Timestamp: ts, Payload: make([]byte, 0)})
+	}
+	st := s.IngestStats()
+	if st.FPS < 24 || st.FPS > 26 {
+		t.Errorf("fps = %.2f, want ~25", st.FPS)
+	}
+	if st.BitrateKbps < 190 || st.BitrateKbps > 210 {
+		t.Errorf("bitrate = %.1f kbps, want ~200", st.BitrateKbps)
+	}
+	if st.GOPFrames != 50 || st.GOPSeconds != 2 {
+		t.Errorf("gop = %d frames / %.2fs, want 50 / 2s", st.GOPFrames, st.GOPSeconds)
+	}
+	if st.AVDriftMs != 0 {
+		t.Errorf("drift = %d, want 0", st.AVDriftMs)
+	}
+	s.Publish(&MediaMessage{Type: MessageTypeVideo, Timestamp: 4100, Payload: []byte{0x27}})
+	if d := s.IngestStats().AVDriftMs; d != 100 {
+		t.Errorf("drift = %d, want 100", d)
+	}
+}
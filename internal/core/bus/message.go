@@ -29,6 +29,7 @@ type MediaMessage struct {
 	Timestamp uint32      // Media timestamp in timebase units
 	Payload   []byte      // Media payload (owned by message, returned to pool on release)
 	IsInit    bool        // True for codec init data (AVC/AAC sequence headers) that late joiners need
+	Received  int64       // Unix nanoseconds when Stream.Publish accepted it; 0 on cached copies
 }
 
 // messagePool is a sync.Pool for MediaMessage instances.
@@ -48,6 +49,7 @@ func AcquireMessage() *MediaMessage {
 	msg.Timestamp = 0
 	msg.Payload = nil
 	msg.IsInit = false
+	msg.Received = 0
 	return msg
 }
 
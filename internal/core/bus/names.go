@@ -0,0 +1,89 @@
+// If you are AI: This file validates stream keys before they reach file
+// paths, URLs or ffmpeg arguments. Every key must pass the built-in safety
+// rules; a NamePolicy can narrow stream names further per app.
+
+package bus
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxNameLength bounds the app and stream name, in bytes.
+const MaxNameLength = 128
+
+// ErrInvalidName is wrapped by every validation failure.
+var ErrInvalidName = errors.New("invalid stream name")
+
+// Validate applies the built-in rules to both parts of k: 1 to
+// MaxNameLength bytes of letters, digits, '_', '-' and '.', and no "..".
+// This excludes path separators and URL metacharacters.
+func (k StreamKey) Validate() error {
+	if err := validName(k.App); err != nil {
+		return fmt.Errorf("%w: app %q: %s", ErrInvalidName, k.App, err)
+	}
+	if err := validName(k.Name); err != nil {
+		return fmt.Errorf("%w: name %q: %s", ErrInvalidName, k.Name, err)
+	}
+	return nil
+}
+
+// validName checks one key part against the built-in rules.
+func validName(s string) error {
+	if s == "" {
+		return errors.New("empty")
+	}
+	if len(s) > MaxNameLength {
+		return fmt.Errorf("longer than %d bytes", MaxNameLength)
+	}
+	if strings.Contains(s, "..") {
+		return errors.New(`contains ".."`)
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.') {
+			return fmt.Errorf("character %q not allowed", c)
+		}
+	}
+	return nil
+}
+
+// NamePolicy adds per-app stream name patterns to the built-in rules. A
+// nil policy applies the built-in rules only.
+type NamePolicy struct {
+	patterns map[string]*regexp.Regexp // by app, "*" for the rest
+}
+
+// NewNamePolicy compiles patterns, keyed by app ("*" covers apps without
+// their own). Patterns match the whole name.
+func NewNamePolicy(patterns map[string]string) (*NamePolicy, error) {
+	p := &NamePolicy{patterns: make(map[string]*regexp.Regexp, len(patterns))}
+	for app, expr := range patterns {
+		re, err := regexp.Compile(`^(?:` + expr + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("app %q: %w", app, err)
+		}
+		p.patterns[app] = re
+	}
+	return p, nil
+}
+
+// Check validates k with the built-in rules, then against its app's pattern.
+func (p *NamePolicy) Check(k StreamKey) error {
+	if err := k.Validate(); err != nil {
+		return err
+	}
+	if p == nil {
+		return nil
+	}
+	re, ok := p.patterns[k.App]
+	if !ok {
+		re = p.patterns["*"]
+	}
+	if re != nil && !re.MatchString(k.Name) {
+		return fmt.Errorf("%w: name %q does not match the pattern for app %q", ErrInvalidName, k.Name, k.App)
+	}
+	return nil
+}
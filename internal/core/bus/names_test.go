@@ -0,0 +1,59 @@
+// If you are AI: This file contains unit tests for stream key validation.
+
+package bus
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestStreamKeyValidate covers the built-in rules.
+func TestStreamKeyValidate(t *testing.T) {
+	for _, name := range []string{"cam1", "show_2024-01.hd", strings.Repeat("a", MaxNameLength)} {
+		if err := NewStreamKey("live", name).Validate(); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	bad := []string{"", "..", "a..b", "a/b", `a\b`, "a?key=1", "a#b", "a b", "a%2F", "é",
+		strings.Repeat("a", MaxNameLength+1)}
+	for _, name := range bad {
+		if err := NewStreamKey("live", name).Validate(); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("%q accepted", name)
+		}
+	}
+	if err := NewStreamKey("../etc", "x").Validate(); err == nil {
+		t.Error("app with a separator accepted")
+	}
+}
+
+// TestNamePolicy checks per-app patterns and the "*" fallback.
+func TestNamePolicy(t *testing.T) {
+	p, err := NewNamePolicy(map[string]string{"premium": "[a-z]{4,8}", "*": "[a-z0-9]+"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		app, name string
+		ok        bool
+	}{
+		{"premium", "show", true},
+		{"premium", "show1", false}, // digits not allowed for premium
+		{"premium", "showtimes", false},
+		{"live", "cam1", true},
+		{"live", "Cam1", false},
+		{"live", "a/b", false}, // built-in rules still apply
+	}
+	for _, c := range cases {
+		if err := p.Check(NewStreamKey(c.app, c.name)); (err == nil) != c.ok {
+			t.Errorf("%s/%s: err = %v, want ok=%v", c.app, c.name, err, c.ok)
+		}
+	}
+	if _, err := NewNamePolicy(map[string]string{"live": "("}); err == nil {
+		t.Error("bad pattern compiled")
+	}
+	var none *NamePolicy
+	if none.Check(NewStreamKey("live", "x")) != nil || none.Check(NewStreamKey("live", "..")) == nil {
+		t.Error("nil policy should apply only the built-in rules")
+	}
+}
@@ -0,0 +1,43 @@
+// If you are AI: This file lets playback handlers ask for a stream that is
+// not live locally. An OnDemand source (the edge puller) can bring it online,
+// e.g. by pulling it from an origin server, before the viewer is served.
+
+package bus
+
+import (
+	"context"
+)
+
+// OnDemand brings a missing stream online. Ensure blocks until the stream
+// has a publisher in the registry, the source gives up, or ctx ends.
+// Concurrent calls for the same key must share one upstream attempt.
+type OnDemand interface {
+	Ensure(ctx context.Context, key StreamKey) error
+}
+
+// SetOnDemand installs the source consulted by Live for missing streams.
+// nil disables on-demand lookups.
+func (r *Registry) SetOnDemand(od OnDemand) {
+	r.mu.Lock()
+	r.onDemand = od
+	r.mu.Unlock()
+}
+
+// Live returns the stream for key if it has a publisher. Otherwise, when an
+// OnDemand source is installed, it asks that source to bring the stream
+// online first. Returns nil when the stream is not and cannot be made live.
+func (r *Registry) Live(ctx context.Context, key StreamKey) *Stream {
+	if s := r.Get(key); s != nil && s.HasPublisher() {
+		return s
+	}
+	r.mu.RLock()
+	od := r.onDemand
+	r.mu.RUnlock()
+	if od == nil || od.Ensure(ctx, key) != nil {
+		return nil
+	}
+	if s := r.Get(key); s != nil && s.HasPublisher() {
+		return s
+	}
+	return nil
+}
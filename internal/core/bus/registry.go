@@ -12,8 +12,9 @@ import (
 // Lock expectations: Mutex-protected for concurrent access.
 // Allocation: Map pre-allocated, stream creation allocates once per stream.
 type Registry struct {
-	mu      sync.RWMutex
-	streams map[StreamKey]*Stream
+	mu       sync.RWMutex
+	streams  map[StreamKey]*Stream
+	onDemand OnDemand // optional; see ondemand.go
 }
 
 // NewRegistry creates a new stream registry.
@@ -3,6 +3,7 @@
 package bus
 
 import (
+	"context"
 	"testing"
 )
 
@@ -135,3 +136,36 @@ func TestRegistryList(t *testing.T) {
 		t.Error("List should contain both streams")
 	}
 }
+
+// fakeOnDemand publishes the requested stream when asked and counts calls.
+type fakeOnDemand struct {
+	reg   *Registry
+	calls int
+}
+
+// Ensure attaches a publisher to key, simulating a successful origin pull.
+func (f *fakeOnDemand) Ensure(_ context.Context, key StreamKey) error {
+	f.calls++
+	s, _ := f.reg.GetOrCreate(key)
+	s.AttachPublisher(1)
+	return nil
+}
+
+// TestRegistryLive checks that Live consults the OnDemand source only for
+// streams without a publisher.
+func TestRegistryLive(t *testing.T) {
+	reg := NewRegistry()
+	key := NewStreamKey("live", "edge")
+	if reg.Live(context.Background(), key) != nil {
+		t.Fatal("Live without OnDemand should return nil for a missing stream")
+	}
+
+	od := &fakeOnDemand{reg: reg}
+	reg.SetOnDemand(od)
+	if reg.Live(context.Background(), key) == nil {
+		t.Fatal("Live should return the stream brought online by OnDemand")
+	}
+	if reg.Live(context.Background(), key) == nil || od.calls != 1 {
+		t.Errorf("live stream should not trigger OnDemand again, calls=%d", od.calls)
+	}
+}
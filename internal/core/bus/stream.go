@@ -9,6 +9,7 @@ package bus
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // defaultLogSize is the per-stream shared-log capacity. Sized to be large
@@ -53,6 +54,8 @@ type Stream struct {
 	initAudio *MediaMessage
 	initMeta  *MediaMessage
 
+	audioCodec *AudioCodec // set by the publisher; see audio.go
+
 	// Shared message log. Single producer (the publisher's goroutine),
 	// many readers (one cursor per subscriber).
 	log *SharedLog
@@ -74,12 +77,28 @@ type Stream struct {
 	// fresh channel and closes the old one, broadcasting "data ready" to
 	// every parked subscriber at once. Idle subscribers cost zero CPU.
 	notify atomic.Pointer[chan struct{}]
+
+	// Timestamp of the most recent non-init message, with bit 32 set once
+	// anything has been published. Lets a replacement publisher continue the
+	// previous timeline (see LastTimestamp).
+	lastTS atomic.Uint64
+
+	// Ingest bitrate, frame rate, GOP and A/V drift (see ingest.go).
+	ingest ingestMeter
+
+	// Wall-clock anchor of the timeline (see clock.go); nil until the
+	// first frame. Kept across publisher handovers with the timeline.
+	clock atomic.Pointer[Clock]
+
+	// Injected and recent timed metadata (see cue.go).
+	cues cueLog
 }
 
 // Publisher represents a stream publisher.
 // Only one publisher can be attached to a stream at a time.
 type Publisher struct {
-	id uint64 // Unique publisher ID
+	id   uint64 // Unique publisher ID
+	addr string // Client address, when the ingest protocol knows it
 }
 
 // NewStream creates a new stream with default capacities suitable for live
@@ -164,6 +183,16 @@ func (s *Stream) AttachPublisher(id uint64) bool {
 	return true
 }
 
+// SetPublisherAddr records the current publisher's client address (the real
+// client when PROXY protocol is in use). No-op without a publisher.
+func (s *Stream) SetPublisherAddr(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.publisher != nil {
+		s.publisher.addr = addr
+	}
+}
+
 // DetachPublisher detaches the current publisher from the stream.
 // Also clears cached init messages since they belong to the publisher's session.
 func (s *Stream) DetachPublisher() {
@@ -173,6 +202,8 @@ func (s *Stream) DetachPublisher() {
 	s.initVideo = nil
 	s.initAudio = nil
 	s.initMeta = nil
+	s.audioCodec = nil
+	s.dropQueued()
 }
 
 // HasPublisher returns true if a publisher is currently attached.
@@ -228,9 +259,20 @@ func (s *Stream) Publish(msg *MediaMessage) {
 	if msg == nil {
 		return
 	}
+	if !msg.IsInit {
+		s.publishQueued(msg.Timestamp) // injected metadata goes first
+	}
+	msg.Received = time.Now().UnixNano() // for publish-to-write latency
 
-	if msg.IsInit {
+	switch {
+	case msg.IsInit:
 		s.cacheInitMessage(msg)
+	case msg.Type == MessageTypeMetadata:
+		s.recordCue(msg) // timed metadata; not a frame
+	default:
+		s.lastTS.Store(uint64(msg.Timestamp) | 1<<32)
+		s.ingest.observe(msg)
+		s.anchorClock(msg)
 	}
 
 	s.log.Publish(msg)
@@ -252,53 +294,3 @@ func (s *Stream) cacheInitMessage(msg *MediaMessage) {
 		s.initMeta = msg.Clone()
 	}
 }
-
-// SubscriberCount returns the number of active subscribers.
-func (s *Stream) SubscriberCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.subscribers)
-}
-
-// IsEmpty returns true if the stream has no publisher and no subscribers.
-func (s *Stream) IsEmpty() bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.publisher == nil && len(s.subscribers) == 0
-}
-
-// MessagesPublished returns the cumulative number of messages routed through Publish.
-// Equal to the highest sequence emitted on the shared log.
-func (s *Stream) MessagesPublished() uint64 {
-	return s.log.LatestSeq()
-}
-
-// HasAudioInit reports whether the publisher has produced an AAC sequence
-// header on this stream. Subscribers use this to set the FLV header's
-// has-audio flag correctly — claiming audio when none is present makes
-// ffmpeg's analyzer hang in find_stream_info.
-func (s *Stream) HasAudioInit() bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.initAudio != nil
-}
-
-// HasVideoInit reports whether an AVC sequence header has been cached.
-func (s *Stream) HasVideoInit() bool {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.initVideo != nil
-}
-
-// TotalDropped returns the sum of dropped-message counts across all current subscribers.
-// Subscribers that have already disconnected are not included; this metric is for live
-// pressure, not a permanent total.
-func (s *Stream) TotalDropped() uint64 {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	var total uint64
-	for _, sub := range s.subscribers {
-		total += sub.Dropped()
-	}
-	return total
-}
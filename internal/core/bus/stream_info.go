@@ -0,0 +1,80 @@
+// If you are AI: This file holds the read-only Stream accessors used by the
+// API, metrics and output handlers. None of them touch the publish hot path.
+
+package bus
+
+// SubscriberCount returns the number of active subscribers.
+func (s *Stream) SubscriberCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.subscribers)
+}
+
+// IsEmpty returns true if the stream has no publisher and no subscribers.
+func (s *Stream) IsEmpty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.publisher == nil && len(s.subscribers) == 0
+}
+
+// MessagesPublished returns the cumulative number of messages routed through Publish.
+// Equal to the highest sequence emitted on the shared log.
+func (s *Stream) MessagesPublished() uint64 {
+	return s.log.LatestSeq()
+}
+
+// HasAudioInit reports whether an audio sequence header has been cached.
+// Codecs without one never set it; see HasAudio.
+func (s *Stream) HasAudioInit() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.initAudio != nil
+}
+
+// HasVideoInit reports whether an AVC sequence header has been cached.
+func (s *Stream) HasVideoInit() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.initVideo != nil
+}
+
+// TotalDropped returns the sum of dropped-message counts across all current subscribers.
+// Subscribers that have already disconnected are not included; this metric is for live
+// pressure, not a permanent total.
+func (s *Stream) TotalDropped() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var total uint64
+	for _, sub := range s.subscribers {
+		total += sub.Dropped()
+	}
+	return total
+}
+
+// LastTimestamp returns the timestamp of the most recent non-init message
+// published on the stream, and false if nothing has been published yet.
+// A publisher that replaces a previous one (relay failover, encoder
+// reconnect) uses it to continue the timeline viewers are already on.
+func (s *Stream) LastTimestamp() (uint32, bool) {
+	v := s.lastTS.Load()
+	return uint32(v), v&(1<<32) != 0
+}
+
+// PublisherAddr returns the current publisher's client address, or "".
+func (s *Stream) PublisherAddr() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.publisher == nil {
+		return ""
+	}
+	return s.publisher.addr
+}
+
+// InitMessages returns the cached video and audio sequence headers and
+// onMetaData (nil when absent). The messages are private clones that are
+// replaced, never modified, so callers may read them freely.
+func (s *Stream) InitMessages() (video, audio, meta *MediaMessage) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.initVideo, s.initAudio, s.initMeta
+}
@@ -71,6 +71,16 @@ func TestPublisherExclusivity(t *testing.T) {
 	if !stream.AttachPublisher(3) {
 		t.Error("Publisher should attach after previous detach")
 	}
+
+	// The client address belongs to the publisher and leaves with it
+	stream.SetPublisherAddr("203.0.113.7:40000")
+	if got := stream.PublisherAddr(); got != "203.0.113.7:40000" {
+		t.Errorf("PublisherAddr = %q", got)
+	}
+	stream.DetachPublisher()
+	if got := stream.PublisherAddr(); got != "" {
+		t.Errorf("PublisherAddr after detach = %q, want empty", got)
+	}
 }
 
 func TestSubscriberAttachDetach(t *testing.T) {
@@ -141,6 +151,9 @@ func TestPublishFanout(t *testing.T) {
 	if read1.Type != MessageTypeVideo {
 		t.Error("Message type mismatch for subscriber 1")
 	}
+	if read1.Received == 0 {
+		t.Error("Publish did not stamp the receive time")
+	}
 
 	read2, ok2 := sub2.Read()
 	if !ok2 {
@@ -179,3 +192,29 @@ func TestStreamWithPublisherAndSubscribers(t *testing.T) {
 		t.Error("Stream with subscribers should not be empty")
 	}
 }
+
+// TestLastTimestampSurvivesPublisherHandover checks that the last non-init
+// timestamp is tracked and kept across a publisher detach.
+func TestLastTimestampSurvivesPublisherHandover(t *testing.T) {
+	stream := NewStream(NewStreamKey("live", "test"))
+	if _, ok := stream.LastTimestamp(); ok {
+		t.Fatal("new stream should have no last timestamp")
+	}
+
+	stream.AttachPublisher(1)
+	for _, m := range []MediaMessage{
+		{Type: MessageTypeVideo, Timestamp: 0, IsInit: true},
+		{Type: MessageTypeVideo, Timestamp: 1200},
+		{Type: MessageTypeAudio, Timestamp: 1234},
+		{Type: MessageTypeAudio, Timestamp: 0, IsInit: true},
+	} {
+		msg := m
+		stream.Publish(&msg)
+	}
+	stream.DetachPublisher()
+
+	ts, ok := stream.LastTimestamp()
+	if !ok || ts != 1234 {
+		t.Errorf("LastTimestamp = (%d, %v), want (1234, true)", ts, ok)
+	}
+}
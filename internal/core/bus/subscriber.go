@@ -14,20 +14,23 @@ import (
 type Subscriber struct {
 	id      uint64
 	stream  *Stream
-	cursor  uint64           // next sequence number to read from the log
-	pending []*MediaMessage  // init messages replayed first; drained in order
-	pendIdx int              // index into pending (0..len(pending))
-	dropped atomic.Uint64    // count of messages skipped due to slow-consumer wrap
+	cursor  uint64          // next sequence number to read from the log
+	pending []*MediaMessage // init messages replayed first; drained in order
+	pendIdx int             // index into pending (0..len(pending))
+	dropped atomic.Uint64   // count of messages skipped due to slow-consumer wrap
+	read    atomic.Uint64   // copy of cursor for Lag, which runs on other goroutines
 }
 
 // newSubscriber allocates a Subscriber pointing at the end of the stream's
 // shared log. Caller must hold s.mu (called from Stream.AttachSubscriber).
 func newSubscriber(id uint64, stream *Stream) *Subscriber {
-	return &Subscriber{
+	s := &Subscriber{
 		id:     id,
 		stream: stream,
 		cursor: stream.log.LatestSeq(), // start at "now"; init replay handled by pending
 	}
+	s.read.Store(s.cursor)
+	return s
 }
 
 // ID returns the unique subscriber identifier.
@@ -56,6 +59,7 @@ func (s *Subscriber) Read() (*MediaMessage, bool) {
 		s.dropped.Add(res.skipped)
 	}
 	s.cursor = next
+	s.read.Store(next)
 	return res.msg, true
 }
 
@@ -63,6 +67,16 @@ func (s *Subscriber) Read() (*MediaMessage, bool) {
 // the publisher overwrote unread slots while it was behind.
 func (s *Subscriber) Dropped() uint64 { return s.dropped.Load() }
 
+// Lag returns how many published messages this subscriber has not read
+// yet. Safe to call from any goroutine.
+func (s *Subscriber) Lag() uint64 {
+	latest, read := s.stream.log.LatestSeq(), s.read.Load()
+	if read >= latest {
+		return 0
+	}
+	return latest - read
+}
+
 // WaitChan returns the channel a caller should park on when Read returned
 // (nil, false). The channel is closed by the next Publish; callers must
 // re-call WaitChan after each wakeup because the stream rotates it on
@@ -0,0 +1,183 @@
+// If you are AI: This file describes what a stream carries by parsing its
+// cached sequence headers (AVC SPS, AAC AudioSpecificConfig), the audio
+// codec descriptor for codecs without one, and onMetaData, and pairs that
+// with the bus's live ingest measurements. It runs on API
+// and metrics reads, never on the publish path.
+
+package mediainfo
+
+import (
+	"bytes"
+	"strings"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/aac"
+	"nonchalant/internal/core/protocol/amf0"
+	"nonchalant/internal/core/protocol/avc"
+	"nonchalant/internal/core/protocol/flv"
+)
+
+// Info describes one stream's media.
+type Info struct {
+	Video  *Video          `json:"video,omitempty"`
+	Audio  *Audio          `json:"audio,omitempty"`
+	Codecs string          `json:"codecs,omitempty"` // RFC 6381, e.g. "avc1.64001F,mp4a.40.2"
+	Ingest bus.IngestStats `json:"ingest"`
+}
+
+// Video describes the video track.
+type Video struct {
+	Codec       string  `json:"codec"`
+	Profile     string  `json:"profile,omitempty"`
+	Level       string  `json:"level,omitempty"`
+	Width       int     `json:"width,omitempty"`
+	Height      int     `json:"height,omitempty"`
+	FPS         float64 `json:"fps,omitempty"` // declared (SPS timing or onMetaData)
+	CodecString string  `json:"codec_string,omitempty"`
+}
+
+// Audio describes the audio track.
+type Audio struct {
+	Codec       string `json:"codec"`
+	Profile     string `json:"profile,omitempty"`
+	SampleRate  int    `json:"sample_rate,omitempty"`
+	Channels    int    `json:"channels,omitempty"`
+	CodecString string `json:"codec_string,omitempty"`
+}
+
+// Probe describes s from its cached init messages and ingest stats.
+func Probe(s *bus.Stream) Info {
+	video, audio, meta := s.InitMessages()
+	info := Info{Ingest: s.IngestStats()}
+	if video != nil {
+		info.Video = probeVideo(video.Payload)
+	}
+	if audio != nil {
+		info.Audio = probeAudio(audio.Payload)
+	}
+	if c, ok := s.AudioCodec(); ok {
+		info.Audio = fillFromCodec(info.Audio, c)
+	}
+	if meta != nil {
+		fillFromMetadata(&info, meta.Payload)
+	}
+	var codecs []string
+	if info.Video != nil && info.Video.CodecString != "" {
+		codecs = append(codecs, info.Video.CodecString)
+	}
+	if info.Audio != nil && info.Audio.CodecString != "" {
+		codecs = append(codecs, info.Audio.CodecString)
+	}
+	info.Codecs = strings.Join(codecs, ",")
+	return info
+}
+
+// probeVideo parses a video sequence header. Legacy and Enhanced RTMP
+// headers both carry the decoder configuration at offset 5.
+func probeVideo(payload []byte) *Video {
+	v := &Video{Codec: flv.VideoCodec(payload)}
+	if v.Codec != "h264" || len(payload) <= 5 {
+		return v
+	}
+	record := payload[5:]
+	v.CodecString = avc.CodecString(record)
+	if sps, err := avc.ParseDecoderConfig(record); err == nil {
+		v.Profile = avc.ProfileName(sps.Profile)
+		v.Level = avc.LevelName(sps.Level)
+		v.Width, v.Height, v.FPS = sps.Width, sps.Height, sps.FPS
+	}
+	return v
+}
+
+// probeAudio parses an audio sequence header (AudioSpecificConfig at
+// offset 2, or 5 for Enhanced RTMP).
+func probeAudio(payload []byte) *Audio {
+	a := &Audio{Codec: flv.AudioCodec(payload)}
+	switch a.Codec {
+	case "aac":
+		offset := 2
+		if payload[0]>>4 == 9 {
+			offset = 5
+		}
+		if len(payload) <= offset {
+			return a
+		}
+		if c, err := aac.ParseConfig(payload[offset:]); err == nil {
+			a.Profile = aac.ProfileName(c.ObjectType)
+			a.SampleRate, a.Channels = c.SampleRate, c.Channels
+			a.CodecString = c.CodecString()
+		}
+	case "mp3":
+		a.CodecString = "mp4a.40.34"
+	case "opus":
+		a.CodecString = "opus"
+	}
+	return a
+}
+
+// fillFromCodec describes a track from the publisher's codec descriptor
+// when there is no sequence header (MP3, G.711, ...), and fills in what
+// the header left unknown.
+func fillFromCodec(a *Audio, c bus.AudioCodec) *Audio {
+	if a == nil {
+		a = &Audio{Codec: c.Name}
+		switch c.Name {
+		case "mp3":
+			a.CodecString = "mp4a.40.34"
+		case "ac3":
+			a.CodecString = "ac-3"
+		case "eac3":
+			a.CodecString = "ec-3"
+		}
+	}
+	if a.SampleRate == 0 {
+		a.SampleRate = c.SampleRate
+	}
+	if a.Channels == 0 {
+		a.Channels = c.Channels
+	}
+	return a
+}
+
+// fillFromMetadata fills fields the sequence headers left unknown from
+// onMetaData, and describes tracks that have no sequence header at all.
+func fillFromMetadata(info *Info, payload []byte) {
+	data, err := amf0.DecodeCommand(bytes.NewReader(payload))
+	if err != nil || len(data) < 2 {
+		return
+	}
+	meta, ok := data[1].(amf0.Object)
+	if !ok {
+		return
+	}
+	num := func(k string) float64 { f, _ := meta[k].(float64); return f }
+	if info.Video == nil && meta["videocodecid"] != nil {
+		info.Video = &Video{Codec: flv.MetadataVideoCodec(meta["videocodecid"])}
+	}
+	if v := info.Video; v != nil {
+		if v.Width == 0 && v.Height == 0 {
+			v.Width, v.Height = int(num("width")), int(num("height"))
+		}
+		if v.FPS == 0 {
+			v.FPS = num("framerate")
+		}
+	}
+	if info.Audio == nil && meta["audiocodecid"] != nil {
+		info.Audio = &Audio{Codec: flv.MetadataAudioCodec(meta["audiocodecid"])}
+	}
+	if a := info.Audio; a != nil {
+		if a.SampleRate == 0 {
+			a.SampleRate = int(num("audiosamplerate"))
+		}
+		if a.Channels == 0 {
+			if n := num("audiochannels"); n > 0 {
+				a.Channels = int(n)
+			} else if stereo, ok := meta["stereo"].(bool); ok {
+				a.Channels = 1
+				if stereo {
+					a.Channels = 2
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// If you are AI: Tests for stream media description from cached init
+// messages, using an x264 1080p30 SPS and an AAC-LC 48 kHz stereo config.
+
+package mediainfo
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// x264SPS is a High@4.0 1920x1080 30 fps SPS as emitted by x264.
+const x264SPS = "67640028acd940780227e5c05a808080a0000003002000000781e30632c0"
+
+// publishInit caches an AVC and AAC sequence header and onMetaData on s.
+func publishInit(t *testing.T, s *bus.Stream) {
+	t.Helper()
+	sps, _ := hex.DecodeString(x264SPS)
+	video := append([]byte{0x17, 0, 0, 0, 0, 1, 0x64, 0x00, 0x28, 0xFF, 0xE1, 0, byte(len(sps))}, sps...)
+	s.Publish(&bus.MediaMessage{Type: bus.MessageTypeVideo, Payload: video, IsInit: true})
+	s.Publish(&bus.MediaMessage{Type: bus.MessageTypeAudio, Payload: []byte{0xAF, 0, 0x11, 0x90}, IsInit: true})
+	meta, err := amf0.EncodeCommand(amf0.Array{"onMetaData", amf0.Object{"width": 1280.0, "framerate": 60.0, "audiosamplerate": 44100.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Publish(&bus.MediaMessage{Type: bus.MessageTypeMetadata, Payload: meta, IsInit: true})
+}
+
+// TestProbe prefers the sequence headers over onMetaData.
+func TestProbe(t *testing.T) {
+	s := bus.NewStream(bus.NewStreamKey("live", "x"))
+	publishInit(t, s)
+	info := Probe(s)
+	wantVideo := Video{Codec: "h264", Profile: "High", Level: "4.0", Width: 1920, Height: 1080, FPS: 30, CodecString: "avc1.640028"}
+	if info.Video == nil || *info.Video != wantVideo {
+		t.Errorf("video = %+v, want %+v", info.Video, wantVideo)
+	}
+	wantAudio := Audio{Codec: "aac", Profile: "LC", SampleRate: 48000, Channels: 2, CodecString: "mp4a.40.2"}
+	if info.Audio == nil || *info.Audio != wantAudio {
+		t.Errorf("audio = %+v, want %+v", info.Audio, wantAudio)
+	}
+	if info.Codecs != "avc1.640028,mp4a.40.2" {
+		t.Errorf("codecs = %q", info.Codecs)
+	}
+}
+
+// TestProbeMetadataOnly describes tracks from onMetaData alone.
+func TestProbeMetadataOnly(t *testing.T) {
+	s := bus.NewStream(bus.NewStreamKey("live", "x"))
+	meta, _ := amf0.EncodeCommand(amf0.Array{"onMetaData", amf0.Object{
+		"videocodecid": "hvc1", "width": 3840.0, "height": 2160.0, "framerate": 50.0,
+		"audiocodecid": 2.0, "stereo": false,
+	}})
+	s.Publish(&bus.MediaMessage{Type: bus.MessageTypeMetadata, Payload: meta, IsInit: true})
+	info := Probe(s)
+	if v := info.Video; v == nil || v.Codec != "hevc" || v.Width != 3840 || v.Height != 2160 || v.FPS != 50 {
+		t.Errorf("video = %+v", v)
+	}
+	if a := info.Audio; a == nil || a.Codec != "mp3" || a.Channels != 1 {
+		t.Errorf("audio = %+v", a)
+	}
+}
+
+// TestProbeAudioCodec describes audio without a sequence header from the
+// publisher's codec descriptor.
+func TestProbeAudioCodec(t *testing.T) {
+	s := bus.NewStream(bus.NewStreamKey("live", "x"))
+	s.SetAudioCodec(bus.AudioCodec{Name: "mp3", SampleRate: 44100, Channels: 2})
+	want := Audio{Codec: "mp3", SampleRate: 44100, Channels: 2, CodecString: "mp4a.40.34"}
+	if info := Probe(s); info.Audio == nil || *info.Audio != want || info.Codecs != "mp4a.40.34" {
+		t.Errorf("audio = %+v, codecs %q", info.Audio, info.Codecs)
+	}
+}
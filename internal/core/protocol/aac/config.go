@@ -0,0 +1,66 @@
+// If you are AI: This file parses the AAC AudioSpecificConfig carried in
+// FLV AAC sequence headers: object type, sample rate and channel count.
+
+package aac
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Config is the decoded AudioSpecificConfig.
+type Config struct {
+	ObjectType int // audio object type, e.g. 2 = AAC-LC
+	SampleRate int // Hz
+	Channels   int // 0 when defined by a program config element
+}
+
+// sampleRates indexes sampling_frequency_index.
+var sampleRates = []int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+// profiles names common audio object types.
+var profiles = map[int]string{1: "Main", 2: "LC", 3: "SSR", 4: "LTP", 5: "HE-AAC", 29: "HE-AACv2"}
+
+// ParseConfig decodes an AudioSpecificConfig.
+func ParseConfig(b []byte) (Config, error) {
+	if len(b) < 2 {
+		return Config{}, errors.New("aac: AudioSpecificConfig too short")
+	}
+	var pos int
+	read := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			if pos >= len(b)*8 {
+				return -1
+			}
+			v = v<<1 | int(b[pos/8]>>(7-pos%8)&1)
+			pos++
+		}
+		return v
+	}
+	var c Config
+	c.ObjectType = read(5)
+	if c.ObjectType == 31 {
+		c.ObjectType = 32 + read(6)
+	}
+	idx := read(4)
+	switch {
+	case idx == 15:
+		c.SampleRate = read(24)
+	case idx >= 0 && idx < len(sampleRates):
+		c.SampleRate = sampleRates[idx]
+	default:
+		return Config{}, fmt.Errorf("aac: bad sampling frequency index %d", idx)
+	}
+	c.Channels = read(4)
+	if c.SampleRate < 0 || c.Channels < 0 {
+		return Config{}, errors.New("aac: AudioSpecificConfig truncated")
+	}
+	return c, nil
+}
+
+// ProfileName names an audio object type ("" if uncommon).
+func ProfileName(objectType int) string { return profiles[objectType] }
+
+// CodecString is the RFC 6381 codecs parameter, e.g. "mp4a.40.2".
+func (c Config) CodecString() string { return fmt.Sprintf("mp4a.40.%d", c.ObjectType) }
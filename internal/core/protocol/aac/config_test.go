@@ -0,0 +1,29 @@
+// If you are AI: Tests for AudioSpecificConfig parsing.
+
+package aac
+
+import "testing"
+
+// TestParseConfig decodes common encoder configs.
+func TestParseConfig(t *testing.T) {
+	cases := []struct {
+		in   []byte
+		want Config
+	}{
+		{[]byte{0x12, 0x10}, Config{ObjectType: 2, SampleRate: 44100, Channels: 2}},
+		{[]byte{0x11, 0x90}, Config{ObjectType: 2, SampleRate: 48000, Channels: 2}},
+		{[]byte{0x2B, 0x8A, 0x08, 0x00}, Config{ObjectType: 5, SampleRate: 22050, Channels: 1}},
+	}
+	for _, c := range cases {
+		got, err := ParseConfig(c.in)
+		if err != nil || got != c.want {
+			t.Errorf("ParseConfig(%x) = %+v, %v; want %+v", c.in, got, err, c.want)
+		}
+	}
+	if got := (Config{ObjectType: 2}).CodecString(); got != "mp4a.40.2" {
+		t.Errorf("CodecString = %q", got)
+	}
+	if _, err := ParseConfig([]byte{0x17}); err == nil {
+		t.Error("one byte parsed")
+	}
+}
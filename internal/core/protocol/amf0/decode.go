@@ -35,6 +35,8 @@ func Decode(r io.Reader) (Value, error) {
 		return decodeObject(r)
 	case TypeECMAArray:
 		return decodeECMAArray(r)
+	case TypeStrictArray:
+		return decodeStrictArray(r)
 	default:
 		return nil, ErrUnexpectedType
 	}
@@ -124,6 +126,23 @@ func decodeECMAArray(r io.Reader) (Object, error) {
 	return decodeObject(r)
 }
 
+// decodeStrictArray decodes an AMF0 strict array.
+func decodeStrictArray(r io.Reader) (Array, error) {
+	var count uint32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	arr := make(Array, 0, min(count, 1024))
+	for i := uint32(0); i < count; i++ {
+		v, err := Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
 // DecodeCommand decodes an AMF0 command message.
 // RTMP commands are a sequence of AMF0 values:
 // command_name (string), transaction_id (number), command_object (object/null), ...args
@@ -2,6 +2,8 @@
 package amf0
 
 import (
+	"bytes"
+	"reflect"
 	"testing"
 )
 
@@ -75,3 +77,29 @@ func TestEncodeCommand_CreateStreamResult(t *testing.T) {
 		t.Fatalf("First byte should be 0x02 (TypeString), got 0x%02x", body[0])
 	}
 }
+
+// TestFromJSONRoundTrip encodes JSON-shaped data, arrays included, and
+// decodes it back.
+func TestFromJSONRoundTrip(t *testing.T) {
+	in := map[string]any{
+		"name":       "question",
+		"time":       float64(3),
+		"parameters": map[string]any{"choices": []any{"a", "b", true, nil}},
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, FromJSON(in)); err != nil {
+		t.Fatal(err)
+	}
+	v, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Object{
+		"name":       "question",
+		"time":       float64(3),
+		"parameters": Object{"choices": Array{"a", "b", true, nil}},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("got %#v, want %#v", v, want)
+	}
+}
@@ -0,0 +1,25 @@
+// If you are AI: This file converts decoded JSON into AMF0 values, for
+// script data that arrives over the HTTP API.
+
+package amf0
+
+// FromJSON converts a value decoded by encoding/json into an AMF0 value:
+// objects become Object, arrays Array; numbers, strings, booleans and null
+// map directly.
+func FromJSON(v any) Value {
+	switch v := v.(type) {
+	case map[string]any:
+		obj := make(Object, len(v))
+		for k, x := range v {
+			obj[k] = FromJSON(x)
+		}
+		return obj
+	case []any:
+		arr := make(Array, len(v))
+		for i, x := range v {
+			arr[i] = FromJSON(x)
+		}
+		return arr
+	}
+	return v
+}
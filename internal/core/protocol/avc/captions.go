@@ -0,0 +1,43 @@
+// If you are AI: This file extracts closed caption data from H.264
+// user_data_registered_itu_t_t35 SEI (payload type 4): the ATSC A/53
+// "GA94" cc_data that carries CEA-608 and CEA-708 captions.
+
+package avc
+
+// seiUserDataT35 is the user_data_registered_itu_t_t35 SEI payload type.
+const seiUserDataT35 = 4
+
+// FrameCaptions returns the cc_data triplets in an AVCC frame's SEI, in
+// order: per triplet a byte with cc_valid (0x04) and cc_type (low two
+// bits) followed by the two cc_data bytes. Nil if the frame has none.
+func FrameCaptions(frame []byte, nalLen int) []byte {
+	var out []byte
+	seiMessages(frame, nalLen, func(typ int, payload []byte) bool {
+		if typ == seiUserDataT35 {
+			out = append(out, ccData(payload)...)
+		}
+		return false
+	})
+	return out
+}
+
+// ccData returns the triplets of an A/53 cc_data() in a T.35 payload:
+// country United States (0xB5), provider ATSC (0x0031), user identifier
+// "GA94" and user_data_type_code 3.
+func ccData(p []byte) []byte {
+	if len(p) < 10 || p[0] != 0xB5 || p[1] != 0x00 || p[2] != 0x31 ||
+		string(p[3:7]) != "GA94" || p[7] != 0x03 || p[8]&0x40 == 0 { // process_cc_data_flag
+		return nil
+	}
+	n := int(p[8] & 0x1F) // cc_count
+	p = p[10:]            // skip em_data
+	if n*3 > len(p) {
+		n = len(p) / 3
+	}
+	out := make([]byte, 0, n*3)
+	for i := 0; i < n; i++ {
+		t := p[i*3 : i*3+3]
+		out = append(out, t[0]&0x07, t[1], t[2])
+	}
+	return out
+}
@@ -0,0 +1,30 @@
+// If you are AI: Tests for extracting A/53 caption data from SEI.
+
+package avc
+
+import (
+	"bytes"
+	"testing"
+)
+
+// captionSEI builds an SEI NAL with one GA94 cc_data message.
+func captionSEI(triplets ...byte) []byte {
+	payload := append([]byte{0xB5, 0x00, 0x31, 'G', 'A', '9', '4', 0x03, 0x40 | byte(len(triplets)/3), 0xFF}, triplets...)
+	payload = append(payload, 0xFF) // marker_bits
+	sei := append([]byte{0x06, seiUserDataT35, byte(len(payload))}, payload...)
+	return append(escape(sei), 0x80)
+}
+
+// TestFrameCaptions reads the triplets after the clock SEI and drops the
+// marker bits.
+func TestFrameCaptions(t *testing.T) {
+	idr := []byte{0x65, 0x88, 0x84}
+	frame := avcc(picTimingSEI(0, 0), captionSEI(0xFC, 0x94, 0x20, 0xF9, 0x00, 0x00, 0xFF, 0x02, 0x21), idr)
+	want := []byte{0x04, 0x94, 0x20, 0x01, 0x00, 0x00, 0x07, 0x02, 0x21}
+	if got := FrameCaptions(frame, 4); !bytes.Equal(got, want) {
+		t.Errorf("FrameCaptions = % x, want % x", got, want)
+	}
+	if got := FrameCaptions(avcc(picTimingSEI(0, 0), idr), 4); got != nil {
+		t.Errorf("frame without captions = % x", got)
+	}
+}
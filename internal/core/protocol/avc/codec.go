@@ -0,0 +1,27 @@
+// If you are AI: This file names H.264 profiles and levels and builds the
+// RFC 6381 codecs parameter from a decoder configuration record.
+
+package avc
+
+import "fmt"
+
+// profiles names profile_idc values.
+var profiles = map[uint8]string{
+	66: "Baseline", 77: "Main", 88: "Extended", 100: "High",
+	110: "High 10", 122: "High 4:2:2", 244: "High 4:4:4",
+}
+
+// ProfileName names a profile_idc ("" if uncommon).
+func ProfileName(profile uint8) string { return profiles[profile] }
+
+// LevelName formats a level_idc, e.g. 31 -> "3.1".
+func LevelName(level uint8) string { return fmt.Sprintf("%d.%d", level/10, level%10) }
+
+// CodecString returns the RFC 6381 codecs parameter ("avc1.PPCCLL") for an
+// AVCDecoderConfigurationRecord, or "" if the record is too short.
+func CodecString(record []byte) string {
+	if len(record) < 4 {
+		return ""
+	}
+	return fmt.Sprintf("avc1.%02X%02X%02X", record[1], record[2], record[3])
+}
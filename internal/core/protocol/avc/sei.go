@@ -0,0 +1,181 @@
+// If you are AI: This file reads the clock timestamp of H.264 picture
+// timing SEI (payload type 1), which encoders use to stamp each picture
+// with the time of day it was captured.
+
+package avc
+
+import "time"
+
+// PicTiming is what reading picture timing SEI needs from the SPS. It is
+// zero unless the VUI sets pic_struct_present_flag.
+type PicTiming struct {
+	NumUnitsInTick     uint32
+	TimeScale          uint32
+	CpbDpbDelays       bool // HRD present: each SEI starts with two delays
+	CpbRemovalDelayLen int
+	DpbOutputDelayLen  int
+	TimeOffsetLen      int
+}
+
+// picTiming reads the VUI after timing_info_present_flag's fields.
+func (r *bitReader) picTiming(units, scale uint32) PicTiming {
+	var t PicTiming
+	r.bits(1) // fixed_frame_rate_flag
+	nal := r.bits(1) == 1
+	if nal {
+		r.hrd(&t)
+	}
+	vcl := r.bits(1) == 1
+	if vcl {
+		r.hrd(&t)
+	}
+	if nal || vcl {
+		t.CpbDpbDelays = true
+		r.bits(1) // low_delay_hrd_flag
+	}
+	if r.bits(1) == 0 || r.err != nil { // pic_struct_present_flag
+		return PicTiming{}
+	}
+	t.NumUnitsInTick, t.TimeScale = units, scale
+	return t
+}
+
+// hrd reads hrd_parameters, keeping the field lengths picture timing uses.
+func (r *bitReader) hrd(t *PicTiming) {
+	n := r.ue() // cpb_cnt_minus1
+	r.bits(8)   // bit_rate_scale, cpb_size_scale
+	for i := uint64(0); i <= n && r.err == nil; i++ {
+		r.ue()    // bit_rate_value_minus1
+		r.ue()    // cpb_size_value_minus1
+		r.bits(1) // cbr_flag
+	}
+	r.bits(5) // initial_cpb_removal_delay_length_minus1
+	t.CpbRemovalDelayLen = int(r.bits(5)) + 1
+	t.DpbOutputDelayLen = int(r.bits(5)) + 1
+	t.TimeOffsetLen = int(r.bits(5))
+}
+
+// clockTimestamps is NumClockTS by pic_struct.
+var clockTimestamps = [9]int{1, 1, 1, 2, 2, 3, 3, 2, 3}
+
+// FrameClock returns the time of day in the first picture timing SEI of
+// an AVCC frame (NAL units prefixed by nalLen-byte lengths), and false if
+// there is none or t cannot read it.
+func FrameClock(frame []byte, nalLen int, t PicTiming) (time.Duration, bool) {
+	if t.TimeScale == 0 {
+		return 0, false
+	}
+	var d time.Duration
+	var ok bool
+	seiMessages(frame, nalLen, func(typ int, payload []byte) bool {
+		if typ == 1 {
+			d, ok = picClock(payload, t)
+		}
+		return ok
+	})
+	return d, ok
+}
+
+// seiMessages calls fn with the type and payload of each SEI message in
+// an AVCC frame, in order, until fn returns true.
+func seiMessages(frame []byte, nalLen int, fn func(typ int, payload []byte) bool) {
+	if nalLen < 1 || nalLen > 4 {
+		return
+	}
+	for len(frame) >= nalLen {
+		n := 0
+		for _, b := range frame[:nalLen] {
+			n = n<<8 | int(b)
+		}
+		frame = frame[nalLen:]
+		if n > len(frame) || n < 1 {
+			return
+		}
+		nal := frame[:n]
+		frame = frame[n:]
+		if nal[0]&0x1F == 6 && seiWalk(unescape(nal[1:]), fn) {
+			return
+		}
+	}
+}
+
+// seiWalk walks the messages of one SEI RBSP, reporting whether fn
+// stopped the walk.
+func seiWalk(rbsp []byte, fn func(typ int, payload []byte) bool) bool {
+	for len(rbsp) > 1 || (len(rbsp) == 1 && rbsp[0] != 0x80) {
+		typ, size := 0, 0
+		for len(rbsp) > 0 && rbsp[0] == 0xFF {
+			typ += 255
+			rbsp = rbsp[1:]
+		}
+		if len(rbsp) == 0 {
+			return false
+		}
+		typ += int(rbsp[0])
+		rbsp = rbsp[1:]
+		for len(rbsp) > 0 && rbsp[0] == 0xFF {
+			size += 255
+			rbsp = rbsp[1:]
+		}
+		if len(rbsp) == 0 {
+			return false
+		}
+		size += int(rbsp[0])
+		rbsp = rbsp[1:]
+		if size > len(rbsp) {
+			return false
+		}
+		if fn(typ, rbsp[:size]) {
+			return true
+		}
+		rbsp = rbsp[size:]
+	}
+	return false
+}
+
+// picClock reads the first clock timestamp of a pic_timing payload.
+func picClock(payload []byte, t PicTiming) (time.Duration, bool) {
+	r := &bitReader{data: payload}
+	if t.CpbDpbDelays {
+		r.bits(t.CpbRemovalDelayLen)
+		r.bits(t.DpbOutputDelayLen)
+	}
+	picStruct := r.bits(4)
+	if picStruct >= uint64(len(clockTimestamps)) {
+		return 0, false
+	}
+	for i := 0; i < clockTimestamps[picStruct] && r.err == nil; i++ {
+		if r.bits(1) == 0 { // clock_timestamp_flag
+			continue
+		}
+		r.bits(2) // ct_type
+		fieldBased := r.bits(1)
+		r.bits(5) // counting_type
+		full := r.bits(1)
+		r.bits(2) // discontinuity_flag, cnt_dropped_flag
+		frames := r.bits(8)
+		var h, m, s uint64
+		if full == 1 {
+			s, m, h = r.bits(6), r.bits(6), r.bits(5)
+		} else if r.bits(1) == 1 {
+			s = r.bits(6)
+			if r.bits(1) == 1 {
+				m = r.bits(6)
+				if r.bits(1) == 1 {
+					h = r.bits(5)
+				}
+			}
+		}
+		var offset int64
+		if n := t.TimeOffsetLen; n > 0 {
+			offset = int64(r.bits(n)<<(64-n)) >> (64 - n) // sign-extend
+		}
+		if r.err != nil || h > 23 || m > 59 || s > 59 {
+			return 0, false
+		}
+		ticks := int64(frames*uint64(t.NumUnitsInTick)*(1+fieldBased)) + offset
+		return time.Duration(h*3600+m*60+s)*time.Second +
+			time.Duration(ticks)*time.Second/time.Duration(t.TimeScale), true
+	}
+	return 0, false
+}
@@ -0,0 +1,92 @@
+// If you are AI: Tests for picture timing SEI, with an SPS whose VUI
+// carries NAL HRD parameters and pic_struct_present_flag.
+
+package avc
+
+import (
+	"testing"
+	"time"
+)
+
+// spsPicTiming builds a 1080p30 SPS with NAL HRD (24-bit delays, 8-bit
+// time offset) and picture structure present.
+func spsPicTiming() []byte {
+	return spsWith(func(w *bitWriter) {
+		w.put(1, 1) // fixed_frame_rate_flag
+		w.put(1, 1) // nal_hrd_parameters_present_flag
+		w.ue(0)     // cpb_cnt_minus1
+		w.put(0, 8) // bit_rate_scale, cpb_size_scale
+		w.ue(999)   // bit_rate_value_minus1
+		w.ue(999)   // cpb_size_value_minus1
+		w.put(0, 1) // cbr_flag
+		w.put(23, 5)
+		w.put(23, 5) // cpb_removal_delay_length_minus1
+		w.put(23, 5) // dpb_output_delay_length_minus1
+		w.put(8, 5)  // time_offset_length
+		w.put(0, 1)  // vcl_hrd_parameters_present_flag
+		w.put(0, 1)  // low_delay_hrd_flag
+		w.put(1, 1)  // pic_struct_present_flag
+		w.put(0, 1)  // bitstream_restriction_flag
+	})
+}
+
+// picTimingSEI builds an SEI NAL with one frame picture timing message
+// stamped 13:45:07 plus frames and a time offset in ticks.
+func picTimingSEI(frames, offset uint64) []byte {
+	w := &bitWriter{}
+	w.put(0, 24) // cpb_removal_delay
+	w.put(0, 24) // dpb_output_delay
+	w.put(0, 4)  // pic_struct: frame
+	w.put(1, 1)  // clock_timestamp_flag
+	w.put(0, 2)  // ct_type
+	w.put(0, 1)  // nuit_field_based_flag
+	w.put(0, 5)  // counting_type
+	w.put(1, 1)  // full_timestamp_flag
+	w.put(0, 2)  // discontinuity, cnt_dropped
+	w.put(frames, 8)
+	w.put(7, 6)  // seconds
+	w.put(45, 6) // minutes
+	w.put(13, 5) // hours
+	w.put(offset, 8)
+	payload := w.data
+	sei := append([]byte{0x06, 1, byte(len(payload))}, payload...)
+	return append(escape(sei), 0x80)
+}
+
+// avcc prefixes each NAL with a 4-byte length.
+func avcc(nals ...[]byte) []byte {
+	var out []byte
+	for _, n := range nals {
+		out = append(out, byte(len(n)>>24), byte(len(n)>>16), byte(len(n)>>8), byte(len(n)))
+		out = append(out, n...)
+	}
+	return out
+}
+
+// TestFrameClock reads the SPS's SEI layout and a frame's time of day.
+func TestFrameClock(t *testing.T) {
+	s, err := ParseSPS(spsPicTiming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PicTiming{NumUnitsInTick: 1, TimeScale: 60, CpbDpbDelays: true,
+		CpbRemovalDelayLen: 24, DpbOutputDelayLen: 24, TimeOffsetLen: 8}
+	if s.Timing != want || s.FPS != 30 {
+		t.Fatalf("timing %+v fps %v, want %+v fps 30", s.Timing, s.FPS, want)
+	}
+
+	idr := []byte{0x65, 0x88, 0x84}
+	frame := avcc(picTimingSEI(15, 0xFE), idr) // 15 frames, offset -2 ticks
+	got, ok := FrameClock(frame, 4, s.Timing)
+	tod := 13*time.Hour + 45*time.Minute + 7*time.Second
+	if want := tod + 13*time.Second/60; !ok || got != want {
+		t.Errorf("FrameClock = %v, %v; want %v", got, ok, want)
+	}
+
+	if _, ok := FrameClock(avcc(idr), 4, s.Timing); ok {
+		t.Error("frame without SEI has a clock")
+	}
+	if s, _ := ParseSPS(sps1080p30()); s.Timing != (PicTiming{}) {
+		t.Errorf("SPS without pic_struct_present has timing %+v", s.Timing)
+	}
+}
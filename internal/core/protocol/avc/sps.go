@@ -0,0 +1,240 @@
+// If you are AI: This file parses the H.264 sequence parameter set (SPS)
+// from an AVC decoder configuration record, enough to learn the profile,
+// level, display size and (when the VUI carries timing) frame rate and
+// what is needed to read picture timing SEI.
+
+package avc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SPS is the subset of a sequence parameter set callers care about.
+type SPS struct {
+	Profile uint8
+	Level   uint8
+	Width   int     // display width after cropping
+	Height  int     // display height after cropping
+	FPS     float64 // 0 when the VUI has no timing info
+	Timing  PicTiming
+}
+
+// errShort is returned when the data ends before a field.
+var errShort = errors.New("avc: truncated SPS")
+
+// ParseDecoderConfig parses the first SPS of an AVCDecoderConfigurationRecord
+// (the body of an FLV AVC sequence header, after its 5-byte tag header).
+func ParseDecoderConfig(record []byte) (SPS, error) {
+	if len(record) < 8 {
+		return SPS{}, fmt.Errorf("avc: decoder config too short (%d bytes)", len(record))
+	}
+	if record[5]&0x1F == 0 {
+		return SPS{}, errors.New("avc: decoder config has no SPS")
+	}
+	n := int(record[6])<<8 | int(record[7])
+	if len(record) < 8+n || n < 1 {
+		return SPS{}, errShort
+	}
+	return ParseSPS(record[8 : 8+n])
+}
+
+// ParseSPS parses an SPS NAL unit, including its one-byte NAL header.
+func ParseSPS(nal []byte) (SPS, error) {
+	if len(nal) < 4 || nal[0]&0x1F != 7 {
+		return SPS{}, errors.New("avc: not an SPS NAL unit")
+	}
+	r := &bitReader{data: unescape(nal[1:])}
+	var s SPS
+	s.Profile = uint8(r.bits(8))
+	r.bits(8) // constraint flags
+	s.Level = uint8(r.bits(8))
+	r.ue() // seq_parameter_set_id
+
+	chroma := uint64(1)
+	switch s.Profile {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		chroma = r.ue()
+		if chroma == 3 {
+			r.bits(1) // separate_colour_plane_flag
+		}
+		r.ue()    // bit_depth_luma_minus8
+		r.ue()    // bit_depth_chroma_minus8
+		r.bits(1) // qpprime_y_zero_transform_bypass_flag
+		if r.bits(1) == 1 {
+			lists := 8
+			if chroma == 3 {
+				lists = 12
+			}
+			for i := 0; i < lists; i++ {
+				if r.bits(1) == 1 {
+					size := 16
+					if i >= 6 {
+						size = 64
+					}
+					r.skipScalingList(size)
+				}
+			}
+		}
+	}
+
+	r.ue() // log2_max_frame_num_minus4
+	switch r.ue() {
+	case 0:
+		r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		r.bits(1) // delta_pic_order_always_zero_flag
+		r.se()    // offset_for_non_ref_pic
+		r.se()    // offset_for_top_to_bottom_field
+		for n := r.ue(); n > 0 && r.err == nil; n-- {
+			r.se() // offset_for_ref_frame
+		}
+	}
+	r.ue()    // max_num_ref_frames
+	r.bits(1) // gaps_in_frame_num_value_allowed_flag
+	widthMBs := int(r.ue()) + 1
+	heightMaps := int(r.ue()) + 1
+	frameMBsOnly := int(r.bits(1))
+	if frameMBsOnly == 0 {
+		r.bits(1) // mb_adaptive_frame_field_flag
+	}
+	r.bits(1) // direct_8x8_inference_flag
+
+	var cropL, cropR, cropT, cropB int
+	if r.bits(1) == 1 {
+		cropL, cropR, cropT, cropB = int(r.ue()), int(r.ue()), int(r.ue()), int(r.ue())
+	}
+	cropX, cropY := 1, 2-frameMBsOnly
+	switch chroma {
+	case 1: // 4:2:0
+		cropX, cropY = 2, 2*(2-frameMBsOnly)
+	case 2: // 4:2:2
+		cropX = 2
+	}
+	s.Width = widthMBs*16 - cropX*(cropL+cropR)
+	s.Height = (2-frameMBsOnly)*heightMaps*16 - cropY*(cropT+cropB)
+
+	if r.bits(1) == 1 {
+		s.FPS, s.Timing = r.vuiTiming()
+	}
+	if r.err != nil {
+		return SPS{}, r.err
+	}
+	return s, nil
+}
+
+// vuiTiming reads the VUI up to its timing info and returns the frame
+// rate, or 0 if timing is absent, and what picture timing SEI needs.
+func (r *bitReader) vuiTiming() (float64, PicTiming) {
+	if r.bits(1) == 1 { // aspect_ratio_info_present_flag
+		if r.bits(8) == 255 { // Extended_SAR
+			r.bits(32)
+		}
+	}
+	if r.bits(1) == 1 { // overscan_info_present_flag
+		r.bits(1)
+	}
+	if r.bits(1) == 1 { // video_signal_type_present_flag
+		r.bits(4)
+		if r.bits(1) == 1 { // colour_description_present_flag
+			r.bits(24)
+		}
+	}
+	if r.bits(1) == 1 { // chroma_loc_info_present_flag
+		r.ue()
+		r.ue()
+	}
+	if r.bits(1) == 0 { // timing_info_present_flag
+		return 0, PicTiming{}
+	}
+	units, scale := r.bits(32), r.bits(32)
+	if units == 0 || r.err != nil {
+		return 0, PicTiming{}
+	}
+	// The rest of the VUI is optional to us: a copy of the reader keeps
+	// a malformed tail from failing the SPS.
+	rest := *r
+	timing := rest.picTiming(uint32(units), uint32(scale))
+	if rest.err != nil {
+		timing = PicTiming{}
+	}
+	// Two fields per frame: time_scale counts field ticks.
+	return float64(scale) / float64(2*units), timing
+}
+
+// skipScalingList consumes one scaling list of size entries.
+func (r *bitReader) skipScalingList(size int) {
+	last, next := int64(8), int64(8)
+	for j := 0; j < size && r.err == nil; j++ {
+		if next != 0 {
+			next = (last + r.se() + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+}
+
+// unescape removes emulation prevention bytes (00 00 03 -> 00 00).
+func unescape(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	zeros := 0
+	for _, c := range b {
+		if zeros >= 2 && c == 3 {
+			zeros = 0
+			continue
+		}
+		out = append(out, c)
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+// bitReader reads big-endian bits and Exp-Golomb codes. The first read
+// past the end sets err; later reads return 0.
+type bitReader struct {
+	data []byte
+	pos  int // bit position
+	err  error
+}
+
+// bits reads n (<= 32) bits.
+func (r *bitReader) bits(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.data)*8 {
+			r.err = errShort
+			return 0
+		}
+		bit := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v
+}
+
+// ue reads an unsigned Exp-Golomb code.
+func (r *bitReader) ue() uint64 {
+	zeros := 0
+	for r.bits(1) == 0 {
+		if r.err != nil || zeros > 31 {
+			r.err = errShort
+			return 0
+		}
+		zeros++
+	}
+	return 1<<zeros - 1 + r.bits(zeros)
+}
+
+// se reads a signed Exp-Golomb code.
+func (r *bitReader) se() int64 {
+	k := r.ue()
+	if k%2 == 1 {
+		return int64(k+1) / 2
+	}
+	return -int64(k / 2)
+}
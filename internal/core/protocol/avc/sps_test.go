@@ -0,0 +1,128 @@
+// If you are AI: Tests for the SPS parser, using a bit writer to build a
+// High-profile 1080p30 SPS the way an encoder would.
+
+package avc
+
+import "testing"
+
+// bitWriter builds test bitstreams.
+type bitWriter struct {
+	data []byte
+	n    int
+}
+
+// put appends the low n bits of v.
+func (w *bitWriter) put(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		w.data[len(w.data)-1] |= byte(v>>i&1) << (7 - w.n%8)
+		w.n++
+	}
+}
+
+// ue appends an unsigned Exp-Golomb code.
+func (w *bitWriter) ue(v uint64) {
+	v++
+	bits := 0
+	for x := v; x > 1; x >>= 1 {
+		bits++
+	}
+	w.put(0, bits)
+	w.put(v, bits+1)
+}
+
+// sps1080p30 builds a High-profile 1920x1080 (1088 coded, cropped) SPS
+// with 30 fps VUI timing.
+func sps1080p30() []byte {
+	return spsWith(func(w *bitWriter) {
+		w.put(1, 1) // fixed_frame_rate_flag
+	})
+}
+
+// spsWith builds the 1080p30 SPS up to its VUI timing info; vui writes
+// the rest of the VUI.
+func spsWith(vui func(w *bitWriter)) []byte {
+	w := &bitWriter{}
+	w.put(100, 8) // profile_idc: High
+	w.put(0, 8)   // constraint flags
+	w.put(40, 8)  // level_idc
+	w.ue(0)       // seq_parameter_set_id
+	w.ue(1)       // chroma_format_idc 4:2:0
+	w.ue(0)       // bit_depth_luma_minus8
+	w.ue(0)       // bit_depth_chroma_minus8
+	w.put(0, 1)   // qpprime_y_zero_transform_bypass_flag
+	w.put(0, 1)   // seq_scaling_matrix_present_flag
+	w.ue(0)       // log2_max_frame_num_minus4
+	w.ue(0)       // pic_order_cnt_type
+	w.ue(0)       // log2_max_pic_order_cnt_lsb_minus4
+	w.ue(4)       // max_num_ref_frames
+	w.put(0, 1)   // gaps_in_frame_num_value_allowed_flag
+	w.ue(119)     // pic_width_in_mbs_minus1
+	w.ue(67)      // pic_height_in_map_units_minus1
+	w.put(1, 1)   // frame_mbs_only_flag
+	w.put(1, 1)   // direct_8x8_inference_flag
+	w.put(1, 1)   // frame_cropping_flag
+	w.ue(0)
+	w.ue(0)
+	w.ue(0)
+	w.ue(4)     // crop bottom 4 units = 8 rows
+	w.put(1, 1) // vui_parameters_present_flag
+	w.put(0, 4) // no aspect, overscan, signal type, chroma loc
+	w.put(1, 1) // timing_info_present_flag
+	w.put(1, 32)
+	w.put(60, 32)
+	vui(w)
+	w.put(1, 1) // rbsp stop bit
+	return append([]byte{0x67}, escape(w.data)...)
+}
+
+// escape inserts emulation prevention bytes, as an encoder must.
+func escape(b []byte) []byte {
+	var out []byte
+	zeros := 0
+	for _, c := range b {
+		if zeros >= 2 && c <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+		out = append(out, c)
+		if c == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+// TestParseDecoderConfig parses the SPS out of a decoder config record.
+func TestParseDecoderConfig(t *testing.T) {
+	nal := sps1080p30()
+	record := []byte{1, 100, 0, 40, 0xFF, 0xE1, byte(len(nal) >> 8), byte(len(nal))}
+	record = append(record, nal...)
+	s, err := ParseDecoderConfig(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := SPS{Profile: 100, Level: 40, Width: 1920, Height: 1080, FPS: 30}
+	if s != want {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+	if got := CodecString(record); got != "avc1.640028" {
+		t.Errorf("CodecString = %q", got)
+	}
+	if _, err := ParseDecoderConfig(record[:len(record)-4]); err == nil {
+		t.Error("truncated record parsed without error")
+	}
+}
+
+// TestUnescape strips emulation prevention bytes only after two zeros.
+func TestUnescape(t *testing.T) {
+	got := unescape([]byte{0, 0, 3, 1, 0, 3, 0, 0, 3})
+	want := []byte{0, 0, 1, 0, 3, 0, 0}
+	if string(got) != string(want) {
+		t.Errorf("unescape = %v, want %v", got, want)
+	}
+}
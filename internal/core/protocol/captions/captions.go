@@ -0,0 +1,89 @@
+// If you are AI: This file turns the cc_data of a stream's video frames
+// (see avc.FrameCaptions) into the caption text on screen. Frames arrive
+// in decode order but carry captions in presentation order, so they are
+// held until no earlier frame can follow. CEA-608 CC1 is decoded, or
+// CEA-708 service 1 for streams that carry no 608.
+
+package captions
+
+import "sort"
+
+// Caption formats.
+const (
+	Format608 = "cea608"
+	Format708 = "cea708"
+)
+
+// maxHeld bounds the frames held for reordering, against timestamps
+// that never let them go.
+const maxHeld = 32
+
+// Update is a change of the captions on screen: Text is shown from the
+// frame it came with until the next update; "" clears the screen.
+type Update struct {
+	Format string
+	Text   string
+}
+
+// Decoder decodes the captions of one stream.
+type Decoder struct {
+	held   []heldFrame
+	c608   cea608
+	c708   cea708
+	has608 bool
+	last   string
+}
+
+// heldFrame is a frame's cc_data waiting for its presentation turn.
+type heldFrame struct {
+	pts  int64
+	data []byte
+}
+
+// Frame takes the cc_data of the video frame with timestamps dts and
+// pts (ms) and returns the updates of the frames that are due: those
+// presented at or before dts, in presentation order.
+func (d *Decoder) Frame(dts, pts int64, ccData []byte) []Update {
+	if len(ccData) > 0 {
+		i := sort.Search(len(d.held), func(i int) bool { return d.held[i].pts > pts })
+		d.held = append(d.held, heldFrame{})
+		copy(d.held[i+1:], d.held[i:])
+		d.held[i] = heldFrame{pts: pts, data: append([]byte(nil), ccData...)}
+	}
+	var out []Update
+	for len(d.held) > 0 && (d.held[0].pts <= dts || len(d.held) > maxHeld) {
+		out = d.decode(d.held[0].data, out)
+		d.held = d.held[1:]
+	}
+	return out
+}
+
+// decode runs one frame's triplets through the decoders, appending the
+// updates to out.
+func (d *Decoder) decode(data []byte, out []Update) []Update {
+	for i := 0; i+3 <= len(data); i += 3 {
+		flags, b1, b2 := data[i], data[i+1], data[i+2]
+		if flags&0x04 == 0 { // cc_valid
+			continue
+		}
+		var text, format string
+		var ok bool
+		switch flags & 0x03 {
+		case 0: // 608 field 1
+			if b1&0x7F != 0 || b2&0x7F != 0 {
+				d.has608 = true
+			}
+			text, ok = d.c608.pair(b1, b2)
+			format = Format608
+		case 2, 3:
+			text, ok = d.c708.triplet(flags&0x03, b1, b2)
+			format = Format708
+			ok = ok && !d.has608
+		}
+		if ok && text != d.last {
+			d.last = text
+			out = append(out, Update{Format: format, Text: text})
+		}
+	}
+	return out
+}
@@ -0,0 +1,243 @@
+// If you are AI: This file decodes CEA-608 caption channel CC1 from the
+// field 1 byte pairs of cc_data: pop-on, roll-up and paint-on captions
+// on the 15 x 32 caption grid, with the special and extended character
+// sets. Text mode and the other channels are skipped.
+
+package captions
+
+import "strings"
+
+// 608 caption modes; mode608None ignores text until a mode is chosen.
+const (
+	mode608None = iota
+	mode608PopOn
+	mode608RollUp
+	mode608PaintOn
+	mode608Text
+)
+
+// grid is the caption screen: 15 rows of 32 columns, 0 where blank.
+type grid [15][32]rune
+
+// cea608 decodes CC1.
+type cea608 struct {
+	displayed, hidden grid
+	mode              int
+	rollRows          int
+	row, col          int
+	channel           int     // channel the last control code was for
+	lastCtrl          [2]byte // previous pair if a control code, for the doubled-code rule
+	dirty             bool    // the displayed grid changed since the last update
+}
+
+// pair decodes one field 1 byte pair, returning the displayed text and
+// true at the points a caption shows, changes or clears.
+func (c *cea608) pair(b1, b2 byte) (string, bool) {
+	b1, b2 = b1&0x7F, b2&0x7F // strip parity
+	if b1 == 0 && b2 == 0 {
+		return "", false
+	}
+	if b1 >= 0x10 && b1 <= 0x1F {
+		if c.lastCtrl == [2]byte{b1, b2} { // sent twice for robustness
+			c.lastCtrl = [2]byte{}
+			return "", false
+		}
+		c.lastCtrl = [2]byte{b1, b2}
+		if c.channel = 1 + int(b1>>3&1); c.channel != 1 {
+			return "", false
+		}
+		return c.control(b1&^0x08, b2)
+	}
+	c.lastCtrl = [2]byte{}
+	if c.channel != 1 || c.mode == mode608None || c.mode == mode608Text || b1 < 0x20 {
+		return "", false
+	}
+	c.put(basic608(b1))
+	if b2 >= 0x20 {
+		c.put(basic608(b2))
+	}
+	return "", false
+}
+
+// control decodes a control pair with the channel bit cleared.
+func (c *cea608) control(b1, b2 byte) (string, bool) {
+	switch {
+	case b1 == 0x14 && b2 >= 0x20 && b2 <= 0x2F:
+		return c.command(b2)
+	case b1 == 0x17 && b2 >= 0x21 && b2 <= 0x23: // tab offsets
+		c.col = min(c.col+int(b2-0x20), 31)
+	case b1 == 0x11 && b2 >= 0x20 && b2 <= 0x2F: // mid-row codes show as a space
+		c.put(' ')
+	case b1 == 0x11 && b2 >= 0x30 && b2 <= 0x3F:
+		c.put(special608[b2-0x30])
+	case (b1 == 0x12 || b1 == 0x13) && b2 >= 0x20 && b2 <= 0x3F: // replaces the fallback character before it
+		c.backspace()
+		c.put(extended608[b1-0x12][b2-0x20])
+	case b2 >= 0x40:
+		c.pac(b1, b2)
+	}
+	if c.mode == mode608PaintOn && c.dirty {
+		return c.update()
+	}
+	return "", false
+}
+
+// command runs a miscellaneous control code.
+func (c *cea608) command(b2 byte) (string, bool) {
+	switch b2 {
+	case 0x20: // RCL: resume caption loading
+		c.mode = mode608PopOn
+	case 0x21: // BS
+		c.backspace()
+	case 0x24: // DER: delete to end of row
+		g := c.target()
+		for i := c.col; i < 32; i++ {
+			g[c.row][i] = 0
+		}
+	case 0x25, 0x26, 0x27: // RU2, RU3, RU4
+		if c.mode != mode608RollUp {
+			c.displayed, c.hidden = grid{}, grid{}
+			c.row, c.col, c.dirty = 14, 0, true
+		}
+		c.mode, c.rollRows = mode608RollUp, int(b2-0x23)
+	case 0x29: // RDC: resume direct captioning
+		c.mode = mode608PaintOn
+	case 0x2A, 0x2B: // TR, RTD: text mode
+		c.mode = mode608Text
+	case 0x2C: // EDM: erase displayed memory
+		c.displayed, c.dirty = grid{}, true
+		return c.update()
+	case 0x2D: // CR
+		if c.mode != mode608RollUp {
+			break
+		}
+		text, ok := c.update()
+		top := max(c.row-c.rollRows+1, 0)
+		for r := 0; r < c.row; r++ {
+			if r >= top {
+				c.displayed[r] = c.displayed[r+1]
+			} else {
+				c.displayed[r] = [32]rune{}
+			}
+		}
+		c.displayed[c.row], c.col = [32]rune{}, 0
+		return text, ok
+	case 0x2E: // ENM: erase non-displayed memory
+		c.hidden = grid{}
+	case 0x2F: // EOC: end of caption, flip memories
+		c.displayed, c.hidden, c.dirty = c.hidden, c.displayed, true
+		return c.update()
+	}
+	return "", false
+}
+
+// pac runs a preamble address code: cursor to the start of a row, or to
+// an indent in it.
+func (c *cea608) pac(b1, b2 byte) {
+	row := pacRows[b1&0x07]
+	if b2&0x20 != 0 {
+		if b1 == 0x10 {
+			return
+		}
+		row++
+	}
+	if c.mode == mode608RollUp && row-1 != c.row { // the roll-up window moves with its base row
+		c.displayed[row-1], c.displayed[c.row] = c.displayed[c.row], [32]rune{}
+	}
+	c.row, c.col = row-1, 0
+	if b2&0x10 != 0 {
+		c.col = int(b2&0x0E) * 2
+	}
+}
+
+// pacRows is the row (1-15) of a PAC by its first byte's low bits; the
+// second byte's 0x20 bit selects the row after it.
+var pacRows = [8]int{11, 1, 3, 12, 14, 5, 7, 9}
+
+// target is the grid characters go to: hidden while loading pop-on.
+func (c *cea608) target() *grid {
+	if c.mode == mode608PopOn {
+		return &c.hidden
+	}
+	c.dirty = true
+	return &c.displayed
+}
+
+// put writes r at the cursor and advances it.
+func (c *cea608) put(r rune) {
+	if r == 0 {
+		return
+	}
+	c.target()[c.row][c.col] = r
+	c.col = min(c.col+1, 31)
+}
+
+// backspace moves the cursor back one column, erasing what is there.
+func (c *cea608) backspace() {
+	if c.col > 0 {
+		c.col--
+		c.target()[c.row][c.col] = 0
+	}
+}
+
+// update returns the displayed text if it changed.
+func (c *cea608) update() (string, bool) {
+	if !c.dirty {
+		return "", false
+	}
+	c.dirty = false
+	return c.displayed.text(), true
+}
+
+// text returns the grid's non-blank rows, trimmed, one per line.
+func (g *grid) text() string {
+	var rows []string
+	for _, r := range g {
+		line := strings.TrimSpace(strings.Map(func(c rune) rune {
+			if c == 0 {
+				return ' '
+			}
+			return c
+		}, string(r[:])))
+		if line != "" {
+			rows = append(rows, line)
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// basic608 maps a basic character code; it is ASCII but for a few.
+func basic608(b byte) rune {
+	switch b {
+	case 0x2A:
+		return 'á'
+	case 0x5C:
+		return 'é'
+	case 0x5E:
+		return 'í'
+	case 0x5F:
+		return 'ó'
+	case 0x60:
+		return 'ú'
+	case 0x7B:
+		return 'ç'
+	case 0x7C:
+		return '÷'
+	case 0x7D:
+		return 'Ñ'
+	case 0x7E:
+		return 'ñ'
+	case 0x7F:
+		return '█'
+	}
+	return rune(b)
+}
+
+// special608 is the special character set, 0x11 0x30-0x3F.
+var special608 = []rune("®°½¿™¢£♪à èâêîôû")
+
+// extended608 is the extended character sets, 0x12 and 0x13 0x20-0x3F.
+var extended608 = [2][]rune{
+	[]rune("ÁÉÓÚÜü‘¡*'─©℠•“”ÀÂÇÈÊËëÎÏïÔÙùÛ«»"),
+	[]rune("ÃãÍÌìÒòÕõ{}\\^_|~ÄäÖöß¥¤│ÅåØø┌┐└┘"),
+}
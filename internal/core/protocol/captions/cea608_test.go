@@ -0,0 +1,93 @@
+// If you are AI: Tests for the CEA-608 decoder and frame reordering.
+
+package captions
+
+import (
+	"reflect"
+	"testing"
+)
+
+// field1 returns cc_data triplets for 608 field 1 byte pairs; control
+// codes are doubled as encoders send them.
+func field1(pairs ...[2]byte) []byte {
+	var out []byte
+	for _, p := range pairs {
+		out = append(out, 0x04, p[0], p[1])
+		if p[0] >= 0x10 && p[0] <= 0x1F {
+			out = append(out, 0x04, p[0], p[1])
+		}
+	}
+	return out
+}
+
+// chars returns the byte pairs spelling s.
+func chars(s string) [][2]byte {
+	var out [][2]byte
+	for i := 0; i < len(s); i += 2 {
+		p := [2]byte{s[i], 0}
+		if i+1 < len(s) {
+			p[1] = s[i+1]
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// seq joins control and character pairs.
+func seq(parts ...any) [][2]byte {
+	var out [][2]byte
+	for _, p := range parts {
+		switch v := p.(type) {
+		case [2]byte:
+			out = append(out, v)
+		case string:
+			out = append(out, chars(v)...)
+		}
+	}
+	return out
+}
+
+var (
+	rcl = [2]byte{0x14, 0x20}
+	eoc = [2]byte{0x14, 0x2F}
+	edm = [2]byte{0x14, 0x2C}
+	ru2 = [2]byte{0x14, 0x25}
+	cr  = [2]byte{0x14, 0x2D}
+)
+
+// TestPopOn loads two rows off screen and shows them on end of caption.
+func TestPopOn(t *testing.T) {
+	var d Decoder
+	cc := field1(seq(rcl, [2]byte{0x14, 0x40}, "Hello,", [2]byte{0x14, 0x60}, "world ",
+		[2]byte{0x11, 0x37}, eoc, edm)...) // ♪
+	want := []Update{{Format608, "Hello,\nworld ♪"}, {Format608, ""}}
+	if got := d.Frame(0, 0, cc); !reflect.DeepEqual(got, want) {
+		t.Errorf("updates = %q, want %q", got, want)
+	}
+}
+
+// TestRollUp shows each line as it is completed, two rows at a time.
+func TestRollUp(t *testing.T) {
+	var d Decoder
+	cc := field1(seq(ru2, cr, [2]byte{0x14, 0x60}, "one", cr, "two", cr, "thr", [2]byte{0x12, 0x21}, cr)...) // É replaces r
+	want := []Update{{Format608, "one"}, {Format608, "one\ntwo"}, {Format608, "two\nthÉ"}}
+	if got := d.Frame(0, 0, cc); !reflect.DeepEqual(got, want) {
+		t.Errorf("updates = %q, want %q", got, want)
+	}
+}
+
+// TestReorder decodes frames in presentation order and holds a frame
+// until its turn, preferring 608 over 708.
+func TestReorder(t *testing.T) {
+	var d Decoder
+	if got := d.Frame(0, 66, field1(seq(eoc)...)); got != nil {
+		t.Fatalf("early update %q", got)
+	}
+	d.Frame(33, 33, field1(seq(rcl, [2]byte{0x14, 0x40}, "B-frame")...))
+	if got := d.Frame(66, 99, nil); !reflect.DeepEqual(got, []Update{{Format608, "B-frame"}}) {
+		t.Errorf("updates = %q", got)
+	}
+	if len(extended608[0]) != 32 || len(extended608[1]) != 32 || len(special608) != 16 {
+		t.Error("character tables have the wrong size")
+	}
+}
@@ -0,0 +1,245 @@
+// If you are AI: This file decodes CEA-708 caption service 1 from DTVCC
+// packets: the G0 and G1 text sets and the window commands that decide
+// what is on screen. Pen and window styling is skipped over.
+
+package captions
+
+import "strings"
+
+// window708 is a caption window's text, a row per line.
+type window708 struct {
+	defined, visible bool
+	rows             [][]rune
+}
+
+// cea708 decodes service 1.
+type cea708 struct {
+	packet  []byte // DTVCC packet being assembled
+	size    int    // its length
+	windows [8]window708
+	current int
+	changed bool // what is on screen changed since the last update
+}
+
+// triplet decodes a DTVCC cc_data pair: ccType 3 starts a packet, 2
+// continues it. It returns the displayed text and true when a complete
+// packet changed it.
+func (c *cea708) triplet(ccType, b1, b2 byte) (string, bool) {
+	if ccType == 3 {
+		c.size = int(b1&0x3F) * 2
+		if c.size == 0 {
+			c.size = 128
+		}
+		c.packet = append(c.packet[:0], b1, b2)
+	} else if c.packet != nil {
+		c.packet = append(c.packet, b1, b2)
+	}
+	if c.packet == nil || len(c.packet) < c.size {
+		return "", false
+	}
+	c.blocks(c.packet[1:c.size])
+	c.packet = nil
+	if !c.changed {
+		return "", false
+	}
+	c.changed = false
+	return c.text(), true
+}
+
+// blocks decodes a packet's service blocks, keeping service 1.
+func (c *cea708) blocks(b []byte) {
+	for len(b) > 0 {
+		svc, size := int(b[0]>>5), int(b[0]&0x1F)
+		b = b[1:]
+		if svc == 7 && size != 0 && len(b) > 0 { // extended service number
+			svc, b = int(b[0]&0x3F), b[1:]
+		}
+		if svc == 0 || size == 0 || size > len(b) {
+			return
+		}
+		if svc == 1 {
+			c.service(b[:size])
+		}
+		b = b[size:]
+	}
+}
+
+// service decodes one service block's codes.
+func (c *cea708) service(b []byte) {
+	for i := 0; i < len(b); {
+		x := b[i]
+		i++
+		switch {
+		case x == 0x10: // EXT1
+			if i >= len(b) {
+				return
+			}
+			y := b[i]
+			i++
+			switch {
+			case y < 0x20: // C2: skip its parameters
+				i += int(y >> 3)
+			case y < 0x80:
+				c.put(g2(y))
+			case y < 0x90: // C3
+				i += 4 + int(y>>3&1)
+			case y < 0xA0: // variable length C3
+				return
+			}
+		case x == 0x03: // ETX
+			c.changed = true
+		case x == 0x08: // BS
+			if w := c.window(); w != nil {
+				if row := &w.rows[len(w.rows)-1]; len(*row) > 0 {
+					*row = (*row)[:len(*row)-1]
+				}
+			}
+		case x == 0x0C: // FF: clear the window
+			if w := c.window(); w != nil {
+				w.rows, c.changed = [][]rune{nil}, true
+			}
+		case x == 0x0D: // CR
+			if w := c.window(); w != nil {
+				w.rows = append(w.rows, nil)
+				if len(w.rows) > 15 {
+					w.rows = w.rows[1:]
+				}
+				c.changed = true
+			}
+		case x == 0x0E: // HCR: clear the row
+			if w := c.window(); w != nil {
+				w.rows[len(w.rows)-1] = nil
+			}
+		case x < 0x10:
+		case x < 0x18:
+			i++
+		case x < 0x20:
+			i += 2
+		case x < 0x7F:
+			c.put(rune(x))
+		case x == 0x7F:
+			c.put('♪')
+		case x <= 0x87: // CW0-7
+			c.current = int(x - 0x80)
+		case x <= 0x8C: // CLW, DSW, HDW, TGW, DLW
+			if i >= len(b) {
+				return
+			}
+			c.windowCommand(x, b[i])
+			i++
+		case x == 0x8D: // DLY
+			i++
+		case x == 0x8F: // RST
+			c.windows, c.changed = [8]window708{}, true
+		case x == 0x90 || x == 0x92: // SPA, SPL
+			i += 2
+		case x == 0x91: // SPC
+			i += 3
+		case x == 0x97: // SWA
+			i += 4
+		case x >= 0x98 && x <= 0x9F: // DF0-7
+			if i+6 > len(b) {
+				return
+			}
+			w := &c.windows[x-0x98]
+			if !w.defined {
+				w.defined, w.rows = true, [][]rune{nil}
+			}
+			w.visible, c.current, c.changed = b[i]&0x20 != 0, int(x-0x98), true
+			i += 6
+		case x >= 0xA0: // G1: Latin-1
+			c.put(rune(x))
+		}
+	}
+}
+
+// windowCommand applies a window command to the windows in bitmap.
+func (c *cea708) windowCommand(cmd, bitmap byte) {
+	for n := range c.windows {
+		w := &c.windows[n]
+		if bitmap&(1<<n) == 0 || !w.defined {
+			continue
+		}
+		switch cmd {
+		case 0x88:
+			w.rows = [][]rune{nil}
+		case 0x89:
+			w.visible = true
+		case 0x8A:
+			w.visible = false
+		case 0x8B:
+			w.visible = !w.visible
+		case 0x8C:
+			*w = window708{}
+		}
+		c.changed = true
+	}
+}
+
+// window returns the current window, nil if it is not defined.
+func (c *cea708) window() *window708 {
+	if w := &c.windows[c.current]; w.defined {
+		return w
+	}
+	return nil
+}
+
+// put appends r to the current window's last row.
+func (c *cea708) put(r rune) {
+	if w := c.window(); w != nil && r != 0 {
+		w.rows[len(w.rows)-1] = append(w.rows[len(w.rows)-1], r)
+	}
+}
+
+// text returns the visible windows' non-blank rows, trimmed, one per line.
+func (c *cea708) text() string {
+	var rows []string
+	for _, w := range c.windows {
+		if !w.visible {
+			continue
+		}
+		for _, r := range w.rows {
+			if line := strings.TrimSpace(string(r)); line != "" {
+				rows = append(rows, line)
+			}
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// g2 maps the G2 characters with a text equivalent, 0 for the rest.
+func g2(b byte) rune {
+	switch b {
+	case 0x20, 0x21:
+		return ' '
+	case 0x25:
+		return '…'
+	case 0x2A:
+		return 'Š'
+	case 0x2C:
+		return 'Œ'
+	case 0x30:
+		return '█'
+	case 0x31:
+		return '‘'
+	case 0x32:
+		return '’'
+	case 0x33:
+		return '“'
+	case 0x34:
+		return '”'
+	case 0x35:
+		return '•'
+	case 0x39:
+		return '™'
+	case 0x3A:
+		return 'š'
+	case 0x3C:
+		return 'œ'
+	case 0x3D:
+		return '℠'
+	case 0x3F:
+		return 'Ÿ'
+	}
+	return 0
+}
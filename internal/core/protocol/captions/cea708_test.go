@@ -0,0 +1,42 @@
+// If you are AI: Tests for the CEA-708 service 1 decoder.
+
+package captions
+
+import (
+	"reflect"
+	"testing"
+)
+
+// dtvcc returns cc_data triplets for a DTVCC packet of service 1 blocks.
+func dtvcc(seq byte, blocks ...[]byte) []byte {
+	data := []byte{0}
+	for _, b := range blocks {
+		data = append(append(data, 1<<5|byte(len(b))), b...)
+	}
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+	data[0] = seq<<6 | byte(len(data)/2)
+	out := []byte{0x07, data[0], data[1]}
+	for i := 2; i < len(data); i += 2 {
+		out = append(out, 0x06, data[i], data[i+1])
+	}
+	return out
+}
+
+// TestService1 shows a hidden window's text when it is displayed and
+// clears it when the window is hidden, ignoring styling commands.
+func TestService1(t *testing.T) {
+	var d Decoder
+	define := []byte{0x98, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}                                      // DF0, hidden
+	text := append([]byte{0x90, 0x05, 0x00, 'C', 'a', 'f', 0xE9, 0x0D, 'o', 'k', 0x10, 0x39}, 0x7F) // SPA, Café CR ok™♪
+	cc := append(dtvcc(0, define, text), dtvcc(1, []byte{0x89, 0x01})...)
+	cc = append(cc, dtvcc(2, []byte{0x8A, 0x01})...)
+	want := []Update{{Format708, "Café\nok™♪"}, {Format708, ""}}
+	if got := d.Frame(0, 0, cc); !reflect.DeepEqual(got, want) {
+		t.Errorf("updates = %q, want %q", got, want)
+	}
+	if got := d.Frame(0, 0, append(field1(seq(rcl)...), dtvcc(3, []byte{0x89, 0x01})...)); got != nil {
+		t.Errorf("708 update with 608 present: %q", got)
+	}
+}
@@ -0,0 +1,136 @@
+// If you are AI: This file reads what an FLV audio tag says about its
+// track: codec, sample rate and channel count, and whether the codec
+// needs a sequence header before frames decode. Legacy sound formats
+// (MP3, G.711, Nellymoser, Speex) carry no sequence header; AAC and the
+// Enhanced RTMP codecs with a configuration record do.
+
+package flv
+
+import (
+	"encoding/binary"
+
+	"nonchalant/internal/core/protocol/aac"
+)
+
+// Enhanced RTMP audio packet types (low nibble of the first byte).
+const (
+	AudioPacketSequenceStart      = 0
+	AudioPacketCodedFrames        = 1
+	AudioPacketSequenceEnd        = 2
+	AudioPacketMultichannelConfig = 4
+	AudioPacketMultitrack         = 5
+)
+
+// AudioFormat describes an audio track as one tag payload declares it.
+// SampleRate and Channels are 0 when the tag does not say.
+type AudioFormat struct {
+	Codec      string
+	SampleRate int // Hz
+	Channels   int
+	NeedsInit  bool // frames are undecodable without a sequence header
+}
+
+// legacyRates indexes the sound rate bits of a legacy audio tag.
+var legacyRates = [4]int{5512, 11025, 22050, 44100}
+
+// fixedRates are the sound formats whose rate the rate bits do not give.
+var fixedRates = map[int]int{4: 16000, 5: 8000, 7: 8000, 8: 8000, 11: 16000, 14: 8000}
+
+// initCodecs are the Enhanced RTMP codecs that send a sequence start.
+var initCodecs = map[string]bool{"aac": true, "opus": true, "flac": true}
+
+// IsAudioSequenceHeader reports whether payload is audio codec
+// configuration: an AAC AudioSpecificConfig or an Enhanced RTMP sequence
+// start.
+func IsAudioSequenceHeader(payload []byte) bool {
+	if len(payload) < 2 {
+		return false
+	}
+	switch int(payload[0] >> 4) {
+	case 10:
+		return payload[1] == 0
+	case audioFormatExHeader:
+		return payload[0]&0x0F == AudioPacketSequenceStart
+	}
+	return false
+}
+
+// ParseAudioFormat describes the track of an FLV audio tag payload. The
+// zero AudioFormat means the payload names no known codec.
+func ParseAudioFormat(payload []byte) AudioFormat {
+	if len(payload) == 0 {
+		return AudioFormat{}
+	}
+	format := int(payload[0] >> 4)
+	if format == audioFormatExHeader {
+		return parseExAudio(payload)
+	}
+	f := AudioFormat{Codec: audioCodecs[format]}
+	if f.Codec == "" {
+		return AudioFormat{}
+	}
+	if format == 10 { // AAC's flags are fixed; the AudioSpecificConfig says
+		f.NeedsInit = true
+		if len(payload) > 2 && payload[1] == 0 {
+			f.SampleRate, f.Channels = ascFormat(payload[2:])
+		}
+		return f
+	}
+	f.SampleRate = legacyRates[payload[0]>>2&0x03]
+	if r, ok := fixedRates[format]; ok {
+		f.SampleRate = r
+	}
+	f.Channels = 1 + int(payload[0]&0x01)
+	if format == 11 { // Speex is always mono
+		f.Channels = 1
+	}
+	return f
+}
+
+// parseExAudio describes an Enhanced RTMP audio tag: FourCC at 1-4, then
+// the packet body.
+func parseExAudio(payload []byte) AudioFormat {
+	if len(payload) < 5 || payload[0]&0x0F == AudioPacketMultitrack {
+		return AudioFormat{}
+	}
+	f := AudioFormat{Codec: fourCCs[string(payload[1:5])]}
+	if f.Codec == "" {
+		return AudioFormat{}
+	}
+	f.NeedsInit = initCodecs[f.Codec]
+	if f.Codec == "opus" {
+		f.SampleRate = 48000 // Opus always decodes at 48 kHz
+	}
+	body := payload[5:]
+	switch payload[0] & 0x0F {
+	case AudioPacketSequenceStart:
+		switch f.Codec {
+		case "aac":
+			f.SampleRate, f.Channels = ascFormat(body)
+		case "opus": // OpusHead: magic(8) version(1) channels(1) ...
+			if len(body) >= 10 && string(body[:8]) == "OpusHead" {
+				f.Channels = int(body[9])
+			}
+		case "flac": // "fLaC" then the STREAMINFO block
+			if len(body) >= 8+18 && string(body[:4]) == "fLaC" {
+				info := binary.BigEndian.Uint64(body[8+10:])
+				f.SampleRate, f.Channels = int(info>>44), int(info>>41&0x07)+1
+			}
+		}
+	case AudioPacketMultichannelConfig: // channel order(1) channel count(1) ...
+		if len(body) >= 2 {
+			f.Channels = int(body[1])
+		}
+	}
+	return f
+}
+
+// ascFormat returns the sample rate and channels of an
+// AudioSpecificConfig, zeros if it does not parse.
+func ascFormat(b []byte) (int, int) {
+	c, err := aac.ParseConfig(b)
+	if err != nil {
+		return 0, 0
+	}
+	return c.SampleRate, c.Channels
+}
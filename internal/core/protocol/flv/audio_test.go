@@ -0,0 +1,54 @@
+// If you are AI: Tests for reading the audio track description from
+// legacy and Enhanced RTMP audio tags.
+
+package flv
+
+import "testing"
+
+// TestParseAudioFormat covers codecs with and without a sequence header.
+func TestParseAudioFormat(t *testing.T) {
+	opusHead := []byte{0x90, 'O', 'p', 'u', 's', 'O', 'p', 'u', 's', 'H', 'e', 'a', 'd', 1, 2, 0x38, 1, 0x80, 0xBB, 0, 0, 0, 0, 0}
+	cases := []struct {
+		name    string
+		payload []byte
+		want    AudioFormat
+	}{
+		{"pcma", []byte{0x72, 0xD5}, AudioFormat{Codec: "pcma", SampleRate: 8000, Channels: 1}},
+		{"pcmu", []byte{0x82, 0xFF}, AudioFormat{Codec: "pcmu", SampleRate: 8000, Channels: 1}},
+		{"mp3", []byte{0x2F, 0xFF, 0xFB}, AudioFormat{Codec: "mp3", SampleRate: 44100, Channels: 2}},
+		{"mp3 22k mono", []byte{0x2A, 0xFF, 0xFB}, AudioFormat{Codec: "mp3", SampleRate: 22050, Channels: 1}},
+		{"speex", []byte{0xB6, 0}, AudioFormat{Codec: "speex", SampleRate: 16000, Channels: 1}},
+		{"aac header", []byte{0xAF, 0, 0x11, 0x90}, AudioFormat{Codec: "aac", SampleRate: 48000, Channels: 2, NeedsInit: true}},
+		{"aac frame", []byte{0xAF, 1, 0x21}, AudioFormat{Codec: "aac", NeedsInit: true}},
+		{"opus start", opusHead, AudioFormat{Codec: "opus", SampleRate: 48000, Channels: 2, NeedsInit: true}},
+		{"opus frame", []byte{0x91, 'O', 'p', 'u', 's', 0xFC}, AudioFormat{Codec: "opus", SampleRate: 48000, NeedsInit: true}},
+		{"ac3 multichannel", []byte{0x94, 'a', 'c', '-', '3', 0, 6}, AudioFormat{Codec: "ac3", Channels: 6}},
+		{"unknown fourcc", []byte{0x91, 'x', 'x', 'x', 'x'}, AudioFormat{}},
+		{"empty", nil, AudioFormat{}},
+	}
+	for _, c := range cases {
+		if got := ParseAudioFormat(c.payload); got != c.want {
+			t.Errorf("%s: %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+// TestIsAudioSequenceHeader accepts AAC and Enhanced RTMP sequence starts
+// only.
+func TestIsAudioSequenceHeader(t *testing.T) {
+	for _, c := range []struct {
+		payload []byte
+		want    bool
+	}{
+		{[]byte{0xAF, 0, 0x11, 0x90}, true},
+		{[]byte{0xAF, 1, 0x21}, false},
+		{[]byte{0x90, 'O', 'p', 'u', 's'}, true},
+		{[]byte{0x91, 'O', 'p', 'u', 's'}, false},
+		{[]byte{0x2F, 0}, false},
+		{[]byte{0x72, 0}, false},
+	} {
+		if got := IsAudioSequenceHeader(c.payload); got != c.want {
+			t.Errorf("% x: %v, want %v", c.payload, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// If you are AI: This file writes onFI script tags, the encoder convention
+// for stamping an FLV timeline with wall-clock date and time, so players
+// can line up streams (camera angles) by absolute time.
+
+package flv
+
+import (
+	"bytes"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// AppendClockTag appends an onFI script tag at timestamp carrying wall as
+// UTC date (sd, dd-mm-yyyy) and time of day (st, hh:mm:ss.mmm).
+func AppendClockTag(dst []byte, timestamp uint32, wall time.Time) []byte {
+	wall = wall.UTC()
+	var b bytes.Buffer
+	_ = amf0.Encode(&b, "onFI")
+	_ = amf0.Encode(&b, amf0.Object{"sd": wall.Format("02-01-2006"), "st": wall.Format("15:04:05.000")})
+	return AppendTag(dst, TagTypeScript, timestamp, b.Bytes())
+}
+
+// AppendClock appends an onFI tag ahead of msg when it is a video keyframe
+// and stream's timeline has a wall-clock anchor; ts is msg's timestamp on
+// the viewer's timeline.
+func AppendClock(dst []byte, stream *bus.Stream, msg *bus.MediaMessage, ts uint32) []byte {
+	if msg.Type != bus.MessageTypeVideo || msg.IsInit || !IsVideoKeyframe(msg.Payload) {
+		return dst
+	}
+	clock, ok := stream.Clock()
+	if !ok {
+		return dst
+	}
+	return AppendClockTag(dst, ts, clock.At(msg.Timestamp))
+}
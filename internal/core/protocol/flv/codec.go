@@ -0,0 +1,102 @@
+// If you are AI: This file names the codec carried by FLV audio and video
+// tags, covering the legacy codec IDs and the Enhanced RTMP FourCCs, so
+// policy checks and reports can speak in "h264" / "aac" rather than IDs.
+
+package flv
+
+// videoCodecs maps legacy FLV video codec IDs to names. 12 is the widely
+// used (non-standard) HEVC extension.
+var videoCodecs = map[int]string{
+	2: "h263", 3: "screen", 4: "vp6", 5: "vp6a", 6: "screen2", 7: "h264", 12: "hevc",
+}
+
+// audioCodecs maps FLV sound formats to names.
+var audioCodecs = map[int]string{
+	0: "pcm", 1: "adpcm", 2: "mp3", 3: "pcm", 4: "nellymoser", 5: "nellymoser",
+	6: "nellymoser", 7: "pcma", 8: "pcmu", 10: "aac", 11: "speex", 14: "mp3",
+}
+
+// fourCCs maps Enhanced RTMP FourCCs to names.
+var fourCCs = map[string]string{
+	"avc1": "h264", "hvc1": "hevc", "av01": "av1", "vp09": "vp9",
+	"mp4a": "aac", ".mp3": "mp3", "Opus": "opus", "ac-3": "ac3", "ec-3": "eac3", "fLaC": "flac",
+}
+
+// audioFormatExHeader is the sound format that marks an Enhanced RTMP
+// audio tag with a FourCC.
+const audioFormatExHeader = 9
+
+// VideoCodecName names a legacy FLV video codec ID ("" if unknown).
+func VideoCodecName(id int) string { return videoCodecs[id] }
+
+// AudioCodecName names an FLV sound format ("" if unknown).
+func AudioCodecName(id int) string { return audioCodecs[id] }
+
+// FourCCName names an Enhanced RTMP FourCC ("" if unknown).
+func FourCCName(fourcc string) string { return fourCCs[fourcc] }
+
+// VideoCodec names the codec of an FLV video tag payload. Enhanced RTMP
+// tags set the top bit of the first byte and carry a FourCC.
+func VideoCodec(payload []byte) string {
+	if len(payload) == 0 {
+		return ""
+	}
+	if payload[0]&0x80 != 0 {
+		if len(payload) < 5 {
+			return ""
+		}
+		return fourCCs[string(payload[1:5])]
+	}
+	return videoCodecs[int(payload[0]&0x0F)]
+}
+
+// AudioCodec names the codec of an FLV audio tag payload.
+func AudioCodec(payload []byte) string {
+	if len(payload) == 0 {
+		return ""
+	}
+	format := int(payload[0] >> 4)
+	if format == audioFormatExHeader {
+		if len(payload) < 5 {
+			return ""
+		}
+		return fourCCs[string(payload[1:5])]
+	}
+	return audioCodecs[format]
+}
+
+// MetadataVideoCodec names an onMetaData videocodecid, which is a legacy
+// codec ID (number) or an Enhanced RTMP FourCC (string).
+func MetadataVideoCodec(v interface{}) string { return metadataCodec(v, videoCodecs) }
+
+// MetadataAudioCodec names an onMetaData audiocodecid.
+func MetadataAudioCodec(v interface{}) string { return metadataCodec(v, audioCodecs) }
+
+// metadataCodec resolves a numeric id through ids or a FourCC string.
+func metadataCodec(v interface{}, ids map[int]string) string {
+	switch id := v.(type) {
+	case float64:
+		return ids[int(id)]
+	case string:
+		return fourCCs[id]
+	}
+	return ""
+}
+
+// KnownCodec reports whether name is a video or audio codec name returned
+// by this package.
+func KnownCodec(name string) bool {
+	for _, m := range []map[int]string{videoCodecs, audioCodecs} {
+		for _, n := range m {
+			if n == name {
+				return true
+			}
+		}
+	}
+	for _, n := range fourCCs {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+// If you are AI: This file rebases a stream's timestamps so each FLV viewer's
+// output starts at zero. Shared by the HTTP-FLV and WebSocket-FLV outputs.
+
+package flv
+
+import "nonchalant/internal/core/bus"
+
+// Rebaser maps stream timestamps onto a viewer's timeline. The zero value
+// is ready to use; the first non-init message becomes time zero.
+type Rebaser struct {
+	offset uint32
+	set    bool
+
+	// OnStart, if set, is called with the stream timestamp that becomes
+	// time zero.
+	OnStart func(origin uint32)
+}
+
+// Rebase returns msg's timestamp relative to the first non-init message.
+// Init messages are always at zero, as is anything before the start. The
+// difference is taken modulo 2^32, so a timeline that wraps after ~49 days
+// stays continuous.
+func (r *Rebaser) Rebase(msg *bus.MediaMessage) uint32 {
+	if msg.IsInit {
+		return 0
+	}
+	if !r.set {
+		r.offset, r.set = msg.Timestamp, true
+		if r.OnStart != nil {
+			r.OnStart(r.offset)
+		}
+	}
+	d := msg.Timestamp - r.offset
+	if int32(d) < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,37 @@
+// If you are AI: This file names FLV script data messages and tells the
+// timed ones (cue points, text, SCTE-35 splices, captions) apart from stream-level onMetaData.
+
+package flv
+
+import (
+	"bytes"
+
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// ScriptMetaData is the stream-level script data message, cached for late
+// joiners. Every other script data message is timed.
+const ScriptMetaData = "onMetaData"
+
+// Timed script data names: events at a point on the timeline, passed to
+// viewers as they happen rather than cached like onMetaData.
+const (
+	ScriptCuePoint = "onCuePoint"
+	ScriptTextData = "onTextData"
+	ScriptSCTE35   = "onSCTE35"
+	ScriptCaption  = "onCaptionInfo"
+)
+
+// ScriptName returns the name a script data payload starts with, or "".
+func ScriptName(payload []byte) string {
+	name, err := amf0.DecodeString(bytes.NewReader(payload))
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// IsTimedScript reports whether name is a timed script data message.
+func IsTimedScript(name string) bool {
+	return name == ScriptCuePoint || name == ScriptTextData || name == ScriptSCTE35 || name == ScriptCaption
+}
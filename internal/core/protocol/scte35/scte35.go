@@ -0,0 +1,222 @@
+// If you are AI: This file parses SCTE-35 splice_info_sections, the ad
+// insertion cues encoders send with a stream: splice_insert, and
+// time_signal with segmentation descriptors. Only what the packager needs
+// to mark ad breaks is decoded: direction, event ID, splice time and
+// planned duration.
+
+package scte35
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Splice command types this package decodes.
+const (
+	CommandSpliceInsert = 0x05
+	CommandTimeSignal   = 0x06
+)
+
+// segmentationTag is the segmentation_descriptor tag, identifier "CUEI".
+const segmentationTag = 0x02
+
+// ErrShort is returned for a section that ends early.
+var ErrShort = errors.New("scte35: section too short")
+
+// Splice is a decoded splice_info_section.
+type Splice struct {
+	Command  byte
+	EventID  uint32 // splice_event_id or segmentation_event_id
+	Cancel   bool   // cancels an earlier event with EventID
+	Out      bool   // an ad break starts: leave the network feed
+	In       bool   // the break ends: return to the network feed
+	PTS      uint64 // splice time on the encoder's 90 kHz clock, pts_adjustment applied
+	HasPTS   bool   // false for splice_immediate or an unspecified time
+	Duration time.Duration
+}
+
+// Parse decodes a splice_info_section. A command other than splice_insert
+// or time_signal, or a time_signal without a segmentation descriptor,
+// decodes with neither Out nor In set.
+func Parse(b []byte) (Splice, error) {
+	r := &bitReader{data: b}
+	if r.bits(8) != 0xFC {
+		return Splice{}, errors.New("scte35: not a splice_info_section")
+	}
+	r.bits(4)
+	if length := int(r.bits(12)); length+3 > len(b) {
+		return Splice{}, ErrShort
+	}
+	r.bits(8) // protocol_version
+	if r.bits(1) == 1 {
+		return Splice{}, errors.New("scte35: encrypted section")
+	}
+	r.bits(6) // encryption_algorithm
+	adjust := r.bits(33)
+	r.bits(8 + 12) // cw_index, tier
+	cmdLen := int(r.bits(12))
+	s := Splice{Command: byte(r.bits(8))}
+	start := r.pos
+	switch s.Command {
+	case CommandSpliceInsert:
+		s.spliceInsert(r)
+	case CommandTimeSignal:
+		s.PTS, s.HasPTS = spliceTime(r)
+	}
+	if cmdLen != 0xFFF && s.Command <= CommandTimeSignal {
+		r.pos = start + cmdLen*8
+	}
+	if s.Command == CommandTimeSignal {
+		s.descriptors(r, int(r.bits(16)))
+	}
+	if r.err != nil {
+		return Splice{}, r.err
+	}
+	if s.HasPTS {
+		s.PTS = (s.PTS + adjust) & (1<<33 - 1)
+	}
+	return s, nil
+}
+
+// ParseBase64 decodes a base64 splice_info_section, the form SCTE-35
+// travels in through text protocols.
+func ParseBase64(v string) (Splice, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+	if err != nil {
+		return Splice{}, fmt.Errorf("scte35: %w", err)
+	}
+	return Parse(b)
+}
+
+// spliceInsert decodes a splice_insert command.
+func (s *Splice) spliceInsert(r *bitReader) {
+	s.EventID = uint32(r.bits(32))
+	s.Cancel = r.bits(1) == 1
+	r.bits(7)
+	if s.Cancel {
+		return
+	}
+	out := r.bits(1) == 1
+	program := r.bits(1) == 1
+	hasDuration := r.bits(1) == 1
+	immediate := r.bits(1) == 1
+	r.bits(4)
+	s.Out, s.In = out, !out
+	switch {
+	case program && !immediate:
+		s.PTS, s.HasPTS = spliceTime(r)
+	case !program:
+		for n := r.bits(8); n > 0; n-- {
+			r.bits(8) // component_tag
+			if !immediate {
+				spliceTime(r)
+			}
+		}
+	}
+	if hasDuration {
+		r.bits(1 + 6) // auto_return, reserved
+		s.Duration = ticks(r.bits(33))
+	}
+	r.bits(16 + 8 + 8) // unique_program_id, avail_num, avails_expected
+}
+
+// descriptors reads the splice_descriptor loop of n bytes, taking the
+// break direction and duration from the first segmentation descriptor
+// that opens or closes one.
+func (s *Splice) descriptors(r *bitReader, n int) {
+	end := r.pos + n*8
+	for r.pos+16 <= end && r.err == nil {
+		tag, length := r.bits(8), int(r.bits(8))
+		next := r.pos + length*8
+		if tag == segmentationTag && r.bits(32) == 0x43554549 && !s.Out && !s.In { // "CUEI"
+			s.segmentation(r)
+		}
+		r.pos = next
+	}
+	r.pos = end
+}
+
+// segmentation decodes a segmentation_descriptor after its identifier.
+func (s *Splice) segmentation(r *bitReader) {
+	id := uint32(r.bits(32))
+	if r.bits(1) == 1 { // segmentation_event_cancel_indicator
+		s.EventID, s.Cancel = id, true
+		return
+	}
+	r.bits(7)
+	program := r.bits(1) == 1
+	hasDuration := r.bits(1) == 1
+	r.bits(6)
+	if !program {
+		for n := r.bits(8); n > 0; n-- {
+			r.bits(8 + 7 + 33) // component_tag, reserved, pts_offset
+		}
+	}
+	var dur time.Duration
+	if hasDuration {
+		dur = ticks(r.bits(40))
+	}
+	r.bits(8) // segmentation_upid_type
+	r.pos += int(r.bits(8)) * 8
+	typ := r.bits(8)
+	if out, in := breakStart(typ), breakEnd(typ); out || in {
+		s.EventID, s.Out, s.In, s.Duration = id, out, in, dur
+	}
+}
+
+// breakStart reports whether a segmentation_type_id opens an ad break:
+// break start, or a provider / distributor advertisement or placement
+// opportunity start.
+func breakStart(typ uint64) bool {
+	switch typ {
+	case 0x22, 0x30, 0x32, 0x34, 0x36, 0x38, 0x3A, 0x44, 0x46:
+		return true
+	}
+	return false
+}
+
+// breakEnd reports whether a segmentation_type_id closes what breakStart
+// opens.
+func breakEnd(typ uint64) bool {
+	return breakStart(typ - 1)
+}
+
+// spliceTime decodes a splice_time(): the PTS, if one is specified.
+func spliceTime(r *bitReader) (uint64, bool) {
+	if r.bits(1) == 0 {
+		r.bits(7)
+		return 0, false
+	}
+	r.bits(6)
+	return r.bits(33), true
+}
+
+// ticks converts 90 kHz clock ticks to a duration.
+func ticks(n uint64) time.Duration {
+	return time.Duration(n * 100000 / 9) // n * 1e9 / 90000 without overflow
+}
+
+// bitReader reads big-endian bits. The first read past the end sets err;
+// later reads return 0.
+type bitReader struct {
+	data []byte
+	pos  int // bit position
+	err  error
+}
+
+// bits reads n (<= 64) bits.
+func (r *bitReader) bits(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		if r.pos >= len(r.data)*8 {
+			r.err = ErrShort
+			return 0
+		}
+		v = v<<1 | uint64(r.data[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v
+}
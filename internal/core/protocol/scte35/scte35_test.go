@@ -0,0 +1,61 @@
+// If you are AI: Tests for splice_info_section parsing, against the
+// sample sections of the SCTE-35 specification.
+
+package scte35
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseSpliceInsert decodes the spec's splice_insert sample.
+func TestParseSpliceInsert(t *testing.T) {
+	s, err := ParseBase64("/DAvAAAAAAAA///wFAVIAACPf+/+c2nALv4AUsz1AAAAAAAKAAhDVUVJAAABNWLbowo=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Splice{Command: CommandSpliceInsert, EventID: 0x4800008F, Out: true,
+		PTS: 0x07369C02E, HasPTS: true, Duration: ticks(0x00052CCF5)}
+	if s != want {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+}
+
+// TestParseTimeSignal decodes the spec's time_signal sample: a provider
+// placement opportunity start.
+func TestParseTimeSignal(t *testing.T) {
+	s, err := ParseBase64("/DA0AAAAAAAA///wBQb+cr0AUAAeAhxDVUVJSAAAjn/PAAGlmbAICAAAAAAsoKGKNAIAmsnRfg==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Splice{Command: CommandTimeSignal, EventID: 0x4800008E, Out: true,
+		PTS: 0x072BD0050, HasPTS: true, Duration: 307 * time.Second}
+	if s != want {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+}
+
+// TestParseSpliceInsertIn decodes an immediate return to the network.
+func TestParseSpliceInsertIn(t *testing.T) {
+	sec := []byte{0xFC, 0x30, 0x19, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0xF0, 0x0A, 0x05,
+		0, 0, 0, 7, 0x7F, 0x1F, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}
+	s, err := Parse(sec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.In || s.Out || s.HasPTS || s.EventID != 7 {
+		t.Errorf("got %+v", s)
+	}
+}
+
+// TestParseErrors rejects non-SCTE-35 and truncated input.
+func TestParseErrors(t *testing.T) {
+	for _, b := range [][]byte{nil, {0x00, 0x30, 0x11}, {0xFC, 0x30, 0x40, 0, 0}} {
+		if _, err := Parse(b); err == nil {
+			t.Errorf("Parse(%x): want error", b)
+		}
+	}
+	if _, err := ParseBase64("not base64!"); err == nil {
+		t.Error("ParseBase64: want error")
+	}
+}
@@ -0,0 +1,142 @@
+// If you are AI: This file implements the in-process event bus. Services
+// emit typed lifecycle events; the API streams them to dashboards over SSE.
+// A short history lets reconnecting clients resume from Last-Event-ID.
+
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type names an event. The values are the SSE "event:" field.
+type Type string
+
+// Event types emitted by the services.
+const (
+	PublishStarted    Type = "publish_started"
+	PublishStopped    Type = "publish_stopped"
+	SubscriberJoined  Type = "subscriber_joined"
+	SubscriberLeft    Type = "subscriber_left"
+	RelayStateChanged Type = "relay_state_changed"
+	PackagerStarted   Type = "packager_started"
+	PackagerExited    Type = "packager_exited"
+	PolicyRejected    Type = "policy_rejected"
+	IngestAlert       Type = "ingest_alert"
+	IngestRecovered   Type = "ingest_recovered"
+)
+
+// Event is one server event. Fields that do not apply to Type are empty.
+type Event struct {
+	ID         uint64    `json:"id"`
+	Type       Type      `json:"type"`
+	Time       time.Time `json:"time"`
+	App        string    `json:"app,omitempty"`
+	Name       string    `json:"name,omitempty"`
+	Protocol   string    `json:"protocol,omitempty"`    // rtmp, httpflv, wsflv, hls, dash
+	SessionID  string    `json:"session_id,omitempty"`  // see /api/sessions/{id}
+	RemoteAddr string    `json:"remote_addr,omitempty"` // client address
+	Target     string    `json:"target,omitempty"`      // relay push target ID
+	State      string    `json:"state,omitempty"`       // new relay status
+	Reason     string    `json:"reason,omitempty"`      // rejection or alert reason
+	Error      string    `json:"error,omitempty"`
+}
+
+// historySize is how many recent events are kept for Last-Event-ID resume.
+const historySize = 1024
+
+// subscriberBuffer is each subscriber's queue length. A subscriber that
+// falls this far behind is closed; SSE clients reconnect and resume.
+const subscriberBuffer = 256
+
+// Bus assigns IDs to events and fans them out. A nil *Bus drops every
+// event, so emitters need no checks.
+type Bus struct {
+	mu      sync.Mutex
+	nextID  uint64
+	history []Event // ring of the last historySize events
+	head    int     // index of the oldest event once history is full
+	subs    map[chan Event]struct{}
+	closed  bool
+}
+
+// NewBus returns an empty bus. IDs start at 1.
+func NewBus() *Bus {
+	return &Bus{nextID: 1, subs: make(map[chan Event]struct{})}
+}
+
+// Emit stamps e with the next ID and the current time and delivers it.
+// It never blocks.
+func (b *Bus) Emit(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	e.ID = b.nextID
+	b.nextID++
+	e.Time = time.Now()
+	if len(b.history) < historySize {
+		b.history = append(b.history, e)
+	} else {
+		b.history[b.head] = e
+		b.head = (b.head + 1) % historySize
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns the retained events with an ID above after, and a
+// channel carrying every later event. The channel is closed when the
+// subscriber falls behind or the bus closes; cancel releases it early.
+func (b *Bus) Subscribe(after uint64) (backlog []Event, ch <-chan Event, cancel func()) {
+	c := make(chan Event, subscriberBuffer)
+	if b == nil {
+		close(c)
+		return nil, c, func() {}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.history {
+		if e := b.history[(b.head+i)%len(b.history)]; e.ID > after {
+			backlog = append(backlog, e)
+		}
+	}
+	if b.closed {
+		close(c)
+		return backlog, c, func() {}
+	}
+	b.subs[c] = struct{}{}
+	return backlog, c, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[c]; ok {
+			delete(b.subs, c)
+			close(c)
+		}
+	}
+}
+
+// Close ends every subscription so long-lived streams return. Later
+// events are dropped.
+func (b *Bus) Close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,63 @@
+// If you are AI: Unit tests for the event bus: ID order, resume from
+// history, slow-subscriber eviction and Close.
+
+package events
+
+import "testing"
+
+// TestResume checks IDs and that Subscribe replays only newer events.
+func TestResume(t *testing.T) {
+	b := NewBus()
+	b.Emit(Event{Type: PublishStarted, App: "live", Name: "a"})
+	b.Emit(Event{Type: SubscriberJoined, App: "live", Name: "a"})
+	b.Emit(Event{Type: PublishStopped, App: "live", Name: "a"})
+
+	backlog, ch, cancel := b.Subscribe(1)
+	defer cancel()
+	if len(backlog) != 2 || backlog[0].ID != 2 || backlog[1].Type != PublishStopped {
+		t.Fatalf("backlog = %+v, want events 2 and 3", backlog)
+	}
+	b.Emit(Event{Type: PolicyRejected, Reason: "bad_name"})
+	if e := <-ch; e.ID != 4 || e.Reason != "bad_name" {
+		t.Fatalf("live event = %+v", e)
+	}
+}
+
+// TestHistoryWraps keeps only the newest historySize events.
+func TestHistoryWraps(t *testing.T) {
+	b := NewBus()
+	for i := 0; i < historySize+10; i++ {
+		b.Emit(Event{Type: SubscriberJoined})
+	}
+	backlog, _, cancel := b.Subscribe(0)
+	cancel()
+	if len(backlog) != historySize || backlog[0].ID != 11 || backlog[len(backlog)-1].ID != historySize+10 {
+		t.Fatalf("backlog has %d events, %d..%d", len(backlog), backlog[0].ID, backlog[len(backlog)-1].ID)
+	}
+}
+
+// TestSlowSubscriber closes a subscriber that stops reading, and Close
+// ends the rest.
+func TestSlowSubscriber(t *testing.T) {
+	b := NewBus()
+	_, slow, _ := b.Subscribe(0)
+	for i := 0; i < subscriberBuffer+1; i++ {
+		b.Emit(Event{Type: SubscriberJoined})
+	}
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Fatalf("slow subscriber got %d events before close, want %d", n, subscriberBuffer)
+	}
+
+	_, ch, cancel := b.Subscribe(0)
+	defer cancel()
+	b.Close()
+	if _, ok := <-ch; ok {
+		t.Fatal("subscription survived Close")
+	}
+	var nilBus *Bus
+	nilBus.Emit(Event{})
+}
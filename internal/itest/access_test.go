@@ -0,0 +1,126 @@
+// If you are AI: Integration test for access rules and per-IP limits.
+// Loopback clients are exempt, so the test speaks PROXY protocol from
+// loopback to appear as other addresses.
+
+package itest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialAs opens a connection to port that claims to come from src.
+func dialAs(t *testing.T, port int, src string) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	fmt.Fprintf(conn, "PROXY TCP4 %s 127.0.0.1 40000 %d\r\n", src, port)
+	return conn
+}
+
+// publishAs connects from src and publishes live/{name}.
+func publishAs(t *testing.T, port int, src, name string) net.Conn {
+	t.Helper()
+	return publishOn(t, dialAs(t, port, src), name)
+}
+
+// publishOn sends the handshake, connect, createStream and publish for
+// live/{name} on conn. It does not wait for the server's answers.
+func publishOn(t *testing.T, conn net.Conn, name string) net.Conn {
+	t.Helper()
+	if err := doHandshake(conn); err != nil {
+		t.Fatalf("handshake from %s: %v", conn.LocalAddr(), err)
+	}
+	if err := sendConnect(conn); err != nil {
+		t.Fatal(err)
+	}
+	sendAMF(t, conn, 0, amfString("createStream"), amfNumber(2), []byte{0x05})
+	sendAMF(t, conn, 1, amfString("publish"), amfNumber(3), []byte{0x05},
+		amfString(name), amfString("live"))
+	return conn
+}
+
+// waitClosed reports whether the server closes conn within d.
+func waitClosed(conn net.Conn, d time.Duration) bool {
+	conn.SetReadDeadline(time.Now().Add(d))
+	_, err := io.Copy(io.Discard, conn)
+	return err == nil // EOF, not a deadline error
+}
+
+// TestAccessControl checks the publish and play ACLs, the per-IP
+// connection cap and the handshake deadline, and their rejection counters.
+func TestAccessControl(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "access.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"proxy_protocol:\n  rtmp: true\n  http: true\n  trusted_cidrs: [\"127.0.0.0/8\"]\n"+
+			"access:\n  max_conns_per_ip: 1\n  handshake_timeout_seconds: 1\n"+
+			"  rules:\n    - app: live\n      publish_allow: [\"10.0.0.0/8\"]\n      play_deny: [\"192.0.2.0/24\"]\n",
+		httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	// Play from a denied range.
+	hc := dialAs(t, httpPort, "192.0.2.5")
+	fmt.Fprint(hc, "GET /live/any.flv HTTP/1.1\r\nHost: lb\r\nConnection: close\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(hc), nil)
+	hc.Close()
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("denied play: resp %v err %v, want 403", resp, err)
+	}
+
+	// Publish from outside publish_allow is refused and disconnected.
+	denied := publishAs(t, rtmpPort, "198.51.100.1", "denied")
+	if !waitClosed(denied, 5*time.Second) {
+		t.Error("denied publisher was not disconnected")
+	}
+	denied.Close()
+
+	// Publish from inside publish_allow works, and holds the IP's only slot.
+	pub := publishAs(t, rtmpPort, "10.1.1.1", "allowed")
+	defer pub.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "allowed") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("allowed publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	second := dialAs(t, rtmpPort, "10.1.1.1")
+	if doHandshake(second) == nil {
+		t.Error("second connection from a capped IP completed a handshake")
+	}
+	second.Close()
+
+	// A client that never sends a handshake is dropped at the deadline.
+	idle := dialAs(t, rtmpPort, "203.0.113.9")
+	if !waitClosed(idle, 5*time.Second) {
+		t.Error("idle client was not dropped at the handshake deadline")
+	}
+	idle.Close()
+
+	metrics := string(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/metrics", httpPort)))
+	for _, reason := range []string{"play_denied", "publish_denied", "conn_limit", "handshake_timeout"} {
+		want := fmt.Sprintf(`nonchalant_rejections_total{reason="%s"} 1`, reason)
+		if !strings.Contains(metrics, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// If you are AI: Integration test for the split layout: the admin surface
+// moves to its own listener on health_port and the public port serves media
+// plus /healthz only.
+
+package itest
+
+import (
+	"fmt"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestSplitAdminListener checks which routes answer on which port.
+func TestSplitAdminListener(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	adminPort := findFreePort(t)
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "split.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: %d\n  http_port: %d\n  rtmp_port: %d\n"+
+			"  layout: split\n  admin_bind: 127.0.0.1\n",
+		adminPort, httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	cases := []struct {
+		port int
+		path string
+		want int
+	}{
+		{httpPort, "/healthz", http.StatusOK},
+		{httpPort, "/api/streams", http.StatusNotFound},
+		{httpPort, "/metrics", http.StatusNotFound},
+		{httpPort, "/debug/pprof/", http.StatusNotFound},
+		{adminPort, "/healthz", http.StatusOK},
+		{adminPort, "/readyz", http.StatusOK},
+		{adminPort, "/api/streams", http.StatusOK},
+		{adminPort, "/metrics", http.StatusOK},
+		{adminPort, "/debug/pprof/", http.StatusOK},
+		{adminPort, "/live/x.flv", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d%s", c.port, c.path))
+		if err != nil {
+			t.Fatalf("GET :%d%s: %v", c.port, c.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.want {
+			t.Errorf("GET :%d%s = %d, want %d", c.port, c.path, resp.StatusCode, c.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// If you are AI: Integration test for viewer analytics: one broadcast with
+// an HTTP-FLV viewer is reported live at /api/analytics and exported as
+// JSON and CSV when the publisher leaves.
+
+package itest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestViewerAnalytics checks the live report and the export on unpublish.
+func TestViewerAnalytics(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	exportDir := filepath.Join(t.TempDir(), "reports")
+	cfgPath := filepath.Join(t.TempDir(), "analytics.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\nanalytics:\n  export_dir: %s\n",
+		httpPort, rtmpPort, exportDir))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	pub, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub.SetDeadline(time.Now().Add(15 * time.Second))
+	publishOn(t, pub, "show")
+	for deadline := time.Now().Add(5 * time.Second); publisherAddr(t, httpPort, "show") == ""; {
+		if time.Now().After(deadline) {
+			t.Fatal("publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	viewer, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer viewer.Close()
+	fmt.Fprintf(viewer, "GET /live/show.flv HTTP/1.1\r\nHost: x\r\nUser-Agent: itest-player\r\n\r\n")
+
+	type report struct {
+		Live          bool `json:"live"`
+		PeakViewers   int  `json:"peak_viewers"`
+		UniqueViewers int  `json:"unique_viewers"`
+		Protocols     map[string]struct {
+			Sessions int `json:"sessions"`
+		} `json:"protocols"`
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d/api/analytics/live/show", httpPort)
+	var rep report
+	for deadline := time.Now().Add(5 * time.Second); rep.PeakViewers == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("viewer never counted: %+v", rep)
+		}
+		time.Sleep(100 * time.Millisecond)
+		_ = json.Unmarshal(mustGet(t, url), &rep)
+	}
+	if !rep.Live || rep.UniqueViewers != 1 || rep.Protocols["httpflv"].Sessions != 1 {
+		t.Errorf("live report = %+v", rep)
+	}
+
+	pub.Close()
+	var files []string
+	for deadline := time.Now().Add(5 * time.Second); len(files) < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("export files = %v", files)
+		}
+		time.Sleep(100 * time.Millisecond)
+		files, _ = filepath.Glob(filepath.Join(exportDir, "live_show_*"))
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil || !strings.Contains(string(data), "httpflv") {
+			t.Errorf("%s: %s (%v)", f, data, err)
+		}
+	}
+	if err := json.Unmarshal(mustGet(t, url), &rep); err != nil || rep.Live {
+		t.Errorf("report after unpublish = %+v (%v)", rep, err)
+	}
+}
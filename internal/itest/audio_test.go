@@ -0,0 +1,103 @@
+// If you are AI: Integration test for audio codecs without a sequence
+// header: a G.711 publisher's HTTP-FLV viewers get the has-audio flag
+// and its frames.
+
+package itest
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// sendAudioAt writes one audio message with timestamp ts on the publish
+// stream.
+func sendAudioAt(t *testing.T, conn net.Conn, ts uint32, body []byte) {
+	t.Helper()
+	hdr := []byte{0x04, byte(ts >> 16), byte(ts >> 8), byte(ts),
+		byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body)), 8, 1, 0, 0, 0}
+	if _, err := conn.Write(append(hdr, body...)); err != nil {
+		t.Fatalf("send audio: %v", err)
+	}
+}
+
+// TestG711Audio publishes A-law audio, which has no sequence header, and
+// plays it back over HTTP-FLV.
+func TestG711Audio(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "audio.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n", httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	pub, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	pub.SetDeadline(time.Now().Add(15 * time.Second))
+	publishOn(t, pub, "cam")
+	sps, _ := hex.DecodeString(x264SPS)
+	sendAt(t, pub, 0, append([]byte{0x17, 0, 0, 0, 0, 1, 0x64, 0x00, 0x28, 0xFF, 0xE1, 0, byte(len(sps))}, sps...))
+	sendAudioAt(t, pub, 0, []byte{0x72, 0xD5, 0xD5, 0xD5})
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "cam") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	viewer, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer viewer.Close()
+	viewer.SetDeadline(time.Now().Add(10 * time.Second))
+	fmt.Fprintf(viewer, "GET /live/cam.flv HTTP/1.1\r\nHost: x\r\n\r\n")
+	br := bufio.NewReader(viewer)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("play: %v %v", resp, err)
+	}
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(br, header); err != nil {
+		t.Fatal(err)
+	}
+	if header[4] != 0x05 {
+		t.Fatalf("FLV header flags %#x, want audio and video", header[4])
+	}
+
+	sendAt(t, pub, 1000, []byte{0x17, 1, 0, 0, 0, 0, 0, 0, 2, 0x65, 0x88})
+	sendAudioAt(t, pub, 1020, []byte{0x72, 0xD5, 0xD5, 0xD5})
+	nextKeyframe(t, br)
+	hdr := make([]byte, 11)
+	for {
+		if _, err := io.ReadFull(br, hdr); err != nil {
+			t.Fatalf("flv tag: %v", err)
+		}
+		body := make([]byte, int(hdr[1])<<16|int(hdr[2])<<8|int(hdr[3])+4)
+		if _, err := io.ReadFull(br, body); err != nil {
+			t.Fatalf("flv tag body: %v", err)
+		}
+		if hdr[0] == 8 {
+			if body[0] != 0x72 {
+				t.Errorf("audio tag % x, want A-law", body[:len(body)-4])
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// If you are AI: Integration test for capacity limits: a publish over
+// max_streams is rejected, a viewer over max_subscribers_per_stream gets 503
+// with Retry-After, and /api/server reports usage against the limits.
+// Only the server's own loopback pulls are exempt.
+
+package itest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// playAs requests /live/{name}.flv as src and returns the response; the
+// connection stays open while the body streams.
+func playAs(t *testing.T, port int, src, name string) (*http.Response, net.Conn) {
+	t.Helper()
+	conn := dialAs(t, port, src)
+	fmt.Fprintf(conn, "GET /live/%s.flv HTTP/1.1\r\nHost: lb\r\n\r\n", name)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("play as %s: %v", src, err)
+	}
+	return resp, conn
+}
+
+// TestCapacityLimits runs a server capped at one stream and one viewer per
+// stream. Viewers appear remote through PROXY headers.
+func TestCapacityLimits(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "capacity.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"proxy_protocol:\n  rtmp: true\n  http: true\n  trusted_cidrs: [\"127.0.0.0/8\"]\n"+
+			"capacity:\n  max_streams: 1\n  max_subscribers_per_stream: 1\n  retry_after_seconds: 7\n",
+		httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	pub := publishAs(t, rtmpPort, "10.0.0.1", "one")
+	defer pub.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "one") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("first publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	over := publishAs(t, rtmpPort, "10.0.0.2", "two")
+	if !waitClosed(over, 5*time.Second) {
+		t.Error("publisher over max_streams was not disconnected")
+	}
+	over.Close()
+	metrics := string(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/metrics", httpPort)))
+	if want := `nonchalant_rejections_total{reason="capacity"} 1`; !strings.Contains(metrics, want) {
+		t.Errorf("metrics missing %s", want)
+	}
+
+	resp, viewer := playAs(t, httpPort, "192.0.2.1", "one")
+	defer viewer.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("first viewer: %d, want 200", resp.StatusCode)
+	}
+	for subscriberCount(t, httpPort, "one") != 1 {
+		if time.Now().After(deadline.Add(5 * time.Second)) {
+			t.Fatal("first viewer never attached")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	resp, second := playAs(t, httpPort, "192.0.2.2", "one")
+	second.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "7" {
+		t.Errorf("second viewer: %d Retry-After %q, want 503 and 7", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	var server struct {
+		Capacity struct {
+			Streams                 int `json:"streams"`
+			MaxStreams              int `json:"max_streams"`
+			BusiestStream           int `json:"busiest_stream_subscribers"`
+			MaxSubscribersPerStream int `json:"max_subscribers_per_stream"`
+		} `json:"capacity"`
+	}
+	if err := json.Unmarshal(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/api/server", httpPort)), &server); err != nil {
+		t.Fatalf("decode /api/server: %v", err)
+	}
+	if c := server.Capacity; c.Streams != 1 || c.MaxStreams != 1 || c.BusiestStream != 1 || c.MaxSubscribersPerStream != 1 {
+		t.Errorf("capacity = %+v", c)
+	}
+
+	// A loopback client, such as a same-host proxy without PROXY headers, is
+	// still limited; a packager pull is not.
+	for ua, want := range map[string]int{"ffplay": http.StatusServiceUnavailable, "nonchalant-packager/hls": http.StatusOK} {
+		conn := dialAs(t, httpPort, "127.0.0.1")
+		fmt.Fprintf(conn, "GET /live/one.flv HTTP/1.1\r\nHost: lb\r\nUser-Agent: %s\r\n\r\n", ua)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		conn.Close()
+		if err != nil || resp.StatusCode != want {
+			t.Errorf("loopback %s: resp %v err %v, want %d", ua, resp, err, want)
+		}
+	}
+}
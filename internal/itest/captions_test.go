@@ -0,0 +1,89 @@
+// If you are AI: Integration test for closed captions: CEA-608 in a
+// publisher's H.264 SEI reaches HTTP-FLV viewers as onCaptionInfo.
+
+package itest
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ccFrame returns an AVC video message (keyframe or not) whose SEI
+// carries 608 field 1 byte pairs.
+func ccFrame(key bool, pairs ...byte) []byte {
+	sei := []byte{0xB5, 0x00, 0x31, 'G', 'A', '9', '4', 0x03, 0x40 | byte(len(pairs)/2), 0xFF}
+	for i := 0; i < len(pairs); i += 2 {
+		sei = append(sei, 0xFC, pairs[i], pairs[i+1])
+	}
+	nal := append([]byte{0x06, 4, byte(len(sei) + 1)}, sei...)
+	nal = append(nal, 0xFF, 0x80)
+	head := byte(0x27)
+	if key {
+		head = 0x17
+	}
+	return append([]byte{head, 1, 0, 0, 0, 0, 0, 0, byte(len(nal))}, nal...)
+}
+
+// TestCaptions publishes a pop-on caption and reads it back as script data.
+func TestCaptions(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "captions.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\ncaptions:\n  \"*\":\n    language: en\n", httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	pub, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	pub.SetDeadline(time.Now().Add(15 * time.Second))
+	publishOn(t, pub, "cc")
+	sps, _ := hex.DecodeString(x264SPS)
+	sendAt(t, pub, 0, append([]byte{0x17, 0, 0, 0, 0, 1, 0x64, 0x00, 0x28, 0xFF, 0xE1, 0, byte(len(sps))}, sps...))
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "cc") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	viewer, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer viewer.Close()
+	viewer.SetDeadline(time.Now().Add(10 * time.Second))
+	fmt.Fprintf(viewer, "GET /live/cc.flv HTTP/1.1\r\nHost: x\r\n\r\n")
+	br := bufio.NewReader(viewer)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("play: %v %v", resp, err)
+	}
+	time.Sleep(200 * time.Millisecond) // let the viewer attach
+
+	sendAt(t, pub, 1000, ccFrame(true, 0x94, 0x20, 0x94, 0x40, 'H', 'i'))
+	sendAt(t, pub, 1040, ccFrame(false, 0x94, 0x2F))
+	if _, err := br.Discard(13); err != nil {
+		t.Fatal(err)
+	}
+	// The viewer's timeline starts at its first keyframe.
+	ts, obj := nextScript(t, br, "onCaptionInfo")
+	if ts != 40 || obj["text"] != "Hi" || obj["type"] != "cea608" || obj["language"] != "en" {
+		t.Errorf("onCaptionInfo at %d = %v", ts, obj)
+	}
+}
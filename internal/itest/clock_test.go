@@ -0,0 +1,136 @@
+// If you are AI: Integration test for the wall clock: an encoder's onFI
+// anchors the stream, HTTP-FLV viewers get onFI ahead of keyframes, and
+// /api/streams reports the anchor.
+
+package itest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// readClockTag reads FLV tags from r until an onFI script tag and returns
+// its sd and st fields.
+func readClockTag(t *testing.T, r io.Reader) (sd, st string) {
+	t.Helper()
+	if _, err := io.CopyN(io.Discard, r, 13); err != nil {
+		t.Fatalf("flv header: %v", err)
+	}
+	_, obj := nextScript(t, r, "onFI")
+	sd, _ = obj["sd"].(string)
+	st, _ = obj["st"].(string)
+	return sd, st
+}
+
+// nextScript reads FLV tags from r until a script tag called name and
+// returns its timestamp and object.
+func nextScript(t *testing.T, r io.Reader, name string) (uint32, amf0.Object) {
+	t.Helper()
+	hdr := make([]byte, 11)
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			t.Fatalf("flv tag: %v", err)
+		}
+		size := int(hdr[1])<<16 | int(hdr[2])<<8 | int(hdr[3])
+		body := make([]byte, size+4)
+		if _, err := io.ReadFull(r, body); err != nil {
+			t.Fatalf("flv tag body: %v", err)
+		}
+		if hdr[0] != 18 {
+			continue
+		}
+		br := bytes.NewReader(body[:size])
+		if got, err := amf0.DecodeString(br); err != nil || got != name {
+			continue
+		}
+		v, _ := amf0.Decode(br)
+		obj, _ := v.(amf0.Object)
+		return uint32(hdr[7])<<24 | uint32(hdr[4])<<16 | uint32(hdr[5])<<8 | uint32(hdr[6]), obj
+	}
+}
+
+// TestWallClock publishes onFI and checks the viewer's clock tags.
+func TestWallClock(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "clock.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n", httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	pub, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	pub.SetDeadline(time.Now().Add(15 * time.Second))
+	publishOn(t, pub, "clock")
+	sps, _ := hex.DecodeString(x264SPS)
+	sendAt(t, pub, 0, append([]byte{0x17, 0, 0, 0, 0, 1, 0x64, 0x00, 0x28, 0xFF, 0xE1, 0, byte(len(sps))}, sps...))
+	var fi bytes.Buffer
+	_ = amf0.Encode(&fi, "onFI")
+	_ = amf0.Encode(&fi, amf0.Object{"sd": "14-03-2026", "st": "09:26:53.000"})
+	sendMessage(t, pub, 0x05, 18, 1, fi.Bytes())
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "clock") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	viewer, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer viewer.Close()
+	viewer.SetDeadline(time.Now().Add(10 * time.Second))
+	fmt.Fprintf(viewer, "GET /live/clock.flv HTTP/1.1\r\nHost: x\r\n\r\n")
+	br := bufio.NewReader(viewer)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("play: %v %v", resp, err)
+	}
+	time.Sleep(200 * time.Millisecond) // let the viewer attach
+
+	sendAt(t, pub, 1500, []byte{0x17, 1, 0, 0, 0})
+	if sd, st := readClockTag(t, br); sd != "14-03-2026" || st != "09:26:54.500" {
+		t.Errorf("viewer onFI = %s %s, want 14-03-2026 09:26:54.500", sd, st)
+	}
+
+	var streams struct {
+		Streams []struct {
+			Name  string `json:"name"`
+			Clock struct {
+				Wall   time.Time `json:"wall"`
+				Source string    `json:"source"`
+			} `json:"clock"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/api/streams", httpPort)), &streams); err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+	for _, s := range streams.Streams {
+		if s.Name == "clock" && (s.Clock.Source != "onfi" || !s.Clock.Wall.Equal(want)) {
+			t.Errorf("/api/streams clock = %+v, want onfi at %v", s.Clock, want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// If you are AI: Integration test for edge mode: an edge instance pulls a
+// stream from an origin instance on the first viewer request, shares one
+// upstream pull between concurrent viewers, and drops it after the idle TTL.
+
+package itest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestEdgePullsFromOrigin starts an origin with a live publisher and an edge
+// that knows only the origin URL template. Three concurrent viewers on the
+// edge must all get FLV data through a single origin subscription, and the
+// pull must be torn down once they leave.
+func TestEdgePullsFromOrigin(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not available")
+	}
+
+	originHTTP, originRTMP, killOrigin := startPlainServer(t)
+	defer killOrigin()
+	stopPub := startLoopingPublisher(t, originRTMP, "live", "edgy")
+	defer stopPub()
+	waitForLiveStream(t, originHTTP, "live", "edgy", 15*time.Second)
+
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	edgeHTTP := findFreePort(t)
+	edgeRTMP := findFreePort(t)
+	edgeCfg := filepath.Join(t.TempDir(), "edge.yaml")
+	mustWrite(t, edgeCfg, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"edge:\n  origin: \"http://127.0.0.1:%d/{app}/{name}.flv\"\n"+
+			"  idle_ttl_seconds: 2\n  wait_seconds: 20\n",
+		edgeHTTP, edgeRTMP, originHTTP))
+	killEdge := startBin(t, binPath, edgeCfg, edgeHTTP)
+	defer killEdge()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/live/edgy.flv", edgeHTTP)
+	var viewers []io.Closer
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := openFLV(url, 64*1024)
+			if err != nil {
+				errs <- err
+				return
+			}
+			mu.Lock()
+			viewers = append(viewers, body)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("edge viewer: %v", err)
+	}
+
+	// All three viewers share one upstream pull.
+	if n := subscriberCount(t, originHTTP, "edgy"); n != 1 {
+		t.Errorf("origin subscriber count = %d, want 1 shared edge pull", n)
+	}
+	for _, v := range viewers {
+		_ = v.Close()
+	}
+
+	// The viewers have gone; the edge keeps the pull for the idle TTL and
+	// then releases its single subscription on the origin.
+	deadline := time.Now().Add(20 * time.Second)
+	for time.Now().Before(deadline) {
+		if subscriberCount(t, originHTTP, "edgy") == 0 {
+			return
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	t.Fatal("edge never released its origin pull after the idle TTL")
+}
+
+// openFLV GETs url, reads n bytes and checks the FLV signature. The body
+// is returned still open so the caller decides when the viewer leaves.
+func openFLV(url string, n int) (io.ReadCloser, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s = %d", url, resp.StatusCode)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("read %s: %w", url, err)
+	}
+	if string(buf[:3]) != "FLV" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s: missing FLV signature", url)
+	}
+	return resp.Body, nil
+}
+
+// subscriberCount returns the subscriber count of live/{name} on the server
+// at httpPort, or -1 if the stream is not listed.
+func subscriberCount(t *testing.T, httpPort int, name string) int {
+	t.Helper()
+	var body struct {
+		Streams []struct {
+			Name            string `json:"name"`
+			SubscriberCount int    `json:"subscriber_count"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/api/streams", httpPort)), &body); err != nil {
+		t.Fatalf("decode streams: %v", err)
+	}
+	for _, s := range body.Streams {
+		if s.Name == name {
+			return s.SubscriberCount
+		}
+	}
+	return -1
+}
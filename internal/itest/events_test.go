@@ -0,0 +1,120 @@
+// If you are AI: Integration test for the server event stream: a publish
+// start and stop arrive on /api/events, and a reconnect with
+// Last-Event-ID replays what was missed.
+
+package itest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent is one parsed Server-Sent Event.
+type sseEvent struct {
+	id, typ, data string
+}
+
+// readEvents streams SSE messages from url (with Last-Event-ID last, if
+// set) onto the returned channel until the response ends.
+func readEvents(t *testing.T, url, last string) (<-chan sseEvent, func()) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if last != "" {
+		req.Header.Set("Last-Event-ID", last)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	out := make(chan sseEvent, 16)
+	go func() {
+		defer close(out)
+		var e sseEvent
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				e.id = line[len("id: "):]
+			case strings.HasPrefix(line, "event: "):
+				e.typ = line[len("event: "):]
+			case strings.HasPrefix(line, "data: "):
+				e.data = line[len("data: "):]
+			case line == "" && e.typ != "":
+				out <- e
+				e = sseEvent{}
+			}
+		}
+	}()
+	return out, func() { resp.Body.Close() }
+}
+
+// nextEvent waits for the next event on ch.
+func nextEvent(t *testing.T, ch <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case e, ok := <-ch:
+		if !ok {
+			t.Fatal("event stream ended")
+		}
+		return e
+	case <-time.After(10 * time.Second):
+		t.Fatal("no event within 10s")
+	}
+	return sseEvent{}
+}
+
+// TestEvents follows one stream's publish start and stop over SSE.
+func TestEvents(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "events.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n", httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/api/events?app=live&name=evt", httpPort)
+	ch, stop := readEvents(t, url, "")
+	defer stop()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	publishOn(t, conn, "evt")
+	started := nextEvent(t, ch)
+	if started.typ != "publish_started" {
+		t.Fatalf("first event = %+v, want publish_started", started)
+	}
+	stop()
+
+	conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "evt") != "" {
+		if time.Now().After(deadline) {
+			t.Fatal("publisher never went away")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Resume after the start: the stop that happened while disconnected is
+	// replayed.
+	ch, stop = readEvents(t, url, started.id)
+	defer stop()
+	if e := nextEvent(t, ch); e.typ != "publish_stopped" {
+		t.Fatalf("replayed event = %+v, want publish_stopped", e)
+	}
+}
@@ -0,0 +1,188 @@
+// If you are AI: Integration test for HTTP/2 and HTTP/3 delivery: the HTTPS
+// listener negotiates h2 and advertises the QUIC listener with Alt-Svc, the
+// QUIC listener answers HTTP/3, and HTTP-FLV streams over h2 without hijack.
+// HTTP/3 connections count against the per-IP connection cap.
+
+package itest
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// TestHTTP2AndHTTP3 starts a server with http2 and an HTTP/3 listener on
+// the same port number as HTTPS.
+func TestHTTP2AndHTTP3(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeSelfSigned(t, certFile, keyFile, "h3.test")
+
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	httpsPort := findFreePort(t)
+	cfgPath := filepath.Join(dir, "h3.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"tls:\n  cert_file: %s\n  key_file: %s\n  https_port: %d\n"+
+			"  http2: true\n  http3_port: %d\n",
+		httpPort, rtmpPort, certFile, keyFile, httpsPort, httpsPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	insecure := &tls.Config{InsecureSkipVerify: true}
+	h2 := &http.Client{Transport: &http.Transport{TLSClientConfig: insecure, ForceAttemptHTTP2: true}}
+	h3 := &http.Client{Transport: &http3.Transport{TLSClientConfig: insecure}}
+	defer h3.Transport.(*http3.Transport).Close()
+
+	url := fmt.Sprintf("https://127.0.0.1:%d/healthz", httpsPort)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := h2.Get(url)
+		if err != nil {
+			t.Fatalf("HTTPS healthz: %v", err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 {
+			t.Fatalf("HTTPS negotiated %s, want HTTP/2", resp.Proto)
+		}
+		alt := resp.Header.Get("Alt-Svc")
+		if strings.Contains(alt, fmt.Sprintf(`h3=":%d"`, httpsPort)) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Alt-Svc = %q, want h3 on :%d", alt, httpsPort)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	resp, err := h3.Get(url)
+	if err != nil {
+		t.Fatalf("HTTP/3 healthz: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 3 {
+		t.Fatalf("HTTP/3 healthz = %d over %s", resp.StatusCode, resp.Proto)
+	}
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Log("ffmpeg not available, skipping HTTP-FLV over HTTP/2")
+		return
+	}
+	stopPub := startLoopingPublisher(t, rtmpPort, "live", "multiplexed")
+	defer stopPub()
+	waitForLiveStream(t, httpPort, "live", "multiplexed", 15*time.Second)
+	resp, err = h2.Get(fmt.Sprintf("https://127.0.0.1:%d/live/multiplexed.flv", httpsPort))
+	if err != nil {
+		t.Fatalf("FLV over HTTP/2: %v", err)
+	}
+	defer resp.Body.Close()
+	buf := make([]byte, 64*1024)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil || string(buf[:3]) != "FLV" {
+		t.Fatalf("FLV over HTTP/2: err %v, signature %q", err, buf[:3])
+	}
+
+	var server struct {
+		FLVSessions map[string]int64 `json:"flv_sessions"`
+	}
+	if err := json.Unmarshal(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/api/server", httpPort)), &server); err != nil {
+		t.Fatalf("decode /api/server: %v", err)
+	}
+	if server.FLVSessions["h2"] != 1 {
+		t.Errorf("flv_sessions = %v, want one h2 session", server.FLVSessions)
+	}
+}
+
+// TestHTTP3ConnLimit holds the one slot max_conns_per_ip allows with a TCP
+// connection and checks that HTTP/3 from the same IP is refused until it
+// closes. Loopback is never limited, so the client dials a non-loopback
+// address of this host.
+func TestHTTP3ConnLimit(t *testing.T) {
+	ip := hostIPv4(t)
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeSelfSigned(t, certFile, keyFile, "h3.test")
+
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	httpsPort := findFreePort(t)
+	cfgPath := filepath.Join(dir, "h3limit.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"tls:\n  cert_file: %s\n  key_file: %s\n  https_port: %d\n  http3_port: %d\n"+
+			"access:\n  max_conns_per_ip: 1\n",
+		httpPort, rtmpPort, certFile, keyFile, httpsPort, httpsPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	url := fmt.Sprintf("https://%s/healthz", net.JoinHostPort(ip, fmt.Sprint(httpsPort)))
+	get := func() error { // a fresh transport, so a fresh QUIC connection
+		tr := &http3.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+		defer tr.Close()
+		resp, err := (&http.Client{Transport: tr, Timeout: 3 * time.Second}).Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	hold, err := net.Dial("tcp", net.JoinHostPort(ip, fmt.Sprint(httpPort)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(hold, "GET /healthz HTTP/1.1\r\nHost: h3.test\r\n\r\n")
+	if resp, err := http.ReadResponse(bufio.NewReader(hold), nil); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("TCP healthz: resp %v err %v", resp, err)
+	}
+	if err := get(); err == nil {
+		t.Fatal("HTTP/3 from an IP at max_conns_per_ip was served")
+	}
+	hold.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for err := get(); err != nil; err = get() {
+		if time.Now().After(deadline) {
+			t.Fatalf("HTTP/3 after the slot was freed: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	metrics := string(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/metrics", httpPort)))
+	if strings.Contains(metrics, `nonchalant_rejections_total{reason="conn_limit"} 0`) { // the retries may add more
+		t.Error("HTTP/3 refusal not counted as conn_limit")
+	}
+}
+
+// hostIPv4 returns a non-loopback IPv4 address of this host, skipping the
+// test when there is none.
+func hostIPv4(t *testing.T) string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Skipf("interface addresses: %v", err)
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && !n.IP.IsLoopback() && n.IP.To4() != nil {
+			return n.IP.String()
+		}
+	}
+	t.Skip("no non-loopback IPv4 address")
+	return ""
+}
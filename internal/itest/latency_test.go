@@ -0,0 +1,99 @@
+// If you are AI: Integration test for delivery latency: frames published
+// over RTMP and written to an HTTP-FLV viewer show up as the viewer's
+// p50 / p99 in the sessions API and in the /metrics histogram.
+
+package itest
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDeliveryLatency publishes keyframes to one HTTP-FLV viewer and reads
+// the resulting latency figures.
+func TestDeliveryLatency(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "latency.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n", httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	pub, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	pub.SetDeadline(time.Now().Add(15 * time.Second))
+	publishOn(t, pub, "lat")
+	// Codec headers up front so the viewer starts without waiting for them.
+	sps, _ := hex.DecodeString(x264SPS)
+	sendMessage(t, pub, 0x06, 9, 1, append([]byte{0x17, 0, 0, 0, 0, 1, 0x64, 0x00, 0x28, 0xFF, 0xE1, 0, byte(len(sps))}, sps...))
+	sendMessage(t, pub, 0x04, 8, 1, []byte{0xAF, 0, 0x11, 0x90})
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "lat") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	viewer, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer viewer.Close()
+	fmt.Fprintf(viewer, "GET /live/lat.flv HTTP/1.1\r\nHost: x\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(viewer), nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("play: %v %v", resp, err)
+	}
+
+	type session struct {
+		Role         string  `json:"role"`
+		LatencyP50Ms float64 `json:"latency_p50_ms"`
+		LatencyP99Ms float64 `json:"latency_p99_ms"`
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d/api/streams/live/lat/sessions", httpPort)
+	var got session
+	for deadline = time.Now().Add(5 * time.Second); got.LatencyP99Ms == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("viewer latency never reported: %+v", got)
+		}
+		sendMessage(t, pub, 0x06, 9, 1, []byte{0x17, 1, 0, 0, 0})
+		time.Sleep(100 * time.Millisecond)
+		var body struct {
+			Sessions []session `json:"sessions"`
+		}
+		if err := json.Unmarshal(mustGet(t, url), &body); err != nil {
+			t.Fatalf("decode sessions: %v", err)
+		}
+		for _, s := range body.Sessions {
+			if s.Role == "viewer" {
+				got = s
+			}
+		}
+	}
+	if got.LatencyP50Ms > got.LatencyP99Ms || got.LatencyP99Ms > 1000 {
+		t.Errorf("viewer latency = %+v", got)
+	}
+
+	metrics := string(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/metrics", httpPort)))
+	if !strings.Contains(metrics, `nonchalant_delivery_latency_seconds_count{protocol="httpflv"}`) {
+		t.Error("/metrics has no httpflv delivery latency histogram")
+	}
+}
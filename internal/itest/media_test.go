@@ -0,0 +1,90 @@
+// If you are AI: Integration test for codec introspection: sequence headers
+// sent over RTMP show up as codec details in /api/streams and /metrics.
+
+package itest
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// x264SPS is a High@4.0 1920x1080 30 fps SPS as emitted by x264.
+const x264SPS = "67640028acd940780227e5c05a808080a0000003002000000781e30632c0"
+
+// TestMediaInfo publishes AVC and AAC sequence headers by hand and reads
+// them back through the API and metrics.
+func TestMediaInfo(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "media.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf("server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n", httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	publishOn(t, conn, "probe")
+	sps, _ := hex.DecodeString(x264SPS)
+	avcHeader := append([]byte{0x17, 0, 0, 0, 0, 1, 0x64, 0x00, 0x28, 0xFF, 0xE1, 0, byte(len(sps))}, sps...)
+	sendMessage(t, conn, 0x06, 9, 1, avcHeader)
+	sendMessage(t, conn, 0x04, 8, 1, []byte{0xAF, 0, 0x11, 0x90})
+
+	type media struct {
+		Video struct {
+			Profile string `json:"profile"`
+			Width   int    `json:"width"`
+			Height  int    `json:"height"`
+		} `json:"video"`
+		Audio struct {
+			SampleRate int `json:"sample_rate"`
+			Channels   int `json:"channels"`
+		} `json:"audio"`
+		Codecs string `json:"codecs"`
+	}
+	var got media
+	deadline := time.Now().Add(5 * time.Second)
+	for got.Codecs != "avc1.640028,mp4a.40.2" {
+		if time.Now().After(deadline) {
+			t.Fatalf("codecs never reported, last %+v", got)
+		}
+		time.Sleep(100 * time.Millisecond)
+		var body struct {
+			Streams []struct {
+				Name  string `json:"name"`
+				Media *media `json:"media"`
+			} `json:"streams"`
+		}
+		if err := json.Unmarshal(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/api/streams", httpPort)), &body); err != nil {
+			t.Fatalf("decode streams: %v", err)
+		}
+		for _, s := range body.Streams {
+			if s.Name == "probe" && s.Media != nil {
+				got = *s.Media
+			}
+		}
+	}
+	if got.Video.Profile != "High" || got.Video.Width != 1920 || got.Video.Height != 1080 ||
+		got.Audio.SampleRate != 48000 || got.Audio.Channels != 2 {
+		t.Errorf("media = %+v", got)
+	}
+
+	metrics := string(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/metrics", httpPort)))
+	if want := `nonchalant_stream_video_height_pixels{app="live",name="probe"} 1080`; !strings.Contains(metrics, want) {
+		t.Errorf("metrics missing %s", want)
+	}
+}
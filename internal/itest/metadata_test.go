@@ -0,0 +1,127 @@
+// If you are AI: Integration test for timed metadata: an onCuePoint
+// injected through the API, and an onCuePoint and an onSCTE35 splice sent
+// by the RTMP publisher, all reach an HTTP-FLV viewer as script tags at
+// the live point.
+
+package itest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// nextKeyframe reads FLV tags up to and including a video keyframe.
+func nextKeyframe(t *testing.T, r io.Reader) {
+	t.Helper()
+	hdr := make([]byte, 11)
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			t.Fatalf("flv tag: %v", err)
+		}
+		size := int(hdr[1])<<16 | int(hdr[2])<<8 | int(hdr[3])
+		body := make([]byte, size+4)
+		if _, err := io.ReadFull(r, body); err != nil {
+			t.Fatalf("flv tag body: %v", err)
+		}
+		if hdr[0] == 9 && size > 1 && body[0] == 0x17 && body[1] == 1 {
+			return
+		}
+	}
+}
+
+// TestTimedMetadata injects and publishes cue points during a stream.
+func TestTimedMetadata(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "metadata.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n", httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	pub, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	pub.SetDeadline(time.Now().Add(15 * time.Second))
+	publishOn(t, pub, "cues")
+	sps, _ := hex.DecodeString(x264SPS)
+	sendAt(t, pub, 0, append([]byte{0x17, 0, 0, 0, 0, 1, 0x64, 0x00, 0x28, 0xFF, 0xE1, 0, byte(len(sps))}, sps...))
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "cues") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	viewer, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer viewer.Close()
+	viewer.SetDeadline(time.Now().Add(10 * time.Second))
+	fmt.Fprintf(viewer, "GET /live/cues.flv HTTP/1.1\r\nHost: x\r\n\r\n")
+	br := bufio.NewReader(viewer)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("play: %v %v", resp, err)
+	}
+	if _, err := io.CopyN(io.Discard, br, 13); err != nil {
+		t.Fatal(err)
+	}
+	// Inject only once the viewer has its first keyframe: metadata ahead
+	// of it is gated like any other non-init message.
+	sendAt(t, pub, 1000, []byte{0x17, 1, 0, 0, 0})
+	nextKeyframe(t, br)
+
+	body := `{"type":"onCuePoint","data":{"name":"question","parameters":{"n":3}}}`
+	res, err := http.Post(fmt.Sprintf("http://127.0.0.1:%d/api/streams/live/cues/metadata", httpPort),
+		"application/json", strings.NewReader(body))
+	if err != nil || res.StatusCode != http.StatusAccepted {
+		t.Fatalf("inject: %v %v", res, err)
+	}
+	res.Body.Close()
+	sendAt(t, pub, 1040, []byte{0x27, 1, 0, 0, 0})
+	ts, obj := nextScript(t, br, "onCuePoint")
+	if obj["name"] != "question" || ts != 40 {
+		t.Errorf("injected cue at %d: %v, want question at 40", ts, obj)
+	}
+
+	var cue bytes.Buffer
+	_ = amf0.Encode(&cue, "onCuePoint")
+	_ = amf0.Encode(&cue, amf0.Object{"name": "overlay"})
+	sendMessage(t, pub, 0x05, 18, 1, cue.Bytes())
+	sendAt(t, pub, 1080, []byte{0x27, 1, 0, 0, 0})
+	if _, obj := nextScript(t, br, "onCuePoint"); obj["name"] != "overlay" {
+		t.Errorf("publisher cue = %v, want overlay", obj)
+	}
+
+	// An immediate splice_insert out of the network, event 1.
+	splice := "/DAbAAAAAAAAAP/wCgUAAAABf98AAAAAAAAAAAAA"
+	var ad bytes.Buffer
+	_ = amf0.Encode(&ad, "onSCTE35")
+	_ = amf0.Encode(&ad, amf0.Object{"data": splice})
+	sendMessage(t, pub, 0x05, 18, 1, ad.Bytes())
+	sendAt(t, pub, 1120, []byte{0x27, 1, 0, 0, 0})
+	if _, obj := nextScript(t, br, "onSCTE35"); obj["data"] != splice {
+		t.Errorf("publisher splice = %v, want %s", obj, splice)
+	}
+}
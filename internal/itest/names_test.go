@@ -0,0 +1,72 @@
+// If you are AI: Integration test for stream name validation: unsafe or
+// off-pattern names are refused on RTMP publish and on every HTTP output.
+
+package itest
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStreamNames runs with a lowercase-only pattern for live and checks
+// publish, HTTP-FLV, WS-FLV and HLS refusals and the bad_name counter.
+func TestStreamNames(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "names.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"stream_names:\n  live: \"[a-z0-9]{3,16}\"\n",
+		httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	publish := func(name string) net.Conn {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		return publishOn(t, conn, name)
+	}
+	good := publish("cam1")
+	defer good.Close()
+	bad := publish("Cam-1")
+	if !waitClosed(bad, 5*time.Second) {
+		t.Error("off-pattern publisher was not disconnected")
+	}
+	bad.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "cam1") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("valid publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	for _, path := range []string{"/live/..%2F..%2Fetc.flv", "/live/CAM1.flv", "/ws/live/a%3Fb", "/hls/live/UPPER.m3u8"} {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d%s", httpPort, path))
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET %s: %d, want 400", path, resp.StatusCode)
+		}
+	}
+
+	metrics := string(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/metrics", httpPort)))
+	if want := `nonchalant_rejections_total{reason="bad_name"} 5`; !strings.Contains(metrics, want) {
+		t.Errorf("metrics missing %s", want)
+	}
+}
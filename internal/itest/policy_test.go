@@ -0,0 +1,76 @@
+// If you are AI: Integration test for publish policies: an H.264 publisher
+// stays live while one sending HEVC is rejected, disconnected, counted and
+// reported on /api/events with the policy detail.
+
+package itest
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPublishPolicy restricts live to H.264 video and sends one frame of
+// each codec.
+func TestPublishPolicy(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "policy.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"publish_policy:\n  live:\n    video_codecs: [h264]\n    max_width: 1920\n",
+		httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	publish := func(name string, frame []byte) net.Conn {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		publishOn(t, conn, name)
+		sendMessage(t, conn, 0x06, 9, 1, frame)
+		return conn
+	}
+
+	ch, stop := readEvents(t, fmt.Sprintf("http://127.0.0.1:%d/api/events?name=bad", httpPort), "")
+	defer stop()
+	good := publish("good", []byte{0x17, 1, 0, 0, 0})
+	defer good.Close()
+	bad := publish("bad", []byte{0x1C, 1, 0, 0, 0}) // codec id 12: HEVC
+	if !waitClosed(bad, 5*time.Second) {
+		t.Error("HEVC publisher was not disconnected")
+	}
+	bad.Close()
+	e := nextEvent(t, ch)
+	for e.typ != "policy_rejected" {
+		e = nextEvent(t, ch)
+	}
+	if !strings.Contains(e.data, `"reason":"publish_policy"`) || !strings.Contains(e.data, `"error":"video codec`) {
+		t.Errorf("policy_rejected = %s, want the reason code and detail", e.data)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "good") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("H.264 publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if publisherAddr(t, httpPort, "bad") != "" {
+		t.Error("HEVC stream is still listed")
+	}
+	metrics := string(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/metrics", httpPort)))
+	if want := `nonchalant_rejections_total{reason="publish_policy"} 1`; !strings.Contains(metrics, want) {
+		t.Errorf("metrics missing %s", want)
+	}
+}
@@ -0,0 +1,134 @@
+// If you are AI: Integration test for PROXY protocol: a publisher behind a
+// (simulated) load balancer shows up in /api/streams with the address from
+// the PROXY header, and HTTP requests prefixed with a header are served.
+
+package itest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestProxyProtocol starts a server that trusts loopback as its load
+// balancer and speaks to it with PROXY v1 headers.
+func TestProxyProtocol(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "proxy.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"proxy_protocol:\n  rtmp: true\n  http: true\n  trusted_cidrs: [\"127.0.0.0/8\"]\n",
+		httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	// HTTP with a header in front of the request.
+	hc, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(hc, "PROXY TCP4 198.51.100.9 10.0.0.1 50000 %d\r\n"+
+		"GET /healthz HTTP/1.1\r\nHost: lb\r\nConnection: close\r\n\r\n", httpPort)
+	resp, err := http.ReadResponse(bufio.NewReader(hc), nil)
+	hc.Close()
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("healthz behind PROXY header: resp %v err %v", resp, err)
+	}
+
+	// RTMP publish with a header in front of the handshake.
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	fmt.Fprintf(conn, "PROXY TCP4 203.0.113.7 10.0.0.1 40000 %d\r\n", rtmpPort)
+	if err := doHandshake(conn); err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	if err := sendConnect(conn); err != nil {
+		t.Fatal(err)
+	}
+	sendAMF(t, conn, 0, amfString("createStream"), amfNumber(2), []byte{0x05})
+	sendAMF(t, conn, 1, amfString("publish"), amfNumber(3), []byte{0x05},
+		amfString("proxied"), amfString("live"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if addr := publisherAddr(t, httpPort, "proxied"); addr != "" {
+			if addr != "203.0.113.7:40000" {
+				t.Fatalf("publisher_addr = %q, want the PROXY source 203.0.113.7:40000", addr)
+			}
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatal("publisher never appeared in /api/streams")
+}
+
+// amfString encodes an AMF0 string.
+func amfString(s string) []byte {
+	return append([]byte{0x02, byte(len(s) >> 8), byte(len(s))}, s...)
+}
+
+// amfNumber encodes an AMF0 number.
+func amfNumber(f float64) []byte {
+	b := make([]byte, 9)
+	binary.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	return b
+}
+
+// sendAMF sends one AMF0 command message on chunk stream 3 for streamID.
+// Bodies must fit in the default 128-byte chunk.
+func sendAMF(t *testing.T, conn net.Conn, streamID uint32, parts ...[]byte) {
+	t.Helper()
+	var body []byte
+	for _, p := range parts {
+		body = append(body, p...)
+	}
+	sendMessage(t, conn, 0x03, 20, streamID, body)
+}
+
+// sendMessage writes one RTMP message of msgType as a single type-0 chunk
+// on chunk stream csID; body must fit the default 128-byte chunk size.
+func sendMessage(t *testing.T, conn net.Conn, csID, msgType byte, streamID uint32, body []byte) {
+	t.Helper()
+	hdr := []byte{csID, 0, 0, 0, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body)), msgType, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(hdr[8:], streamID)
+	if _, err := conn.Write(append(hdr, body...)); err != nil {
+		t.Fatalf("send message: %v", err)
+	}
+}
+
+// publisherAddr returns publisher_addr of live/{name}, or "".
+func publisherAddr(t *testing.T, httpPort int, name string) string {
+	t.Helper()
+	var body struct {
+		Streams []struct {
+			Name          string `json:"name"`
+			PublisherAddr string `json:"publisher_addr"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/api/streams", httpPort)), &body); err != nil {
+		t.Fatalf("decode streams: %v", err)
+	}
+	for _, s := range body.Streams {
+		if s.Name == name {
+			return s.PublisherAddr
+		}
+	}
+	return ""
+}
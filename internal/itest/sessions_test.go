@@ -0,0 +1,114 @@
+// If you are AI: Integration test for the session endpoints: a publisher
+// and an HTTP-FLV viewer are listed on their stream, and each can be
+// disconnected by ID.
+
+package itest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// streamSession is the subset of a session entry this test reads.
+type streamSession struct {
+	ID        string `json:"id"`
+	Protocol  string `json:"protocol"`
+	Role      string `json:"role"`
+	UserAgent string `json:"user_agent"`
+}
+
+// TestSessions lists a stream's publisher and viewer, then kicks both.
+func TestSessions(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "sessions.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n", httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	pubConn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pubConn.Close()
+	pubConn.SetDeadline(time.Now().Add(15 * time.Second))
+	publishOn(t, pubConn, "kickme")
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "kickme") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	viewer, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer viewer.Close()
+	fmt.Fprintf(viewer, "GET /live/kickme.flv HTTP/1.1\r\nHost: x\r\nUser-Agent: itest-player\r\n\r\n")
+
+	list := func() []streamSession {
+		var body struct {
+			Sessions []streamSession `json:"sessions"`
+		}
+		url := fmt.Sprintf("http://127.0.0.1:%d/api/streams/live/kickme/sessions", httpPort)
+		if err := json.Unmarshal(mustGet(t, url), &body); err != nil {
+			t.Fatalf("decode sessions: %v", err)
+		}
+		return body.Sessions
+	}
+	var sessions []streamSession
+	for deadline = time.Now().Add(5 * time.Second); len(sessions) < 2; sessions = list() {
+		if time.Now().After(deadline) {
+			t.Fatalf("sessions = %+v, want publisher and viewer", sessions)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	byRole := map[string]streamSession{}
+	for _, s := range sessions {
+		byRole[s.Role] = s
+	}
+	if p := byRole["publisher"]; p.Protocol != "rtmp" {
+		t.Errorf("publisher session = %+v", p)
+	}
+	if v := byRole["viewer"]; v.Protocol != "httpflv" || v.UserAgent != "itest-player" {
+		t.Errorf("viewer session = %+v", v)
+	}
+
+	kick := func(id string) int {
+		req, _ := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://127.0.0.1:%d/api/sessions/%s", httpPort, id), nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("DELETE session: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := kick(byRole["viewer"].ID); code != http.StatusOK {
+		t.Fatalf("kick viewer: %d", code)
+	}
+	if !waitClosed(viewer, 5*time.Second) {
+		t.Error("kicked viewer was not disconnected")
+	}
+	if code := kick(byRole["publisher"].ID); code != http.StatusOK {
+		t.Fatalf("kick publisher: %d", code)
+	}
+	if !waitClosed(pubConn, 5*time.Second) {
+		t.Error("kicked publisher was not disconnected")
+	}
+	if code := kick(byRole["publisher"].ID); code != http.StatusNotFound {
+		t.Errorf("second kick: %d, want 404", code)
+	}
+}
@@ -0,0 +1,119 @@
+// If you are AI: Integration test for the timestamp sanitizer: an encoder
+// restart that sends the timeline back to zero reaches an HTTP-FLV viewer
+// as a small forward step and is counted in /metrics.
+
+package itest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sendAt writes one video message with timestamp ts on the publish stream.
+func sendAt(t *testing.T, conn net.Conn, ts uint32, body []byte) {
+	t.Helper()
+	hdr := []byte{0x06, byte(ts >> 16), byte(ts >> 8), byte(ts),
+		byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body)), 9, 1, 0, 0, 0}
+	if _, err := conn.Write(append(hdr, body...)); err != nil {
+		t.Fatalf("send video: %v", err)
+	}
+}
+
+// readVideoTimestamps reads FLV tags from r until it has n non-init video
+// timestamps.
+func readVideoTimestamps(t *testing.T, r io.Reader, n int) []uint32 {
+	t.Helper()
+	if _, err := io.CopyN(io.Discard, r, 13); err != nil { // FLV header + first PreviousTagSize
+		t.Fatalf("flv header: %v", err)
+	}
+	var out []uint32
+	hdr := make([]byte, 11)
+	for len(out) < n {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			t.Fatalf("flv tag after %v: %v", out, err)
+		}
+		size := int(hdr[1])<<16 | int(hdr[2])<<8 | int(hdr[3])
+		body := make([]byte, size+4)
+		if _, err := io.ReadFull(r, body); err != nil {
+			t.Fatalf("flv tag body: %v", err)
+		}
+		if hdr[0] == 9 && body[1] == 1 {
+			out = append(out, binary.BigEndian.Uint32([]byte{hdr[7], hdr[4], hdr[5], hdr[6]}))
+		}
+	}
+	return out
+}
+
+// TestTimestampSanitizer restarts a publisher's timeline mid-stream.
+func TestTimestampSanitizer(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "timestamps.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"timestamps:\n  \"*\":\n    max_jump_ms: 1000\n",
+		httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	pub, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	pub.SetDeadline(time.Now().Add(15 * time.Second))
+	publishOn(t, pub, "ts")
+	sps, _ := hex.DecodeString(x264SPS)
+	sendAt(t, pub, 0, append([]byte{0x17, 0, 0, 0, 0, 1, 0x64, 0x00, 0x28, 0xFF, 0xE1, 0, byte(len(sps))}, sps...))
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "ts") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	viewer, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer viewer.Close()
+	viewer.SetDeadline(time.Now().Add(10 * time.Second))
+	fmt.Fprintf(viewer, "GET /live/ts.flv HTTP/1.1\r\nHost: x\r\n\r\n")
+	br := bufio.NewReader(viewer)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("play: %v %v", resp, err)
+	}
+	time.Sleep(200 * time.Millisecond) // let the viewer attach
+
+	for _, ts := range []uint32{10000, 10040, 10080, 0, 40, 80} {
+		sendAt(t, pub, ts, []byte{0x17, 1, 0, 0, 0})
+	}
+	got := readVideoTimestamps(t, br, 6)
+	want := []uint32{0, 40, 80, 113, 153, 193}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("viewer timestamps = %v, want %v", got, want)
+		}
+	}
+
+	metrics := string(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/metrics", httpPort)))
+	if !strings.Contains(metrics, `nonchalant_timestamp_corrections_total{app="live",kind="jump",name="ts"} 1`) {
+		t.Errorf("/metrics has no timestamp jump correction")
+	}
+}
@@ -0,0 +1,147 @@
+// If you are AI: Integration test for the TLS listeners: HTTPS serves the
+// media routes (including hijacked HTTP-FLV), RTMPS completes the RTMP
+// handshake, and rewritten certificate files are picked up without a restart.
+
+package itest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestTLSListeners starts a server with https_port and rtmps_port.
+func TestTLSListeners(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeSelfSigned(t, certFile, keyFile, "first.test")
+
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	httpsPort := findFreePort(t)
+	rtmpsPort := findFreePort(t)
+	cfgPath := filepath.Join(dir, "tls.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"tls:\n  cert_file: %s\n  key_file: %s\n  https_port: %d\n  rtmps_port: %d\n"+
+			"  reload_seconds: 1\n",
+		httpPort, rtmpPort, certFile, keyFile, httpsPort, rtmpsPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	insecure := &tls.Config{InsecureSkipVerify: true}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: insecure}}
+	resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/healthz", httpsPort))
+	if err != nil {
+		t.Fatalf("HTTPS healthz: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.TLS == nil {
+		t.Fatalf("HTTPS healthz = %d (tls %v)", resp.StatusCode, resp.TLS != nil)
+	}
+
+	conn, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpsPort), insecure)
+	if err != nil {
+		t.Fatalf("RTMPS dial: %v", err)
+	}
+	if err := doHandshake(conn); err != nil {
+		t.Fatalf("RTMP handshake over TLS: %v", err)
+	}
+	if cn := peerCN(conn); cn != "first.test" {
+		t.Errorf("RTMPS served %q, want first.test", cn)
+	}
+	conn.Close()
+
+	// Renew the certificate in place; the next handshakes must use it.
+	writeSelfSigned(t, certFile, keyFile, "renewed.test")
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		c, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpsPort), insecure)
+		if err != nil {
+			t.Fatalf("HTTPS dial: %v", err)
+		}
+		cn := peerCN(c)
+		c.Close()
+		if cn == "renewed.test" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("certificate not reloaded, still serving %q", cn)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Log("ffmpeg not available, skipping HTTP-FLV over HTTPS")
+		return
+	}
+	stopPub := startLoopingPublisher(t, rtmpPort, "live", "secure")
+	defer stopPub()
+	waitForLiveStream(t, httpPort, "live", "secure", 15*time.Second)
+	resp, err = client.Get(fmt.Sprintf("https://127.0.0.1:%d/live/secure.flv", httpsPort))
+	if err != nil {
+		t.Fatalf("HTTPS FLV: %v", err)
+	}
+	defer resp.Body.Close()
+	buf := make([]byte, 64*1024)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		t.Fatalf("read HTTPS FLV: %v", err)
+	}
+	if string(buf[:3]) != "FLV" {
+		t.Fatal("HTTPS FLV: missing FLV signature")
+	}
+}
+
+// writeSelfSigned writes a self-signed certificate for cn and its key.
+func writeSelfSigned(t *testing.T, certFile, keyFile, cn string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, keyFile, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})))
+	mustWrite(t, certFile, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	// Make sure the rewrite is visible even on filesystems with coarse mtimes.
+	later := time.Now().Add(time.Second)
+	_ = os.Chtimes(certFile, later, later)
+}
+
+// peerCN returns the CN of the certificate the server presented on c.
+func peerCN(c *tls.Conn) string {
+	certs := c.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return ""
+	}
+	return certs[0].Subject.CommonName
+}
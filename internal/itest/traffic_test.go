@@ -0,0 +1,93 @@
+// If you are AI: Integration test for traffic accounting: a publisher and
+// an HTTP-FLV viewer show up in /metrics as ingress / egress bytes, live
+// session gauges, and TTFB / duration histograms once the viewer leaves.
+
+package itest
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTrafficMetrics streams a few frames to one viewer and reads the
+// byte counters and session metrics.
+func TestTrafficMetrics(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "traffic.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n", httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	pub, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+	pub.SetDeadline(time.Now().Add(15 * time.Second))
+	publishOn(t, pub, "bytes")
+	sps, _ := hex.DecodeString(x264SPS)
+	sendMessage(t, pub, 0x06, 9, 1, append([]byte{0x17, 0, 0, 0, 0, 1, 0x64, 0x00, 0x28, 0xFF, 0xE1, 0, byte(len(sps))}, sps...))
+	sendMessage(t, pub, 0x04, 8, 1, []byte{0xAF, 0, 0x11, 0x90})
+	deadline := time.Now().Add(5 * time.Second)
+	for publisherAddr(t, httpPort, "bytes") == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("publisher never went live")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	viewer, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", httpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(viewer, "GET /live/bytes.flv HTTP/1.1\r\nHost: x\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(viewer), nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("play: %v %v", resp, err)
+	}
+	for i := 0; i < 5; i++ {
+		sendMessage(t, pub, 0x06, 9, 1, []byte{0x17, 1, 0, 0, 0})
+	}
+
+	metricsURL := fmt.Sprintf("http://127.0.0.1:%d/metrics", httpPort)
+	waitFor := func(want ...string) {
+		t.Helper()
+		var body string
+		for deadline = time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(100 * time.Millisecond) {
+			body = string(mustGet(t, metricsURL))
+			missing := false
+			for _, w := range want {
+				missing = missing || !strings.Contains(body, w)
+			}
+			if !missing {
+				return
+			}
+		}
+		t.Fatalf("/metrics never had %q\n%s", want, body)
+	}
+	waitFor(
+		`nonchalant_ingress_bytes_total{app="live",name="bytes"}`,
+		`nonchalant_egress_bytes_total{app="live",name="bytes",protocol="httpflv"}`,
+		`nonchalant_sessions{protocol="httpflv",role="viewer"} 1`,
+		`nonchalant_sessions{protocol="rtmp",role="publisher"} 1`,
+		`nonchalant_session_ttfb_seconds_count{protocol="httpflv"} 1`,
+	)
+
+	viewer.Close()
+	sendMessage(t, pub, 0x06, 9, 1, []byte{0x17, 1, 0, 0, 0}) // makes the server notice the close
+	waitFor(`nonchalant_session_duration_seconds_count{protocol="httpflv"} 1`)
+}
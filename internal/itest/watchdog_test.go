@@ -0,0 +1,84 @@
+// If you are AI: Integration test for the ingest watchdog: a publisher
+// that stops sending media is flagged in /metrics and disconnected.
+
+package itest
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestIngestWatchdog stalls a publisher and waits for the alert and the
+// disconnect.
+func TestIngestWatchdog(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "watchdog.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"ingest_watchdog:\n  live:\n    no_media_seconds: 1\n    disconnect: true\n",
+		httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(15 * time.Second))
+	publishOn(t, conn, "stall")
+	sendMessage(t, conn, 0x06, 9, 1, []byte{0x17, 1, 0, 0, 0})
+
+	// The connection stays open but no more media arrives.
+	if !waitClosed(conn, 5*time.Second) {
+		t.Fatal("stalled publisher was not disconnected")
+	}
+	metrics := string(mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/metrics", httpPort)))
+	if !strings.Contains(metrics, `nonchalant_ingest_alerts_total{reason="no_media"} 1`) {
+		t.Errorf("/metrics has no no_media alert:\n%s", metrics)
+	}
+}
+
+// TestWatchdogDeleteStream stops a watched publish the way OBS does,
+// deleteStream then disconnect, which closes the session twice. The
+// server must survive it.
+func TestWatchdogDeleteStream(t *testing.T) {
+	binPath := filepath.Join(t.TempDir(), "nonchalant")
+	if out, err := exec.Command("go", "build", "-o", binPath, "../../cmd/nonchalant").CombinedOutput(); err != nil {
+		t.Fatalf("build: %v\n%s", err, out)
+	}
+	httpPort := findFreePort(t)
+	rtmpPort := findFreePort(t)
+	cfgPath := filepath.Join(t.TempDir(), "watchdog.yaml")
+	mustWrite(t, cfgPath, fmt.Sprintf(
+		"server:\n  health_port: 8080\n  http_port: %d\n  rtmp_port: %d\n"+
+			"ingest_watchdog:\n  \"*\":\n    no_media_seconds: 5\n",
+		httpPort, rtmpPort))
+	kill := startBin(t, binPath, cfgPath, httpPort)
+	defer kill()
+
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", rtmpPort))
+		if err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		publishOn(t, conn, "obs")
+		sendMessage(t, conn, 0x06, 9, 1, []byte{0x17, 1, 0, 0, 0})
+		sendAMF(t, conn, 1, amfString("deleteStream"), amfNumber(4), []byte{0x05}, amfNumber(1))
+		time.Sleep(200 * time.Millisecond)
+		conn.Close()
+		time.Sleep(200 * time.Millisecond)
+	}
+	mustGet(t, fmt.Sprintf("http://127.0.0.1:%d/api/streams", httpPort))
+}
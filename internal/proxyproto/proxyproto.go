@@ -0,0 +1,234 @@
+// If you are AI: This file implements HAProxy PROXY protocol v1/v2 for
+// listeners behind a TCP load balancer. Only connections from trusted CIDRs
+// may carry a header; for those, RemoteAddr reports the client address the
+// header names instead of the load balancer's.
+
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// v2Signature starts every PROXY protocol v2 header.
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// defaultHeaderTimeout bounds how long a trusted peer may take to send the
+// header before the connection fails.
+const defaultHeaderTimeout = 5 * time.Second
+
+// Policy decides which peers may send a PROXY header. A nil *Policy means
+// PROXY protocol is off and Wrap returns listeners unchanged.
+type Policy struct {
+	trusted []*net.IPNet
+	timeout time.Duration
+}
+
+// NewPolicy parses the trusted CIDRs. timeout <= 0 uses the default.
+func NewPolicy(cidrs []string, timeout time.Duration) (*Policy, error) {
+	p := &Policy{timeout: timeout}
+	if p.timeout <= 0 {
+		p.timeout = defaultHeaderTimeout
+	}
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("trusted cidr %q: %w", c, err)
+		}
+		p.trusted = append(p.trusted, n)
+	}
+	return p, nil
+}
+
+// Trusted reports whether addr belongs to a trusted CIDR.
+func (p *Policy) Trusted(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, n := range p.trusted {
+		if n.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap returns a listener whose connections from trusted peers are parsed
+// for a PROXY header. A nil receiver returns ln unchanged.
+func (p *Policy) Wrap(ln net.Listener) net.Listener {
+	if p == nil {
+		return ln
+	}
+	return &listener{Listener: ln, policy: p}
+}
+
+// listener wraps accepted connections from trusted peers in *Conn.
+type listener struct {
+	net.Listener
+	policy *Policy
+}
+
+// Accept returns the next connection. The header is read lazily, on the
+// first Read or RemoteAddr, so a slow peer never blocks the accept loop.
+func (l *listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil || !l.policy.Trusted(c.RemoteAddr()) {
+		return c, err
+	}
+	return &Conn{Conn: c, r: bufio.NewReader(c), timeout: l.policy.timeout}, nil
+}
+
+// Conn is a connection from a trusted peer. A PROXY header is optional, so
+// health checks that connect directly keep working.
+type Conn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+
+	once   sync.Once
+	remote net.Addr // from the header; nil when absent or LOCAL
+	err    error
+
+	mu       sync.Mutex
+	deadline time.Time // read deadline set by the caller
+}
+
+// Read reads from the connection after the header.
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the client address from the header, or the peer's
+// address when the peer sent none.
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// SetDeadline records the read deadline so readHeader can restore it.
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline records the read deadline so readHeader can restore it.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return c.Conn.SetReadDeadline(t)
+}
+
+// readHeader consumes a v1 or v2 header if one is present.
+func (c *Conn) readHeader() {
+	c.mu.Lock()
+	restore := c.deadline
+	c.mu.Unlock()
+	limit := time.Now().Add(c.timeout)
+	if !restore.IsZero() && restore.Before(limit) {
+		limit = restore
+	}
+	_ = c.Conn.SetReadDeadline(limit)
+	defer func() { _ = c.Conn.SetReadDeadline(restore) }()
+
+	first, err := c.r.Peek(1)
+	if err != nil {
+		c.err = err
+		return
+	}
+	switch first[0] {
+	case 'P':
+		if b, err := c.r.Peek(6); err == nil && string(b) == "PROXY " {
+			c.remote, c.err = readV1(c.r)
+		}
+	case '\r':
+		if b, err := c.r.Peek(len(v2Signature)); err == nil && bytes.Equal(b, v2Signature) {
+			c.remote, c.err = readV2(c.r)
+		}
+	}
+	if c.err != nil {
+		c.err = fmt.Errorf("proxy protocol from %s: %w", c.Conn.RemoteAddr(), c.err)
+	}
+}
+
+// readV1 parses "PROXY TCP4|TCP6 src dst sport dport\r\n" or
+// "PROXY UNKNOWN ...\r\n" (which keeps the peer address).
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < 107 { // maximum v1 header length
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, fmt.Errorf("v1 header too long or not CRLF-terminated")
+	}
+	f := strings.Fields(string(line[:len(line)-2]))
+	if len(f) >= 2 && f[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(f) != 6 || (f[1] != "TCP4" && f[1] != "TCP6") {
+		return nil, fmt.Errorf("malformed v1 header %q", line)
+	}
+	ip := net.ParseIP(f[2])
+	port, err := strconv.ParseUint(f[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, fmt.Errorf("malformed v1 source %s:%s", f[2], f[4])
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readV2 parses a binary v2 header. LOCAL commands and non-TCP families
+// keep the peer address; TLVs are skipped.
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported v2 version %d", hdr[12]>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(hdr[14:16]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	if hdr[12]&0x0f == 0 { // LOCAL: health check from the proxy itself
+		return nil, nil
+	}
+	switch hdr[13] {
+	case 0x11: // TCP over IPv4
+		if len(body) < 12 {
+			return nil, fmt.Errorf("short v2 IPv4 address block")
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(binary.BigEndian.Uint16(body[8:10]))}, nil
+	case 0x21: // TCP over IPv6
+		if len(body) < 36 {
+			return nil, fmt.Errorf("short v2 IPv6 address block")
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:34]))}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,118 @@
+// If you are AI: Unit tests for PROXY protocol parsing and the trusted-CIDR
+// gate, over real loopback TCP connections.
+
+package proxyproto
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// roundTrip listens through p, sends prefix+"hello" from a client and
+// returns the server side's RemoteAddr and the payload it read.
+func roundTrip(t *testing.T, p *Policy, prefix []byte) (string, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := p.Wrap(ln)
+	defer wrapped.Close()
+
+	go func() {
+		c, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		_, _ = c.Write(append(append([]byte{}, prefix...), "hello"...))
+	}()
+
+	c, err := wrapped.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(c, buf); err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	return c.RemoteAddr().String(), string(buf)
+}
+
+// v2Header builds a v2 PROXY TCP4 header for src:port.
+func v2Header(src net.IP, port uint16) []byte {
+	body := make([]byte, 12)
+	copy(body[0:4], src.To4())
+	copy(body[4:8], net.IPv4(10, 0, 0, 1).To4())
+	binary.BigEndian.PutUint16(body[8:10], port)
+	binary.BigEndian.PutUint16(body[10:12], 1935)
+	hdr := append([]byte{}, v2Signature...)
+	hdr = append(hdr, 0x21, 0x11, 0, byte(len(body)))
+	return append(hdr, body...)
+}
+
+// TestTrustedHeaders checks v1, v2 and header-less connections from a
+// trusted peer.
+func TestTrustedHeaders(t *testing.T) {
+	p, err := NewPolicy([]string{"127.0.0.0/8"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		prefix []byte
+		want   string
+	}{
+		{"v1", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 40000 1935\r\n"), "203.0.113.7:40000"},
+		{"v1 ipv6", []byte("PROXY TCP6 2001:db8::1 2001:db8::2 40001 80\r\n"), "[2001:db8::1]:40001"},
+		{"v2", v2Header(net.IPv4(198, 51, 100, 9), 50000), "198.51.100.9:50000"},
+	}
+	for _, tc := range cases {
+		addr, payload := roundTrip(t, p, tc.prefix)
+		if addr != tc.want || payload != "hello" {
+			t.Errorf("%s: addr %s payload %q, want %s hello", tc.name, addr, payload, tc.want)
+		}
+	}
+
+	// No header: the peer address stands and nothing is consumed. "P" must
+	// not be mistaken for a header (e.g. an HTTP POST).
+	for _, prefix := range [][]byte{nil, []byte("POST ")} {
+		addr, payload := roundTrip(t, p, prefix)
+		host, _, _ := net.SplitHostPort(addr)
+		if host != "127.0.0.1" || (prefix == nil && payload != "hello") || (prefix != nil && payload != "POST ") {
+			t.Errorf("prefix %q: addr %s payload %q", prefix, addr, payload)
+		}
+	}
+}
+
+// TestUntrustedPeerIgnored checks that a header from an untrusted peer is
+// not honoured; it reaches the protocol handler as ordinary bytes.
+func TestUntrustedPeerIgnored(t *testing.T) {
+	p, err := NewPolicy([]string{"192.0.2.0/24"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, payload := roundTrip(t, p, []byte("PROXY TCP4 203.0.113.7 10.0.0.1 40000 1935\r\n"))
+	if host, _, _ := net.SplitHostPort(addr); host != "127.0.0.1" || payload != "PROXY" {
+		t.Errorf("untrusted peer: addr %s payload %q", addr, payload)
+	}
+}
+
+// TestNilPolicy checks that a nil policy leaves the listener untouched.
+func TestNilPolicy(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var p *Policy
+	if p.Wrap(ln) != ln {
+		t.Error("nil policy must return the listener unchanged")
+	}
+	if _, err := NewPolicy([]string{"not-a-cidr"}, 0); err == nil {
+		t.Error("expected error for an invalid CIDR")
+	}
+}
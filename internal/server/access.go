@@ -0,0 +1,87 @@
+// If you are AI: This file builds the connection guard from config and
+// applies its play ACL to the media routes.
+
+package server
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"nonchalant/internal/access"
+	"nonchalant/internal/config"
+	"nonchalant/internal/events"
+	"nonchalant/internal/svc/rtmp"
+)
+
+// newGuard builds the guard for c and hands it, with the handshake
+// deadline, to the RTMP server. CIDRs were checked by config validation,
+// so parse errors cannot occur here.
+func newGuard(c config.AccessConfig, rtmpServer *rtmp.Server) *access.Guard {
+	rules := make([]access.Rule, len(c.Rules))
+	for i, r := range c.Rules {
+		rules[i] = access.Rule{
+			App:          r.App,
+			PublishAllow: r.PublishAllow,
+			PublishDeny:  r.PublishDeny,
+			PlayAllow:    r.PlayAllow,
+			PlayDeny:     r.PlayDeny,
+		}
+	}
+	g, _ := access.New(rules, access.Limits{
+		MaxConnsPerIP: c.MaxConnsPerIP,
+		ConnectRate:   c.ConnectRate,
+		ConnectBurst:  c.ConnectBurst,
+	})
+	rtmpServer.SetGuard(g)
+	rtmpServer.SetHandshakeTimeout(handshakeTimeout(c))
+	return g
+}
+
+// handshakeTimeout is the configured handshake deadline.
+func handshakeTimeout(c config.AccessConfig) time.Duration {
+	return time.Duration(c.HandshakeTimeoutSeconds) * time.Second
+}
+
+// guardPlay answers 403 to playback requests the guard's play rules refuse.
+// Non-media routes (health, API) pass through.
+func guardPlay(g *access.Guard, ev *events.Bus, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app, name, _ := playStream(r.URL.Path); app != "" && !g.Allow(app, access.Play, r.RemoteAddr) {
+			emitRejected(ev, r, app, name, access.ReasonPlayDenied)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// playStream returns the app and stream name of a playback path, or "" for
+// other routes. subscribes is true for HTTP-FLV (/{app}/{name}.flv) and
+// WS-FLV (/ws/{app}/{name}), which attach a bus subscriber; HLS and DASH
+// (/hls/{app}/{name}/..., /dash/{app}/{name}/...) serve files.
+func playStream(path string) (app, name string, subscribes bool) {
+	p := strings.TrimPrefix(path, "/")
+	switch {
+	case strings.HasPrefix(p, "ws/"):
+		p, subscribes = p[len("ws/"):], true
+	case strings.HasPrefix(p, "hls/"):
+		p = p[len("hls/"):]
+	case strings.HasPrefix(p, "dash/"):
+		p = p[len("dash/"):]
+	case strings.HasSuffix(p, ".flv"):
+		p, subscribes = strings.TrimSuffix(p, ".flv"), true
+	default:
+		return "", "", false
+	}
+	app, name, ok := strings.Cut(p, "/")
+	if !ok {
+		return "", "", false
+	}
+	if !subscribes {
+		name, _, _ = strings.Cut(name, "/")
+		// Legacy manifest URLs: /hls/{app}/{name}.m3u8, /dash/{app}/{name}.mpd.
+		name = strings.TrimSuffix(strings.TrimSuffix(name, ".m3u8"), ".mpd")
+	}
+	return app, name, subscribes
+}
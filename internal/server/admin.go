@@ -0,0 +1,25 @@
+// If you are AI: This file mounts the profiling routes of the admin surface.
+// They land on the admin listener in the split layout and next to media in
+// the combined one.
+
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"nonchalant/internal/auth"
+)
+
+// registerPprof mounts /debug/pprof/* (CPU, heap, goroutine, allocs, mutex,
+// block). Profiling is admin-only once admin tokens are configured.
+func registerPprof(mux *http.ServeMux, admin *auth.AdminPolicy) {
+	route := func(path string, h http.HandlerFunc) {
+		mux.Handle(path, auth.RequireRole(admin, auth.RoleAdmin, h))
+	}
+	route("/debug/pprof/", pprof.Index)
+	route("/debug/pprof/cmdline", pprof.Cmdline)
+	route("/debug/pprof/profile", pprof.Profile)
+	route("/debug/pprof/symbol", pprof.Symbol)
+	route("/debug/pprof/trace", pprof.Trace)
+}
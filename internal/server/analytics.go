@@ -0,0 +1,22 @@
+// If you are AI: This file builds the viewer analytics recorder from
+// config and connects it to the session table that feeds it.
+
+package server
+
+import (
+	"nonchalant/internal/analytics"
+	"nonchalant/internal/config"
+	"nonchalant/internal/sessions"
+)
+
+// newAnalytics returns a recorder fed by table. Reports are exported to
+// c.ExportDir when the analytics section is present.
+func newAnalytics(c *config.AnalyticsConfig, table *sessions.Table) *analytics.Recorder {
+	dir := ""
+	if c != nil {
+		dir = c.ExportDir
+	}
+	rec := analytics.New(dir)
+	table.SetAnalytics(rec)
+	return rec
+}
@@ -0,0 +1,128 @@
+// If you are AI: This file wires the capacity limiter: publish caps on the
+// RTMP server, 503 + Retry-After for playback over a limit, and egress
+// metering of every media response.
+
+package server
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+
+	"nonchalant/internal/capacity"
+	"nonchalant/internal/config"
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
+	"nonchalant/internal/sessions"
+	"nonchalant/internal/svc/api"
+	"nonchalant/internal/svc/rtmp"
+)
+
+// defaultRetryAfter is the Retry-After, in seconds, sent with a 503.
+const defaultRetryAfter = 10
+
+// newCapacity builds the limiter for c and hands it to the RTMP server and
+// the API. It returns nil without a capacity section.
+func newCapacity(c *config.CapacityConfig, reg *bus.Registry, rtmpServer *rtmp.Server, apiSvc *api.Service) *capacity.Limiter {
+	if c == nil {
+		return nil
+	}
+	apps := make(map[string]capacity.Limits, len(c.Apps))
+	for app, l := range c.Apps {
+		apps[app] = capacityLimits(l)
+	}
+	l := capacity.New(reg, capacityLimits(c.CapacityLimits), apps)
+	rtmpServer.SetPublishLimiter(l)
+	apiSvc.SetCapacitySource(l)
+	return l
+}
+
+// capacityLimits maps one set of configured caps.
+func capacityLimits(l config.CapacityLimits) capacity.Limits {
+	return capacity.Limits{
+		MaxStreams:              l.MaxStreams,
+		MaxSubscribersPerStream: l.MaxSubscribersPerStream,
+		MaxSubscribers:          l.MaxSubscribers,
+		MaxEgressKbps:           l.MaxEgressKbps,
+	}
+}
+
+// limitPlay answers 503 with Retry-After to playback over capacity and
+// meters the bytes of admitted playback. The server's own pulls (the
+// packager and push relays: loopback with their user agent) are neither
+// limited nor metered; other loopback clients, such as a same-host proxy,
+// are.
+func limitPlay(l *capacity.Limiter, c *config.CapacityConfig, ev *events.Bus, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	retryAfter := strconv.Itoa(defaultRetryAfter)
+	if c.RetryAfterSeconds > 0 {
+		retryAfter = strconv.Itoa(c.RetryAfterSeconds)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app, name, subscribes := playStream(r.URL.Path)
+		if app == "" || sessions.OutputProtocol(r.RemoteAddr, r.UserAgent()) != sessions.ProtoHTTPFLV {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if reason := l.AllowPlay(app, name, subscribes); reason != "" {
+			emitRejected(ev, r, app, name, reason)
+			w.Header().Set("Retry-After", retryAfter)
+			http.Error(w, "over capacity: "+reason, http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(&meteredWriter{ResponseWriter: w, m: l.Meter(app, name)}, r)
+	})
+}
+
+// meteredWriter counts response bytes, including those written to a
+// hijacked connection (HTTP-FLV on HTTP/1.1, WS-FLV).
+type meteredWriter struct {
+	http.ResponseWriter
+	m *capacity.Meter
+}
+
+// Write counts and writes a response body chunk.
+func (w *meteredWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.m.Add(n)
+	return n, err
+}
+
+// Flush flushes the underlying writer.
+func (w *meteredWriter) Flush() { _ = w.FlushError() }
+
+// FlushError flushes the underlying writer and reports its error.
+func (w *meteredWriter) FlushError() error {
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack takes over the connection; writes to it are still metered.
+func (w *meteredWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	mc := &meteredConn{Conn: conn, m: w.m}
+	rw.Writer.Reset(mc)
+	return mc, rw, nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer for
+// deadlines.
+func (w *meteredWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// meteredConn counts bytes written to a hijacked connection.
+type meteredConn struct {
+	net.Conn
+	m *capacity.Meter
+}
+
+// Write counts and writes to the connection.
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.m.Add(n)
+	return n, err
+}
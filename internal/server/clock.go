@@ -0,0 +1,58 @@
+// If you are AI: This file tells the packager where its ffmpeg pull's
+// timeline sits on the stream: the stream's wall-clock anchor and timed
+// metadata from the bus, and the timestamp the pull's HTTP-FLV session
+// started at.
+
+package server
+
+import (
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
+	"nonchalant/internal/svc/pkger"
+)
+
+// outputClock implements pkger.ClockSource and pkger.CueSource.
+type outputClock struct {
+	registry *bus.Registry
+	table    *sessions.Table
+}
+
+// OutputOrigin returns the wall-clock time of timestamp zero on the
+// HTTP-FLV output read by userAgent on key.
+func (c outputClock) OutputOrigin(key bus.StreamKey, userAgent string) (time.Time, bool) {
+	stream := c.registry.Get(key)
+	if stream == nil {
+		return time.Time{}, false
+	}
+	clock, ok := stream.Clock()
+	if !ok {
+		return time.Time{}, false
+	}
+	origin, ok := c.table.Origin(key, userAgent)
+	if !ok {
+		return time.Time{}, false
+	}
+	return clock.At(origin), true
+}
+
+// OutputCues returns key's recent timed metadata that came after the start
+// of the HTTP-FLV output read by userAgent, timed from that start.
+func (c outputClock) OutputCues(key bus.StreamKey, userAgent string) []pkger.Cue {
+	stream := c.registry.Get(key)
+	if stream == nil {
+		return nil
+	}
+	origin, ok := c.table.Origin(key, userAgent)
+	if !ok {
+		return nil
+	}
+	var out []pkger.Cue
+	for _, cue := range stream.Cues() {
+		if d := int32(cue.Timestamp - origin); d >= 0 {
+			out = append(out, pkger.Cue{ID: cue.ID, Time: time.Duration(d) * time.Millisecond, Payload: cue.Payload})
+		}
+	}
+	return out
+}
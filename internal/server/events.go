@@ -0,0 +1,35 @@
+// If you are AI: This file builds the server event bus and hands it to the
+// services that emit lifecycle events; /api/events streams them.
+
+package server
+
+import (
+	"net/http"
+
+	"nonchalant/internal/events"
+	"nonchalant/internal/sessions"
+	"nonchalant/internal/svc/relay"
+	"nonchalant/internal/svc/rtmp"
+)
+
+// newEvents creates the event bus and connects the emitters that exist
+// before the HTTP services: the session table (publish and viewer
+// lifecycle), RTMP publish rejections and relay status changes.
+func newEvents(table *sessions.Table, rtmpServer *rtmp.Server, relayMgr *relay.Manager) *events.Bus {
+	ev := events.NewBus()
+	table.SetEvents(ev)
+	rtmpServer.SetEvents(ev)
+	relayMgr.SetEvents(ev)
+	return ev
+}
+
+// emitRejected reports a refused playback request of app/name on ev.
+func emitRejected(ev *events.Bus, r *http.Request, app, name, reason string) {
+	ev.Emit(events.Event{
+		Type:       events.PolicyRejected,
+		App:        app,
+		Name:       name,
+		RemoteAddr: r.RemoteAddr,
+		Reason:     reason,
+	})
+}
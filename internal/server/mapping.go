@@ -0,0 +1,41 @@
+// If you are AI: This file maps config values onto the types and identities
+// the services expect.
+
+package server
+
+import (
+	"nonchalant/internal/config"
+	"nonchalant/internal/svc/pkger"
+)
+
+// firstKey returns the first non-empty key in keys, or "" if there are none.
+// Used to pick a default identity for outbound relay connections.
+func firstKey(keys []string) string {
+	for _, k := range keys {
+		if k != "" {
+			return k
+		}
+	}
+	return ""
+}
+
+// ladderToPkger maps the YAML ABR ladder onto the pkger-local rung type.
+// Avoids forcing the pkger package to import config (and the cycle that comes
+// with it).
+func ladderToPkger(rungs []config.LadderRung) []pkger.LadderRung {
+	if len(rungs) == 0 {
+		return nil
+	}
+	out := make([]pkger.LadderRung, len(rungs))
+	for i, r := range rungs {
+		out[i] = pkger.LadderRung{
+			Name:         r.Name,
+			Width:        r.Width,
+			Height:       r.Height,
+			VideoBitrate: r.VideoBitrate,
+			AudioBitrate: r.AudioBitrate,
+			AudioOnly:    r.AudioOnly,
+		}
+	}
+	return out
+}
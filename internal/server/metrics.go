@@ -0,0 +1,33 @@
+// If you are AI: This file builds the metrics service and connects the
+// sources that exist before the media services are created.
+
+package server
+
+import (
+	"net/http"
+
+	"nonchalant/internal/access"
+	"nonchalant/internal/auth"
+	"nonchalant/internal/config"
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
+	"nonchalant/internal/svc/metrics"
+	"nonchalant/internal/svc/relay"
+	"nonchalant/internal/svc/rtmp"
+)
+
+// newMetrics mounts /metrics on mux with rejections, delivery latency,
+// traffic, ingest watchdog alerts and timestamp corrections. The HTTP-FLV session source is added
+// once that service exists.
+func newMetrics(cfg *config.Config, mux *http.ServeMux, registry *bus.Registry, relayMgr *relay.Manager,
+	guard *access.Guard, table *sessions.Table, rtmpServer *rtmp.Server) *metrics.Service {
+	m := metrics.NewService(registry, relayMgr)
+	m.SetScrapeKeys(auth.NewKeySet([]string{cfg.Admin.MetricsToken}))
+	m.SetRejectionSource(guard)
+	m.SetLatencySource(table)
+	m.SetTrafficSource(table)
+	m.SetIngestAlertSource(rtmpServer)
+	m.SetTimestampSource(rtmpServer)
+	m.RegisterRoutes(mux)
+	return m
+}
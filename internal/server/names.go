@@ -0,0 +1,39 @@
+// If you are AI: This file builds the stream name policy from config and
+// applies it to the media routes, so every output refuses unsafe names the
+// same way RTMP publish does.
+
+package server
+
+import (
+	"net/http"
+
+	"nonchalant/internal/access"
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
+	"nonchalant/internal/svc/rtmp"
+)
+
+// newNamePolicy compiles the stream_names patterns and hands them to the
+// RTMP server. Patterns were checked by config validation.
+func newNamePolicy(patterns map[string]string, rtmpServer *rtmp.Server) *bus.NamePolicy {
+	p, _ := bus.NewNamePolicy(patterns)
+	rtmpServer.SetNamePolicy(p)
+	return p
+}
+
+// checkNames answers 400 to playback requests whose app or stream name
+// fails p, counting them as bad_name rejections. Non-media routes pass
+// through.
+func checkNames(p *bus.NamePolicy, g *access.Guard, ev *events.Bus, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app, name, _ := playStream(r.URL.Path); app != "" {
+			if err := p.Check(bus.NewStreamKey(app, name)); err != nil {
+				g.Reject(access.ReasonBadName)
+				emitRejected(ev, r, app, name, access.ReasonBadName)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,110 @@
+// If you are AI: This file converts the per-app publish_policy,
+// ingest_watchdog, timestamps and captions config for the RTMP server and
+// the packager.
+
+package server
+
+import (
+	"time"
+
+	"nonchalant/internal/config"
+	"nonchalant/internal/svc/pkger"
+	"nonchalant/internal/svc/rtmp"
+)
+
+// publishPolicies converts c for rtmp.Server.SetPublishPolicies (nil when
+// no app has a policy).
+func publishPolicies(c map[string]config.PublishPolicyConfig) map[string]*rtmp.PublishPolicy {
+	if len(c) == 0 {
+		return nil
+	}
+	out := make(map[string]*rtmp.PublishPolicy, len(c))
+	for app, p := range c {
+		out[app] = &rtmp.PublishPolicy{
+			VideoCodecs:    p.VideoCodecs,
+			AudioCodecs:    p.AudioCodecs,
+			MaxWidth:       p.MaxWidth,
+			MaxHeight:      p.MaxHeight,
+			MaxFPS:         p.MaxFPS,
+			MaxBitrateKbps: p.MaxBitrateKbps,
+			BitrateWindow:  time.Duration(p.BitrateWindowSeconds) * time.Second,
+			BitrateGrace:   time.Duration(p.BitrateGraceSeconds) * time.Second,
+		}
+	}
+	return out
+}
+
+// ingestWatchdogs converts c for rtmp.Server.SetWatchdogs (nil when no app
+// is watched).
+func ingestWatchdogs(c map[string]config.IngestWatchdogConfig) map[string]*rtmp.Watchdog {
+	if len(c) == 0 {
+		return nil
+	}
+	seconds := func(n int) time.Duration { return time.Duration(n) * time.Second }
+	out := make(map[string]*rtmp.Watchdog, len(c))
+	for app, w := range c {
+		out[app] = &rtmp.Watchdog{
+			NoMedia:        seconds(w.NoMediaSeconds),
+			NoKeyframe:     seconds(w.NoKeyframeSeconds),
+			NoVideo:        seconds(w.NoVideoSeconds),
+			TimestampJump:  seconds(w.TimestampJumpSeconds),
+			MinBitrateKbps: w.MinBitrateKbps,
+			BitrateWindow:  seconds(w.BitrateWindowSeconds),
+			Disconnect:     w.Disconnect,
+		}
+	}
+	return out
+}
+
+// timestampSanitizers converts c for rtmp.Server.SetSanitizers (nil when no
+// app is sanitized).
+func timestampSanitizers(c map[string]config.TimestampConfig) map[string]*rtmp.Sanitizer {
+	if len(c) == 0 {
+		return nil
+	}
+	out := make(map[string]*rtmp.Sanitizer, len(c))
+	for app, ts := range c {
+		out[app] = &rtmp.Sanitizer{
+			MaxJump:    time.Duration(ts.MaxJumpMs) * time.Millisecond,
+			Interleave: time.Duration(ts.InterleaveMs) * time.Millisecond,
+		}
+	}
+	return out
+}
+
+// captionDecoders converts c for rtmp.Server.SetCaptions (nil when no app
+// decodes captions).
+func captionDecoders(c map[string]config.CaptionsConfig) map[string]*rtmp.Captions {
+	if len(c) == 0 {
+		return nil
+	}
+	out := make(map[string]*rtmp.Captions, len(c))
+	for app, cc := range c {
+		out[app] = &rtmp.Captions{Language: captionTrack(cc).Language}
+	}
+	return out
+}
+
+// captionTracks converts c for pkger.Options.Captions.
+func captionTracks(c map[string]config.CaptionsConfig) map[string]pkger.CaptionTrack {
+	if len(c) == 0 {
+		return nil
+	}
+	out := make(map[string]pkger.CaptionTrack, len(c))
+	for app, cc := range c {
+		out[app] = captionTrack(cc)
+	}
+	return out
+}
+
+// captionTrack returns cc's rendition with the defaults filled in.
+func captionTrack(cc config.CaptionsConfig) pkger.CaptionTrack {
+	t := pkger.CaptionTrack{Language: cc.Language, Name: cc.Name}
+	if t.Language == "" {
+		t.Language = "en"
+	}
+	if t.Name == "" {
+		t.Name = "Captions"
+	}
+	return t
+}
@@ -0,0 +1,28 @@
+// If you are AI: This file builds the PROXY protocol policies for the public
+// listeners from config.
+
+package server
+
+import (
+	"time"
+
+	"nonchalant/internal/config"
+	"nonchalant/internal/proxyproto"
+)
+
+// proxyPolicies returns the policies for the RTMP and HTTP listener groups;
+// either is nil when PROXY protocol is off for it. CIDRs were checked by
+// config validation, so parse errors cannot occur here.
+func proxyPolicies(c *config.ProxyConfig) (rtmp, http *proxyproto.Policy) {
+	if c == nil {
+		return nil, nil
+	}
+	p, _ := proxyproto.NewPolicy(c.TrustedCIDRs, time.Duration(c.HeaderTimeoutSeconds)*time.Second)
+	if c.RTMP {
+		rtmp = p
+	}
+	if c.HTTP {
+		http = p
+	}
+	return rtmp, http
+}
@@ -6,14 +6,24 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
-	"net/http/pprof"
-	"time"
+	"strconv"
 
+	"github.com/quic-go/quic-go/http3"
+
+	"nonchalant/internal/access"
 	"nonchalant/internal/auth"
+	"nonchalant/internal/capacity"
+	"nonchalant/internal/certs"
 	"nonchalant/internal/config"
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
+	"nonchalant/internal/proxyproto"
+	"nonchalant/internal/sessions"
 	"nonchalant/internal/svc/api"
+	"nonchalant/internal/svc/cluster"
+	"nonchalant/internal/svc/edge"
 	"nonchalant/internal/svc/health"
 	"nonchalant/internal/svc/httpflv"
 	"nonchalant/internal/svc/metrics"
@@ -27,6 +37,15 @@ import (
 // Server wraps the HTTP server and its dependencies.
 type Server struct {
 	httpServer   *http.Server
+	adminServer  *http.Server  // nil in the combined layout
+	httpsServer  *http.Server  // nil without tls.https_port
+	http3Server  *http3.Server // nil without tls.http3_port
+	certs        *certs.Store  // nil until startTLS loads certificates
+	httpProxy    *proxyproto.Policy
+	guard        *access.Guard
+	limiter      *capacity.Limiter // nil without a capacity section
+	table        *sessions.Table   // swept for idle HLS / DASH viewers
+	events       *events.Bus       // lifecycle events behind /api/events
 	healthSvc    *health.Service
 	apiSvc       *api.Service
 	metricsSvc   *metrics.Service
@@ -35,6 +54,8 @@ type Server struct {
 	wsflvSvc     *wsflv.Service
 	rtmpServer   *rtmp.Server
 	relayMgr     *relay.Manager
+	edgePuller   *edge.Puller
+	directory    *cluster.Directory
 	transcodeMgr *transcode.Manager
 	registry     *bus.Registry
 }
@@ -42,10 +63,19 @@ type Server struct {
 // New creates a new server instance with the given configuration.
 // The server is not started until Start is called.
 func New(cfg *config.Config) *Server {
+	// mux serves media. In the split layout adminMux is a separate mux for
+	// the admin listener; in the combined layout both are the same mux.
 	mux := http.NewServeMux()
+	adminMux := mux
+	if cfg.Server.Layout == "split" {
+		adminMux = http.NewServeMux()
+	}
 
 	healthSvc := health.New()
 	healthSvc.RegisterRoutes(mux)
+	if adminMux != mux {
+		healthSvc.RegisterRoutes(adminMux)
+	}
 
 	// Create bus registry
 	registry := bus.NewRegistry()
@@ -56,6 +86,19 @@ func New(cfg *config.Config) *Server {
 	playKeys := auth.NewKeySet(cfg.Auth.PlayKeys)
 
 	rtmpServer := rtmp.NewServer(registry, publishKeys)
+	rtmpProxy, httpProxy := proxyPolicies(cfg.Proxy)
+	rtmpServer.SetProxyPolicy(rtmpProxy)
+	guard := newGuard(cfg.Access, rtmpServer) // ACLs and per-IP limits (access.go)
+	rtmpServer.SetPublishPolicies(publishPolicies(cfg.PublishPolicy))
+	rtmpServer.SetWatchdogs(ingestWatchdogs(cfg.Watchdog))
+	rtmpServer.SetSanitizers(timestampSanitizers(cfg.Timestamps))
+	rtmpServer.SetCaptions(captionDecoders(cfg.Captions))
+	names := newNamePolicy(cfg.StreamNames, rtmpServer) // stream name rules (names.go)
+
+	// Every publisher and viewer is listed here, for /api/sessions.
+	table := sessions.NewTable()
+	rtmpServer.SetSessionTable(table)
+	viewers := newAnalytics(cfg.Analytics, table) // per-broadcast reports (analytics.go)
 
 	// Create relay manager and tell it where our local listeners are so it
 	// can build URLs back into us when relays reconnect via ffmpeg. The first
@@ -66,6 +109,15 @@ func New(cfg *config.Config) *Server {
 		cfg.Server.RTMPPort, cfg.Server.HTTPPort,
 		firstKey(cfg.Auth.PublishKeys), firstKey(cfg.Auth.PlayKeys),
 	)
+	ev := newEvents(table, rtmpServer, relayMgr) // lifecycle events (events.go)
+
+	// Edge mode and the cluster directory (see topology.go). Either may be
+	// nil; in cluster proxy mode the directory feeds the edge puller.
+	directory := newDirectory(cfg, registry)
+	edgePuller := newEdgePuller(cfg, registry, directory)
+
+	// Admin auth: nil policy keeps /api and pprof open (legacy behaviour).
+	admin := adminPolicy(cfg.Admin)
 
 	// Create transcode manager (optional, works with or without FFmpeg)
 	transcodeMgr := transcode.NewManager(registry)
@@ -73,18 +125,21 @@ func New(cfg *config.Config) *Server {
 	// Register API and metrics BEFORE httpflv — httpflv mounts a catch-all "/"
 	// pattern which would otherwise mask /api/* and /metrics.
 	apiSvc := api.NewService(registry, relayMgr)
-	apiSvc.RegisterRoutes(mux)
+	apiSvc.SetAdminPolicy(admin)
+	apiSvc.SetSessionTable(table)
+	apiSvc.SetEvents(ev)
+	apiSvc.SetAnalytics(viewers)
+	apiSvc.RegisterRoutes(adminMux)
+
+	if directory != nil {
+		directory.SetAdminPolicy(admin)
+		directory.RegisterRoutes(adminMux)
+	}
 
-	metricsSvc := metrics.NewService(registry, relayMgr)
-	metricsSvc.RegisterRoutes(mux)
+	metricsSvc := newMetrics(cfg, adminMux, registry, relayMgr, guard, table, rtmpServer) // (metrics.go)
 
-	// pprof: /debug/pprof/* (CPU, heap, goroutine, allocs, mutex, block).
-	// Mounted before httpflv's catch-all so the routes are reachable.
-	mux.HandleFunc("/debug/pprof/", pprof.Index)
-	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	// pprof is mounted before httpflv's catch-all so the routes are reachable.
+	registerPprof(adminMux, admin)
 
 	// HLS / DASH packager service. If creation fails (e.g. no writable temp
 	// directory) we log and continue — the rest of the server still works.
@@ -92,31 +147,65 @@ func New(cfg *config.Config) *Server {
 		pkger.Options{
 			LowLatency: cfg.HLS.LowLatency,
 			Ladder:     ladderToPkger(cfg.HLS.Ladder),
+			Captions:   captionTracks(cfg.Captions),
 		})
 	if pkgerErr != nil {
 		log.Printf("HLS/DASH packager disabled: %v", pkgerErr)
 	} else {
+		pkgerSvc.SetSessionTable(table)
+		pkgerSvc.SetEvents(ev)
+		pkgerSvc.SetClock(outputClock{registry, table}) // wall-clock manifests (clock.go)
+		pkgerSvc.SetCues(outputClock{registry, table})  // timed metadata in segments
 		pkgerSvc.RegisterRoutes(mux)
 	}
 
 	// Create WebSocket-FLV service (uses a distinct /ws/ prefix)
 	wsflvSvc := wsflv.NewService(registry, playKeys)
+	wsflvSvc.SetSessionTable(table)
 	wsflvSvc.RegisterRoutes(mux)
 
 	// Create HTTP-FLV service (catch-all on "/", must register last)
 	httpflvSvc := httpflv.NewService(registry, playKeys)
+	httpflvSvc.SetSessionTable(table)
 	httpflvSvc.RegisterRoutes(mux)
-
-	// HTTP server listens on HTTP port
-	// Health endpoint is also available on this port
-	// NOTE: Health port is kept for backward compatibility but not used
+	apiSvc.SetSessionSource(httpflvSvc)
+	metricsSvc.SetSessionSource(httpflvSvc)
+
+	// HTTP server listens on HTTP port. /healthz is always available here;
+	// the rest of the admin surface is too unless the layout is split.
+	// Capacity limits (capacity.go) apply after any cluster redirect; the
+	// play ACL runs before it, after the stream name check.
+	limiter := newCapacity(cfg.Capacity, registry, rtmpServer, apiSvc)
+	handler := limitPlay(limiter, cfg.Capacity, ev, mux)
+	if directory != nil && cfg.Cluster.Mode != "proxy" {
+		handler = directory.Redirect(handler)
+	}
+	handler = checkNames(names, guard, ev, guardPlay(guard, ev, handler))
 	httpServer := &http.Server{
-		Addr:    fmt.Sprintf(":%d", cfg.Server.HTTPPort),
-		Handler: mux,
+		Addr:              fmt.Sprintf(":%d", cfg.Server.HTTPPort),
+		Handler:           handler,
+		ReadHeaderTimeout: handshakeTimeout(cfg.Access),
+	}
+
+	// Split layout: the admin surface gets its own listener on health_port.
+	var adminServer *http.Server
+	if adminMux != mux {
+		adminServer = &http.Server{
+			Addr:              net.JoinHostPort(cfg.Server.AdminBind, strconv.Itoa(cfg.Server.HealthPort)),
+			Handler:           adminMux,
+			ReadHeaderTimeout: handshakeTimeout(cfg.Access),
+		}
 	}
 
 	return &Server{
 		httpServer:   httpServer,
+		adminServer:  adminServer,
+		httpsServer:  newHTTPSServer(cfg, handler),
+		httpProxy:    httpProxy,
+		guard:        guard,
+		limiter:      limiter,
+		table:        table,
+		events:       ev,
 		healthSvc:    healthSvc,
 		apiSvc:       apiSvc,
 		metricsSvc:   metricsSvc,
@@ -125,6 +214,8 @@ func New(cfg *config.Config) *Server {
 		wsflvSvc:     wsflvSvc,
 		rtmpServer:   rtmpServer,
 		relayMgr:     relayMgr,
+		edgePuller:   edgePuller,
+		directory:    directory,
 		transcodeMgr: transcodeMgr,
 		registry:     registry,
 	}
@@ -145,6 +236,9 @@ func (s *Server) Start(cfg *config.Config) error {
 		}
 	}
 
+	// Session sweeps, egress metering and cluster membership (shutdown.go)
+	s.startLoops()
+
 	// Start RTMP server
 	if err := s.rtmpServer.Listen(fmt.Sprintf(":%d", cfg.Server.RTMPPort)); err != nil {
 		return fmt.Errorf("RTMP server listen: %w", err)
@@ -155,79 +249,46 @@ func (s *Server) Start(cfg *config.Config) error {
 		}
 	}()
 
-	// Start HTTP server (blocks)
-	return s.httpServer.ListenAndServe()
-}
-
-// Shutdown gracefully stops the server with a timeout.
-// Returns an error if shutdown fails or times out.
-func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
-}
-
-// ShutdownWithTimeout stops the server with a fixed 5-second timeout.
-// This is a convenience wrapper around Shutdown.
-func (s *Server) ShutdownWithTimeout() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Stop relay manager
-	if s.relayMgr != nil {
-		if err := s.relayMgr.Stop(); err != nil {
-			log.Printf("relay manager stop: %v", err)
-		}
+	// HTTPS and RTMPS (optional, see tls.go)
+	if err := s.startTLS(cfg); err != nil {
+		return err
 	}
 
-	// Stop transcode manager
-	if s.transcodeMgr != nil {
-		if err := s.transcodeMgr.Stop(); err != nil {
-			log.Printf("transcode manager stop: %v", err)
-		}
+	// Bind the HTTP listeners before reporting ready.
+	httpLn, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("HTTP listen: %w", err)
 	}
-
-	// Close RTMP server
-	if s.rtmpServer != nil {
-		if err := s.rtmpServer.Close(); err != nil {
-			log.Printf("rtmp server close: %v", err)
+	httpLn = s.guard.Wrap(s.httpProxy.Wrap(httpLn))
+	if s.adminServer != nil {
+		adminLn, err := net.Listen("tcp", s.adminServer.Addr)
+		if err != nil {
+			_ = httpLn.Close()
+			return fmt.Errorf("admin listen: %w", err)
 		}
+		log.Printf("admin listener on %s", s.adminServer.Addr)
+		go func() {
+			if err := s.adminServer.Serve(adminLn); err != nil && err != http.ErrServerClosed {
+				log.Printf("admin server exited: %v", err)
+			}
+		}()
 	}
+	s.healthSvc.SetReady(true)
 
-	// Stop HLS/DASH packager (kills any spawned ffmpeg subprocesses)
-	if s.pkgerSvc != nil {
-		s.pkgerSvc.Stop()
-	}
-
-	return s.Shutdown(ctx)
+	// Serve media (blocks)
+	return s.httpServer.Serve(httpLn)
 }
 
-// firstKey returns the first non-empty key in keys, or "" if there are none.
-// Used to pick a default identity for outbound relay connections.
-func firstKey(keys []string) string {
-	for _, k := range keys {
-		if k != "" {
-			return k
-		}
-	}
-	return ""
-}
-
-// ladderToPkger maps the YAML ABR ladder onto the pkger-local rung type.
-// Avoids forcing the pkger package to import config (and the cycle that comes
-// with it).
-func ladderToPkger(rungs []config.LadderRung) []pkger.LadderRung {
-	if len(rungs) == 0 {
-		return nil
-	}
-	out := make([]pkger.LadderRung, len(rungs))
-	for i, r := range rungs {
-		out[i] = pkger.LadderRung{
-			Name:         r.Name,
-			Width:        r.Width,
-			Height:       r.Height,
-			VideoBitrate: r.VideoBitrate,
-			AudioBitrate: r.AudioBitrate,
-			AudioOnly:    r.AudioOnly,
+// Shutdown gracefully stops the server with a timeout.
+// Returns an error if shutdown fails or times out.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.healthSvc.SetReady(false)
+	s.events.Close() // ends /api/events streams so Shutdown can drain
+	s.stopTLS(ctx)
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(ctx); err != nil {
+			log.Printf("admin server shutdown: %v", err)
 		}
 	}
-	return out
+	return s.httpServer.Shutdown(ctx)
 }
@@ -1,9 +1,11 @@
-// If you are AI: This file handles graceful shutdown orchestration for the server process.
+// If you are AI: This file handles graceful shutdown orchestration for the server process,
+// and starts the background loops it stops.
 
 package server
 
 import (
 	"context"
+	"log"
 	"os"
 	"os/signal"
 	"syscall"
@@ -56,3 +58,65 @@ func (h *ShutdownHandler) Wait() error {
 func (h *ShutdownHandler) Context() context.Context {
 	return h.ctx
 }
+
+// startLoops starts the background loops ShutdownWithTimeout stops: the
+// session sweeper, egress metering (optional) and cluster heartbeats
+// (optional).
+func (s *Server) startLoops() {
+	s.table.Start()
+	if s.limiter != nil {
+		s.limiter.Start()
+	}
+	if s.directory != nil {
+		s.directory.Start()
+	}
+}
+
+// ShutdownWithTimeout stops the server with a fixed 5-second timeout.
+// This is a convenience wrapper around Shutdown.
+func (s *Server) ShutdownWithTimeout() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Stop relay manager
+	if s.relayMgr != nil {
+		if err := s.relayMgr.Stop(); err != nil {
+			log.Printf("relay manager stop: %v", err)
+		}
+	}
+
+	// Leave the cluster
+	if s.directory != nil {
+		s.directory.Stop()
+	}
+
+	// Stop egress metering and session sweeps
+	s.limiter.Stop()
+	s.table.Stop()
+
+	// Stop on-demand edge pulls
+	if s.edgePuller != nil {
+		s.edgePuller.Stop()
+	}
+
+	// Stop transcode manager
+	if s.transcodeMgr != nil {
+		if err := s.transcodeMgr.Stop(); err != nil {
+			log.Printf("transcode manager stop: %v", err)
+		}
+	}
+
+	// Close RTMP server
+	if s.rtmpServer != nil {
+		if err := s.rtmpServer.Close(); err != nil {
+			log.Printf("rtmp server close: %v", err)
+		}
+	}
+
+	// Stop HLS/DASH packager (kills any spawned ffmpeg subprocesses)
+	if s.pkgerSvc != nil {
+		s.pkgerSvc.Stop()
+	}
+
+	return s.Shutdown(ctx)
+}
@@ -0,0 +1,167 @@
+// If you are AI: This file wires the optional TLS listeners: HTTPS (HTTP/1.1
+// and optionally HTTP/2) and HTTP/3 serving the same routes as the media
+// port, and RTMPS ingest. All share one certificate store that reloads
+// certificates when their files change.
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+
+	"nonchalant/internal/certs"
+	"nonchalant/internal/config"
+)
+
+// defaultCertReload is how often certificate files are polled for changes.
+const defaultCertReload = 10 * time.Second
+
+// newHTTPSServer returns the HTTPS twin of the media server, or nil when no
+// https_port is configured.
+func newHTTPSServer(cfg *config.Config, handler http.Handler) *http.Server {
+	if cfg.TLS == nil || cfg.TLS.HTTPSPort == 0 {
+		return nil
+	}
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.TLS.HTTPSPort),
+		Handler:           handler,
+		ReadHeaderTimeout: handshakeTimeout(cfg.Access),
+	}
+}
+
+// startTLS loads the certificates and starts the HTTPS and RTMPS listeners.
+// It is a no-op without a tls section.
+func (s *Server) startTLS(cfg *config.Config) error {
+	t := cfg.TLS
+	if t == nil {
+		return nil
+	}
+	sni := make(map[string]certs.Pair, len(t.SNI))
+	for name, p := range t.SNI {
+		sni[name] = certs.Pair{CertFile: p.CertFile, KeyFile: p.KeyFile}
+	}
+	store, err := certs.NewStore(certs.Pair{CertFile: t.CertFile, KeyFile: t.KeyFile}, sni)
+	if err != nil {
+		return fmt.Errorf("load TLS certificates: %w", err)
+	}
+	minVersion, err := certs.ParseVersion(t.MinVersion)
+	if err != nil {
+		return err
+	}
+	tlsCfg := store.TLSConfig(minVersion)
+
+	if t.RTMPSPort != 0 {
+		if err := s.rtmpServer.ListenTLS(fmt.Sprintf(":%d", t.RTMPSPort), tlsCfg); err != nil {
+			return fmt.Errorf("RTMPS listen: %w", err)
+		}
+		go func() {
+			if err := s.rtmpServer.AcceptTLS(); err != nil {
+				log.Printf("RTMPS accept loop exited: %v", err)
+			}
+		}()
+	}
+
+	if t.HTTP3Port != 0 {
+		if err := s.startHTTP3(t.HTTP3Port, tlsCfg); err != nil {
+			return err
+		}
+	}
+
+	if s.httpsServer != nil {
+		// The listener terminates TLS itself. HTTP/1.1 clients get the
+		// hijacked HTTP-FLV path on the (*tls.Conn) connection; with http2
+		// enabled, h2 clients get the flushing path instead.
+		httpsCfg := tlsCfg
+		if t.HTTP2 {
+			httpsCfg = tlsCfg.Clone()
+			httpsCfg.NextProtos = []string{"h2", "http/1.1"}
+			s.httpsServer.TLSConfig = httpsCfg
+		}
+		if s.http3Server != nil {
+			s.httpsServer.Handler = altSvc(s.http3Server, s.httpsServer.Handler)
+		}
+		ln, err := net.Listen("tcp", s.httpsServer.Addr)
+		if err != nil {
+			return fmt.Errorf("HTTPS listen: %w", err)
+		}
+		tlsLn := tls.NewListener(s.guard.Wrap(s.httpProxy.Wrap(ln)), httpsCfg)
+		go func() {
+			if err := s.httpsServer.Serve(tlsLn); err != nil && err != http.ErrServerClosed {
+				log.Printf("HTTPS server exited: %v", err)
+			}
+		}()
+	}
+
+	reload := time.Duration(t.ReloadSeconds) * time.Second
+	if reload <= 0 {
+		reload = defaultCertReload
+	}
+	store.Watch(reload)
+	s.certs = store
+	return nil
+}
+
+// startHTTP3 binds the QUIC listener on UDP port and serves the HTTPS
+// handler over HTTP/3. The guard's per-IP limits apply to each QUIC
+// connection, as they do to TCP ones.
+func (s *Server) startHTTP3(port int, tlsCfg *tls.Config) error {
+	pc, err := net.ListenPacket("udp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("HTTP/3 listen: %w", err)
+	}
+	tlsCfg = http3.ConfigureTLSConfig(tlsCfg)
+	ln, err := quic.ListenEarly(pc, tlsCfg, &quic.Config{Allow0RTT: true})
+	if err != nil {
+		_ = pc.Close()
+		return fmt.Errorf("HTTP/3 listen: %w", err)
+	}
+	h3 := &http3.Server{
+		Handler:   s.httpsServer.Handler,
+		TLSConfig: tlsCfg,
+		Port:      port,
+	}
+	s.http3Server = h3
+	go func() {
+		defer pc.Close()
+		defer ln.Close()
+		if err := h3.ServeListener(s.guard.WrapQUIC(ln)); err != nil && err != http.ErrServerClosed {
+			log.Printf("HTTP/3 server exited: %v", err)
+		}
+	}()
+	return nil
+}
+
+// altSvc advertises the HTTP/3 listener on every HTTPS response so clients
+// can upgrade on their next request.
+func altSvc(h3 *http3.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = h3.SetQUICHeaders(w.Header())
+		next.ServeHTTP(w, r)
+	})
+}
+
+// stopTLS shuts down the HTTPS and HTTP/3 listeners and the certificate
+// watcher. The RTMPS listener is closed together with the plain RTMP one.
+func (s *Server) stopTLS(ctx context.Context) {
+	if s.http3Server != nil {
+		if err := s.http3Server.Shutdown(ctx); err != nil {
+			log.Printf("HTTP/3 server shutdown: %v", err)
+		}
+	}
+	if s.httpsServer != nil {
+		if err := s.httpsServer.Shutdown(ctx); err != nil {
+			log.Printf("HTTPS server shutdown: %v", err)
+		}
+	}
+	if s.certs != nil {
+		s.certs.Stop()
+	}
+}
@@ -0,0 +1,81 @@
+// If you are AI: This file builds the optional multi-node pieces of the
+// server from config: the edge puller, the cluster directory and the admin
+// token policy. Each returns nil when its feature is not configured.
+
+package server
+
+import (
+	"time"
+
+	"nonchalant/internal/auth"
+	"nonchalant/internal/config"
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/svc/cluster"
+	"nonchalant/internal/svc/edge"
+)
+
+// newDirectory creates the cluster directory, or nil without a cluster section.
+func newDirectory(cfg *config.Config, registry *bus.Registry) *cluster.Directory {
+	c := cfg.Cluster
+	if c == nil {
+		return nil
+	}
+	mode := c.Mode
+	if mode == "" {
+		mode = "redirect"
+	}
+	return cluster.NewDirectory(registry, cluster.Options{
+		NodeID:       c.NodeID,
+		AdvertiseURL: c.AdvertiseURL,
+		Peers:        c.Peers,
+		Secret:       c.Secret,
+		Mode:         mode,
+		Heartbeat:    time.Duration(c.HeartbeatSeconds) * time.Second,
+		PeerTimeout:  time.Duration(c.PeerTimeoutSeconds) * time.Second,
+		PlayKey:      firstKey(cfg.Auth.PlayKeys),
+	})
+}
+
+// newEdgePuller creates the on-demand puller used by edge mode and by
+// cluster proxy mode, installs it on the registry, and returns it. Returns
+// nil when neither feature is configured.
+func newEdgePuller(cfg *config.Config, registry *bus.Registry, dir *cluster.Directory) *edge.Puller {
+	proxy := dir != nil && cfg.Cluster.Mode == "proxy"
+	if cfg.Edge == nil && !proxy {
+		return nil
+	}
+	var opts edge.Options
+	if cfg.Edge != nil {
+		opts = edge.Options{
+			Origin:  cfg.Edge.Origin,
+			Origins: cfg.Edge.Origins,
+			IdleTTL: time.Duration(cfg.Edge.IdleTTLSeconds) * time.Second,
+			Wait:    time.Duration(cfg.Edge.WaitSeconds) * time.Second,
+		}
+	}
+	if proxy {
+		opts.Resolve = dir.OriginURL
+	}
+	p := edge.NewPuller(registry, opts)
+	p.SetEndpoints(
+		cfg.Server.RTMPPort, cfg.Server.HTTPPort,
+		firstKey(cfg.Auth.PublishKeys), firstKey(cfg.Auth.PlayKeys),
+	)
+	registry.SetOnDemand(p)
+	if dir != nil {
+		// A stream we pull from elsewhere is not ours to advertise.
+		dir.SetExclude(p.Pulling)
+	}
+	return p
+}
+
+// adminPolicy converts the admin token config. Roles were checked by
+// config validation, so parse errors cannot occur here.
+func adminPolicy(a config.AdminConfig) *auth.AdminPolicy {
+	tokens := make([]auth.AdminToken, 0, len(a.Tokens))
+	for _, t := range a.Tokens {
+		role, _ := auth.ParseRole(t.Role)
+		tokens = append(tokens, auth.AdminToken{Name: t.Name, Token: t.Token, Role: role})
+	}
+	return auth.NewAdminPolicy(tokens)
+}
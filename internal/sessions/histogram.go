@@ -0,0 +1,81 @@
+// If you are AI: This file holds the fixed-bucket histograms the table
+// keeps per protocol (delivery latency, session duration, time to first
+// byte) and their Prometheus-shaped snapshots.
+
+package sessions
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// histogram is a fixed-bucket duration histogram safe for concurrent use.
+type histogram struct {
+	bounds []float64       // upper bounds in seconds, ascending
+	counts []atomic.Uint64 // per bucket; the last one is +Inf
+	sum    atomic.Int64    // nanoseconds
+}
+
+// newHistogram returns an empty histogram over bounds.
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]atomic.Uint64, len(bounds)+1)}
+}
+
+// observe adds one sample.
+func (h *histogram) observe(d time.Duration) {
+	i := sort.SearchFloat64s(h.bounds, d.Seconds())
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// Histogram is a snapshot of one protocol's histogram in the Prometheus
+// shape: cumulative counts per upper bound.
+type Histogram struct {
+	Count   uint64
+	Sum     float64 // seconds
+	Buckets map[float64]uint64
+}
+
+// snapshot returns the histogram's current state.
+func (h *histogram) snapshot() Histogram {
+	out := Histogram{Sum: time.Duration(h.sum.Load()).Seconds(), Buckets: make(map[float64]uint64, len(h.bounds))}
+	for i := range h.counts {
+		out.Count += h.counts[i].Load()
+		if i < len(h.bounds) {
+			out.Buckets[h.bounds[i]] = out.Count
+		}
+	}
+	return out
+}
+
+// histogramSet is one histogram per protocol over shared bounds. Guarded
+// by the table's mutex.
+type histogramSet struct {
+	bounds []float64
+	byProt map[string]*histogram
+}
+
+// newHistogramSet returns an empty set over bounds.
+func newHistogramSet(bounds []float64) histogramSet {
+	return histogramSet{bounds: bounds, byProt: make(map[string]*histogram)}
+}
+
+// get returns protocol's histogram, creating it on first use.
+func (hs histogramSet) get(protocol string) *histogram {
+	h := hs.byProt[protocol]
+	if h == nil {
+		h = newHistogram(hs.bounds)
+		hs.byProt[protocol] = h
+	}
+	return h
+}
+
+// snapshot returns every protocol's histogram.
+func (hs histogramSet) snapshot() map[string]Histogram {
+	out := make(map[string]Histogram, len(hs.byProt))
+	for proto, h := range hs.byProt {
+		out[proto] = h.snapshot()
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+// If you are AI: This file records publish-to-write latency: how long a
+// message sat between Stream.Publish and the socket write that sent it.
+// Each protocol has a histogram for /metrics; each session keeps
+// its recent samples for p50 / p99 in the sessions API.
+
+package sessions
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Protocol labels for internal consumers of the HTTP-FLV output, which
+// identify themselves with these user agents from loopback.
+const (
+	ProtoPackager = "packager"
+	ProtoRelay    = "relay"
+
+	UserAgentPackager = "nonchalant-packager"
+	UserAgentRelay    = "nonchalant-relay"
+)
+
+// OutputProtocol returns the protocol label for an HTTP-FLV request:
+// ProtoPackager or ProtoRelay for the server's own ffmpeg clients,
+// ProtoHTTPFLV for everyone else. The user agents may carry a suffix
+// ("nonchalant-packager/hls").
+func OutputProtocol(remoteAddr, userAgent string) string {
+	if ip := net.ParseIP(hostOf(remoteAddr)); ip == nil || !ip.IsLoopback() {
+		return ProtoHTTPFLV
+	}
+	switch {
+	case strings.HasPrefix(userAgent, UserAgentPackager):
+		return ProtoPackager
+	case strings.HasPrefix(userAgent, UserAgentRelay):
+		return ProtoRelay
+	}
+	return ProtoHTTPFLV
+}
+
+// LatencyBuckets are the histogram upper bounds, in seconds.
+var LatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Latency returns the publish-to-write histogram of every protocol that
+// has delivered something.
+func (t *Table) Latency() map[string]Histogram {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.latency.snapshot()
+}
+
+// recentSamples is how many of a session's latest samples feed its
+// p50 / p99.
+const recentSamples = 256
+
+// recent is a ring of a session's latest latency samples.
+type recent struct {
+	mu      sync.Mutex
+	samples [recentSamples]time.Duration
+	n       int // samples written, capped at recentSamples
+	next    int
+}
+
+// add records d.
+func (r *recent) add(d time.Duration) {
+	r.mu.Lock()
+	r.samples[r.next] = d
+	r.next = (r.next + 1) % recentSamples
+	if r.n < recentSamples {
+		r.n++
+	}
+	r.mu.Unlock()
+}
+
+// quantiles returns the p50 and p99 of the recorded samples.
+func (r *recent) quantiles() (p50, p99 time.Duration) {
+	r.mu.Lock()
+	s := make([]time.Duration, r.n)
+	copy(s, r.samples[:r.n])
+	r.mu.Unlock()
+	if len(s) == 0 {
+		return 0, 0
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	return s[(len(s)-1)*50/100], s[(len(s)-1)*99/100]
+}
+
+// ObserveDelivery records the latency of writing a message that
+// Stream.Publish accepted at received (Unix nanoseconds, as in
+// bus.MediaMessage.Received). Zero received times are ignored, as are
+// messages published before the session opened: the GOP replayed to a
+// late joiner measures the GOP length, not the delivery path.
+func (s *Session) ObserveDelivery(received int64) {
+	if s == nil || received == 0 || received < s.started.UnixNano() {
+		return
+	}
+	d := time.Duration(time.Now().UnixNano() - received)
+	if d < 0 {
+		d = 0
+	}
+	s.latency.observe(d)
+	s.recent.add(d)
+}
@@ -0,0 +1,59 @@
+// If you are AI: Unit tests for delivery latency: protocol labelling of
+// internal clients, per-protocol histograms and per-session quantiles.
+
+package sessions
+
+import (
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// TestOutputProtocol labels loopback ffmpeg clients by user agent only.
+func TestOutputProtocol(t *testing.T) {
+	cases := []struct {
+		addr, ua, want string
+	}{
+		{"127.0.0.1:5000", UserAgentPackager, ProtoPackager},
+		{"127.0.0.1:5000", UserAgentPackager + "/hls", ProtoPackager},
+		{"[::1]:5000", UserAgentRelay, ProtoRelay},
+		{"127.0.0.1:5000", "ffplay", ProtoHTTPFLV},
+		{"10.0.0.1:5000", UserAgentPackager, ProtoHTTPFLV},
+	}
+	for _, c := range cases {
+		if got := OutputProtocol(c.addr, c.ua); got != c.want {
+			t.Errorf("OutputProtocol(%q, %q) = %q, want %q", c.addr, c.ua, got, c.want)
+		}
+	}
+}
+
+// TestObserveDelivery feeds known latencies and checks the protocol
+// histogram and the session's p50 / p99.
+func TestObserveDelivery(t *testing.T) {
+	now := time.Now()
+	tbl := NewTable()
+	tbl.now = func() time.Time { return now.Add(-time.Second) }
+	s := tbl.Open(ProtoHTTPFLV, RoleViewer, bus.NewStreamKey("live", "a"), "10.0.0.1:5000", "", nil)
+	defer s.Close()
+	for i := 1; i <= 100; i++ {
+		s.ObserveDelivery(now.Add(-time.Duration(i) * time.Millisecond).UnixNano())
+	}
+	s.ObserveDelivery(0)                                    // cached copies carry no receive time
+	s.ObserveDelivery(now.Add(-2 * time.Second).UnixNano()) // replayed from before the session
+
+	h := tbl.Latency()[ProtoHTTPFLV]
+	if h.Count != 100 {
+		t.Fatalf("Count = %d, want 100", h.Count)
+	}
+	if h.Buckets[0.001] > 1 || h.Buckets[0.25] != 100 {
+		t.Fatalf("Buckets = %v", h.Buckets)
+	}
+	info := s.Info()
+	if info.LatencyP50Ms < 49 || info.LatencyP50Ms > 60 {
+		t.Errorf("p50 = %.2fms, want about 50", info.LatencyP50Ms)
+	}
+	if info.LatencyP99Ms < 98 || info.LatencyP99Ms > 110 {
+		t.Errorf("p99 = %.2fms, want about 99", info.LatencyP99Ms)
+	}
+}
@@ -0,0 +1,63 @@
+// If you are AI: This file reports sessions opening and closing to the
+// event bus and the analytics recorder. Changes are collected while t.mu is
+// held and reported after it is released, so neither sink runs under the
+// table lock.
+
+package sessions
+
+import (
+	"time"
+
+	"nonchalant/internal/events"
+)
+
+// change is a session opened, or closed at end, under t.mu.
+type change struct {
+	s    *Session
+	open bool
+	end  time.Time
+}
+
+// report emits and records changes in order. t.mu must not be held.
+func (t *Table) report(changes ...change) {
+	for _, c := range changes {
+		t.emit(c.s, c.open)
+		t.record(c.s, c.open, c.end)
+	}
+}
+
+// emit reports s opening or closing on the event bus.
+func (t *Table) emit(s *Session, open bool) {
+	typ := events.SubscriberLeft
+	switch {
+	case s.role == RolePublisher && open:
+		typ = events.PublishStarted
+	case s.role == RolePublisher:
+		typ = events.PublishStopped
+	case open:
+		typ = events.SubscriberJoined
+	}
+	t.events.Emit(events.Event{
+		Type:       typ,
+		App:        s.key.App,
+		Name:       s.key.Name,
+		Protocol:   s.protocol,
+		SessionID:  s.id,
+		RemoteAddr: s.remoteAddr,
+	})
+}
+
+// record reports s opening, or closing at end, to the analytics recorder.
+func (t *Table) record(s *Session, open bool, end time.Time) {
+	switch {
+	case s.role == RolePublisher && open:
+		t.analytics.PublishStarted(s.key)
+	case s.role == RolePublisher:
+		t.analytics.PublishStopped(s.key)
+	case s.protocol == ProtoPackager || s.protocol == ProtoRelay:
+	case open:
+		t.analytics.ViewerJoined(s.key, s.id, s.protocol, s.remoteAddr, s.userAgent, s.subject)
+	default:
+		t.analytics.ViewerLeft(s.key, s.id, end)
+	}
+}
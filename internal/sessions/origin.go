@@ -0,0 +1,67 @@
+// If you are AI: This file records where each FLV session's output
+// timeline starts on its stream, so the packager can map the timeline
+// ffmpeg sees to the stream's wall clock. Only the server's own packager
+// sessions (loopback, see OutputProtocol) are indexed for the lookup; a
+// remote viewer sending the packager's User-Agent cannot move the origin.
+
+package sessions
+
+import "nonchalant/internal/core/bus"
+
+// originKey indexes packager sessions by stream and User-Agent.
+type originKey struct {
+	key       bus.StreamKey
+	userAgent string
+}
+
+// SetOrigin records the stream timestamp the session's output starts at
+// (FLV viewers rebase it to zero). Nil-safe.
+func (s *Session) SetOrigin(ts uint32) {
+	if s != nil {
+		s.origin.Store(uint64(ts) | 1<<32)
+	}
+}
+
+// Origin returns the output origin of the newest packager session on key
+// with userAgent, and false if there is none or it has not written media
+// yet.
+func (t *Table) Origin(key bus.StreamKey, userAgent string) (uint32, bool) {
+	if t == nil {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list := t.origins[originKey{key, userAgent}]
+	if len(list) == 0 {
+		return 0, false
+	}
+	v := list[len(list)-1].origin.Load()
+	return uint32(v), v&(1<<32) != 0
+}
+
+// indexOriginLocked adds a packager session to the origin index, newest
+// last. Caller must hold t.mu.
+func (t *Table) indexOriginLocked(s *Session) {
+	if s.protocol == ProtoPackager {
+		k := originKey{s.key, s.userAgent}
+		t.origins[k] = append(t.origins[k], s)
+	}
+}
+
+// unindexOriginLocked removes s from the origin index. Caller must hold
+// t.mu.
+func (t *Table) unindexOriginLocked(s *Session) {
+	k := originKey{s.key, s.userAgent}
+	list := t.origins[k]
+	for i, o := range list {
+		if o == s {
+			list = append(list[:i:i], list[i+1:]...)
+			break
+		}
+	}
+	if len(list) == 0 {
+		delete(t.origins, k)
+	} else {
+		t.origins[k] = list
+	}
+}
@@ -0,0 +1,42 @@
+// If you are AI: Unit tests for the output origin lookup the packager
+// uses to place its timeline on the stream's.
+
+package sessions
+
+import (
+	"testing"
+
+	"nonchalant/internal/core/bus"
+)
+
+// TestOrigin takes the newest packager session's origin and ignores
+// remote viewers that send the packager's User-Agent.
+func TestOrigin(t *testing.T) {
+	tbl := NewTable()
+	key := bus.NewStreamKey("live", "a")
+	ua := UserAgentPackager + "/hls"
+	if _, ok := tbl.Origin(key, ua); ok {
+		t.Fatal("origin without sessions")
+	}
+	old := tbl.Open(OutputProtocol("127.0.0.1:5000", ua), RoleViewer, key, "127.0.0.1:5000", ua, nil)
+	old.SetOrigin(1000)
+	cur := tbl.Open(OutputProtocol("127.0.0.1:5001", ua), RoleViewer, key, "127.0.0.1:5001", ua, nil)
+	if _, ok := tbl.Origin(key, ua); ok {
+		t.Fatal("origin before the newest packager wrote media")
+	}
+	cur.SetOrigin(2000)
+	spoof := tbl.Open(OutputProtocol("203.0.113.9:4000", ua), RoleViewer, key, "203.0.113.9:4000", ua, nil)
+	spoof.SetOrigin(9000)
+	if ts, ok := tbl.Origin(key, ua); !ok || ts != 2000 {
+		t.Fatalf("origin = %d, %v; want 2000 from the newest packager", ts, ok)
+	}
+	cur.Close()
+	if ts, ok := tbl.Origin(key, ua); !ok || ts != 1000 {
+		t.Fatalf("origin after close = %d, %v; want 1000", ts, ok)
+	}
+	old.Close()
+	spoof.Close()
+	if _, ok := tbl.Origin(key, ua); ok || len(tbl.origins) != 0 {
+		t.Fatal("origin index kept closed sessions")
+	}
+}
@@ -0,0 +1,155 @@
+// If you are AI: This file holds one entry of the session table: its
+// identity, byte counters and the bus subscriber that reports drops and lag.
+// Byte counts also feed the table's per-stream totals.
+
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// Session is one registered connection or request-based viewer. All
+// methods are safe on a nil *Session and from any goroutine.
+type Session struct {
+	table      *Table
+	id         string
+	protocol   string
+	role       string
+	key        bus.StreamKey
+	remoteAddr string
+	userAgent  string
+	subject    string // play key the viewer was admitted with, if any
+	started    time.Time
+	kick       func()
+	kickOnce   sync.Once
+	clientKey  string       // set for request-based sessions
+	lastSeen   atomic.Int64 // unix nanos of the last request (request-based only)
+	sent       atomic.Uint64
+	received   atomic.Uint64
+	sub        atomic.Pointer[bus.Subscriber]
+	latency    *histogram // the protocol's, shared
+	recent     recent     // this session's latest latencies
+	ttfb       *histogram // the protocol's, shared
+	firstByte  atomic.Bool
+	traffic    *traffic      // the stream and protocol's byte total, shared
+	origin     atomic.Uint64 // output origin timestamp, bit 32 set once known
+}
+
+// newSession builds an unregistered session.
+func newSession(t *Table, protocol, role string, key bus.StreamKey, remoteAddr, userAgent, subject string, kick func()) *Session {
+	return &Session{
+		table:      t,
+		id:         newID(),
+		protocol:   protocol,
+		role:       role,
+		key:        key,
+		remoteAddr: remoteAddr,
+		userAgent:  userAgent,
+		subject:    subject,
+		started:    t.now(),
+		kick:       kick,
+	}
+}
+
+// ID returns the session ID, or "" for a nil session.
+func (s *Session) ID() string {
+	if s == nil {
+		return ""
+	}
+	return s.id
+}
+
+// AddSent counts n bytes written to the client. The first call also
+// records the session's time to first byte.
+func (s *Session) AddSent(n int) {
+	if s == nil || n <= 0 {
+		return
+	}
+	if s.firstByte.CompareAndSwap(false, true) {
+		s.ttfb.observe(s.table.now().Sub(s.started))
+	}
+	s.sent.Add(uint64(n))
+	s.traffic.sent.Add(uint64(n))
+}
+
+// AddReceived counts n bytes read from the client.
+func (s *Session) AddReceived(n int) {
+	if s != nil && n > 0 {
+		s.received.Add(uint64(n))
+		s.traffic.received.Add(uint64(n))
+	}
+}
+
+// endAt returns when s ended if it is closed at now: now for a
+// connection, the last request for a request-based session, which is
+// only noticed when it is swept.
+func (s *Session) endAt(now time.Time) time.Time {
+	if s.clientKey != "" {
+		return time.Unix(0, s.lastSeen.Load())
+	}
+	return now
+}
+
+// SetSubscriber reports drops and lag from sub for this session.
+func (s *Session) SetSubscriber(sub *bus.Subscriber) {
+	if s != nil {
+		s.sub.Store(sub)
+	}
+}
+
+// Close unregisters the session. Safe to call more than once.
+func (s *Session) Close() {
+	if s == nil {
+		return
+	}
+	s.table.mu.Lock()
+	changes := s.table.removeLocked(s, nil)
+	s.table.mu.Unlock()
+	s.table.report(changes...)
+}
+
+// Info returns a snapshot of the session.
+func (s *Session) Info() Info {
+	info := Info{
+		ID:            s.id,
+		Protocol:      s.protocol,
+		Role:          s.role,
+		App:           s.key.App,
+		Name:          s.key.Name,
+		RemoteAddr:    s.remoteAddr,
+		UserAgent:     s.userAgent,
+		Started:       s.started,
+		BytesSent:     s.sent.Load(),
+		BytesReceived: s.received.Load(),
+	}
+	if sub := s.sub.Load(); sub != nil {
+		info.Dropped = sub.Dropped()
+		info.Lag = sub.Lag()
+	}
+	p50, p99 := s.recent.quantiles()
+	info.LatencyP50Ms = float64(p50) / float64(time.Millisecond)
+	info.LatencyP99Ms = float64(p99) / float64(time.Millisecond)
+	return info
+}
+
+// hostOf strips the port from addr.
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// newID returns a random 16-character hex session ID.
+func newID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,77 @@
+// If you are AI: This file runs the table's sweeper. Request-based
+// sessions, kick bans and byte totals expire on a ticker rather than on
+// each request, so Touch stays O(1) under the table mutex.
+
+package sessions
+
+import (
+	"context"
+	"time"
+)
+
+// sweepInterval is how often the sweeper runs. An idle HLS / DASH viewer
+// stays listed for up to idleTimeout plus this.
+const sweepInterval = 5 * time.Second
+
+// Start runs the sweeper until Stop. A nil table does nothing.
+func (t *Table) Start() {
+	if t == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.stopped = make(chan struct{})
+	go func() {
+		defer close(t.stopped)
+		tick := time.NewTicker(sweepInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				t.sweep()
+			}
+		}
+	}()
+}
+
+// Stop ends the sweeper, if running, and waits for it.
+func (t *Table) Stop() {
+	if t == nil || t.cancel == nil {
+		return
+	}
+	t.cancel()
+	<-t.stopped
+}
+
+// sweep runs one pass of sweepLocked and reports the sessions it ended.
+func (t *Table) sweep() {
+	t.mu.Lock()
+	changes := t.sweepLocked(t.now())
+	t.mu.Unlock()
+	t.report(changes...)
+}
+
+// sweepLocked drops idle request-based sessions, expired bans and stale
+// byte totals, returning the ended sessions for the caller to report.
+func (t *Table) sweepLocked(now time.Time) (changes []change) {
+	for _, s := range t.touched {
+		if s.idle(now) {
+			changes = t.removeLocked(s, changes)
+		}
+	}
+	for ck, until := range t.banned {
+		if now.After(until) {
+			delete(t.banned, ck)
+		}
+	}
+	t.sweepTrafficLocked(now)
+	return changes
+}
+
+// idle reports whether request-based s has had no request for
+// idleTimeout at now.
+func (s *Session) idle(now time.Time) bool {
+	return now.Sub(time.Unix(0, s.lastSeen.Load())) > idleTimeout
+}
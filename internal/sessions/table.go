@@ -0,0 +1,254 @@
+// If you are AI: This file implements the shared session table. Every
+// output service (and the RTMP publisher path) registers its connections
+// here so the API can list them per stream and disconnect one by ID.
+
+package sessions
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"nonchalant/internal/analytics"
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
+)
+
+// Roles of a session on its stream.
+const (
+	RolePublisher = "publisher"
+	RoleViewer    = "viewer"
+)
+
+// Protocol labels reported in Info.Protocol.
+const (
+	ProtoRTMP    = "rtmp"
+	ProtoHTTPFLV = "httpflv"
+	ProtoWSFLV   = "wsflv"
+	ProtoHLS     = "hls"
+	ProtoDASH    = "dash"
+)
+
+// Timeouts for request-based (HLS / DASH) sessions, which have no
+// connection to watch: a session ends after idleTimeout without requests,
+// and a kicked client is refused for kickBan.
+const (
+	idleTimeout = 30 * time.Second
+	kickBan     = time.Minute
+)
+
+// Table holds the live sessions of every protocol. A nil *Table records
+// nothing, so services work unchanged without one.
+type Table struct {
+	mu        sync.Mutex
+	byID      map[string]*Session
+	touched   map[string]*Session  // request-based sessions by client key
+	banned    map[string]time.Time // kicked request-based clients, until
+	now       func() time.Time     // replaced in tests
+	events    *events.Bus          // nil emits nothing
+	analytics *analytics.Recorder  // nil records nothing
+	latency   histogramSet         // publish-to-write, by protocol
+	durations histogramSet         // ended sessions' lifetimes, by protocol
+	ttfb      histogramSet         // open to first byte, by protocol
+	traffic   map[trafficKey]*traffic
+	origins   map[originKey][]*Session // packager sessions, oldest first
+	cancel    context.CancelFunc       // stops the sweeper (sweep.go)
+	stopped   chan struct{}
+}
+
+// NewTable returns an empty table.
+func NewTable() *Table {
+	return &Table{
+		byID:      make(map[string]*Session),
+		touched:   make(map[string]*Session),
+		banned:    make(map[string]time.Time),
+		latency:   newHistogramSet(LatencyBuckets),
+		durations: newHistogramSet(DurationBuckets),
+		ttfb:      newHistogramSet(TTFBBuckets),
+		traffic:   make(map[trafficKey]*traffic),
+		origins:   make(map[originKey][]*Session),
+		now:       time.Now,
+	}
+}
+
+// SetEvents emits PublishStarted / PublishStopped for publishers and
+// SubscriberJoined / SubscriberLeft for viewers on b as sessions open and
+// close. Must be called before the table is used.
+func (t *Table) SetEvents(b *events.Bus) { t.events = b }
+
+// SetAnalytics feeds publisher and viewer starts and ends to r. The
+// server's own packager and relay pulls are not viewers and are left out.
+// Must be called before the table is used.
+func (t *Table) SetAnalytics(r *analytics.Recorder) { t.analytics = r }
+
+// Info is a point-in-time view of one session, as served by the API.
+type Info struct {
+	ID            string    `json:"id"`
+	Protocol      string    `json:"protocol"`
+	Role          string    `json:"role"`
+	App           string    `json:"app"`
+	Name          string    `json:"name"`
+	RemoteAddr    string    `json:"remote_addr"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	Started       time.Time `json:"started"`
+	BytesSent     uint64    `json:"bytes_sent"`
+	BytesReceived uint64    `json:"bytes_received"`
+	Dropped       uint64    `json:"dropped"`                  // bus messages skipped for this viewer
+	Lag           uint64    `json:"lag"`                      // published messages not yet read
+	LatencyP50Ms  float64   `json:"latency_p50_ms,omitempty"` // publish-to-write, recent writes
+	LatencyP99Ms  float64   `json:"latency_p99_ms,omitempty"`
+}
+
+// Open registers a connection-based session. kick is called (once) when
+// the session is disconnected through the table; it should make the
+// serving goroutine return, which then calls Close. Returns nil on a nil
+// table.
+func (t *Table) Open(protocol, role string, key bus.StreamKey, remoteAddr, userAgent string, kick func()) *Session {
+	return t.OpenAs(protocol, role, key, remoteAddr, userAgent, "", kick)
+}
+
+// OpenAs is Open for a viewer admitted with the play key subject, which
+// analytics counts unique viewers by.
+func (t *Table) OpenAs(protocol, role string, key bus.StreamKey, remoteAddr, userAgent, subject string, kick func()) *Session {
+	if t == nil {
+		return nil
+	}
+	s := newSession(t, protocol, role, key, remoteAddr, userAgent, subject, kick)
+	t.mu.Lock()
+	t.attachLocked(s)
+	t.byID[s.id] = s
+	t.indexOriginLocked(s)
+	t.mu.Unlock()
+	t.report(change{s: s, open: true})
+	return s
+}
+
+// Touch records one request of a request-based (HLS / DASH) viewer. The
+// client is identified by protocol, stream, IP and user agent; the first
+// request opens a session and later ones extend it. ok is false when the
+// client was kicked recently and the request should be refused. Only
+// this client's entries are checked; the sweeper expires the rest.
+func (t *Table) Touch(protocol string, key bus.StreamKey, remoteAddr, userAgent string) (s *Session, ok bool) {
+	return t.TouchAs(protocol, key, remoteAddr, userAgent, "")
+}
+
+// TouchAs is Touch for a viewer admitted with the play key subject, as in
+// OpenAs.
+func (t *Table) TouchAs(protocol string, key bus.StreamKey, remoteAddr, userAgent, subject string) (s *Session, ok bool) {
+	if t == nil {
+		return nil, true
+	}
+	ck := protocol + " " + key.String() + " " + hostOf(remoteAddr) + " " + userAgent
+	now := t.now()
+	t.mu.Lock()
+	if until, banned := t.banned[ck]; banned {
+		if !now.After(until) {
+			t.mu.Unlock()
+			return nil, false
+		}
+		delete(t.banned, ck)
+	}
+	var changes []change
+	s = t.touched[ck]
+	if s != nil && s.idle(now) { // not swept yet: the old session has ended
+		changes = t.removeLocked(s, changes)
+		s = nil
+	}
+	if s == nil {
+		s = newSession(t, protocol, RoleViewer, key, remoteAddr, userAgent, subject, nil)
+		s.clientKey = ck
+		t.attachLocked(s)
+		t.byID[s.id] = s
+		t.touched[ck] = s
+		changes = append(changes, change{s: s, open: true})
+	}
+	s.lastSeen.Store(now.UnixNano())
+	t.mu.Unlock()
+	t.report(changes...)
+	return s, true
+}
+
+// List returns the sessions on key, oldest first.
+func (t *Table) List(key bus.StreamKey) []Info {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	out := make([]Info, 0)
+	for _, s := range t.byID {
+		if s.key == key {
+			out = append(out, s.Info())
+		}
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Started.Equal(out[j].Started) {
+			return out[i].Started.Before(out[j].Started)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Get returns the session with id.
+func (t *Table) Get(id string) (Info, bool) {
+	if t == nil {
+		return Info{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.byID[id]
+	if !ok {
+		return Info{}, false
+	}
+	return s.Info(), true
+}
+
+// Kick disconnects the session with id and reports whether it existed.
+// Connection-based sessions are closed through their kick func; a
+// request-based client is forgotten and refused for a while.
+func (t *Table) Kick(id string) bool {
+	if t == nil {
+		return false
+	}
+	var changes []change
+	t.mu.Lock()
+	s, ok := t.byID[id]
+	if ok && s.clientKey != "" {
+		changes = t.removeLocked(s, changes)
+		t.banned[s.clientKey] = t.now().Add(kickBan)
+	}
+	t.mu.Unlock()
+	t.report(changes...)
+	if ok && s.kick != nil {
+		s.kickOnce.Do(s.kick)
+	}
+	return ok
+}
+
+// Len returns the number of live sessions.
+func (t *Table) Len() int {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.byID)
+}
+
+// removeLocked unregisters s and, the first time, appends its end to
+// changes for the caller to report once t.mu is released.
+func (t *Table) removeLocked(s *Session, changes []change) []change {
+	if _, ok := t.byID[s.id]; !ok {
+		return changes
+	}
+	delete(t.byID, s.id)
+	now := t.now()
+	t.detachLocked(s, now)
+	t.unindexOriginLocked(s)
+	if s.clientKey != "" && t.touched[s.clientKey] == s {
+		delete(t.touched, s.clientKey)
+	}
+	return append(changes, change{s: s, end: s.endAt(now)})
+}
@@ -0,0 +1,135 @@
+// If you are AI: Unit tests for the session table: listing per stream,
+// kicking connection- and request-based sessions, and idle expiry.
+
+package sessions
+
+import (
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
+)
+
+// TestOpenListKick registers sessions on two streams, lists one and kicks
+// a viewer.
+func TestOpenListKick(t *testing.T) {
+	tbl := NewTable()
+	key := bus.NewStreamKey("live", "a")
+	kicked := 0
+	pub := tbl.Open(ProtoRTMP, RolePublisher, key, "10.0.0.1:5000", "FMLE/3.0", func() { kicked++ })
+	view := tbl.Open(ProtoHTTPFLV, RoleViewer, key, "10.0.0.2:6000", "ffplay", func() { kicked++ })
+	other := tbl.Open(ProtoWSFLV, RoleViewer, bus.NewStreamKey("live", "b"), "10.0.0.3:7000", "", nil)
+	defer other.Close()
+
+	pub.AddReceived(1000)
+	view.AddSent(500)
+	list := tbl.List(key)
+	if len(list) != 2 {
+		t.Fatalf("List = %d sessions, want 2", len(list))
+	}
+	if list[0].Role != RolePublisher || list[0].BytesReceived != 1000 {
+		t.Fatalf("publisher = %+v", list[0])
+	}
+	if list[1].Protocol != ProtoHTTPFLV || list[1].BytesSent != 500 || list[1].UserAgent != "ffplay" {
+		t.Fatalf("viewer = %+v", list[1])
+	}
+
+	if !tbl.Kick(view.ID()) || !tbl.Kick(view.ID()) {
+		t.Fatal("Kick of a live session returned false")
+	}
+	if kicked != 1 {
+		t.Fatalf("kick func ran %d times, want 1", kicked)
+	}
+	view.Close()
+	view.Close()
+	if _, ok := tbl.Get(view.ID()); ok {
+		t.Fatal("closed session still listed")
+	}
+	if tbl.Kick("nope") {
+		t.Fatal("Kick of an unknown ID returned true")
+	}
+	if tbl.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", tbl.Len())
+	}
+}
+
+// TestTouch groups requests from one client into one session, bans a
+// kicked client and expires idle sessions.
+func TestTouch(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tbl := NewTable()
+	tbl.now = func() time.Time { return now }
+	key := bus.NewStreamKey("live", "a")
+
+	s1, ok := tbl.Touch(ProtoHLS, key, "10.0.0.1:5000", "hls.js")
+	s2, _ := tbl.Touch(ProtoHLS, key, "10.0.0.1:5001", "hls.js")
+	if !ok || s1 != s2 {
+		t.Fatal("requests from one client opened two sessions")
+	}
+	other, _ := tbl.Touch(ProtoHLS, key, "10.0.0.2:5000", "hls.js")
+	if other == s1 {
+		t.Fatal("different clients share a session")
+	}
+
+	tbl.Kick(s1.ID())
+	if _, ok := tbl.Touch(ProtoHLS, key, "10.0.0.1:5002", "hls.js"); ok {
+		t.Fatal("kicked client was let back in")
+	}
+	now = now.Add(kickBan + time.Second)
+	if _, ok := tbl.Touch(ProtoHLS, key, "10.0.0.1:5002", "hls.js"); !ok {
+		t.Fatal("ban did not expire")
+	}
+
+	now = now.Add(idleTimeout + time.Second)
+	if back, _ := tbl.Touch(ProtoHLS, key, "10.0.0.2:5001", "hls.js"); back == other {
+		t.Fatal("an idle session was extended before the sweep")
+	}
+	now = now.Add(idleTimeout + time.Second)
+	tbl.sweep()
+	if n := len(tbl.List(key)); n != 0 {
+		t.Fatalf("%d idle sessions still listed", n)
+	}
+}
+
+// TestNilTable checks that a nil table and nil sessions are inert.
+func TestNilTable(t *testing.T) {
+	var tbl *Table
+	tbl.Start()
+	defer tbl.Stop()
+	s := tbl.Open(ProtoRTMP, RolePublisher, bus.NewStreamKey("live", "a"), "", "", nil)
+	s.AddSent(1)
+	s.SetSubscriber(nil)
+	s.Close()
+	if _, ok := tbl.Touch(ProtoHLS, bus.NewStreamKey("live", "a"), "", ""); !ok {
+		t.Fatal("nil table refused a request")
+	}
+	if tbl.Kick("x") || tbl.List(bus.NewStreamKey("live", "a")) != nil {
+		t.Fatal("nil table reported sessions")
+	}
+}
+
+// TestEvents checks the lifecycle events emitted as sessions come and go.
+func TestEvents(t *testing.T) {
+	tbl := NewTable()
+	ev := events.NewBus()
+	tbl.SetEvents(ev)
+	key := bus.NewStreamKey("live", "a")
+	pub := tbl.Open(ProtoRTMP, RolePublisher, key, "10.0.0.1:5000", "", nil)
+	view := tbl.Open(ProtoWSFLV, RoleViewer, key, "10.0.0.2:6000", "", nil)
+	view.Close()
+	view.Close()
+	pub.Close()
+
+	backlog, _, cancel := ev.Subscribe(0)
+	cancel()
+	want := []events.Type{events.PublishStarted, events.SubscriberJoined, events.SubscriberLeft, events.PublishStopped}
+	if len(backlog) != len(want) {
+		t.Fatalf("got %d events, want %d", len(backlog), len(want))
+	}
+	for i, e := range backlog {
+		if e.Type != want[i] || e.App != "live" || e.SessionID == "" {
+			t.Errorf("event %d = %+v, want %s", i, e, want[i])
+		}
+	}
+}
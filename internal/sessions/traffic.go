@@ -0,0 +1,152 @@
+// If you are AI: This file aggregates sessions for capacity planning:
+// bytes in and out per stream and protocol, live sessions per protocol and
+// role, and per-protocol histograms of session duration and time to
+// first byte. The totals outlive the sessions that produced them.
+
+package sessions
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// Histogram upper bounds, in seconds.
+var (
+	DurationBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 7200, 14400, 43200}
+	TTFBBuckets     = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+)
+
+// trafficRetention is how long a stream / protocol byte total is kept
+// after its last session ends, so a scrape still sees the final bytes.
+const trafficRetention = 10 * time.Minute
+
+// trafficKey identifies one byte total.
+type trafficKey struct {
+	key      bus.StreamKey
+	protocol string
+}
+
+// traffic is the byte total of every session, past and present, of one
+// protocol on one stream.
+type traffic struct {
+	sent      atomic.Uint64
+	received  atomic.Uint64
+	open      int       // live sessions; guarded by Table.mu
+	idleSince time.Time // when open last dropped to 0; guarded by Table.mu
+}
+
+// Traffic is the byte total of one protocol on one stream.
+type Traffic struct {
+	App      string
+	Name     string
+	Protocol string
+	Sent     uint64 // egress
+	Received uint64 // ingress
+}
+
+// Group is a protocol / role pair for Active.
+type Group struct {
+	Protocol string
+	Role     string
+}
+
+// Traffic returns the byte totals, sorted by stream then protocol.
+func (t *Table) Traffic() []Traffic {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	out := make([]Traffic, 0, len(t.traffic))
+	for k, tr := range t.traffic {
+		out = append(out, Traffic{
+			App:      k.key.App,
+			Name:     k.key.Name,
+			Protocol: k.protocol,
+			Sent:     tr.sent.Load(),
+			Received: tr.received.Load(),
+		})
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].App != out[j].App {
+			return out[i].App < out[j].App
+		}
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Protocol < out[j].Protocol
+	})
+	return out
+}
+
+// Active returns the number of live sessions per protocol and role.
+func (t *Table) Active() map[Group]int {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[Group]int)
+	for _, s := range t.byID {
+		out[Group{s.protocol, s.role}]++
+	}
+	return out
+}
+
+// Durations returns the lifetime histogram of ended sessions by protocol.
+func (t *Table) Durations() map[string]Histogram {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.durations.snapshot()
+}
+
+// TTFB returns the histogram of time from a session opening to its first
+// byte written to the client, by protocol.
+func (t *Table) TTFB() map[string]Histogram {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ttfb.snapshot()
+}
+
+// attachLocked registers s with its protocol's histograms and byte total.
+// Caller must hold t.mu.
+func (t *Table) attachLocked(s *Session) {
+	s.latency = t.latency.get(s.protocol)
+	s.ttfb = t.ttfb.get(s.protocol)
+	k := trafficKey{s.key, s.protocol}
+	tr := t.traffic[k]
+	if tr == nil {
+		tr = new(traffic)
+		t.traffic[k] = tr
+	}
+	tr.open++
+	s.traffic = tr
+}
+
+// detachLocked records s's lifetime and releases its byte total.
+// Caller must hold t.mu.
+func (t *Table) detachLocked(s *Session, now time.Time) {
+	t.durations.get(s.protocol).observe(s.endAt(now).Sub(s.started))
+	if s.traffic.open--; s.traffic.open == 0 {
+		s.traffic.idleSince = now
+	}
+}
+
+// sweepTrafficLocked forgets byte totals idle for trafficRetention.
+// Caller must hold t.mu.
+func (t *Table) sweepTrafficLocked(now time.Time) {
+	for k, tr := range t.traffic {
+		if tr.open == 0 && now.Sub(tr.idleSince) > trafficRetention {
+			delete(t.traffic, k)
+		}
+	}
+}
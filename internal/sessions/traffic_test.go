@@ -0,0 +1,97 @@
+// If you are AI: Unit tests for the table's aggregates: byte totals per
+// stream and protocol, live counts, and duration / TTFB histograms.
+
+package sessions
+
+import (
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// TestTraffic checks that byte totals survive their sessions and are
+// forgotten after trafficRetention.
+func TestTraffic(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tbl := NewTable()
+	tbl.now = func() time.Time { return now }
+	key := bus.NewStreamKey("live", "a")
+
+	pub := tbl.Open(ProtoRTMP, RolePublisher, key, "10.0.0.1:5000", "", nil)
+	v1 := tbl.Open(ProtoHTTPFLV, RoleViewer, key, "10.0.0.2:5000", "", nil)
+	v2 := tbl.Open(ProtoHTTPFLV, RoleViewer, key, "10.0.0.3:5000", "", nil)
+	pub.AddReceived(1000)
+	v1.AddSent(300)
+	v2.AddSent(200)
+	hls, _ := tbl.Touch(ProtoHLS, key, "10.0.0.4:5000", "")
+	hls.AddSent(50)
+
+	active := tbl.Active()
+	if active[Group{ProtoHTTPFLV, RoleViewer}] != 2 || active[Group{ProtoRTMP, RolePublisher}] != 1 {
+		t.Fatalf("Active = %v", active)
+	}
+	v1.Close()
+	v2.Close()
+	want := []Traffic{
+		{"live", "a", ProtoHLS, 50, 0},
+		{"live", "a", ProtoHTTPFLV, 500, 0},
+		{"live", "a", ProtoRTMP, 0, 1000},
+	}
+	got := tbl.Traffic()
+	if len(got) != len(want) {
+		t.Fatalf("Traffic = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Traffic[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	now = now.Add(idleTimeout + time.Second)
+	tbl.sweep() // the HLS viewer idles out
+	now = now.Add(trafficRetention + time.Second)
+	pub.AddReceived(1) // keeps the live publisher's total
+	tbl.sweep()
+	for _, tr := range tbl.Traffic() {
+		if tr.Protocol != ProtoRTMP || tr.Received != 1001 {
+			t.Errorf("stale total kept: %+v", tr)
+		}
+	}
+	pub.Close()
+}
+
+// TestDurationAndTTFB checks the lifetime and first-byte histograms.
+func TestDurationAndTTFB(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tbl := NewTable()
+	tbl.now = func() time.Time { return now }
+	key := bus.NewStreamKey("live", "a")
+
+	s := tbl.Open(ProtoWSFLV, RoleViewer, key, "10.0.0.1:5000", "", nil)
+	now = now.Add(40 * time.Millisecond)
+	s.AddSent(10)
+	now = now.Add(20 * time.Millisecond)
+	s.AddSent(10) // only the first write counts
+	now = now.Add(10 * time.Second)
+	s.Close()
+
+	ttfb := tbl.TTFB()[ProtoWSFLV]
+	if ttfb.Count != 1 || ttfb.Buckets[0.025] != 0 || ttfb.Buckets[0.05] != 1 {
+		t.Errorf("TTFB = %+v", ttfb)
+	}
+	d := tbl.Durations()[ProtoWSFLV]
+	if d.Count != 1 || d.Buckets[5] != 0 || d.Buckets[15] != 1 {
+		t.Errorf("Durations = %+v", d)
+	}
+
+	// A request-based session ends at its last request, not at the sweep.
+	tbl.Touch(ProtoHLS, key, "10.0.0.2:5000", "")
+	now = now.Add(3 * time.Second)
+	tbl.Touch(ProtoHLS, key, "10.0.0.2:5000", "")
+	now = now.Add(idleTimeout + time.Second)
+	tbl.sweep()
+	if d := tbl.Durations()[ProtoHLS]; d.Count != 1 || d.Buckets[1] != 0 || d.Buckets[5] != 1 {
+		t.Errorf("HLS Durations = %+v", d)
+	}
+}
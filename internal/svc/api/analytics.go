@@ -0,0 +1,40 @@
+// If you are AI: This file implements the viewer analytics endpoint: the
+// live or last broadcast of one stream, as JSON or CSV.
+
+package api
+
+import (
+	"net/http"
+
+	"nonchalant/internal/analytics"
+	"nonchalant/internal/core/bus"
+)
+
+// SetAnalytics enables /api/analytics, backed by r. Without it every
+// stream reports no broadcast.
+func (s *Service) SetAnalytics(r *analytics.Recorder) { s.analytics = r }
+
+// handleAnalytics handles GET /api/analytics/{app}/{name}. Returns the
+// viewer report of the live broadcast, or of the last one when the stream
+// is offline; ?format=csv returns it as CSV. Responds 404 when the stream
+// has not been published since startup.
+func (s *Service) handleAnalytics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rep, ok := s.analytics.Report(bus.NewStreamKey(r.PathValue("app"), r.PathValue("name")))
+	if !ok {
+		s.writeError(w, http.StatusNotFound, "no broadcast")
+		return
+	}
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		s.writeJSON(w, http.StatusOK, rep)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		_ = analytics.WriteCSV(w, rep)
+	default:
+		s.writeError(w, http.StatusBadRequest, "format must be json or csv")
+	}
+}
@@ -0,0 +1,51 @@
+// If you are AI: Unit tests for the viewer analytics endpoint.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nonchalant/internal/analytics"
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/svc/relay"
+)
+
+// TestAnalyticsEndpoint reads a live broadcast as JSON and CSV.
+func TestAnalyticsEndpoint(t *testing.T) {
+	registry := bus.NewRegistry()
+	service := NewService(registry, relay.NewManager(registry))
+	rec := analytics.New("")
+	service.SetAnalytics(rec)
+	mux := http.NewServeMux()
+	service.RegisterRoutes(mux)
+
+	key := bus.NewStreamKey("live", "a")
+	rec.PublishStarted(key)
+	rec.ViewerJoined(key, "v1", "wsflv", "10.0.0.2:6000", "ffplay", "")
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/analytics/live/a", nil))
+	var rep analytics.Report
+	if err := json.NewDecoder(w.Body).Decode(&rep); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("json: status %d, err %v", w.Code, err)
+	}
+	if !rep.Live || rep.PeakViewers != 1 || rep.Protocols["wsflv"].Sessions != 1 {
+		t.Fatalf("report = %+v", rep)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/analytics/live/a?format=csv", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") || !strings.Contains(w.Body.String(), "live,a,") {
+		t.Fatalf("csv: %s\n%s", ct, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/analytics/live/none", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown stream: status %d, want 404", w.Code)
+	}
+}
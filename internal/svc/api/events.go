@@ -0,0 +1,110 @@
+// If you are AI: This file implements GET /api/events, which streams the
+// server event bus as Server-Sent Events. Clients resume after a reconnect
+// with Last-Event-ID and may filter by app and stream name.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nonchalant/internal/events"
+)
+
+// eventKeepalive is how often an idle event stream gets a comment line,
+// so proxies and load balancers do not time it out.
+const eventKeepalive = 15 * time.Second
+
+// SetEvents enables /api/events, streaming events from b.
+func (s *Service) SetEvents(b *events.Bus) { s.events = b }
+
+// handleEvents handles GET /api/events[?app=..&name=..].
+// Retained events newer than the Last-Event-ID header (or the
+// last_event_id query parameter) are replayed first; the stream then
+// follows the bus until the client goes away or the server shuts down.
+func (s *Service) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.events == nil {
+		s.writeError(w, http.StatusServiceUnavailable, "events not enabled")
+		return
+	}
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("last_event_id")
+	}
+	var after uint64
+	if last != "" {
+		id, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		after = id
+	}
+	app, name := r.URL.Query().Get("app"), r.URL.Query().Get("name")
+	match := func(e events.Event) bool {
+		return (app == "" || e.App == app) && (name == "" || e.Name == name)
+	}
+
+	backlog, ch, cancel := s.events.Subscribe(after)
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	for _, e := range backlog {
+		if match(e) && writeEvent(w, e) != nil {
+			return
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+
+	tick := time.NewTicker(eventKeepalive)
+	defer tick.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-tick.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-ch:
+			if !ok {
+				return // fell behind or shutting down; the client resumes
+			}
+			if !match(e) {
+				continue
+			}
+			if writeEvent(w, e) != nil {
+				return
+			}
+		}
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes e as one SSE message.
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
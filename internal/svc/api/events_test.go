@@ -0,0 +1,55 @@
+// If you are AI: Unit tests for the /api/events Server-Sent Events stream.
+
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
+	"nonchalant/internal/svc/relay"
+)
+
+// TestEventStream resumes after Last-Event-ID and filters by stream.
+func TestEventStream(t *testing.T) {
+	registry := bus.NewRegistry()
+	service := NewService(registry, relay.NewManager(registry))
+	ev := events.NewBus()
+	service.SetEvents(ev)
+	mux := http.NewServeMux()
+	service.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	defer ev.Close()
+
+	ev.Emit(events.Event{Type: events.PublishStarted, App: "live", Name: "a"})
+	ev.Emit(events.Event{Type: events.PublishStarted, App: "live", Name: "b"})
+	ev.Emit(events.Event{Type: events.SubscriberJoined, App: "live", Name: "a"})
+
+	req, _ := http.NewRequest("GET", srv.URL+"/api/events?name=a", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	ev.Emit(events.Event{Type: events.PublishStopped, App: "live", Name: "a"})
+
+	var ids []string
+	sc := bufio.NewScanner(resp.Body)
+	for len(ids) < 2 && sc.Scan() {
+		if id, ok := strings.CutPrefix(sc.Text(), "id: "); ok {
+			ids = append(ids, id)
+		}
+	}
+	if strings.Join(ids, ",") != "3,4" {
+		t.Fatalf("event IDs = %v, want 3 and 4", ids)
+	}
+}
@@ -7,24 +7,33 @@ import (
 	"encoding/json"
 	"net/http"
 	"runtime"
+
+	"nonchalant/internal/capacity"
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/mediainfo"
 )
 
 // ServerResponse represents the /api/server response.
 type ServerResponse struct {
-	Version         string   `json:"version"`
-	Uptime          int64    `json:"uptime"` // seconds
-	GoVersion       string   `json:"go_version"`
-	EnabledServices []string `json:"enabled_services"`
+	Version         string           `json:"version"`
+	Uptime          int64            `json:"uptime"` // seconds
+	GoVersion       string           `json:"go_version"`
+	EnabledServices []string         `json:"enabled_services"`
+	FLVSessions     map[string]int64 `json:"flv_sessions,omitempty"` // by "http/1.1", "h2", "h3"
+	Capacity        *capacity.Report `json:"capacity,omitempty"`     // usage against configured limits
 }
 
 // StreamInfo represents information about a stream.
 type StreamInfo struct {
-	App               string `json:"app"`
-	Name              string `json:"name"`
-	HasPublisher      bool   `json:"has_publisher"`
-	SubscriberCount   int    `json:"subscriber_count"`
-	MessagesPublished uint64 `json:"messages_published"`
-	MessagesDropped   uint64 `json:"messages_dropped"`
+	App               string          `json:"app"`
+	Name              string          `json:"name"`
+	HasPublisher      bool            `json:"has_publisher"`
+	SubscriberCount   int             `json:"subscriber_count"`
+	MessagesPublished uint64          `json:"messages_published"`
+	MessagesDropped   uint64          `json:"messages_dropped"`
+	PublisherAddr     string          `json:"publisher_addr,omitempty"` // client address, after PROXY protocol
+	Media             *mediainfo.Info `json:"media,omitempty"`          // codecs and ingest; only while publishing
+	Clock             *bus.Clock      `json:"clock,omitempty"`          // wall-clock anchor of the timeline
 }
 
 // StreamsResponse represents the /api/streams response.
@@ -65,6 +74,13 @@ func (s *Service) handleServer(w http.ResponseWriter, r *http.Request) {
 			"metrics",
 		},
 	}
+	if s.sessions != nil {
+		response.FLVSessions = s.sessions.Sessions()
+	}
+	if s.capacity != nil {
+		usage := s.capacity.Usage()
+		response.Capacity = &usage
+	}
 
 	s.writeJSON(w, http.StatusOK, response)
 }
@@ -96,6 +112,14 @@ func (s *Service) handleStreams(w http.ResponseWriter, r *http.Request) {
 			SubscriberCount:   stream.SubscriberCount(),
 			MessagesPublished: stream.MessagesPublished(),
 			MessagesDropped:   stream.TotalDropped(),
+			PublisherAddr:     stream.PublisherAddr(),
+		}
+		if info.HasPublisher {
+			media := mediainfo.Probe(stream)
+			info.Media = &media
+		}
+		if clock, ok := stream.Clock(); ok {
+			info.Clock = &clock
 		}
 		streams = append(streams, info)
 	}
@@ -125,8 +149,15 @@ func (s *Service) handleRelay(w http.ResponseWriter, r *http.Request) {
 			App:       rt.App,
 			Name:      rt.Name,
 			Mode:      rt.Mode,
+			Target:    rt.Target,
 			RemoteURL: rt.RemoteURL,
+			Sources:   rt.Sources,
+			Source:    rt.Source,
+			Enabled:   rt.Enabled,
 			Running:   rt.Running,
+			Status:    string(rt.Status),
+			LastError: rt.LastError,
+			Attempts:  rt.Attempts,
 		})
 	}
 
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"nonchalant/internal/config"
 	"nonchalant/internal/core/bus"
 	"nonchalant/internal/svc/relay"
 )
@@ -19,6 +20,13 @@ type fakeRelayMgr struct{}
 func (fakeRelayMgr) TaskCount() int                 { return 0 }
 func (fakeRelayMgr) GetTasks() []relay.TaskInfo     { return nil }
 func (fakeRelayMgr) Restart(app, name string) error { return nil }
+func (fakeRelayMgr) AddTarget(app, name string, target config.PushTarget) error {
+	return nil
+}
+func (fakeRelayMgr) RemoveTarget(app, name, id string) error { return nil }
+func (fakeRelayMgr) SetTargetEnabled(app, name, id string, enabled bool) error {
+	return nil
+}
 
 // BenchmarkHandleStreams measures /api/streams response time as a function of
 // the number of streams in the registry. The handler walks the registry on
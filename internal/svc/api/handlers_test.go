@@ -7,8 +7,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"nonchalant/internal/auth"
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
 	"nonchalant/internal/svc/relay"
+	"strings"
 	"testing"
 )
 
@@ -151,3 +154,107 @@ func TestHandleRelayRestart(t *testing.T) {
 		t.Errorf("Expected status 400, got %d", w2.Code)
 	}
 }
+
+func TestHandleRelayTargets(t *testing.T) {
+	registry := bus.NewRegistry()
+	relayMgr := relay.NewManager(registry)
+	defer relayMgr.Stop()
+	service := NewService(registry, relayMgr)
+	mux := http.NewServeMux()
+	service.RegisterRoutes(mux)
+
+	do := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	add := `{"app":"live","name":"x","target":{"id":"yt","url":"rtmp://127.0.0.1:1/a/x","disabled":true}}`
+	if code := do("POST", "/api/relay/targets", add); code != http.StatusCreated {
+		t.Fatalf("add: status %d, want 201", code)
+	}
+	if code := do("POST", "/api/relay/targets", add); code != http.StatusBadRequest {
+		t.Errorf("duplicate add: status %d, want 400", code)
+	}
+	if code := do("POST", "/api/relay/targets/disable", `{"app":"live","name":"x","id":"nope"}`); code != http.StatusNotFound {
+		t.Errorf("disable unknown: status %d, want 404", code)
+	}
+	if code := do("POST", "/api/relay/targets/enable", `{"app":"live","name":"x","id":"yt"}`); code != http.StatusOK {
+		t.Errorf("enable: status %d, want 200", code)
+	}
+	if tasks := relayMgr.GetTasks(); len(tasks) != 1 || !tasks[0].Enabled {
+		t.Errorf("tasks after enable = %+v", tasks)
+	}
+	if code := do("DELETE", "/api/relay/targets", `{"app":"live","name":"x","id":"yt"}`); code != http.StatusOK {
+		t.Errorf("remove: status %d, want 200", code)
+	}
+	if relayMgr.TaskCount() != 0 {
+		t.Errorf("TaskCount after remove = %d, want 0", relayMgr.TaskCount())
+	}
+}
+
+// TestAdminPolicyRoutes verifies that RegisterRoutes enforces the admin
+// policy: a read token may list relays but not restart them.
+func TestAdminPolicyRoutes(t *testing.T) {
+	registry := bus.NewRegistry()
+	service := NewService(registry, relay.NewManager(registry))
+	service.SetAdminPolicy(auth.NewAdminPolicy([]auth.AdminToken{{Name: "ro", Token: "t", Role: auth.RoleRead}}))
+	mux := http.NewServeMux()
+	service.RegisterRoutes(mux)
+
+	cases := []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/api/relay", "", http.StatusUnauthorized},
+		{"GET", "/api/relay", "t", http.StatusOK},
+		{"POST", "/api/relay/restart", "t", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(`{}`))
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Errorf("%s %s token=%q: got %d, want %d", c.method, c.path, c.token, w.Code, c.want)
+		}
+	}
+}
+
+// TestSessionEndpoints lists a stream's sessions and kicks one by ID.
+func TestSessionEndpoints(t *testing.T) {
+	registry := bus.NewRegistry()
+	service := NewService(registry, relay.NewManager(registry))
+	table := sessions.NewTable()
+	service.SetSessionTable(table)
+	mux := http.NewServeMux()
+	service.RegisterRoutes(mux)
+
+	kicked := false
+	viewer := table.Open(sessions.ProtoHTTPFLV, sessions.RoleViewer,
+		bus.NewStreamKey("live", "a"), "10.0.0.2:6000", "ffplay", func() { kicked = true })
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/api/streams/live/a/sessions", nil))
+	var list SessionsResponse
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("list: status %d, err %v", w.Code, err)
+	}
+	if len(list.Sessions) != 1 || list.Sessions[0].ID != viewer.ID() {
+		t.Fatalf("sessions = %+v, want the one viewer", list.Sessions)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/sessions/"+viewer.ID(), nil))
+	if w.Code != http.StatusOK || !kicked {
+		t.Fatalf("kick: status %d, kicked %v", w.Code, kicked)
+	}
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/sessions/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("kick unknown: status %d, want 404", w.Code)
+	}
+}
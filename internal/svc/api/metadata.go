@@ -0,0 +1,55 @@
+// If you are AI: This file implements timed metadata injection: an
+// onCuePoint or onTextData script message published into a live stream at
+// its current live point, reaching every output.
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/amf0"
+	"nonchalant/internal/core/protocol/flv"
+)
+
+// maxMetadataBody bounds an injected message; script tags are small.
+const maxMetadataBody = 64 << 10
+
+// MetadataRequest is the body of POST /api/streams/{app}/{name}/metadata.
+// Type is "onCuePoint", "onTextData", "onSCTE35" or "onCaptionInfo"; Data becomes the AMF0 object.
+type MetadataRequest struct {
+	Type string         `json:"type"`
+	Data map[string]any `json:"data"`
+}
+
+// handleMetadata handles POST /api/streams/{app}/{name}/metadata. The
+// message is queued for the stream's next frame; 202 means it was
+// accepted, 404 that the stream has no publisher.
+func (s *Service) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req MetadataRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMetadataBody)).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !flv.IsTimedScript(req.Type) {
+		s.writeError(w, http.StatusBadRequest, "type must be onCuePoint, onTextData, onSCTE35 or onCaptionInfo")
+		return
+	}
+	var payload bytes.Buffer
+	_ = amf0.Encode(&payload, req.Type)
+	_ = amf0.Encode(&payload, amf0.FromJSON(map[string]any(req.Data)))
+
+	key := bus.NewStreamKey(r.PathValue("app"), r.PathValue("name"))
+	stream := s.registry.Get(key)
+	if stream == nil || stream.InjectMetadata(payload.Bytes()) != nil {
+		s.writeError(w, http.StatusNotFound, "stream not live")
+		return
+	}
+	s.writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+}
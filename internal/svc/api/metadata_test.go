@@ -0,0 +1,60 @@
+// If you are AI: Unit tests for the timed metadata injection endpoint.
+
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/amf0"
+	"nonchalant/internal/svc/relay"
+)
+
+// TestHandleMetadata injects an onCuePoint into a live stream and rejects
+// unknown types and streams without a publisher.
+func TestHandleMetadata(t *testing.T) {
+	registry := bus.NewRegistry()
+	service := NewService(registry, relay.NewManager(registry))
+	mux := http.NewServeMux()
+	service.RegisterRoutes(mux)
+	post := func(path, body string) int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return w.Code
+	}
+	cue := `{"type":"onCuePoint","data":{"name":"question","parameters":{"n":3}}}`
+
+	if code := post("/api/streams/live/q/metadata", cue); code != http.StatusNotFound {
+		t.Errorf("unknown stream: status %d, want 404", code)
+	}
+	stream, _ := registry.GetOrCreate(bus.NewStreamKey("live", "q"))
+	if code := post("/api/streams/live/q/metadata", cue); code != http.StatusNotFound {
+		t.Errorf("no publisher: status %d, want 404", code)
+	}
+	stream.AttachPublisher(1)
+	if code := post("/api/streams/live/q/metadata", `{"type":"onMetaData","data":{}}`); code != http.StatusBadRequest {
+		t.Errorf("onMetaData: status %d, want 400", code)
+	}
+	if code := post("/api/streams/live/q/metadata", cue); code != http.StatusAccepted {
+		t.Fatalf("inject: status %d, want 202", code)
+	}
+
+	sub, _ := stream.AttachSubscriber(0, bus.BackpressureDropOldest)
+	stream.Publish(&bus.MediaMessage{Type: bus.MessageTypeVideo, Timestamp: 100, Payload: []byte{0x27, 1}})
+	msg, ok := sub.Read()
+	if !ok || msg.Type != bus.MessageTypeMetadata {
+		t.Fatalf("first message = %+v, want metadata", msg)
+	}
+	r := bytes.NewReader(msg.Payload)
+	name, _ := amf0.DecodeString(r)
+	v, _ := amf0.Decode(r)
+	obj, _ := v.(amf0.Object)
+	params, _ := obj["parameters"].(amf0.Object)
+	if name != "onCuePoint" || obj["name"] != "question" || params["n"] != float64(3) {
+		t.Errorf("payload = %s %v", name, v)
+	}
+}
@@ -0,0 +1,121 @@
+// If you are AI: This file implements the push-relay target endpoints.
+// Simulcast destinations can be added, removed, enabled and disabled one at
+// a time; siblings and local viewers are never touched.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"nonchalant/internal/config"
+)
+
+// RelayTargetRequest is the body of the /api/relay/targets* endpoints.
+// Target is only read by POST /api/relay/targets; ID by the other calls.
+type RelayTargetRequest struct {
+	App    string             `json:"app"`
+	Name   string             `json:"name"`
+	ID     string             `json:"id,omitempty"`
+	Target *RelayTargetConfig `json:"target,omitempty"`
+}
+
+// RelayTargetConfig mirrors config.PushTarget for API requests.
+type RelayTargetConfig struct {
+	ID                string `json:"id"`
+	URL               string `json:"url"`
+	Reconnect         bool   `json:"reconnect"`
+	MaxBackoffSeconds int    `json:"max_backoff_seconds,omitempty"`
+	Disabled          bool   `json:"disabled,omitempty"`
+}
+
+// handleRelayTargets handles POST (add) and DELETE (remove) on
+// /api/relay/targets. Body: {"app","name","target":{...}} for POST and
+// {"app","name","id"} for DELETE.
+func (s *Service) handleRelayTargets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	req, ok := s.decodeTargetRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if req.Target == nil {
+			s.writeError(w, http.StatusBadRequest, "target is required")
+			return
+		}
+		t := config.PushTarget{
+			ID:                req.Target.ID,
+			URL:               req.Target.URL,
+			Reconnect:         req.Target.Reconnect,
+			MaxBackoffSeconds: req.Target.MaxBackoffSeconds,
+			Disabled:          req.Target.Disabled,
+		}
+		if err := s.relayMgr.AddTarget(req.App, req.Name, t); err != nil {
+			s.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusCreated, map[string]string{"status": "added"})
+		return
+	}
+
+	if req.ID == "" {
+		s.writeError(w, http.StatusBadRequest, "id is required")
+		return
+	}
+	if err := s.relayMgr.RemoveTarget(req.App, req.Name, req.ID); err != nil {
+		s.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]string{"status": "removed"})
+}
+
+// handleRelayTargetToggle returns the handler for POST
+// /api/relay/targets/enable and /disable. Body: {"app","name","id"}.
+func (s *Service) handleRelayTargetToggle(enabled bool) http.HandlerFunc {
+	status := "disabled"
+	if enabled {
+		status = "enabled"
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		req, ok := s.decodeTargetRequest(w, r)
+		if !ok {
+			return
+		}
+		if req.ID == "" {
+			s.writeError(w, http.StatusBadRequest, "id is required")
+			return
+		}
+		if err := s.relayMgr.SetTargetEnabled(req.App, req.Name, req.ID, enabled); err != nil {
+			s.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]string{"status": status})
+	}
+}
+
+// decodeTargetRequest parses and validates the common request fields.
+// On failure it writes the error response and returns ok=false.
+func (s *Service) decodeTargetRequest(w http.ResponseWriter, r *http.Request) (RelayTargetRequest, bool) {
+	var req RelayTargetRequest
+	if r.Body == nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid request body")
+		return req, false
+	}
+	if req.App == "" || req.Name == "" {
+		s.writeError(w, http.StatusBadRequest, "app and name are required")
+		return req, false
+	}
+	return req, true
+}
@@ -7,7 +7,13 @@ import (
 	"net/http"
 	"time"
 
+	"nonchalant/internal/analytics"
+	"nonchalant/internal/auth"
+	"nonchalant/internal/capacity"
+	"nonchalant/internal/config"
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
+	"nonchalant/internal/sessions"
 	"nonchalant/internal/svc/relay"
 )
 
@@ -15,24 +21,51 @@ import (
 type Service struct {
 	registry  *bus.Registry
 	relayMgr  RelayManager
+	admin     *auth.AdminPolicy
+	sessions  SessionSource
+	capacity  CapacitySource
+	table     *sessions.Table // nil disables the session endpoints
+	events    *events.Bus     // nil disables /api/events
+	analytics *analytics.Recorder
 	startTime int64
 }
 
+// SessionSource reports active HTTP-FLV sessions by protocol.
+type SessionSource interface {
+	Sessions() map[string]int64
+}
+
+// CapacitySource reports load against the configured capacity limits.
+type CapacitySource interface {
+	Usage() capacity.Report
+}
+
 // RelayManager defines the interface for relay management.
 // This allows the API to work with relay manager without tight coupling.
 type RelayManager interface {
 	TaskCount() int
 	GetTasks() []relay.TaskInfo
 	Restart(app, name string) error
+	AddTarget(app, name string, target config.PushTarget) error
+	RemoveTarget(app, name, id string) error
+	SetTargetEnabled(app, name, id string, enabled bool) error
 }
 
 // RelayTaskInfo represents information about a relay task for API responses.
+// Push relays report one entry per target.
 type RelayTaskInfo struct {
-	App       string `json:"app"`
-	Name      string `json:"name"`
-	Mode      string `json:"mode"`
-	RemoteURL string `json:"remote_url"`
-	Running   bool   `json:"running"`
+	App       string   `json:"app"`
+	Name      string   `json:"name"`
+	Mode      string   `json:"mode"`
+	Target    string   `json:"target,omitempty"`
+	RemoteURL string   `json:"remote_url"`
+	Sources   []string `json:"sources,omitempty"`
+	Source    string   `json:"source"`
+	Enabled   bool     `json:"enabled"`
+	Running   bool     `json:"running"`
+	Status    string   `json:"status"`
+	LastError string   `json:"last_error,omitempty"`
+	Attempts  int      `json:"attempts"`
 }
 
 // NewService creates a new API service.
@@ -44,13 +77,35 @@ func NewService(registry *bus.Registry, relayMgr RelayManager) *Service {
 	}
 }
 
-// RegisterRoutes registers API routes on the provided mux.
+// SetAdminPolicy enables token auth on every API route. nil (the default)
+// leaves the API open. Must be called before RegisterRoutes.
+func (s *Service) SetAdminPolicy(p *auth.AdminPolicy) { s.admin = p }
+
+// SetSessionSource adds per-protocol HTTP-FLV session counts to
+// /api/server. Optional.
+func (s *Service) SetSessionSource(src SessionSource) { s.sessions = src }
+
+// SetCapacitySource adds capacity usage to /api/server. Optional.
+func (s *Service) SetCapacitySource(src CapacitySource) { s.capacity = src }
+
+// RegisterRoutes registers API routes on the provided mux. Each route is
+// wrapped in auth.Admin: GET needs the read role, mutations the listed role.
 func (s *Service) RegisterRoutes(mux *http.ServeMux) {
-	// API routes
-	mux.HandleFunc("/api/server", s.handleServer)
-	mux.HandleFunc("/api/streams", s.handleStreams)
-	mux.HandleFunc("/api/relay", s.handleRelay)
-	mux.HandleFunc("/api/relay/restart", s.handleRelayRestart)
+	route := func(path string, write auth.Role, h http.HandlerFunc) {
+		mux.Handle(path, auth.Admin(s.admin, write, h))
+	}
+	route("/api/server", auth.RoleRead, s.handleServer)
+	route("/api/streams", auth.RoleRead, s.handleStreams)
+	route("/api/streams/{app}/{name}/sessions", auth.RoleRead, s.handleStreamSessions)
+	route("/api/streams/{app}/{name}/metadata", auth.RoleOperate, s.handleMetadata)
+	route("/api/sessions/{id}", auth.RoleOperate, s.handleSession)
+	route("/api/events", auth.RoleRead, s.handleEvents)
+	route("/api/analytics/{app}/{name}", auth.RoleRead, s.handleAnalytics)
+	route("/api/relay", auth.RoleRead, s.handleRelay)
+	route("/api/relay/restart", auth.RoleOperate, s.handleRelayRestart)
+	route("/api/relay/targets", auth.RoleAdmin, s.handleRelayTargets)
+	route("/api/relay/targets/enable", auth.RoleOperate, s.handleRelayTargetToggle(true))
+	route("/api/relay/targets/disable", auth.RoleOperate, s.handleRelayTargetToggle(false))
 }
 
 // getCurrentTime returns current Unix timestamp.
@@ -0,0 +1,58 @@
+// If you are AI: This file implements the per-session endpoints: listing
+// the publisher and viewers of one stream, and disconnecting one of them.
+
+package api
+
+import (
+	"net/http"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
+)
+
+// SessionsResponse represents the /api/streams/{app}/{name}/sessions response.
+type SessionsResponse struct {
+	Sessions []sessions.Info `json:"sessions"`
+}
+
+// SetSessionTable enables the session endpoints, backed by t. Without it
+// they list nothing and every ID is unknown.
+func (s *Service) SetSessionTable(t *sessions.Table) { s.table = t }
+
+// handleStreamSessions handles GET /api/streams/{app}/{name}/sessions.
+// Returns every protocol's publisher and viewers of the stream, oldest first.
+func (s *Service) handleStreamSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	key := bus.NewStreamKey(r.PathValue("app"), r.PathValue("name"))
+	list := s.table.List(key)
+	if list == nil {
+		list = []sessions.Info{}
+	}
+	s.writeJSON(w, http.StatusOK, SessionsResponse{Sessions: list})
+}
+
+// handleSession handles GET (inspect) and DELETE (disconnect) on
+// /api/sessions/{id}. Responds 404 for unknown IDs.
+func (s *Service) handleSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		info, ok := s.table.Get(id)
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, info)
+	case http.MethodDelete:
+		if !s.table.Kick(id) {
+			s.writeError(w, http.StatusNotFound, "session not found")
+			return
+		}
+		s.writeJSON(w, http.StatusOK, map[string]string{"status": "disconnected"})
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
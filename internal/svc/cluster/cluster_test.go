@@ -0,0 +1,139 @@
+// If you are AI: Unit tests for the cluster directory: heartbeat exchange,
+// stream ownership, redirects and playback path parsing.
+
+package cluster
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// newNode starts a Directory behind an httptest server.
+func newNode(t *testing.T, id, secret string) (*Directory, *bus.Registry, *httptest.Server) {
+	t.Helper()
+	reg := bus.NewRegistry()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	d := NewDirectory(reg, Options{NodeID: id, AdvertiseURL: srv.URL, Secret: secret, Heartbeat: time.Second})
+	d.RegisterRoutes(mux)
+	t.Cleanup(srv.Close)
+	return d, reg, srv
+}
+
+// TestHeartbeatExchange: one heartbeat teaches both nodes about each other.
+func TestHeartbeatExchange(t *testing.T) {
+	a, regA, srvA := newNode(t, "a", "s3cret")
+	b, _, srvB := newNode(t, "b", "s3cret")
+	a.opts.Peers = []string{srvB.URL}
+	b.opts.Peers = []string{srvA.URL}
+
+	stream, _ := regA.GetOrCreate(bus.NewStreamKey("live", "cam1"))
+	stream.AttachPublisher(1)
+	regA.GetOrCreate(bus.NewStreamKey("live", "idle")) // no publisher: not advertised
+
+	a.beatAll()
+
+	key := bus.NewStreamKey("live", "cam1")
+	if owner, ok := b.Owner(key); !ok || owner != srvA.URL {
+		t.Fatalf("b.Owner(cam1) = %q, %v; want %q", owner, ok, srvA.URL)
+	}
+	if _, ok := b.Owner(bus.NewStreamKey("live", "idle")); ok {
+		t.Error("stream without publisher must not be advertised")
+	}
+	st := a.Status()
+	if len(st.Members) != 2 || !st.Members[0].Self || st.Members[1].NodeID != "b" || !st.Members[1].Alive {
+		t.Errorf("a.Status members = %+v", st.Members)
+	}
+	if got := b.OriginURL(key); got != srvA.URL+"/live/cam1.flv" {
+		t.Errorf("OriginURL = %q", got)
+	}
+}
+
+// TestHeartbeatSecret: a mismatched secret is rejected.
+func TestHeartbeatSecret(t *testing.T) {
+	a, _, _ := newNode(t, "a", "one")
+	b, _, srvB := newNode(t, "b", "two")
+	a.opts.Peers = []string{srvB.URL}
+	a.beatAll()
+	if len(b.Status().Members) != 1 || len(a.Status().Members) != 1 {
+		t.Error("nodes with different secrets must not learn about each other")
+	}
+}
+
+// TestHeartbeatPeers: heartbeats need a secret to be configured, and a
+// sender may only advertise a configured peer's host, which would
+// otherwise become a redirect target and pull source.
+func TestHeartbeatPeers(t *testing.T) {
+	open, _, srvOpen := newNode(t, "open", "")
+	resp, err := http.Post(srvOpen.URL+heartbeatPath, "application/json",
+		strings.NewReader(`{"node_id":"x","url":"http://evil.example","streams":["live/cam1"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized || len(open.Status().Members) != 1 {
+		t.Errorf("heartbeat without a configured secret: %d", resp.StatusCode)
+	}
+
+	d, _, _ := newNode(t, "b", "s3cret")
+	d.opts.Peers = []string{"http://10.0.0.1:8082"}
+	for _, u := range []string{"http://evil.example:8081", "javascript://10.0.0.1", "http://u@10.0.0.1:8081", "http://10.0.0.1:8081/x"} {
+		if d.record(heartbeat{NodeID: "a", URL: u, Streams: []string{"live/cam1"}}) {
+			t.Errorf("accepted advertised URL %q", u)
+		}
+	}
+	if !d.record(heartbeat{NodeID: "a", URL: "https://10.0.0.1:8443/", Streams: []string{"live/cam1"}}) {
+		t.Error("peer host on another port and scheme rejected")
+	}
+}
+
+// TestRedirect: a viewer of a stream owned by a peer gets a 302.
+func TestRedirect(t *testing.T) {
+	d, _, _ := newNode(t, "b", "s3cret")
+	d.opts.Peers = []string{"http://node-a:8082"}
+	d.record(heartbeat{NodeID: "a", URL: "http://node-a:8081", Streams: []string{"live/cam1"}})
+	h := d.Redirect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live/cam1.flv?key=k", nil))
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "http://node-a:8081/live/cam1.flv?key=k" {
+		t.Errorf("got %d Location=%q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/live/other.flv", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown stream: got %d, want 404 from next", w.Code)
+	}
+}
+
+// TestMediaKey covers every playback URL shape.
+func TestMediaKey(t *testing.T) {
+	cases := map[string]string{
+		"/live/cam1.flv":               "live/cam1",
+		"/ws/live/cam1":                "live/cam1",
+		"/hls/live/cam1.m3u8":          "live/cam1",
+		"/hls/live/cam1/index.m3u8":    "live/cam1",
+		"/dash/live/cam1/v0/seg-1.m4s": "live/cam1",
+		"/api/streams":                 "",
+		"/debug/pprof/":                "",
+		"/live/cam1/extra.flv":         "",
+	}
+	for p, want := range cases {
+		key, ok := mediaKey(p)
+		got := ""
+		if ok {
+			got = key.String()
+		}
+		if got != want {
+			t.Errorf("mediaKey(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
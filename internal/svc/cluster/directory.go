@@ -0,0 +1,257 @@
+// If you are AI: This file implements the cluster stream directory. Each node
+// periodically POSTs a heartbeat listing its locally published streams to
+// every static peer; the peer answers with its own heartbeat, so one request
+// per peer per interval keeps both sides' view of "which node has which
+// StreamKey" fresh. Peers that miss heartbeats for the timeout are down.
+
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"nonchalant/internal/auth"
+	"nonchalant/internal/core/bus"
+)
+
+const (
+	// defaultHeartbeat is the heartbeat interval.
+	defaultHeartbeat = 2 * time.Second
+	// secretHeader carries the shared cluster secret on heartbeats.
+	secretHeader = "X-Cluster-Secret"
+	// heartbeatPath is where peers receive heartbeats.
+	heartbeatPath = "/api/cluster/heartbeat"
+)
+
+// Options configures a Directory. See config.ClusterConfig for semantics.
+type Options struct {
+	NodeID       string
+	AdvertiseURL string
+	Peers        []string
+	Secret       string
+	Mode         string        // "redirect" or "proxy"; informational here
+	Heartbeat    time.Duration // <= 0 uses defaultHeartbeat
+	PeerTimeout  time.Duration // <= 0 uses three heartbeats
+	PlayKey      string        // appended to proxy pull URLs when set
+}
+
+// heartbeat is the wire format exchanged between nodes.
+type heartbeat struct {
+	NodeID  string   `json:"node_id"`
+	URL     string   `json:"url"`
+	Streams []string `json:"streams"` // "app/name" of locally published streams
+}
+
+// peerState is the last heartbeat received from a node.
+type peerState struct {
+	hb   heartbeat
+	seen time.Time
+}
+
+// Directory tracks cluster membership and stream location.
+// It is safe for concurrent use.
+type Directory struct {
+	registry *bus.Registry
+	opts     Options
+	admin    *auth.AdminPolicy
+	exclude  func(bus.StreamKey) bool
+	client   *http.Client
+
+	mu    sync.RWMutex
+	peers map[string]*peerState // by node ID
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewDirectory creates a Directory. Call Start to begin heartbeating.
+func NewDirectory(registry *bus.Registry, opts Options) *Directory {
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = defaultHeartbeat
+	}
+	if opts.PeerTimeout <= 0 {
+		opts.PeerTimeout = 3 * opts.Heartbeat
+	}
+	opts.AdvertiseURL = strings.TrimRight(opts.AdvertiseURL, "/")
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Directory{
+		registry: registry,
+		opts:     opts,
+		client:   &http.Client{Timeout: opts.Heartbeat},
+		peers:    make(map[string]*peerState),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// SetAdminPolicy protects GET /api/cluster with the read role. Must be
+// called before RegisterRoutes.
+func (d *Directory) SetAdminPolicy(p *auth.AdminPolicy) { d.admin = p }
+
+// SetExclude hides local streams for which fn returns true from heartbeats,
+// e.g. streams that are themselves pulled from a peer.
+func (d *Directory) SetExclude(fn func(bus.StreamKey) bool) { d.exclude = fn }
+
+// Start launches the heartbeat loop.
+func (d *Directory) Start() {
+	go d.loop()
+}
+
+// Stop ends the heartbeat loop and waits for it.
+func (d *Directory) Stop() {
+	d.cancel()
+	<-d.done
+}
+
+// Owner returns the URL of a live peer advertising key. When several peers
+// do, the lowest node ID wins so every node picks the same owner.
+func (d *Directory) Owner(key bus.StreamKey) (string, bool) {
+	want := key.String()
+	now := time.Now()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	best := ""
+	url := ""
+	for id, ps := range d.peers {
+		if now.Sub(ps.seen) > d.opts.PeerTimeout || (best != "" && id > best) {
+			continue
+		}
+		for _, s := range ps.hb.Streams {
+			if s == want {
+				best, url = id, ps.hb.URL
+				break
+			}
+		}
+	}
+	return url, url != ""
+}
+
+// OriginURL returns the HTTP-FLV URL of key on its owning peer, or "" when
+// no live peer has it. Used as the edge puller's resolver in proxy mode.
+func (d *Directory) OriginURL(key bus.StreamKey) string {
+	owner, ok := d.Owner(key)
+	if !ok {
+		return ""
+	}
+	u := fmt.Sprintf("%s/%s/%s.flv", owner, key.App, key.Name)
+	if d.opts.PlayKey != "" {
+		u += "?key=" + d.opts.PlayKey
+	}
+	return u
+}
+
+// loop sends heartbeats every interval until Stop.
+func (d *Directory) loop() {
+	defer close(d.done)
+	tick := time.NewTicker(d.opts.Heartbeat)
+	defer tick.Stop()
+	for {
+		d.beatAll()
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// beatAll sends one heartbeat to every peer in parallel.
+func (d *Directory) beatAll() {
+	hb := d.self()
+	body, _ := json.Marshal(hb)
+	var wg sync.WaitGroup
+	for _, peer := range d.opts.Peers {
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			if reply, err := d.beat(peer, body); err == nil {
+				d.record(reply)
+			}
+		}(strings.TrimRight(peer, "/"))
+	}
+	wg.Wait()
+}
+
+// beat POSTs body to peer and decodes the peer's own heartbeat in reply.
+func (d *Directory) beat(peer string, body []byte) (heartbeat, error) {
+	var reply heartbeat
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, peer+heartbeatPath, bytes.NewReader(body))
+	if err != nil {
+		return reply, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if d.opts.Secret != "" {
+		req.Header.Set(secretHeader, d.opts.Secret)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return reply, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return reply, fmt.Errorf("heartbeat to %s: status %d", peer, resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&reply)
+	return reply, err
+}
+
+// record stores a heartbeat received from (or returned by) a peer and
+// reports whether it was accepted. The advertised URL becomes a redirect
+// target and, in proxy mode, a pull source, so it must name a configured
+// peer's host.
+func (d *Directory) record(hb heartbeat) bool {
+	if hb.NodeID == "" || hb.NodeID == d.opts.NodeID || !d.knownPeer(hb.URL) {
+		return false
+	}
+	hb.URL = strings.TrimRight(hb.URL, "/")
+	d.mu.Lock()
+	d.peers[hb.NodeID] = &peerState{hb: hb, seen: time.Now()}
+	d.mu.Unlock()
+	return true
+}
+
+// knownPeer reports whether raw is an http(s) URL on the host of one of
+// the configured peers. Ports may differ: peers can be listed by their
+// admin listener while advertising the media port.
+func (d *Directory) knownPeer(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" ||
+		u.User != nil || strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		return false
+	}
+	for _, p := range d.opts.Peers {
+		if pu, err := url.Parse(p); err == nil && strings.EqualFold(pu.Hostname(), u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// self builds this node's heartbeat from the registry.
+func (d *Directory) self() heartbeat {
+	return heartbeat{NodeID: d.opts.NodeID, URL: d.opts.AdvertiseURL, Streams: d.localStreams()}
+}
+
+// localStreams lists locally published streams, sorted, minus exclusions.
+func (d *Directory) localStreams() []string {
+	streams := []string{}
+	for _, key := range d.registry.List() {
+		s := d.registry.Get(key)
+		if s == nil || !s.HasPublisher() || (d.exclude != nil && d.exclude(key)) {
+			continue
+		}
+		streams = append(streams, key.String())
+	}
+	sort.Strings(streams)
+	return streams
+}
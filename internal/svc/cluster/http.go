@@ -0,0 +1,163 @@
+// If you are AI: This file exposes the cluster directory over HTTP: the
+// heartbeat receiver, GET /api/cluster, and the redirect middleware that
+// sends viewers of a stream published elsewhere to the owning node.
+
+package cluster
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"nonchalant/internal/auth"
+	"nonchalant/internal/core/bus"
+)
+
+// Member is the API view of one cluster node.
+type Member struct {
+	NodeID   string     `json:"node_id"`
+	URL      string     `json:"url"`
+	Self     bool       `json:"self"`
+	Alive    bool       `json:"alive"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+	Streams  []string   `json:"streams"`
+}
+
+// StatusResponse is the body of GET /api/cluster.
+type StatusResponse struct {
+	NodeID  string            `json:"node_id"`
+	Mode    string            `json:"mode"`
+	Members []Member          `json:"members"`
+	Streams map[string]string `json:"streams"` // "app/name" -> node ID
+}
+
+// RegisterRoutes mounts /api/cluster and the heartbeat receiver. Both must
+// be registered before the httpflv catch-all.
+func (d *Directory) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("/api/cluster", auth.Admin(d.admin, auth.RoleRead, http.HandlerFunc(d.handleStatus)))
+	mux.HandleFunc(heartbeatPath, d.handleHeartbeat)
+}
+
+// Status returns the membership table with this node first, then peers by
+// node ID, plus the stream-to-node map.
+func (d *Directory) Status() StatusResponse {
+	self := d.self()
+	resp := StatusResponse{
+		NodeID:  d.opts.NodeID,
+		Mode:    d.opts.Mode,
+		Members: []Member{{NodeID: self.NodeID, URL: self.URL, Self: true, Alive: true, Streams: self.Streams}},
+		Streams: make(map[string]string),
+	}
+	for _, s := range self.Streams {
+		resp.Streams[s] = self.NodeID
+	}
+
+	now := time.Now()
+	d.mu.RLock()
+	peers := make([]Member, 0, len(d.peers))
+	for id, ps := range d.peers {
+		seen := ps.seen
+		alive := now.Sub(seen) <= d.opts.PeerTimeout
+		peers = append(peers, Member{NodeID: id, URL: ps.hb.URL, Alive: alive, LastSeen: &seen, Streams: ps.hb.Streams})
+		if !alive {
+			continue
+		}
+		for _, s := range ps.hb.Streams {
+			if cur, ok := resp.Streams[s]; !ok || (cur != self.NodeID && id < cur) {
+				resp.Streams[s] = id
+			}
+		}
+	}
+	d.mu.RUnlock()
+	sort.Slice(peers, func(i, j int) bool { return peers[i].NodeID < peers[j].NodeID })
+	resp.Members = append(resp.Members, peers...)
+	return resp
+}
+
+// handleStatus handles GET /api/cluster.
+func (d *Directory) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, d.Status())
+}
+
+// handleHeartbeat handles POST /api/cluster/heartbeat: record the sender and
+// reply with this node's own heartbeat. Without a configured secret every
+// heartbeat is refused, and a sender advertising a URL off the peer list is
+// forbidden.
+func (d *Directory) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if d.opts.Secret == "" ||
+		subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), []byte(d.opts.Secret)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var hb heartbeat
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&hb); err != nil {
+		http.Error(w, "invalid heartbeat", http.StatusBadRequest)
+		return
+	}
+	if !d.record(hb) {
+		http.Error(w, "unknown peer", http.StatusForbidden)
+		return
+	}
+	writeJSON(w, http.StatusOK, d.self())
+}
+
+// Redirect returns middleware that answers playback requests for streams
+// that are not live here but are advertised by a peer with a 302 to the
+// same path on that peer. Everything else goes to next.
+func (d *Directory) Redirect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			if key, ok := mediaKey(r.URL.Path); ok {
+				if s := d.registry.Get(key); s == nil || !s.HasPublisher() {
+					if owner, ok := d.Owner(key); ok {
+						http.Redirect(w, r, owner+r.URL.RequestURI(), http.StatusFound)
+						return
+					}
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// mediaKey extracts the stream key from a playback URL path:
+//
+//	/{app}/{name}.flv
+//	/ws/{app}/{name}
+//	/hls/{app}/{name}.m3u8, /hls/{app}/{name}/...   (same for /dash/)
+func mediaKey(p string) (bus.StreamKey, bool) {
+	parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "ws":
+		return bus.NewStreamKey(parts[1], parts[2]), parts[1] != "" && parts[2] != ""
+	case len(parts) >= 3 && (parts[0] == "hls" || parts[0] == "dash"):
+		name := parts[2]
+		if len(parts) == 3 {
+			name = strings.TrimSuffix(name, path.Ext(name))
+		}
+		return bus.NewStreamKey(parts[1], name), parts[1] != "" && name != ""
+	case len(parts) == 2 && path.Ext(parts[1]) == ".flv":
+		name := strings.TrimSuffix(parts[1], ".flv")
+		return bus.NewStreamKey(parts[0], name), parts[0] != "" && name != ""
+	}
+	return bus.StreamKey{}, false
+}
+
+// writeJSON writes v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
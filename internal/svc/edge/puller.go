@@ -0,0 +1,259 @@
+// If you are AI: This file implements edge mode. When a viewer asks for a
+// stream that is not live locally, the Puller starts a relay pull from the
+// configured origin into our own RTMP ingest, coalescing concurrent first
+// requests into one upstream pull, and tears the pull down once the stream
+// has had no viewers for the idle TTL.
+
+package edge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/svc/relay"
+)
+
+const (
+	// defaultIdleTTL keeps a pull alive this long after its last viewer.
+	defaultIdleTTL = 30 * time.Second
+	// defaultWait bounds how long a first viewer waits for the origin.
+	defaultWait = 10 * time.Second
+	// reapInterval is how often idle pulls are checked.
+	reapInterval = time.Second
+	// pollInterval is how often Ensure re-checks the local stream.
+	pollInterval = 50 * time.Millisecond
+)
+
+// Options configures a Puller. Origin and Origins values are URL templates
+// with "{app}" and "{name}" placeholders; Origins is keyed by app and takes
+// precedence over Origin. Resolve, when set, is consulted first and returns
+// a complete source URL or "" (used by cluster proxy mode).
+type Options struct {
+	Origin  string
+	Origins map[string]string
+	Resolve func(bus.StreamKey) string
+	IdleTTL time.Duration // <= 0 uses defaultIdleTTL
+	Wait    time.Duration // <= 0 uses defaultWait
+}
+
+// pull is one on-demand upstream pull.
+type pull struct {
+	task      *relay.PullTask
+	done      chan struct{} // closed when task.Start returns
+	err       error         // Start's result; valid after done is closed
+	idleSince time.Time     // zero while the stream has viewers
+}
+
+// Puller implements bus.OnDemand for edge servers.
+// It is safe for concurrent use.
+type Puller struct {
+	registry *bus.Registry
+	opts     Options
+
+	rtmpPort int
+	httpPort int
+	pubKey   string
+	playKey  string
+
+	mu     sync.Mutex
+	pulls  map[bus.StreamKey]*pull
+	ctx    context.Context
+	cancel context.CancelFunc
+	reaped chan struct{}
+}
+
+// NewPuller creates a Puller and starts its idle reaper. Call Stop to
+// terminate all pulls.
+func NewPuller(registry *bus.Registry, opts Options) *Puller {
+	if opts.IdleTTL <= 0 {
+		opts.IdleTTL = defaultIdleTTL
+	}
+	if opts.Wait <= 0 {
+		opts.Wait = defaultWait
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Puller{
+		registry: registry,
+		opts:     opts,
+		rtmpPort: 1935,
+		httpPort: 8081,
+		pulls:    make(map[bus.StreamKey]*pull),
+		ctx:      ctx,
+		cancel:   cancel,
+		reaped:   make(chan struct{}),
+	}
+	go p.reapLoop()
+	return p
+}
+
+// SetEndpoints records where our local listeners are so pulls can republish
+// into our RTMP ingest. Must be called before the first Ensure.
+func (p *Puller) SetEndpoints(rtmpPort, httpPort int, publishKey, playKey string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rtmpPort = rtmpPort
+	p.httpPort = httpPort
+	p.pubKey = publishKey
+	p.playKey = playKey
+}
+
+// Ensure makes key live locally, pulling it from its origin if needed. It
+// returns once the stream has a publisher, the pull fails, the wait timeout
+// elapses, or ctx ends.
+func (p *Puller) Ensure(ctx context.Context, key bus.StreamKey) error {
+	if s := p.registry.Get(key); s != nil && s.HasPublisher() {
+		return nil
+	}
+	pl, err := p.acquire(key)
+	if err != nil {
+		return err
+	}
+	deadline := time.NewTimer(p.opts.Wait)
+	defer deadline.Stop()
+	tick := time.NewTicker(pollInterval)
+	defer tick.Stop()
+	for {
+		if s := p.registry.Get(key); s != nil && s.HasPublisher() {
+			return nil
+		}
+		select {
+		case <-pl.done:
+			return fmt.Errorf("edge pull %s ended: %v", key, pl.err)
+		case <-deadline.C:
+			return fmt.Errorf("edge pull %s: origin produced nothing within %s", key, p.opts.Wait)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// Active returns the keys of the streams currently pulled on demand.
+func (p *Puller) Active() []bus.StreamKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	keys := make([]bus.StreamKey, 0, len(p.pulls))
+	for k := range p.pulls {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Pulling reports whether key is currently pulled on demand.
+func (p *Puller) Pulling(key bus.StreamKey) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.pulls[key]
+	return ok
+}
+
+// Stop terminates every pull and the reaper.
+func (p *Puller) Stop() {
+	p.cancel()
+	<-p.reaped
+	p.mu.Lock()
+	pulls := p.pulls
+	p.pulls = make(map[bus.StreamKey]*pull)
+	p.mu.Unlock()
+	for _, pl := range pulls {
+		_ = pl.task.Stop()
+		<-pl.done
+	}
+}
+
+// acquire returns the pull for key, starting one if none is in flight. A
+// pull whose task has already ended is replaced so a later viewer retries.
+func (p *Puller) acquire(key bus.StreamKey) (*pull, error) {
+	origin := p.originURL(key)
+	if origin == "" {
+		return nil, fmt.Errorf("no origin configured for app %q", key.App)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ctx.Err() != nil {
+		return nil, fmt.Errorf("edge puller stopped")
+	}
+	if pl, ok := p.pulls[key]; ok {
+		select {
+		case <-pl.done:
+		default:
+			return pl, nil
+		}
+	}
+	task := relay.NewPullTask(p.registry, key.App, key.Name, origin, true)
+	task.SetEndpoints(p.rtmpPort, p.httpPort, p.pubKey, p.playKey)
+	pl := &pull{task: task, done: make(chan struct{})}
+	p.pulls[key] = pl
+	log.Printf("edge: pulling %s from %s", key, origin)
+	go func() {
+		pl.err = task.Start(p.ctx)
+		close(pl.done)
+	}()
+	return pl, nil
+}
+
+// originURL resolves or expands the origin template for key, or returns ""
+// when no origin covers it.
+func (p *Puller) originURL(key bus.StreamKey) string {
+	if p.opts.Resolve != nil {
+		if u := p.opts.Resolve(key); u != "" {
+			return u
+		}
+	}
+	tmpl, ok := p.opts.Origins[key.App]
+	if !ok {
+		tmpl = p.opts.Origin
+	}
+	if tmpl == "" {
+		return ""
+	}
+	return strings.NewReplacer("{app}", key.App, "{name}", key.Name).Replace(tmpl)
+}
+
+// reapLoop periodically stops pulls that have been idle for the TTL.
+func (p *Puller) reapLoop() {
+	defer close(p.reaped)
+	tick := time.NewTicker(reapInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case now := <-tick.C:
+			p.reapOnce(now)
+		}
+	}
+}
+
+// reapOnce stops pulls whose stream has had no subscribers for the idle TTL
+// and forgets pulls whose task has already ended.
+func (p *Puller) reapOnce(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, pl := range p.pulls {
+		select {
+		case <-pl.done:
+			delete(p.pulls, key)
+			continue
+		default:
+		}
+		if s := p.registry.Get(key); s != nil && s.SubscriberCount() > 0 {
+			pl.idleSince = time.Time{}
+			continue
+		}
+		if pl.idleSince.IsZero() {
+			pl.idleSince = now
+			continue
+		}
+		if now.Sub(pl.idleSince) >= p.opts.IdleTTL {
+			log.Printf("edge: %s idle for %s, stopping pull", key, p.opts.IdleTTL)
+			_ = pl.task.Stop()
+			delete(p.pulls, key)
+		}
+	}
+}
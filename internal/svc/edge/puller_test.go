@@ -0,0 +1,94 @@
+// If you are AI: This file contains unit tests for the edge puller: origin
+// template expansion, coalescing of concurrent pulls, and idle teardown.
+
+package edge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/svc/relay"
+)
+
+// TestOriginURL checks template expansion and per-app overrides.
+func TestOriginURL(t *testing.T) {
+	p := NewPuller(bus.NewRegistry(), Options{
+		Origin:  "http://origin:8081/{app}/{name}.flv",
+		Origins: map[string]string{"vip": "rtmp://vip-origin/{app}/{name}"},
+	})
+	defer p.Stop()
+
+	if got := p.originURL(bus.NewStreamKey("live", "a")); got != "http://origin:8081/live/a.flv" {
+		t.Errorf("default origin = %q", got)
+	}
+	if got := p.originURL(bus.NewStreamKey("vip", "b")); got != "rtmp://vip-origin/vip/b" {
+		t.Errorf("per-app origin = %q", got)
+	}
+
+	none := NewPuller(bus.NewRegistry(), Options{Origins: map[string]string{"vip": "x"}})
+	defer none.Stop()
+	if err := none.Ensure(context.Background(), bus.NewStreamKey("live", "a")); err == nil {
+		t.Error("expected error for an app without an origin")
+	}
+}
+
+// TestAcquireCoalesces checks that a pull already in flight is shared.
+func TestAcquireCoalesces(t *testing.T) {
+	reg := bus.NewRegistry()
+	p := NewPuller(reg, Options{Origin: "rtmp://origin/{app}/{name}"})
+	defer p.Stop()
+
+	key := bus.NewStreamKey("live", "a")
+	inflight := &pull{task: relay.NewPullTask(reg, "live", "a", "rtmp://origin/live/a", true), done: make(chan struct{})}
+	p.mu.Lock()
+	p.pulls[key] = inflight
+	p.mu.Unlock()
+	defer close(inflight.done)
+
+	for i := 0; i < 3; i++ {
+		got, err := p.acquire(key)
+		if err != nil || got != inflight {
+			t.Fatalf("acquire %d: got %p err %v, want shared pull %p", i, got, err, inflight)
+		}
+	}
+}
+
+// TestReapIdle checks that a pull survives while viewers are attached and is
+// torn down once it has been idle for the TTL.
+func TestReapIdle(t *testing.T) {
+	reg := bus.NewRegistry()
+	p := NewPuller(reg, Options{Origin: "rtmp://origin/{app}/{name}", IdleTTL: 10 * time.Second})
+	defer p.Stop()
+	// Halt the background reaper so only the explicit reapOnce calls below
+	// drive the idle clock.
+	p.cancel()
+	<-p.reaped
+
+	key := bus.NewStreamKey("live", "a")
+	stream, _ := reg.GetOrCreate(key)
+	_, subID := stream.AttachSubscriber(0, bus.BackpressureDropOldest)
+	task := relay.NewPullTask(reg, "live", "a", "rtmp://origin/live/a", true)
+	p.mu.Lock()
+	p.pulls[key] = &pull{task: task, done: make(chan struct{})}
+	p.mu.Unlock()
+
+	now := time.Now()
+	p.reapOnce(now)
+	p.reapOnce(now.Add(time.Minute))
+	if len(p.Active()) != 1 {
+		t.Fatal("pull with a viewer must not be reaped")
+	}
+
+	stream.DetachSubscriber(subID)
+	p.reapOnce(now)
+	p.reapOnce(now.Add(5 * time.Second))
+	if len(p.Active()) != 1 {
+		t.Fatal("pull reaped before the idle TTL")
+	}
+	p.reapOnce(now.Add(10 * time.Second))
+	if len(p.Active()) != 0 {
+		t.Fatal("idle pull not reaped after the TTL")
+	}
+}
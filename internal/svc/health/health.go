@@ -1,23 +1,34 @@
-// If you are AI: This file implements the health check endpoint for monitoring and integration tests.
+// If you are AI: This file implements the health check endpoints for monitoring and integration tests.
+// /healthz is liveness (process is up); /readyz is readiness (listeners are
+// bound and the server is accepting traffic, cleared again on shutdown).
 
 package health
 
 import (
 	"net/http"
+	"sync/atomic"
 )
 
 // Service provides health check functionality.
-type Service struct{}
+type Service struct {
+	ready atomic.Bool
+}
 
-// New creates a new health service instance.
+// New creates a new health service instance. It starts not ready.
 func New() *Service {
 	return &Service{}
 }
 
+// SetReady flips the readiness reported by /readyz.
+func (s *Service) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
 // RegisterRoutes adds health check routes to the provided mux.
-// Currently registers /healthz which returns 200 OK.
+// Registers /healthz (always 200) and /readyz (200 once ready, else 503).
 func (s *Service) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
 }
 
 // handleHealth responds to health check requests.
@@ -29,3 +40,17 @@ func (s *Service) handleHealth(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// handleReady responds 200 while the server is accepting traffic and 503
+// during startup and shutdown, so load balancers drain us first.
+func (s *Service) handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.ready.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -5,15 +5,19 @@ package httpflv
 
 import (
 	"context"
+	"io"
+	"net"
 	"net/http"
 	"path"
 	"strings"
 	"time"
 
+	"nonchalant/internal/auth"
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
 )
 
-// waitForStreams polls the stream's cached init data and returns the
+// waitForStreams polls the stream's audio codec and cached init data and returns the
 // (hasAudio, hasVideo) flags suitable for the FLV header. It returns
 // when both flags are true, when ctx ends, or when timeout elapses —
 // whichever comes first. If a publisher only ever produces video, this
@@ -21,7 +25,7 @@ import (
 func waitForStreams(ctx context.Context, stream *bus.Stream, timeout time.Duration) (bool, bool) {
 	deadline := time.Now().Add(timeout)
 	for {
-		hasAudio := stream.HasAudioInit()
+		hasAudio := stream.HasAudio()
 		hasVideo := stream.HasVideoInit()
 		if (hasAudio && hasVideo) || time.Now().After(deadline) {
 			return hasAudio, hasVideo
@@ -37,6 +41,8 @@ func waitForStreams(ctx context.Context, stream *bus.Stream, timeout time.Durati
 // Handler handles HTTP-FLV requests.
 type Handler struct {
 	registry *bus.Registry
+	sessions sessionCounts
+	table    *sessions.Table // nil unless SetSessionTable was called
 }
 
 // NewHandler creates a new HTTP-FLV handler.
@@ -73,66 +79,99 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	app := parts[0]
 	name := parts[1]
-
-	// Get stream from registry
-	streamKey := bus.NewStreamKey(app, name)
-	stream := h.registry.Get(streamKey)
-	if stream == nil {
-		w.WriteHeader(http.StatusNotFound)
+	if bus.NewStreamKey(app, name).Validate() != nil {
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	// Check if stream has a publisher
-	if !stream.HasPublisher() {
+	// Resolve a live stream. On an edge this may pull it from the origin
+	// first; concurrent first viewers share that one pull.
+	stream := h.registry.Live(r.Context(), bus.NewStreamKey(app, name))
+	if stream == nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
+	counter := h.sessions.counter(protoName(r))
+	counter.Add(1)
+	defer counter.Add(-1)
+
+	// Kicking the session through the table cancels ctx, which ends the
+	// tag loop and closes a hijacked connection.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	sess := h.table.OpenAs(sessions.OutputProtocol(r.RemoteAddr, r.UserAgent()), sessions.RoleViewer,
+		bus.NewStreamKey(app, name), r.RemoteAddr, r.UserAgent(), auth.Subject(r), cancel)
+	defer sess.Close()
+
 	// Hijack the connection so we can write raw FLV bytes directly to the
 	// TCP socket. This bypasses Go's HTTP chunked-transfer encoding and the
 	// double-flush (bufio + http.ResponseWriter.Flush) that pprof showed
 	// dominating CPU at high fan-out — each FLV tag becomes exactly one
-	// syscall.write instead of two.
-	hijacker, ok := w.(http.Hijacker)
-	if !ok {
-		http.Error(w, "stream hijack unsupported", http.StatusInternalServerError)
-		return
+	// syscall.write instead of two. On the HTTPS listener the hijacked conn
+	// is the *tls.Conn, so the same writes are encrypted transparently.
+	// HTTP/2 and HTTP/3 cannot hijack; they take the flushing path below.
+	rc := http.NewResponseController(w)
+	if r.ProtoMajor == 1 {
+		if conn, _, err := rc.Hijack(); err == nil {
+			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+			defer stop()
+			h.serveHijacked(ctx, conn, stream, sess)
+			return
+		}
 	}
-	conn, _, err := hijacker.Hijack()
-	if err != nil {
-		http.Error(w, "hijack failed", http.StatusInternalServerError)
+	w.Header().Set("Content-Type", "video/x-flv")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
 		return
 	}
-	defer conn.Close()
+	h.serveTags(ctx, &flushWriter{w: w, rc: rc}, stream, sess)
+}
 
-	// Send a minimal HTTP/1.1 response. NOTE: no Transfer-Encoding — without
-	// it the response is "until close", which is what we want for live FLV.
-	// We're now responsible for the connection's lifetime.
+// serveHijacked sends a minimal HTTP/1.1 response head on a hijacked
+// connection and then streams FLV tags on it.
+func (h *Handler) serveHijacked(ctx context.Context, conn net.Conn, stream *bus.Stream, sess *sessions.Session) {
+	// NOTE: no Transfer-Encoding — without it the response is "until close",
+	// which is what we want for live FLV. We're now responsible for the
+	// connection's lifetime.
 	headers := "HTTP/1.1 200 OK\r\n" +
 		"Content-Type: video/x-flv\r\n" +
 		"Cache-Control: no-cache\r\n" +
 		"Connection: close\r\n" +
 		"Access-Control-Allow-Origin: *\r\n" +
 		"\r\n"
-	if _, err := conn.Write([]byte(headers)); err != nil {
+	n, err := conn.Write([]byte(headers))
+	sess.AddSent(n)
+	if err != nil {
 		return
 	}
+	h.serveTags(ctx, conn, stream, sess)
+}
 
-	sub := NewSubscriber(conn, stream)
+// serveTags attaches a subscriber writing to w and streams until ctx ends
+// (server shutdown, client disconnect or kick) or a write fails. Bytes
+// written and the subscriber's drops and lag are reported on sess.
+func (h *Handler) serveTags(ctx context.Context, w io.Writer, stream *bus.Stream, sess *sessions.Session) {
+	if sess != nil {
+		w = &countingWriter{w: w, sess: sess}
+	}
+	sub := NewSubscriber(w, stream)
 	defer sub.Detach()
 	sub.Attach()
+	sess.SetSubscriber(sub.BusSubscriber())
+	sub.SetSession(sess)
 
 	// Wait briefly for the publisher's codec init data so we can claim only
 	// the streams that actually exist in the FLV header. Claiming audio when
 	// none is present makes ffmpeg's analyzer hang in find_stream_info.
-	hasAudio, hasVideo := waitForStreams(r.Context(), stream, 2*time.Second)
+	hasAudio, hasVideo := waitForStreams(ctx, stream, 2*time.Second)
 	if err := sub.WriteHeader(hasAudio, hasVideo); err != nil {
 		return
 	}
-
-	// Process messages until the request context is cancelled (server shutdown
-	// or client disconnect) or a write error fires.
-	_ = sub.ProcessMessages(r.Context())
+	_ = sub.ProcessMessages(ctx)
 }
 
 // RegisterRoutes registers HTTP-FLV routes on the given mux.
@@ -78,3 +78,43 @@ func TestHTTPFLVHandlerWithPublisher(t *testing.T) {
 		t.Errorf("Response does not start with FLV signature, got: %v", hdr[:3])
 	}
 }
+
+// TestHTTPFLVHandlerHTTP2 checks the non-hijack path: over HTTP/2 the
+// handler streams through ResponseController flushes and counts the
+// session under "h2".
+func TestHTTPFLVHandlerHTTP2(t *testing.T) {
+	registry := bus.NewRegistry()
+	handler := NewHandler(registry)
+
+	key := bus.NewStreamKey("live", "test")
+	stream, _ := registry.GetOrCreate(key)
+	stream.AttachPublisher(1)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(handler.ServeHTTP))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/live/test.flv")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("negotiated %s, want HTTP/2", resp.Proto)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "video/x-flv" {
+		t.Errorf("Expected Content-Type video/x-flv, got %s", got)
+	}
+
+	hdr := make([]byte, 9)
+	if _, err := io.ReadFull(resp.Body, hdr); err != nil {
+		t.Fatalf("read header: %v", err)
+	}
+	if !bytes.HasPrefix(hdr, []byte("FLV")) {
+		t.Errorf("Response does not start with FLV signature, got: %v", hdr[:3])
+	}
+	if got := handler.Sessions(); got[ProtoHTTP2] != 1 || got[ProtoHTTP1] != 0 {
+		t.Errorf("sessions = %v, want one h2 session", got)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"nonchalant/internal/auth"
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
 )
 
 // Service provides HTTP-FLV streaming functionality.
@@ -25,6 +26,15 @@ func NewService(registry *bus.Registry, playKeys *auth.KeySet) *Service {
 	}
 }
 
+// Sessions returns the number of active HTTP-FLV sessions by protocol
+// ("http/1.1", "h2", "h3").
+func (s *Service) Sessions() map[string]int64 {
+	return s.handler.Sessions()
+}
+
+// SetSessionTable registers every HTTP-FLV viewer in t. Optional.
+func (s *Service) SetSessionTable(t *sessions.Table) { s.handler.SetSessionTable(t) }
+
 // RegisterRoutes registers HTTP-FLV routes on the provided mux.
 // When play keys are configured, the catch-all is gated by auth.Gate.
 func (s *Service) RegisterRoutes(mux *http.ServeMux) {
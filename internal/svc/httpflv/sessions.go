@@ -0,0 +1,106 @@
+// If you are AI: This file holds the pieces of the non-hijack HTTP-FLV path
+// (HTTP/2, HTTP/3), the per-protocol session counters used to compare
+// the delivery paths, and the hooks into the shared session table.
+
+package httpflv
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"nonchalant/internal/sessions"
+)
+
+// Protocol labels used in session counts.
+const (
+	ProtoHTTP1 = "http/1.1"
+	ProtoHTTP2 = "h2"
+	ProtoHTTP3 = "h3"
+)
+
+// protoName maps the request's HTTP version onto a session-count label.
+func protoName(r *http.Request) string {
+	switch r.ProtoMajor {
+	case 2:
+		return ProtoHTTP2
+	case 3:
+		return ProtoHTTP3
+	}
+	return ProtoHTTP1
+}
+
+// sessionCounts tracks active HTTP-FLV sessions per HTTP protocol.
+type sessionCounts struct {
+	h1, h2, h3 atomic.Int64
+}
+
+// counter returns the gauge for proto.
+func (c *sessionCounts) counter(proto string) *atomic.Int64 {
+	switch proto {
+	case ProtoHTTP2:
+		return &c.h2
+	case ProtoHTTP3:
+		return &c.h3
+	}
+	return &c.h1
+}
+
+// Sessions returns the number of active HTTP-FLV sessions by protocol label.
+func (h *Handler) Sessions() map[string]int64 {
+	return map[string]int64{
+		ProtoHTTP1: h.sessions.h1.Load(),
+		ProtoHTTP2: h.sessions.h2.Load(),
+		ProtoHTTP3: h.sessions.h3.Load(),
+	}
+}
+
+// SetSessionTable registers every viewer in t so it can be listed and
+// kicked through the API.
+func (h *Handler) SetSessionTable(t *sessions.Table) { h.table = t }
+
+// countingWriter counts the bytes a viewer is sent on its session. It
+// forwards write deadlines so the subscriber's stall eviction still works.
+type countingWriter struct {
+	w    io.Writer
+	sess *sessions.Session
+}
+
+// Write writes p and counts what was written.
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.sess.AddSent(n)
+	return n, err
+}
+
+// SetWriteDeadline sets the deadline on the wrapped writer when it has one.
+func (c *countingWriter) SetWriteDeadline(t time.Time) error {
+	if d, ok := c.w.(deadlineSetter); ok {
+		return d.SetWriteDeadline(t)
+	}
+	return nil
+}
+
+// flushWriter is the subscriber's writer when the connection cannot be
+// hijacked: each tag goes through the ResponseWriter and is flushed at once
+// so it leaves in its own HTTP/2 or HTTP/3 DATA frame.
+type flushWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// Write writes p to the response body and flushes it.
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, f.rc.Flush()
+}
+
+// SetWriteDeadline forwards the subscriber's per-write deadline to the
+// response where the server supports it.
+func (f *flushWriter) SetWriteDeadline(t time.Time) error {
+	return f.rc.SetWriteDeadline(t)
+}
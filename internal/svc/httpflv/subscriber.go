@@ -12,21 +12,22 @@ import (
 
 	"nonchalant/internal/core/bus"
 	"nonchalant/internal/core/protocol/flv"
+	"nonchalant/internal/sessions"
 )
 
 // Subscriber represents an HTTP-FLV client subscriber.
 // Reads messages from bus and writes FLV tags directly to the underlying
 // TCP connection (post-Hijack). One conn.Write per tag = one syscall.
 type Subscriber struct {
-	conn          io.Writer       // typically a *net.TCPConn after Hijack
-	deadliner     deadlineSetter  // set on conn when available; nil for tests
+	conn          io.Writer      // typically a *net.TCPConn after Hijack
+	deadliner     deadlineSetter // set on conn when available; nil for tests
 	busSubscriber *bus.Subscriber
 	stream        *bus.Stream
 	subscriberID  uint64
 	headerWritten bool
-	gotKeyframe   bool   // True after first video keyframe received
-	tsOffset      uint32 // First non-init timestamp, subtracted from all subsequent
-	tsBaseSet     bool   // True after tsOffset is captured
+	gotKeyframe   bool              // True after first video keyframe received
+	rebase        flv.Rebaser       // starts the viewer's timeline at zero
+	session       *sessions.Session // publish-to-write latency; nil records nothing
 }
 
 // deadlineSetter narrows the net.Conn surface we use for the per-write
@@ -114,35 +115,21 @@ func (s *Subscriber) ProcessMessages(ctx context.Context) error {
 		if !ok {
 			continue
 		}
-		tagBuf := bus.AcquirePayload()
-		tagBuf = flv.AppendTag(tagBuf, tagType, s.rebaseTimestamp(msg), msg.Payload)
+		ts := s.rebase.Rebase(msg)
+		tagBuf := flv.AppendClock(bus.AcquirePayload(), s.stream, msg, ts)
+		tagBuf = flv.AppendTag(tagBuf, tagType, ts, msg.Payload)
 
+		received := msg.Received
 		s.armWriteDeadline()
 		_, werr := s.conn.Write(tagBuf)
 		bus.ReleasePayload(tagBuf)
 		if werr != nil {
 			return werr
 		}
+		s.session.ObserveDelivery(received)
 	}
 }
 
-// rebaseTimestamp adjusts a message timestamp so the subscriber's stream starts at ts=0.
-// Init messages always return ts=0. The first non-init timestamp becomes the offset
-// that is subtracted from all subsequent timestamps.
-func (s *Subscriber) rebaseTimestamp(msg *bus.MediaMessage) uint32 {
-	if msg.IsInit {
-		return 0
-	}
-	if !s.tsBaseSet {
-		s.tsOffset = msg.Timestamp
-		s.tsBaseSet = true
-	}
-	if msg.Timestamp < s.tsOffset {
-		return 0 // Guard against underflow
-	}
-	return msg.Timestamp - s.tsOffset
-}
-
 // Attach attaches the subscriber to the stream.
 // Returns the subscriber ID for later detach.
 func (s *Subscriber) Attach() uint64 {
@@ -152,6 +139,13 @@ func (s *Subscriber) Attach() uint64 {
 	return id
 }
 
+// SetSession records each write's publish-to-write latency, and where
+// the output timeline starts, on sess.
+func (s *Subscriber) SetSession(sess *sessions.Session) {
+	s.session = sess
+	s.rebase.OnStart = sess.SetOrigin
+}
+
 // Detach detaches the subscriber from the stream.
 func (s *Subscriber) Detach() {
 	if s.stream != nil && s.subscriberID != 0 {
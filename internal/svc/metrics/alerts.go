@@ -0,0 +1,85 @@
+// If you are AI: This file exports the ingest watchdog's alerts (a counter
+// per reason and a gauge for each condition active on a stream) and the
+// timestamp sanitizer's corrections.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"nonchalant/internal/core/bus"
+)
+
+// IngestAlertSource reports ingest watchdog alerts.
+type IngestAlertSource interface {
+	IngestAlertTotals() map[string]uint64
+	ActiveIngestAlerts() map[bus.StreamKey][]string
+}
+
+// alertCollector reads an IngestAlertSource on scrape.
+type alertCollector struct {
+	src    IngestAlertSource
+	total  *prometheus.Desc
+	active *prometheus.Desc
+}
+
+// newAlertCollector builds the descriptors.
+func newAlertCollector(src IngestAlertSource) *alertCollector {
+	return &alertCollector{
+		src: src,
+		total: prometheus.NewDesc("nonchalant_ingest_alerts_total",
+			"Ingest watchdog alerts raised, by reason.",
+			[]string{"reason"}, nil),
+		active: prometheus.NewDesc("nonchalant_ingest_alert_active",
+			"Ingest watchdog conditions active on a stream (always 1).",
+			[]string{"app", "name", "reason"}, nil),
+	}
+}
+
+// Describe sends both descriptors to the channel.
+func (c *alertCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.active
+}
+
+// Collect emits the totals and one sample per active condition.
+func (c *alertCollector) Collect(ch chan<- prometheus.Metric) {
+	for reason, n := range c.src.IngestAlertTotals() {
+		ch <- prometheus.MustNewConstMetric(c.total, prometheus.CounterValue, float64(n), reason)
+	}
+	for key, reasons := range c.src.ActiveIngestAlerts() {
+		for _, reason := range reasons {
+			ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, 1, key.App, key.Name, reason)
+		}
+	}
+}
+
+// TimestampSource reports timestamp sanitizer corrections.
+type TimestampSource interface {
+	TimestampCorrections() map[bus.StreamKey]map[string]uint64
+}
+
+// timestampCollector reads a TimestampSource on scrape.
+type timestampCollector struct {
+	src  TimestampSource
+	desc *prometheus.Desc
+}
+
+// newTimestampCollector builds the descriptor.
+func newTimestampCollector(src TimestampSource) *timestampCollector {
+	return &timestampCollector{src: src, desc: prometheus.NewDesc("nonchalant_timestamp_corrections_total",
+		"Publisher timestamps corrected by the sanitizer, by kind.",
+		[]string{"app", "name", "kind"}, nil)}
+}
+
+// Describe sends the descriptor to the channel.
+func (c *timestampCollector) Describe(ch chan<- *prometheus.Desc) { ch <- c.desc }
+
+// Collect emits one counter per stream and kind.
+func (c *timestampCollector) Collect(ch chan<- prometheus.Metric) {
+	for key, kinds := range c.src.TimestampCorrections() {
+		for kind, n := range kinds {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, float64(n), key.App, key.Name, kind)
+		}
+	}
+}
@@ -109,3 +109,75 @@ func (c *collector) Collect(ch chan<- prometheus.Metric) {
 		)
 	}
 }
+
+// SessionSource reports active HTTP-FLV sessions by protocol.
+type SessionSource interface {
+	Sessions() map[string]int64
+}
+
+// sessionCollector exports HTTP-FLV session counts by protocol so the
+// hijacked HTTP/1.1 path can be compared with HTTP/2 and HTTP/3.
+type sessionCollector struct {
+	src  SessionSource
+	desc *prometheus.Desc
+}
+
+// newSessionCollector builds the collector for src.
+func newSessionCollector(src SessionSource) *sessionCollector {
+	return &sessionCollector{
+		src: src,
+		desc: prometheus.NewDesc(
+			"nonchalant_httpflv_sessions",
+			"Number of active HTTP-FLV sessions by HTTP protocol.",
+			[]string{"proto"}, nil,
+		),
+	}
+}
+
+// Describe sends the session descriptor to the channel.
+func (c *sessionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect emits one gauge sample per protocol.
+func (c *sessionCollector) Collect(ch chan<- prometheus.Metric) {
+	for proto, n := range c.src.Sessions() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), proto)
+	}
+}
+
+// RejectionSource reports refused clients by reason.
+type RejectionSource interface {
+	Rejections() map[string]uint64
+}
+
+// rejectionCollector exports connection and publish / play refusals by
+// reason (ACL, per-IP limits, handshake timeouts).
+type rejectionCollector struct {
+	src  RejectionSource
+	desc *prometheus.Desc
+}
+
+// newRejectionCollector builds the collector for src.
+func newRejectionCollector(src RejectionSource) *rejectionCollector {
+	return &rejectionCollector{
+		src: src,
+		desc: prometheus.NewDesc(
+			"nonchalant_rejections_total",
+			"Cumulative count of refused clients by reason.",
+			[]string{"reason"}, nil,
+		),
+	}
+}
+
+// Describe sends the rejection descriptor to the channel.
+func (c *rejectionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect emits one counter sample per reason.
+func (c *rejectionCollector) Collect(ch chan<- prometheus.Metric) {
+	for reason, n := range c.src.Rejections() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, float64(n), reason)
+	}
+}
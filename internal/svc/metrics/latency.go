@@ -0,0 +1,46 @@
+// If you are AI: This file exports publish-to-write delivery latency as a
+// histogram per output protocol, read from the session table on scrape.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"nonchalant/internal/sessions"
+)
+
+// LatencySource reports delivery latency histograms by protocol.
+type LatencySource interface {
+	Latency() map[string]sessions.Histogram
+}
+
+// latencyCollector exports the time between Stream.Publish accepting a
+// message and an output writing it to a socket.
+type latencyCollector struct {
+	src  LatencySource
+	desc *prometheus.Desc
+}
+
+// newLatencyCollector builds the collector for src.
+func newLatencyCollector(src LatencySource) *latencyCollector {
+	return &latencyCollector{
+		src: src,
+		desc: prometheus.NewDesc(
+			"nonchalant_delivery_latency_seconds",
+			"Time from a message being published to an output writing it, by output protocol.",
+			[]string{"protocol"}, nil,
+		),
+	}
+}
+
+// Describe sends the latency descriptor to the channel.
+func (c *latencyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect emits one histogram per protocol.
+func (c *latencyCollector) Collect(ch chan<- prometheus.Metric) {
+	for proto, h := range c.src.Latency() {
+		ch <- prometheus.MustNewConstHistogram(c.desc, h.Count, h.Sum, h.Buckets, proto)
+	}
+}
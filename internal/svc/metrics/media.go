@@ -0,0 +1,99 @@
+// If you are AI: This file exports per-stream codec and ingest quality as
+// labelled gauges: info gauges carry the codec labels, numeric gauges the
+// resolution, sample rate and live ingest measurements.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/mediainfo"
+)
+
+// mediaCollector probes every published stream on scrape.
+type mediaCollector struct {
+	registry   *bus.Registry
+	video      *prometheus.Desc
+	audio      *prometheus.Desc
+	width      *prometheus.Desc
+	height     *prometheus.Desc
+	sampleRate *prometheus.Desc
+	channels   *prometheus.Desc
+	bitrate    *prometheus.Desc
+	fps        *prometheus.Desc
+	gopFrames  *prometheus.Desc
+	gopSeconds *prometheus.Desc
+	drift      *prometheus.Desc
+}
+
+// newMediaCollector builds the descriptors.
+func newMediaCollector(reg *bus.Registry) *mediaCollector {
+	stream := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(name, help, []string{"app", "name"}, nil)
+	}
+	return &mediaCollector{
+		registry: reg,
+		video: prometheus.NewDesc("nonchalant_stream_video_info",
+			"Video track of a published stream (always 1).",
+			[]string{"app", "name", "codec", "profile", "level", "codec_string"}, nil),
+		audio: prometheus.NewDesc("nonchalant_stream_audio_info",
+			"Audio track of a published stream (always 1).",
+			[]string{"app", "name", "codec", "profile", "codec_string"}, nil),
+		width:      stream("nonchalant_stream_video_width_pixels", "Video width of a published stream."),
+		height:     stream("nonchalant_stream_video_height_pixels", "Video height of a published stream."),
+		sampleRate: stream("nonchalant_stream_audio_sample_rate_hz", "Audio sample rate of a published stream."),
+		channels:   stream("nonchalant_stream_audio_channels", "Audio channel count of a published stream."),
+		bitrate:    stream("nonchalant_stream_ingest_bitrate_kbps", "Ingest bitrate over the last second of media."),
+		fps:        stream("nonchalant_stream_ingest_fps", "Ingest video frame rate over the last second of media."),
+		gopFrames:  stream("nonchalant_stream_gop_frames", "Frames in the last complete GOP."),
+		gopSeconds: stream("nonchalant_stream_gop_seconds", "Duration of the last complete GOP."),
+		drift:      stream("nonchalant_stream_av_drift_seconds", "Latest video minus latest audio timestamp."),
+	}
+}
+
+// Describe sends all descriptors to the channel.
+func (c *mediaCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{c.video, c.audio, c.width, c.height, c.sampleRate,
+		c.channels, c.bitrate, c.fps, c.gopFrames, c.gopSeconds, c.drift} {
+		ch <- d
+	}
+}
+
+// Collect emits the gauges for each stream that has a publisher. Track
+// fields that are unknown (zero) are skipped.
+func (c *mediaCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, key := range c.registry.List() {
+		stream := c.registry.Get(key)
+		if stream == nil || !stream.HasPublisher() {
+			continue
+		}
+		info := mediainfo.Probe(stream)
+		emit := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, key.App, key.Name)
+		}
+		known := func(d *prometheus.Desc, v int) {
+			if v > 0 {
+				emit(d, float64(v))
+			}
+		}
+		if v := info.Video; v != nil {
+			ch <- prometheus.MustNewConstMetric(c.video, prometheus.GaugeValue, 1,
+				key.App, key.Name, v.Codec, v.Profile, v.Level, v.CodecString)
+			known(c.width, v.Width)
+			known(c.height, v.Height)
+		}
+		if a := info.Audio; a != nil {
+			ch <- prometheus.MustNewConstMetric(c.audio, prometheus.GaugeValue, 1,
+				key.App, key.Name, a.Codec, a.Profile, a.CodecString)
+			known(c.sampleRate, a.SampleRate)
+			known(c.channels, a.Channels)
+		}
+		in := info.Ingest
+		emit(c.bitrate, in.BitrateKbps)
+		emit(c.fps, in.FPS)
+		emit(c.gopFrames, float64(in.GOPFrames))
+		emit(c.gopSeconds, in.GOPSeconds)
+		emit(c.drift, float64(in.AVDriftMs)/1000)
+	}
+}
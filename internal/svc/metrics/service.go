@@ -10,6 +10,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"nonchalant/internal/auth"
 	"nonchalant/internal/core/bus"
 )
 
@@ -18,6 +19,7 @@ type Service struct {
 	registry *bus.Registry
 	relayMgr RelayManager
 	promReg  *prometheus.Registry
+	scrape   *auth.KeySet
 }
 
 // RelayManager is the minimal surface metrics needs from the relay manager.
@@ -42,13 +44,52 @@ func NewService(reg *bus.Registry, relays RelayManager) *Service {
 
 	// Custom collector that walks the registry on each scrape.
 	s.promReg.MustRegister(newCollector(reg, relays))
+	s.promReg.MustRegister(newMediaCollector(reg))
 	return s
 }
 
+// SetScrapeKeys requires a bearer token from ks to scrape /metrics. nil
+// (the default) leaves the endpoint open. Must be called before RegisterRoutes.
+func (s *Service) SetScrapeKeys(ks *auth.KeySet) { s.scrape = ks }
+
+// SetSessionSource exports nonchalant_httpflv_sessions{proto} from src.
+func (s *Service) SetSessionSource(src SessionSource) {
+	s.promReg.MustRegister(newSessionCollector(src))
+}
+
+// SetRejectionSource exports nonchalant_rejections_total{reason} from src.
+func (s *Service) SetRejectionSource(src RejectionSource) {
+	s.promReg.MustRegister(newRejectionCollector(src))
+}
+
+// SetLatencySource exports nonchalant_delivery_latency_seconds{protocol}
+// from src.
+func (s *Service) SetLatencySource(src LatencySource) {
+	s.promReg.MustRegister(newLatencyCollector(src))
+}
+
+// SetTrafficSource exports byte counters, session gauges and the session
+// duration / TTFB histograms from src.
+func (s *Service) SetTrafficSource(src TrafficSource) {
+	s.promReg.MustRegister(newTrafficCollector(src))
+}
+
+// SetIngestAlertSource exports nonchalant_ingest_alerts_total{reason} and
+// nonchalant_ingest_alert_active{app,name,reason} from src.
+func (s *Service) SetIngestAlertSource(src IngestAlertSource) {
+	s.promReg.MustRegister(newAlertCollector(src))
+}
+
+// SetTimestampSource exports
+// nonchalant_timestamp_corrections_total{app,name,kind} from src.
+func (s *Service) SetTimestampSource(src TimestampSource) {
+	s.promReg.MustRegister(newTimestampCollector(src))
+}
+
 // RegisterRoutes mounts /metrics on the given mux.
 func (s *Service) RegisterRoutes(mux *http.ServeMux) {
-	mux.Handle("/metrics", promhttp.HandlerFor(s.promReg, promhttp.HandlerOpts{
+	mux.Handle("/metrics", auth.BearerGate(s.scrape, promhttp.HandlerFor(s.promReg, promhttp.HandlerOpts{
 		ErrorLog:      nil,
 		ErrorHandling: promhttp.ContinueOnError,
-	}))
+	})))
 }
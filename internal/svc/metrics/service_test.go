@@ -7,8 +7,10 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
 )
 
 // fakeRelayMgr is a minimal RelayManager for tests.
@@ -74,3 +76,159 @@ func TestPerStreamCountersIncrement(t *testing.T) {
 		t.Errorf("expected relay_tasks=1, body:\n%s", body)
 	}
 }
+
+// fakeSessions is a fixed SessionSource for tests.
+type fakeSessions map[string]int64
+
+// Sessions returns the fixed counts.
+func (f fakeSessions) Sessions() map[string]int64 { return f }
+
+// TestSessionGauge checks the per-protocol HTTP-FLV session gauge.
+func TestSessionGauge(t *testing.T) {
+	svc := NewService(bus.NewRegistry(), &fakeRelayMgr{})
+	svc.SetSessionSource(fakeSessions{"http/1.1": 4, "h2": 2, "h3": 0})
+	mux := http.NewServeMux()
+	svc.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`nonchalant_httpflv_sessions{proto="http/1.1"} 4`,
+		`nonchalant_httpflv_sessions{proto="h2"} 2`,
+		`nonchalant_httpflv_sessions{proto="h3"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics body missing %q", want)
+		}
+	}
+}
+
+// TestMediaGauges checks the codec info and numeric gauges for a stream
+// that has published an AAC sequence header.
+func TestMediaGauges(t *testing.T) {
+	registry := bus.NewRegistry()
+	svc := NewService(registry, &fakeRelayMgr{})
+	mux := http.NewServeMux()
+	svc.RegisterRoutes(mux)
+
+	stream, _ := registry.GetOrCreate(bus.NewStreamKey("live", "x"))
+	stream.AttachPublisher(1)
+	stream.Publish(&bus.MediaMessage{Type: bus.MessageTypeAudio, Payload: []byte{0xAF, 0, 0x12, 0x10}, IsInit: true})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`nonchalant_stream_audio_info{app="live",codec="aac",codec_string="mp4a.40.2",name="x",profile="LC"} 1`,
+		`nonchalant_stream_audio_sample_rate_hz{app="live",name="x"} 44100`,
+		`nonchalant_stream_audio_channels{app="live",name="x"} 2`,
+		`nonchalant_stream_ingest_bitrate_kbps{app="live",name="x"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics body missing %q", want)
+		}
+	}
+}
+
+// TestLatencyHistogram checks the per-protocol delivery latency histogram.
+func TestLatencyHistogram(t *testing.T) {
+	tbl := sessions.NewTable()
+	sess := tbl.Open(sessions.ProtoWSFLV, sessions.RoleViewer, bus.NewStreamKey("live", "x"), "10.0.0.1:1", "", nil)
+	defer sess.Close()
+	received := time.Now().UnixNano()
+	time.Sleep(30 * time.Millisecond)
+	sess.ObserveDelivery(received)
+
+	svc := NewService(bus.NewRegistry(), &fakeRelayMgr{})
+	svc.SetLatencySource(tbl)
+	mux := http.NewServeMux()
+	svc.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`nonchalant_delivery_latency_seconds_bucket{protocol="wsflv",le="0.025"} 0`,
+		`nonchalant_delivery_latency_seconds_bucket{protocol="wsflv",le="+Inf"} 1`,
+		`nonchalant_delivery_latency_seconds_count{protocol="wsflv"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics body missing %q", want)
+		}
+	}
+}
+
+// TestTrafficMetrics checks byte counters, session gauges and the session
+// histograms from a live table.
+func TestTrafficMetrics(t *testing.T) {
+	tbl := sessions.NewTable()
+	key := bus.NewStreamKey("live", "x")
+	pub := tbl.Open(sessions.ProtoRTMP, sessions.RolePublisher, key, "10.0.0.1:1", "", nil)
+	defer pub.Close()
+	pub.AddReceived(4096)
+	view := tbl.Open(sessions.ProtoWSFLV, sessions.RoleViewer, key, "10.0.0.2:1", "", nil)
+	view.AddSent(1024)
+	view.Close()
+
+	svc := NewService(bus.NewRegistry(), &fakeRelayMgr{})
+	svc.SetTrafficSource(tbl)
+	mux := http.NewServeMux()
+	svc.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`nonchalant_ingress_bytes_total{app="live",name="x"} 4096`,
+		`nonchalant_egress_bytes_total{app="live",name="x",protocol="wsflv"} 1024`,
+		`nonchalant_sessions{protocol="rtmp",role="publisher"} 1`,
+		`nonchalant_session_duration_seconds_count{protocol="wsflv"} 1`,
+		`nonchalant_session_ttfb_seconds_count{protocol="wsflv"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics body missing %q", want)
+		}
+	}
+}
+
+// fakeAlerts is a fixed IngestAlertSource and TimestampSource for tests.
+type fakeAlerts struct{}
+
+// IngestAlertTotals returns a single no_media count.
+func (fakeAlerts) IngestAlertTotals() map[string]uint64 {
+	return map[string]uint64{"no_media": 2}
+}
+
+// ActiveIngestAlerts reports one stream with a low bitrate.
+func (fakeAlerts) ActiveIngestAlerts() map[bus.StreamKey][]string {
+	return map[bus.StreamKey][]string{bus.NewStreamKey("live", "x"): {"low_bitrate"}}
+}
+
+// TimestampCorrections reports one stream with a smoothed jump.
+func (fakeAlerts) TimestampCorrections() map[bus.StreamKey]map[string]uint64 {
+	return map[bus.StreamKey]map[string]uint64{bus.NewStreamKey("live", "x"): {"jump": 3}}
+}
+
+// TestIngestAlertMetrics checks the alert counter and active gauge, and
+// the timestamp corrections.
+func TestIngestAlertMetrics(t *testing.T) {
+	svc := NewService(bus.NewRegistry(), &fakeRelayMgr{})
+	svc.SetIngestAlertSource(fakeAlerts{})
+	svc.SetTimestampSource(fakeAlerts{})
+	mux := http.NewServeMux()
+	svc.RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`nonchalant_ingest_alerts_total{reason="no_media"} 2`,
+		`nonchalant_ingest_alert_active{app="live",name="x",reason="low_bitrate"} 1`,
+		`nonchalant_timestamp_corrections_total{app="live",kind="jump",name="x"} 3`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics body missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// If you are AI: This file exports the session table's aggregates for
+// capacity planning and cost attribution: byte counters per stream (and
+// per protocol for egress), live sessions per protocol, and histograms of
+// session duration and time to first byte.
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
+)
+
+// TrafficSource reports byte totals, live sessions and session histograms.
+type TrafficSource interface {
+	Traffic() []sessions.Traffic
+	Active() map[sessions.Group]int
+	Durations() map[string]sessions.Histogram
+	TTFB() map[string]sessions.Histogram
+}
+
+// trafficCollector reads a TrafficSource on scrape.
+type trafficCollector struct {
+	src      TrafficSource
+	ingress  *prometheus.Desc
+	egress   *prometheus.Desc
+	sessions *prometheus.Desc
+	duration *prometheus.Desc
+	ttfb     *prometheus.Desc
+}
+
+// newTrafficCollector builds the descriptors.
+func newTrafficCollector(src TrafficSource) *trafficCollector {
+	return &trafficCollector{
+		src: src,
+		ingress: prometheus.NewDesc("nonchalant_ingress_bytes_total",
+			"Bytes received from publishers, per stream.",
+			[]string{"app", "name"}, nil),
+		egress: prometheus.NewDesc("nonchalant_egress_bytes_total",
+			"Bytes written to clients, per stream and output protocol.",
+			[]string{"app", "name", "protocol"}, nil),
+		sessions: prometheus.NewDesc("nonchalant_sessions",
+			"Live sessions by protocol and role.",
+			[]string{"protocol", "role"}, nil),
+		duration: prometheus.NewDesc("nonchalant_session_duration_seconds",
+			"Lifetime of ended sessions, by protocol.",
+			[]string{"protocol"}, nil),
+		ttfb: prometheus.NewDesc("nonchalant_session_ttfb_seconds",
+			"Time from a session opening to its first byte written, by protocol.",
+			[]string{"protocol"}, nil),
+	}
+}
+
+// Describe sends all descriptors to the channel.
+func (c *trafficCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.ingress
+	ch <- c.egress
+	ch <- c.sessions
+	ch <- c.duration
+	ch <- c.ttfb
+}
+
+// Collect emits the byte counters, session gauges and histograms.
+func (c *trafficCollector) Collect(ch chan<- prometheus.Metric) {
+	ingress := make(map[bus.StreamKey]uint64)
+	for _, tr := range c.src.Traffic() {
+		if tr.Received > 0 {
+			ingress[bus.NewStreamKey(tr.App, tr.Name)] += tr.Received
+		}
+		if tr.Sent > 0 {
+			ch <- prometheus.MustNewConstMetric(c.egress, prometheus.CounterValue, float64(tr.Sent), tr.App, tr.Name, tr.Protocol)
+		}
+	}
+	for key, n := range ingress {
+		ch <- prometheus.MustNewConstMetric(c.ingress, prometheus.CounterValue, float64(n), key.App, key.Name)
+	}
+	for g, n := range c.src.Active() {
+		ch <- prometheus.MustNewConstMetric(c.sessions, prometheus.GaugeValue, float64(n), g.Protocol, g.Role)
+	}
+	for proto, h := range c.src.Durations() {
+		ch <- prometheus.MustNewConstHistogram(c.duration, h.Count, h.Sum, h.Buckets, proto)
+	}
+	for proto, h := range c.src.TTFB() {
+		ch <- prometheus.MustNewConstHistogram(c.ttfb, h.Count, h.Sum, h.Buckets, proto)
+	}
+}
@@ -0,0 +1,139 @@
+// If you are AI: This file pairs a stream's SCTE-35 splices into ad
+// breaks. Splices arrive as timed metadata (onSCTE35, or onCuePoint with
+// a scte35 field) carrying a base64 splice_info_section; the splice
+// point is the message's timestamp.
+
+package pkger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"sort"
+	"strings"
+	"time"
+
+	"nonchalant/internal/core/protocol/amf0"
+	"nonchalant/internal/core/protocol/flv"
+	"nonchalant/internal/core/protocol/scte35"
+)
+
+// adBreak is an ad break on the packager's input timeline. out or in is
+// -1 when that edge is not in the cue log (yet).
+type adBreak struct {
+	id      uint32
+	out, in time.Duration
+	planned time.Duration // break duration the out splice announced
+	outSCTE []byte
+	inSCTE  []byte // nil for an automatic return after planned
+}
+
+// spliceOf returns the SCTE-35 section in a timed metadata payload and
+// its decoding: an onSCTE35 message's base64 string, or the base64
+// "scte35" field (also "data" for onSCTE35) of the message's object or
+// of its "parameters".
+func spliceOf(payload []byte) ([]byte, scte35.Splice, bool) {
+	r := bytes.NewReader(payload)
+	name, err := amf0.DecodeString(r)
+	if err != nil || name != flv.ScriptSCTE35 && name != flv.ScriptCuePoint {
+		return nil, scte35.Splice{}, false
+	}
+	v, err := amf0.Decode(r)
+	if err != nil {
+		return nil, scte35.Splice{}, false
+	}
+	keys := []string{"scte35"}
+	if name == flv.ScriptSCTE35 {
+		keys = append(keys, "data")
+	}
+	enc, _ := v.(string)
+	if obj, ok := v.(amf0.Object); ok {
+		enc = field(obj, keys)
+		if params, ok := obj["parameters"].(amf0.Object); ok && enc == "" {
+			enc = field(params, keys)
+		}
+	} else if name != flv.ScriptSCTE35 {
+		enc = ""
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(enc))
+	if err != nil || len(raw) == 0 {
+		return nil, scte35.Splice{}, false
+	}
+	s, err := scte35.Parse(raw)
+	return raw, s, err == nil && (s.Out || s.In || s.Cancel)
+}
+
+// field returns the first of keys that is a string in obj.
+func field(obj amf0.Object, keys []string) string {
+	for _, k := range keys {
+		if v, ok := obj[k].(string); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// adBreaks pairs the SCTE-35 splices among cues into ad breaks, in time
+// order. An in splice closes the open break with its event ID, or else
+// the latest open one; a break with a planned duration and no in splice
+// returns automatically; a cancel drops the event's break.
+func adBreaks(cues []Cue) []adBreak {
+	var breaks []adBreak
+	for _, c := range cues {
+		raw, s, ok := spliceOf(c.Payload)
+		if !ok {
+			continue
+		}
+		switch {
+		case s.Cancel:
+			kept := breaks[:0]
+			for _, b := range breaks {
+				if b.id != s.EventID {
+					kept = append(kept, b)
+				}
+			}
+			breaks = kept
+		case s.Out:
+			breaks = append(breaks, adBreak{id: s.EventID, out: c.Time, in: -1, planned: s.Duration, outSCTE: raw})
+		case s.In:
+			open := -1
+			for i := range breaks {
+				if breaks[i].in < 0 && (open < 0 || breaks[i].id == s.EventID) {
+					open = i
+				}
+			}
+			if open < 0 {
+				breaks = append(breaks, adBreak{id: s.EventID, out: -1})
+				open = len(breaks) - 1
+			}
+			breaks[open].in, breaks[open].inSCTE = c.Time, raw
+		}
+	}
+	for i := range breaks {
+		if breaks[i].in < 0 && breaks[i].planned > 0 {
+			breaks[i].in = breaks[i].out + breaks[i].planned
+		}
+	}
+	return breaks
+}
+
+// spliceEdge is one end of an ad break.
+type spliceEdge struct {
+	at  time.Duration
+	out bool
+	b   *adBreak
+}
+
+// edges returns the known ends of breaks in time order.
+func edges(breaks []adBreak) []spliceEdge {
+	var out []spliceEdge
+	for i := range breaks {
+		if b := &breaks[i]; b.out >= 0 {
+			out = append(out, spliceEdge{at: b.out, out: true, b: b})
+		}
+		if b := &breaks[i]; b.in >= 0 {
+			out = append(out, spliceEdge{at: b.in, b: b})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].at < out[j].at })
+	return out
+}
@@ -1,6 +1,7 @@
 // If you are AI: This file builds the ffmpeg command line for one Packager.
-// Single-rendition mode does stream-copy (cheap). ABR mode (Options.Ladder
-// non-empty) transcodes one rendition per rung.
+// Single-rendition mode does stream-copy (cheap), but for audio the format
+// cannot carry, which goes out as AAC. ABR mode (Options.Ladder non-empty)
+// transcodes one rendition per rung.
 
 package pkger
 
@@ -17,6 +18,8 @@ func (p *Packager) ffmpegArgs() []string {
 	common := []string{
 		"-hide_banner", "-loglevel", "warning",
 		"-fflags", "+nobuffer",
+		// Labels our pull as "packager" in the sessions API and latency metrics.
+		"-user_agent", p.userAgent(),
 		"-i", p.sourceURL,
 	}
 	if len(p.opts.Ladder) > 0 {
@@ -26,11 +29,13 @@ func (p *Packager) ffmpegArgs() []string {
 }
 
 // singleRenditionArgs returns the original stream-copy invocation.
-// Cheap — no transcoding — but produces only one variant.
+// Cheap — no video transcoding — but produces only one variant.
 func (p *Packager) singleRenditionArgs(common []string) []string {
 	args := append([]string{}, common...)
 	args = append(args, "-c", "copy")
-	manifestPath := filepath.Join(p.workDir, p.manifest)
+	if p.transcodeAudio {
+		args = append(args, "-c:a", "aac", "-b:a", "128k")
+	}
 	switch p.format {
 	case FormatDASH:
 		segDur := "2"
@@ -42,20 +47,25 @@ func (p *Packager) singleRenditionArgs(common []string) []string {
 			"-seg_duration", segDur,
 			"-window_size", "5",
 			"-remove_at_exit", "1",
-			"-y", manifestPath,
+			"-y", filepath.Join(p.workDir, p.manifest),
 		)
 	}
 	// HLS
 	segPattern := filepath.Join(p.workDir, "seg_%05d.ts")
 	hlsTime := "2"
-	hlsFlags := "delete_segments+independent_segments"
+	// program_date_time stamps segments; the handler rewrites the stamps
+	// to the stream's wall clock.
+	hlsFlags := "delete_segments+independent_segments+program_date_time"
 	hlsArgs := []string{
 		"-f", "hls",
 		"-hls_list_size", "5",
 	}
+	if p.captions != nil {
+		// The subtitle rendition is declared in a master playlist.
+		hlsArgs = append(hlsArgs, "-master_pl_name", p.manifest)
+	}
 	if p.opts.LowLatency {
 		hlsTime = "1"
-		hlsFlags = "delete_segments+independent_segments+program_date_time"
 		hlsArgs = append(hlsArgs, "-hls_segment_type", "fmp4")
 		segPattern = filepath.Join(p.workDir, "seg_%05d.m4s")
 		hlsArgs = append(hlsArgs, "-hls_fmp4_init_filename", "init.mp4")
@@ -64,7 +74,7 @@ func (p *Packager) singleRenditionArgs(common []string) []string {
 		"-hls_time", hlsTime,
 		"-hls_flags", hlsFlags,
 		"-hls_segment_filename", segPattern,
-		"-y", manifestPath,
+		"-y", filepath.Join(p.workDir, p.playlistName()),
 	)
 	return append(args, hlsArgs...)
 }
@@ -152,10 +162,9 @@ func audioBitrate(ladder []LadderRung) int {
 // the per-variant playlist + segment template paths.
 func hlsABRArgs(workDir, manifest string, opts Options, video, audio []LadderRung) []string {
 	hlsTime := "2"
-	hlsFlags := "delete_segments+independent_segments"
+	hlsFlags := "delete_segments+independent_segments+program_date_time"
 	if opts.LowLatency {
 		hlsTime = "1"
-		hlsFlags = "delete_segments+independent_segments+program_date_time"
 	}
 	streamMap := buildVarStreamMap(video, audio)
 	args := []string{
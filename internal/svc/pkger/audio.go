@@ -0,0 +1,70 @@
+// If you are AI: This file decides what the packager does with a stream's
+// audio: copy codecs the output container carries, transcode the rest to
+// AAC, and refuse codecs ffmpeg cannot decode without external libraries.
+// A new packager waits for the first audio tag before deciding.
+
+package pkger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// ErrAudioCodec is returned for streams whose audio can be neither
+// carried as published nor transcoded to AAC.
+var ErrAudioCodec = errors.New("unsupported audio codec")
+
+// audioWait is how long a new packager waits for the stream's first
+// audio tag before treating it as video-only, as the HTTP-FLV handler
+// does for its header flags.
+const audioWait = 2 * time.Second
+
+// tsAudio is the audio MPEG-TS segments carry as published.
+var tsAudio = map[string]bool{"aac": true, "mp3": true, "ac3": true, "eac3": true}
+
+// mp4Audio is the audio fMP4 segments (DASH, low-latency HLS) carry as
+// published.
+var mp4Audio = map[string]bool{"aac": true, "mp3": true, "opus": true, "ac3": true, "eac3": true, "flac": true}
+
+// undecodableAudio lists codecs ffmpeg has no native decoder for.
+var undecodableAudio = map[string]bool{"speex": true}
+
+// audioPlan reports whether the packager must transcode audio in codec
+// (a flv codec name, "" for a video-only stream) to AAC for format. ABR mode
+// transcodes audio regardless.
+func audioPlan(format Format, opts Options, codec string) (bool, error) {
+	carried := tsAudio
+	if format == FormatDASH || opts.LowLatency && len(opts.Ladder) == 0 {
+		carried = mp4Audio
+	}
+	switch {
+	case codec == "" || len(opts.Ladder) == 0 && carried[codec]:
+		return false, nil
+	case undecodableAudio[codec]:
+		return false, fmt.Errorf("%w: %s cannot be packaged for %s", ErrAudioCodec, codec, format)
+	}
+	return len(opts.Ladder) == 0, nil
+}
+
+// waitForAudio polls stream's audio codec until it is known, ctx ends or
+// timeout elapses, and returns its flv name ("" without audio). The
+// packager's audio arguments are fixed at launch, so guessing copy before
+// a G.711 or Speex tag arrives would break the muxer.
+func waitForAudio(ctx context.Context, stream *bus.Stream, timeout time.Duration) string {
+	deadline := time.Now().Add(timeout)
+	for {
+		audio, ok := stream.AudioCodec()
+		if ok || time.Now().After(deadline) {
+			return audio.Name
+		}
+		select {
+		case <-ctx.Done():
+			return ""
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
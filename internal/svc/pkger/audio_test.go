@@ -0,0 +1,78 @@
+// If you are AI: Tests for choosing between copying and transcoding a
+// stream's audio per output format.
+
+package pkger
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// TestAudioPlan copies what the container carries, transcodes the rest
+// and refuses what ffmpeg cannot decode.
+func TestAudioPlan(t *testing.T) {
+	abr := Options{Ladder: []LadderRung{{Name: "720p"}}}
+	cases := []struct {
+		format    Format
+		opts      Options
+		codec     string
+		transcode bool
+		err       bool
+	}{
+		{FormatHLS, Options{}, "", false, false},
+		{FormatHLS, Options{}, "aac", false, false},
+		{FormatHLS, Options{}, "mp3", false, false},
+		{FormatHLS, Options{}, "pcma", true, false},
+		{FormatHLS, Options{}, "opus", true, false},
+		{FormatHLS, Options{LowLatency: true}, "opus", false, false},
+		{FormatDASH, Options{}, "opus", false, false},
+		{FormatDASH, Options{}, "pcmu", true, false},
+		{FormatHLS, abr, "pcma", false, false},
+		{FormatHLS, Options{}, "speex", false, true},
+		{FormatDASH, abr, "speex", false, true},
+	}
+	for _, c := range cases {
+		transcode, err := audioPlan(c.format, c.opts, c.codec)
+		if transcode != c.transcode || (err != nil) != c.err {
+			t.Errorf("%s %+v %q: transcode %v err %v", c.format, c.opts, c.codec, transcode, err)
+		}
+		if err != nil && !errors.Is(err, ErrAudioCodec) {
+			t.Errorf("%q: %v is not ErrAudioCodec", c.codec, err)
+		}
+	}
+}
+
+// TestWaitForAudio waits for a codec that arrives after the packager is
+// requested and gives up on a video-only stream.
+func TestWaitForAudio(t *testing.T) {
+	stream := bus.NewStream(bus.NewStreamKey("live", "cam"))
+	if got := waitForAudio(context.Background(), stream, 100*time.Millisecond); got != "" {
+		t.Fatalf("video-only stream: %q", got)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		stream.SetAudioCodec(bus.AudioCodec{Name: "pcma"})
+	}()
+	if got := waitForAudio(context.Background(), stream, 5*time.Second); got != "pcma" {
+		t.Fatalf("codec = %q, want pcma", got)
+	}
+}
+
+// TestTranscodeAudioArgs re-encodes only the audio of a single rendition.
+func TestTranscodeAudioArgs(t *testing.T) {
+	p := newPackager("live", "cam", FormatHLS, "http://127.0.0.1/live/cam.flv", t.TempDir(), Options{})
+	if args := p.ffmpegArgs(); slices.Contains(args, "-c:a") {
+		t.Errorf("copy mode args %q", args)
+	}
+	p.transcodeAudio = true
+	args := p.ffmpegArgs()
+	i := slices.Index(args, "-c:a")
+	if i < 0 || args[i+1] != "aac" || args[slices.Index(args, "-c")+1] != "copy" {
+		t.Errorf("transcode args %q", args)
+	}
+}
@@ -0,0 +1,186 @@
+// If you are AI: This file puts the stream's wall clock into HLS and DASH
+// manifests. ffmpeg stamps them with its own start time; we know which
+// stream timestamp its HTTP-FLV pull started at, so we rewrite
+// EXT-X-PROGRAM-DATE-TIME and availabilityStartTime from the stream's
+// anchor and add a DASH UTCTiming element for client clock sync. Manifests
+// also get the stream's ad breaks (splice.go) and captions (subtitles.go).
+
+package pkger
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
+)
+
+// ClockSource maps a packager's input to wall-clock time.
+type ClockSource interface {
+	// OutputOrigin returns the wall-clock time of timestamp zero on the
+	// HTTP-FLV output read by the client with userAgent on key.
+	OutputOrigin(key bus.StreamKey, userAgent string) (time.Time, bool)
+}
+
+// Date-time layouts: ffmpeg writes a numeric zone, we write UTC.
+const (
+	ffmpegPDTLayout = "2006-01-02T15:04:05.000-0700"
+	pdtLayout       = "2006-01-02T15:04:05.000Z"
+)
+
+// userAgent identifies this packager's pull so its output origin can be
+// found among the HTTP-FLV sessions.
+func (p *Packager) userAgent() string {
+	return sessions.UserAgentPackager + "/" + string(p.format)
+}
+
+// manifestClock remembers ffmpeg's program date-time of segment 0 per
+// playlist, the time ffmpeg's timeline starts at.
+type manifestClock struct {
+	mu sync.Mutex
+	t0 map[string]time.Time
+}
+
+// rewriteManifest returns the manifest file (relative to the work dir)
+// with wall-clock times from the stream's clock, the stream's ad breaks
+// and its caption rendition, or body unchanged when the clock is unknown.
+func (p *Packager) rewriteManifest(file string, body []byte) []byte {
+	var breaks []adBreak
+	if p.cues != nil {
+		breaks = adBreaks(p.cues.OutputCues(bus.NewStreamKey(p.app, p.name), p.userAgent()))
+	}
+	if p.format == FormatDASH && p.cues != nil {
+		body = addEventStream(addInbandEvents(body), breaks)
+	}
+	if p.captions != nil {
+		body = p.addCaptions(file, body)
+	}
+	origin, ok := p.origin()
+	if !ok {
+		return body
+	}
+	if p.format == FormatDASH {
+		return rewriteMPD(body, origin, time.Now())
+	}
+	body, ok = p.datePlaylist(file, body, origin)
+	if !ok || len(breaks) == 0 {
+		return body
+	}
+	// Only MPEG-TS segments are split; fMP4 ones just get the tags.
+	return markPlaylist(body, origin, breaks, func(uri string) []tsKeyframe {
+		if path.Ext(uri) != ".ts" {
+			return nil
+		}
+		seg, err := os.ReadFile(filepath.Join(p.workDir, filepath.FromSlash(path.Join(path.Dir(file), uri))))
+		if err != nil {
+			return nil
+		}
+		return keyframes(seg)
+	})
+}
+
+// origin returns the wall-clock time of timestamp zero on the packager's
+// input, false while it is unknown.
+func (p *Packager) origin() (time.Time, bool) {
+	if p.clock == nil {
+		return time.Time{}, false
+	}
+	return p.clock.OutputOrigin(bus.NewStreamKey(p.app, p.name), p.userAgent())
+}
+
+// datePlaylist rewrites the program date-times of the HLS playlist file
+// to wall clock from origin, reporting whether ffmpeg's start is known.
+func (p *Packager) datePlaylist(file string, body []byte, origin time.Time) ([]byte, bool) {
+	p.times.mu.Lock()
+	defer p.times.mu.Unlock()
+	if p.times.t0 == nil {
+		p.times.t0 = make(map[string]time.Time)
+	}
+	t0 := p.times.t0[file]
+	body = rewritePlaylist(body, origin, &t0)
+	if !t0.IsZero() {
+		p.times.t0[file] = t0
+	}
+	return body, !t0.IsZero()
+}
+
+// rewritePlaylist moves each EXT-X-PROGRAM-DATE-TIME to origin plus its
+// offset from *t0. A zero *t0 is learned from the first date-time of a
+// playlist that still starts at media sequence 0; until then the playlist
+// is returned as is.
+func rewritePlaylist(body []byte, origin time.Time, t0 *time.Time) []byte {
+	const tag = "#EXT-X-PROGRAM-DATE-TIME:"
+	if !bytes.Contains(body, []byte(tag)) {
+		return body
+	}
+	var out bytes.Buffer
+	first := bytes.Contains(body, []byte("#EXT-X-MEDIA-SEQUENCE:0\n"))
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line := sc.Text()
+		if v, ok := strings.CutPrefix(line, tag); ok {
+			if pdt, err := parsePDT(v); err == nil {
+				if t0.IsZero() && first {
+					*t0 = pdt
+				}
+				if t0.IsZero() {
+					return body
+				}
+				line = tag + origin.Add(pdt.Sub(*t0)).UTC().Format(pdtLayout)
+			}
+			first = false
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// parsePDT parses a program date-time in ffmpeg's layout or RFC 3339.
+func parsePDT(v string) (time.Time, error) {
+	t, err := time.Parse(ffmpegPDTLayout, v)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339Nano, v)
+	}
+	return t, err
+}
+
+// availabilityStart matches the MPD attribute ffmpeg sets to its start time.
+var availabilityStart = regexp.MustCompile(`availabilityStartTime="[^"]*"`)
+
+// adaptationSet matches an AdaptationSet start tag.
+var adaptationSet = regexp.MustCompile(`<AdaptationSet[^>]*[^/]>`)
+
+// addInbandEvents declares the ID3 emsg events of injectEmsg in each
+// adaptation set, so DASH players surface them.
+func addInbandEvents(body []byte) []byte {
+	if bytes.Contains(body, []byte(id3Scheme)) {
+		return body
+	}
+	event := `<InbandEventStream schemeIdUri="` + id3Scheme + `" value="` + emsgValue + `"/>`
+	return adaptationSet.ReplaceAllFunc(body, func(tag []byte) []byte {
+		return append(append([]byte(nil), tag...), event...)
+	})
+}
+
+// rewriteMPD sets availabilityStartTime to origin and adds a direct
+// UTCTiming element carrying now unless the MPD has one.
+func rewriteMPD(body []byte, origin, now time.Time) []byte {
+	body = availabilityStart.ReplaceAll(body, []byte(`availabilityStartTime="`+origin.UTC().Format(pdtLayout)+`"`))
+	if bytes.Contains(body, []byte("<UTCTiming")) {
+		return body
+	}
+	timing := `<UTCTiming schemeIdUri="urn:mpeg:dash:utc:direct:2014" value="` + now.UTC().Format(pdtLayout) + `"/>` + "\n"
+	i := bytes.LastIndex(body, []byte("</MPD>"))
+	if i < 0 {
+		return body
+	}
+	return append(append(body[:i:i], timing...), body[i:]...)
+}
@@ -0,0 +1,56 @@
+// If you are AI: This file unit-tests rewriting ffmpeg's manifest times
+// onto the stream's wall clock.
+
+package pkger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRewritePlaylist learns ffmpeg's start from segment 0 and keeps each
+// segment's offset from it.
+func TestRewritePlaylist(t *testing.T) {
+	origin := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	first := "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:0\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2026-10-18T12:00:00.000+0200\n#EXTINF:2.000,\nseg_00000.ts\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2026-10-18T12:00:02.000+0200\n#EXTINF:2.000,\nseg_00001.ts\n"
+	var t0 time.Time
+	got := string(rewritePlaylist([]byte(first), origin, &t0))
+	for _, want := range []string{
+		"#EXT-X-PROGRAM-DATE-TIME:2026-03-14T09:00:00.000Z\n#EXTINF:2.000,\nseg_00000.ts",
+		"#EXT-X-PROGRAM-DATE-TIME:2026-03-14T09:00:02.000Z\n#EXTINF:2.000,\nseg_00001.ts",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("playlist missing %q:\n%s", want, got)
+		}
+	}
+
+	later := "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:7\n" +
+		"#EXT-X-PROGRAM-DATE-TIME:2026-10-18T10:00:14.000Z\n#EXTINF:2.000,\nseg_00007.ts\n"
+	if got := string(rewritePlaylist([]byte(later), origin, &t0)); !strings.Contains(got, "2026-03-14T09:00:14.000Z") {
+		t.Errorf("later playlist:\n%s", got)
+	}
+
+	var unknown time.Time
+	if got := string(rewritePlaylist([]byte(later), origin, &unknown)); got != later {
+		t.Errorf("playlist rewritten without segment 0:\n%s", got)
+	}
+}
+
+// TestRewriteMPD sets availabilityStartTime and adds UTCTiming once.
+func TestRewriteMPD(t *testing.T) {
+	origin := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	now := origin.Add(time.Minute)
+	mpd := `<MPD type="dynamic" availabilityStartTime="2026-10-18T10:00:00.123Z">` + "\n<Period/>\n</MPD>\n"
+	got := string(rewriteMPD([]byte(mpd), origin, now))
+	want := `<MPD type="dynamic" availabilityStartTime="2026-03-14T09:00:00.000Z">` + "\n<Period/>\n" +
+		`<UTCTiming schemeIdUri="urn:mpeg:dash:utc:direct:2014" value="2026-03-14T09:01:00.000Z"/>` + "\n</MPD>\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if again := string(rewriteMPD([]byte(got), origin, now)); strings.Count(again, "<UTCTiming") != 1 {
+		t.Errorf("UTCTiming added twice:\n%s", again)
+	}
+}
@@ -0,0 +1,172 @@
+// If you are AI: This file puts a stream's timed metadata (onCuePoint,
+// onTextData) into HLS and DASH segments as they are served: ID3 tags in
+// MPEG-TS segments, ID3 in emsg boxes in fMP4 segments. Each tag carries
+// one TXXX frame: the message name and its data as JSON.
+
+package pkger
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// CueSource supplies timed metadata on a packager's input timeline.
+type CueSource interface {
+	// OutputCues returns key's recent timed metadata, timed from the
+	// start of the HTTP-FLV output read by the client with userAgent.
+	OutputCues(key bus.StreamKey, userAgent string) []Cue
+}
+
+// Cue is one timed metadata message on a packager's input timeline.
+type Cue struct {
+	ID      uint64
+	Time    time.Duration
+	Payload []byte // AMF0 script data: name, then value
+}
+
+// id3Scheme identifies ID3 in emsg boxes (AOM "ID3 Timed Metadata in
+// CMAF").
+const id3Scheme = "https://aomedia.org/emsg/ID3"
+
+// maxID3Tag bounds an ID3 tag: a TS PES packet's 16-bit length field
+// must hold the tag plus the PES header.
+const maxID3Tag = 65000
+
+// id3Tag returns an ID3v2.4 tag with one TXXX frame: the script data's
+// name as description and its value as JSON. False if payload does not
+// decode or the tag would exceed maxID3Tag.
+func id3Tag(payload []byte) ([]byte, bool) {
+	r := bytes.NewReader(payload)
+	name, err := amf0.DecodeString(r)
+	if err != nil {
+		return nil, false
+	}
+	v, err := amf0.Decode(r)
+	if err != nil {
+		return nil, false
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	frame := append([]byte{3}, name...) // 3: UTF-8
+	frame = append(append(frame, 0), value...)
+	if len(frame)+20 > maxID3Tag { // tag and frame headers
+		return nil, false
+	}
+	tag := append([]byte("ID3\x04\x00\x00"), syncsafe(len(frame)+10)...)
+	tag = append(append(tag, "TXXX"...), syncsafe(len(frame))...)
+	tag = append(tag, 0, 0) // frame flags
+	return append(tag, frame...), true
+}
+
+// syncsafe encodes n as a 4-byte ID3 synchsafe integer.
+func syncsafe(n int) []byte {
+	return []byte{byte(n>>21) & 0x7F, byte(n>>14) & 0x7F, byte(n>>7) & 0x7F, byte(n) & 0x7F}
+}
+
+// cuesIn returns the cues in [start, end) with their ID3 tags.
+func cuesIn(cues []Cue, start, end time.Duration) []timedTag {
+	var out []timedTag
+	for _, c := range cues {
+		if c.Time < start || c.Time >= end {
+			continue
+		}
+		if tag, ok := id3Tag(c.Payload); ok {
+			out = append(out, timedTag{id: c.ID, at: c.Time, id3: tag})
+		}
+	}
+	return out
+}
+
+// timedTag is an ID3 tag at a time on the packager's input timeline.
+type timedTag struct {
+	id  uint64
+	at  time.Duration
+	id3 []byte
+}
+
+// segmentWithCues returns the segment at full (file relative to the work
+// dir), or the part of it a split playlist names, with the stream's timed
+// metadata in it; false when there is nothing to change and the file can
+// be served as is.
+func (p *Packager) segmentWithCues(file, full string, part tsPart) ([]byte, bool) {
+	ext := filepath.Ext(file)
+	if ext != ".ts" && ext != ".m4s" || strings.HasPrefix(path.Base(file), "init") {
+		return nil, false
+	}
+	var cues []Cue
+	if p.cues != nil {
+		cues = withoutCaptions(p.cues.OutputCues(bus.NewStreamKey(p.app, p.name), p.userAgent()))
+	}
+	if len(cues) == 0 && (ext != ".ts" || part == wholeSegment) {
+		return nil, false
+	}
+	seg, err := os.ReadFile(full)
+	if err != nil {
+		return nil, false
+	}
+	if ext == ".ts" {
+		return p.tsWithCues(file, seg, part, cues)
+	}
+	init, err := os.ReadFile(filepath.Join(filepath.Dir(full), initFor(path.Base(file))))
+	if err != nil {
+		return nil, false
+	}
+	return injectEmsg(seg, init, cues)
+}
+
+// segmentTiming returns where an HLS segment starts on the input timeline
+// and its duration, from ffmpeg's program date-time and EXTINF in the
+// segment's playlist.
+func (p *Packager) segmentTiming(file string) (time.Duration, time.Duration, bool) {
+	playlist := path.Join(path.Dir(file), p.playlistName())
+	p.times.mu.Lock()
+	t0 := p.times.t0[playlist]
+	p.times.mu.Unlock()
+	if t0.IsZero() {
+		return 0, 0, false
+	}
+	body, err := os.ReadFile(filepath.Join(p.workDir, filepath.FromSlash(playlist)))
+	if err != nil {
+		return 0, 0, false
+	}
+	var pdt time.Time
+	var dur time.Duration
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			pdt, _ = parsePDT(strings.TrimPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			v, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			d, err := time.ParseDuration(v + "s")
+			if err != nil {
+				d = 0
+			}
+			dur = d
+		case line == path.Base(file):
+			return pdt.Sub(t0), dur, !pdt.IsZero() && dur > 0
+		}
+	}
+	return 0, 0, false
+}
+
+// initFor returns the init segment name for an fMP4 media segment: ffmpeg's
+// DASH muxer names them per representation, the HLS muxer uses init.mp4.
+func initFor(segment string) string {
+	if rest, ok := strings.CutPrefix(segment, "chunk-stream"); ok {
+		if id, _, ok := strings.Cut(rest, "-"); ok {
+			return "init-stream" + id + ".m4s"
+		}
+	}
+	return "init.mp4"
+}
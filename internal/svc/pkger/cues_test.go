@@ -0,0 +1,182 @@
+// If you are AI: This file unit-tests carrying timed metadata in served
+// segments: ID3 PES in MPEG-TS, emsg in fMP4, with synthetic segments
+// shaped like ffmpeg's.
+
+package pkger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// fakeCues is a CueSource with fixed cues.
+type fakeCues []Cue
+
+// OutputCues returns the fixed cues.
+func (f fakeCues) OutputCues(bus.StreamKey, string) []Cue { return f }
+
+// question returns an onCuePoint payload.
+func question() []byte {
+	var b bytes.Buffer
+	_ = amf0.Encode(&b, "onCuePoint")
+	_ = amf0.Encode(&b, amf0.Object{"name": "question"})
+	return b.Bytes()
+}
+
+// TestID3TagLimit drops a cue too large for one PES packet.
+func TestID3TagLimit(t *testing.T) {
+	var b bytes.Buffer
+	_ = amf0.Encode(&b, "onTextData")
+	_ = amf0.Encode(&b, amf0.Object{"text": strings.Repeat("x", maxID3Tag)})
+	if _, ok := id3Tag(b.Bytes()); ok {
+		t.Fatal("oversized cue was tagged")
+	}
+	if tag, ok := id3Tag(question()); !ok || len(tag) > maxID3Tag {
+		t.Fatalf("question tag %d bytes, ok %v", len(tag), ok)
+	}
+}
+
+// tsPSI wraps a PSI section (CRC appended) in one TS packet on pid.
+func tsPSI(pid int, section []byte) []byte {
+	section = binary.BigEndian.AppendUint32(section, crc32MPEG(section))
+	pkt := bytes.Repeat([]byte{0xFF}, tsPacket)
+	copy(pkt, []byte{0x47, 0x40 | byte(pid>>8), byte(pid), 0x10, 0})
+	copy(pkt[5:], section)
+	return pkt
+}
+
+// tsSegment builds PAT, PMT (one H.264 stream on 0x100) and one video PES
+// at pts.
+func tsSegment(pts int64) []byte {
+	pat := []byte{0x00, 0xB0, 13, 0, 1, 0xC1, 0, 0, 0, 1, 0xF0, 0x00}
+	pmt := []byte{0x02, 0xB0, 18, 0, 1, 0xC1, 0, 0, 0xE1, 0x00, 0xF0, 0, 0x1B, 0xE1, 0x00, 0xF0, 0}
+	var seg []byte
+	seg = append(seg, tsPSI(0, pat)...)
+	seg = append(seg, tsPSI(0x1000, pmt)...)
+	var cc byte
+	video := appendPES(nil, pts, []byte{0, 0, 0, 1, 0x65}, &cc)
+	video[1], video[2] = 0x41, 0x00 // PID 0x100
+	video[9] = 0xE0                 // stream_id: video
+	return append(seg, video...)
+}
+
+// TestSegmentWithCuesTS adds an ID3 stream to the PMT and a PES at the
+// cue's time on the segment's PTS timeline.
+func TestSegmentWithCuesTS(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	mustWriteFile(t, filepath.Join(dir, "index.m3u8"), "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:3\n"+
+		"#EXT-X-PROGRAM-DATE-TIME:2026-10-18T12:00:06.000+0000\n#EXTINF:2.000000,\nseg_00003.ts\n")
+	seg := tsSegment(126000 + 6*90000)
+	mustWriteFile(t, filepath.Join(dir, "seg_00003.ts"), string(seg))
+
+	p := &Packager{app: "live", name: "q", format: FormatHLS, workDir: dir,
+		cues: fakeCues{{ID: 7, Time: 6500 * time.Millisecond, Payload: question()}, {ID: 8, Time: 9 * time.Second, Payload: question()}}}
+	p.times.t0 = map[string]time.Time{"index.m3u8": t0}
+	out, ok := p.segmentWithCues("seg_00003.ts", filepath.Join(dir, "seg_00003.ts"), wholeSegment)
+	if !ok || len(out) != len(seg)+tsPacket {
+		t.Fatalf("ok %v, %d bytes; want one more packet than %d", ok, len(out), len(seg))
+	}
+
+	pmt := tsPayload(out[tsPacket : 2*tsPacket])[1:]
+	length := 3 + int(binary.BigEndian.Uint16(pmt[1:])&0x0FFF)
+	if crc32MPEG(pmt[:length]) != 0 {
+		t.Error("PMT CRC is wrong")
+	}
+	if !bytes.Contains(pmt[:length], []byte{0x15, 0xE0 | id3PID>>8, id3PID & 0xFF}) {
+		t.Errorf("PMT has no ID3 stream: % x", pmt[:length])
+	}
+
+	id3 := out[len(seg):]
+	if pid := int(id3[1]&0x1F)<<8 | int(id3[2]); pid != id3PID || id3[1]&0x40 == 0 {
+		t.Fatalf("metadata packet PID %#x", pid)
+	}
+	pes := tsPayload(id3)
+	if pts, _ := pesPTS(pes); pts != 126000+6*90000+45000 {
+		t.Errorf("ID3 PTS = %d, want %d", pts, 126000+6*90000+45000)
+	}
+	if tag := pes[14:]; !bytes.HasPrefix(tag, []byte("ID3\x04")) || !bytes.Contains(tag, []byte(`TXXX`)) ||
+		!bytes.Contains(tag, []byte("onCuePoint\x00{\"name\":\"question\"}")) {
+		t.Errorf("ID3 tag = %q", tag)
+	}
+}
+
+// box builds an MP4 box.
+func box(typ string, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	return append(binary.BigEndian.AppendUint32(nil, uint32(8+len(body))), append([]byte(typ), body...)...)
+}
+
+// u32 encodes big-endian 32-bit values.
+func u32(vs ...uint32) []byte {
+	var b []byte
+	for _, v := range vs {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+// TestSegmentWithCuesMP4 puts an emsg ahead of the fragment that covers
+// the cue and declares the events in the MPD.
+func TestSegmentWithCuesMP4(t *testing.T) {
+	dir := t.TempDir()
+	init := box("moov", box("trak",
+		box("tkhd", u32(0, 0, 0, 1)),
+		box("mdia", box("mdhd", u32(0, 0, 0, 90000)))))
+	mustWriteFile(t, filepath.Join(dir, "init-stream0.m4s"), string(init))
+	traf := box("traf",
+		box("tfhd", u32(0x020000, 1)),
+		box("tfdt", append([]byte{1, 0, 0, 0}, binary.BigEndian.AppendUint64(nil, 180000)...)),
+		box("trun", u32(0x000301, 2, 0, 45000, 10, 45000, 10)))
+	seg := append(box("styp", []byte("msdh")), box("moof", box("mfhd", u32(0, 5)), traf)...)
+	seg = append(seg, box("mdat", make([]byte, 20))...)
+	mustWriteFile(t, filepath.Join(dir, "chunk-stream0-00005.m4s"), string(seg))
+
+	p := &Packager{app: "live", name: "q", format: FormatDASH, workDir: dir,
+		cues: fakeCues{{ID: 3, Time: 2500 * time.Millisecond, Payload: question()}, {ID: 4, Time: 3 * time.Second, Payload: question()}}}
+	out, ok := p.segmentWithCues("chunk-stream0-00005.m4s", filepath.Join(dir, "chunk-stream0-00005.m4s"), wholeSegment)
+	if !ok {
+		t.Fatal("no emsg added")
+	}
+	var types []string
+	walkBoxes(out, func(typ string, body []byte, _ int) bool {
+		types = append(types, typ)
+		if typ == "emsg" {
+			if at := binary.BigEndian.Uint64(body[8:]); at != 2500 {
+				t.Errorf("emsg presentation_time = %d, want 2500", at)
+			}
+			if id := binary.BigEndian.Uint32(body[20:]); id != 3 {
+				t.Errorf("emsg id = %d, want 3", id)
+			}
+			if !bytes.Contains(body, []byte(id3Scheme+"\x00"+emsgValue+"\x00ID3")) {
+				t.Errorf("emsg body = %q", body)
+			}
+		}
+		return true
+	})
+	if got := strings.Join(types, ","); got != "styp,emsg,moof,mdat" {
+		t.Errorf("boxes = %s", got)
+	}
+
+	mpd := `<MPD><Period><AdaptationSet id="0" contentType="video"><Representation/></AdaptationSet></Period></MPD>`
+	got := string(p.rewriteManifest("manifest.mpd", []byte(mpd)))
+	if want := `<AdaptationSet id="0" contentType="video"><InbandEventStream schemeIdUri="` + id3Scheme + `" value="0"/>`; !strings.Contains(got, want) {
+		t.Errorf("MPD = %s", got)
+	}
+}
+
+// mustWriteFile writes a test fixture.
+func mustWriteFile(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
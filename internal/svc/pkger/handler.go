@@ -4,17 +4,25 @@
 package pkger
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"net/http"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
+
+	"nonchalant/internal/auth"
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
 )
 
 // Handler serves packaged HLS / DASH files via HTTP.
 type Handler struct {
-	mgr *Manager
+	mgr   *Manager
+	table *sessions.Table // nil unless SetSessionTable was called
 }
 
 // NewHandler binds a handler to the given manager.
@@ -30,12 +38,12 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 // serveHLS routes /hls/{app}/{name}.m3u8 plus its segments.
 // In LL-HLS mode the segments are .m4s + a top-level init.mp4.
 func (h *Handler) serveHLS(w http.ResponseWriter, r *http.Request) {
-	h.serve(w, r, FormatHLS, "/hls/", []string{".m3u8", ".ts", ".m4s", ".mp4"})
+	h.serve(w, r, FormatHLS, "/hls/", []string{".m3u8", ".ts", ".m4s", ".mp4", ".vtt"})
 }
 
 // serveDASH routes /dash/{app}/{name}.mpd and /dash/{app}/{name}/{file}.
 func (h *Handler) serveDASH(w http.ResponseWriter, r *http.Request) {
-	h.serve(w, r, FormatDASH, "/dash/", []string{".mpd", ".m4s", ".mp4"})
+	h.serve(w, r, FormatDASH, "/dash/", []string{".mpd", ".m4s", ".mp4", ".vtt"})
 }
 
 // serve resolves the packager and serves the requested file from its work dir.
@@ -70,11 +78,29 @@ func (h *Handler) serve(w http.ResponseWriter, r *http.Request, format Format, p
 		return
 	}
 
-	pkg, err := h.mgr.GetOrCreate(app, name, format)
+	pkg, err := h.mgr.GetOrCreate(r.Context(), app, name, format)
+	if errors.Is(err, bus.ErrInvalidName) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, ErrAudioCodec) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
+	// HLS / DASH viewers have no connection to track: each request extends
+	// the client's session, and a kicked client is refused for a while.
+	sess, ok := h.table.TouchAs(string(format), bus.NewStreamKey(app, name), r.RemoteAddr, r.UserAgent(), auth.Subject(r))
+	if !ok {
+		http.Error(w, "session ended", http.StatusForbidden)
+		return
+	}
+	if sess != nil {
+		w = &countingResponseWriter{ResponseWriter: w, sess: sess}
+	}
 	pkg.Touch()
 
 	// Treat a request whose tail filename equals the packager's manifest
@@ -102,12 +128,60 @@ func (h *Handler) serve(w http.ResponseWriter, r *http.Request, format Format, p
 	// this they fail with a CORS preflight error.
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Headers", "Range")
-	if strings.HasSuffix(file, ".m3u8") {
+	if strings.HasPrefix(file, captionDir+"/") {
+		// The caption rendition is made from timed metadata on each read.
+		body, ok := pkg.captionFile(file)
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if strings.HasSuffix(file, ".m3u8") {
+			w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		} else {
+			w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		}
+		http.ServeContent(w, r, file, time.Time{}, bytes.NewReader(body))
+		return
+	}
+	switch {
+	case strings.HasSuffix(file, ".m3u8"):
 		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
-	} else if strings.HasSuffix(file, ".mpd") {
+	case strings.HasSuffix(file, ".mpd"):
 		w.Header().Set("Content-Type", "application/dash+xml")
+	default:
+		// Segments carry the stream's timed metadata, added on each read.
+		if body, ok := pkg.segmentWithCues(file, full, partOf(r.URL.Query())); ok {
+			http.ServeContent(w, r, file, time.Time{}, bytes.NewReader(body))
+		} else {
+			http.ServeFile(w, r, full)
+		}
+		return
+	}
+	// Manifests carry wall-clock times, rewritten on each read.
+	body, err := os.ReadFile(full)
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
 	}
-	http.ServeFile(w, r, full)
+	body = pkg.rewriteManifest(file, body)
+	http.ServeContent(w, r, file, time.Time{}, bytes.NewReader(body))
+}
+
+// SetSessionTable registers HLS / DASH viewers in t so they can be listed
+// and kicked through the API.
+func (h *Handler) SetSessionTable(t *sessions.Table) { h.table = t }
+
+// countingResponseWriter counts the body bytes sent on a viewer's session.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	sess *sessions.Session
+}
+
+// Write writes p and counts what was written.
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.sess.AddSent(n)
+	return n, err
 }
 
 // splitPath parses URLs of the canonical form:
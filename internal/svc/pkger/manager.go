@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
 )
 
 // Manager owns and reaps packager instances.
@@ -28,6 +29,9 @@ type Manager struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
 	gcDone  chan struct{}
+	events  *events.Bus // packager start / exit; nil emits nothing
+	clock   ClockSource // wall clock for manifests; nil keeps ffmpeg's
+	cues    CueSource   // timed metadata for segments; nil adds none
 }
 
 // NewManager creates a Manager. httpPort is used to construct the source URL
@@ -54,16 +58,47 @@ func NewManager(registry *bus.Registry, httpPort int, idleTTL time.Duration, opt
 	return m, nil
 }
 
+// SetEvents emits PackagerStarted and PackagerExited on b.
+func (m *Manager) SetEvents(b *events.Bus) {
+	m.mu.Lock()
+	m.events = b
+	m.mu.Unlock()
+}
+
+// SetClock rewrites manifest times from c.
+func (m *Manager) SetClock(c ClockSource) {
+	m.mu.Lock()
+	m.clock = c
+	m.mu.Unlock()
+}
+
+// SetCues adds timed metadata from c to segments.
+func (m *Manager) SetCues(c CueSource) {
+	m.mu.Lock()
+	m.cues = c
+	m.mu.Unlock()
+}
+
 // GetOrCreate returns the live packager for (app,name,format), starting one
 // if necessary. Returns an error if the underlying stream has no publisher
-// or if ffmpeg fails to launch.
-func (m *Manager) GetOrCreate(app, name string, format Format) (*Packager, error) {
-	stream := m.registry.Get(bus.NewStreamKey(app, name))
-	if stream == nil || !stream.HasPublisher() {
+// (and cannot be pulled on demand), if its audio cannot be packaged
+// (ErrAudioCodec) or if ffmpeg fails to launch. Names
+// that fail bus validation (they become a work dir name) wrap
+// bus.ErrInvalidName.
+func (m *Manager) GetOrCreate(ctx context.Context, app, name string, format Format) (*Packager, error) {
+	if err := bus.NewStreamKey(app, name).Validate(); err != nil {
+		return nil, err
+	}
+	stream := m.registry.Live(ctx, bus.NewStreamKey(app, name))
+	if stream == nil {
 		return nil, fmt.Errorf("stream not live: %s/%s", app, name)
 	}
 
 	key := keyFor(app, name, format)
+	if p := m.existing(key); p != nil {
+		return p, nil
+	}
+	audio := waitForAudio(ctx, stream, audioWait) // outside the lock
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if p, ok := m.pkgers[key]; ok {
@@ -73,7 +108,15 @@ func (m *Manager) GetOrCreate(app, name string, format Format) (*Packager, error
 
 	workDir := filepath.Join(m.rootDir, fmt.Sprintf("%s-%s-%s", app, name, format))
 	sourceURL := fmt.Sprintf("http://127.0.0.1:%d/%s/%s.flv", m.httpPort, app, name)
+	transcodeAudio, err := audioPlan(format, m.opts, audio)
+	if err != nil {
+		return nil, err
+	}
 	p := newPackager(app, name, format, sourceURL, workDir, m.opts)
+	p.transcodeAudio = transcodeAudio
+	p.events = m.events
+	p.clock = m.clock
+	p.cues = m.cues
 	if err := p.Start(m.ctx); err != nil {
 		return nil, err
 	}
@@ -81,6 +124,17 @@ func (m *Manager) GetOrCreate(app, name string, format Format) (*Packager, error
 	return p, nil
 }
 
+// existing returns the live packager for key, touched, or nil.
+func (m *Manager) existing(key string) *Packager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := m.pkgers[key]
+	if p != nil {
+		p.Touch()
+	}
+	return p
+}
+
 // Stop terminates all running packagers and removes the root temp dir.
 func (m *Manager) Stop() {
 	m.mu.Lock()
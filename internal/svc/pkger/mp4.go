@@ -0,0 +1,196 @@
+// If you are AI: This file adds ID3 timed metadata to fMP4 segments (DASH
+// and low-latency HLS) as emsg boxes. It reads just enough of the boxes to
+// place the segment on the timeline: track timescales from the init
+// segment, decode time and sample durations from each moof.
+
+package pkger
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// emsgValue is the emsg value paired with id3Scheme, also declared in
+// the MPD's InbandEventStream.
+const emsgValue = "0"
+
+// walkBoxes calls fn with the type, payload and offset of each box in b,
+// stopping early if fn returns false. Returns false on a malformed box.
+func walkBoxes(b []byte, fn func(typ string, body []byte, at int) bool) bool {
+	for at := 0; at < len(b); {
+		if len(b)-at < 8 {
+			return false
+		}
+		size, hdr := int(binary.BigEndian.Uint32(b[at:])), 8
+		switch size {
+		case 0:
+			size = len(b) - at
+		case 1:
+			if len(b)-at < 16 {
+				return false
+			}
+			size, hdr = int(binary.BigEndian.Uint64(b[at+8:])), 16
+		}
+		if size < hdr || size > len(b)-at {
+			return false
+		}
+		if !fn(string(b[at+4:at+8]), b[at+hdr:at+size], at) {
+			return true
+		}
+		at += size
+	}
+	return true
+}
+
+// child returns the payload of the first box of typ in b.
+func child(b []byte, typ string) []byte {
+	var out []byte
+	walkBoxes(b, func(t string, body []byte, _ int) bool {
+		if t == typ {
+			out = body
+			return false
+		}
+		return true
+	})
+	return out
+}
+
+// timescales maps track ID to media timescale from an init segment.
+func timescales(init []byte) map[uint32]uint32 {
+	out := make(map[uint32]uint32)
+	walkBoxes(child(init, "moov"), func(typ string, trak []byte, _ int) bool {
+		if typ != "trak" {
+			return true
+		}
+		tkhd, mdhd := child(trak, "tkhd"), child(child(trak, "mdia"), "mdhd")
+		idAt, scaleAt := 12, 12 // version 0: 32-bit times
+		if len(tkhd) > 0 && tkhd[0] == 1 {
+			idAt = 20
+		}
+		if len(mdhd) > 0 && mdhd[0] == 1 {
+			scaleAt = 20
+		}
+		if len(tkhd) >= idAt+4 && len(mdhd) >= scaleAt+4 {
+			out[binary.BigEndian.Uint32(tkhd[idAt:])] = binary.BigEndian.Uint32(mdhd[scaleAt:])
+		}
+		return true
+	})
+	return out
+}
+
+// fragmentSpan returns the decode time and duration of a traf, in its
+// track's timescale, and the track ID.
+func fragmentSpan(traf []byte) (track uint32, start, dur uint64, ok bool) {
+	tfhd, tfdt, trun := child(traf, "tfhd"), child(traf, "tfdt"), child(traf, "trun")
+	if len(tfhd) < 8 || len(tfdt) < 8 || len(trun) < 8 {
+		return 0, 0, 0, false
+	}
+	track = binary.BigEndian.Uint32(tfhd[4:])
+	var defDur uint64
+	if flags := binary.BigEndian.Uint32(tfhd) & 0xFFFFFF; flags&0x08 != 0 {
+		at := 8
+		if flags&0x01 != 0 {
+			at += 8
+		}
+		if flags&0x02 != 0 {
+			at += 4
+		}
+		if len(tfhd) < at+4 {
+			return 0, 0, 0, false
+		}
+		defDur = uint64(binary.BigEndian.Uint32(tfhd[at:]))
+	}
+	if tfdt[0] == 1 {
+		if len(tfdt) < 12 {
+			return 0, 0, 0, false
+		}
+		start = binary.BigEndian.Uint64(tfdt[4:])
+	} else {
+		start = uint64(binary.BigEndian.Uint32(tfdt[4:]))
+	}
+
+	flags := binary.BigEndian.Uint32(trun) & 0xFFFFFF
+	count := int(binary.BigEndian.Uint32(trun[4:]))
+	at := 8
+	if flags&0x01 != 0 {
+		at += 4
+	}
+	if flags&0x04 != 0 {
+		at += 4
+	}
+	if flags&0x100 == 0 {
+		return track, start, defDur * uint64(count), true
+	}
+	stride := 4
+	for _, f := range []uint32{0x200, 0x400, 0x800} {
+		if flags&f != 0 {
+			stride += 4
+		}
+	}
+	if count < 0 || len(trun) < at+count*stride {
+		return 0, 0, 0, false
+	}
+	for i := 0; i < count; i++ {
+		dur += uint64(binary.BigEndian.Uint32(trun[at+i*stride:]))
+	}
+	return track, start, dur, true
+}
+
+// injectEmsg returns seg with an emsg box for each cue inside it, ahead of
+// its first fragment. False if there is none or seg cannot be read.
+func injectEmsg(seg, init []byte, cues []Cue) ([]byte, bool) {
+	scales := timescales(init)
+	var track uint32
+	var from, to time.Duration
+	insert, found := -1, false
+	walkBoxes(seg, func(typ string, body []byte, at int) bool {
+		if typ == "sidx" && insert < 0 {
+			insert = at
+		}
+		if typ != "moof" {
+			return true
+		}
+		if insert < 0 {
+			insert = at
+		}
+		id, start, dur, ok := fragmentSpan(child(body, "traf"))
+		if !ok || scales[id] == 0 || (found && id != track) {
+			return true
+		}
+		scale := time.Duration(scales[id])
+		s, e := time.Duration(start)*time.Second/scale, time.Duration(start+dur)*time.Second/scale
+		if !found {
+			track, from, to, found = id, s, e, true
+		}
+		from, to = min(from, s), max(to, e)
+		return true
+	})
+	if !found {
+		return nil, false
+	}
+	tags := cuesIn(cues, from, to)
+	if len(tags) == 0 {
+		return nil, false
+	}
+	var boxes []byte
+	for _, t := range tags {
+		boxes = append(boxes, emsgBox(t)...)
+	}
+	out := append(append(append([]byte(nil), seg[:insert]...), boxes...), seg[insert:]...)
+	return out, true
+}
+
+// emsgBox returns a version 1 emsg box carrying t's ID3 tag at its
+// absolute time, in milliseconds.
+func emsgBox(t timedTag) []byte {
+	b := []byte{0, 0, 0, 0, 'e', 'm', 's', 'g', 1, 0, 0, 0}
+	b = binary.BigEndian.AppendUint32(b, 1000) // timescale
+	b = binary.BigEndian.AppendUint64(b, uint64(t.at/time.Millisecond))
+	b = binary.BigEndian.AppendUint32(b, 0) // event_duration
+	b = binary.BigEndian.AppendUint32(b, uint32(t.id))
+	b = append(append(b, id3Scheme...), 0)
+	b = append(append(b, emsgValue...), 0)
+	b = append(b, t.id3...)
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
@@ -13,6 +13,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"nonchalant/internal/events"
 )
 
 // Format selects the packaging output type.
@@ -34,6 +36,9 @@ type Packager struct {
 	workDir   string
 	manifest  string
 	opts      Options
+	captions  *CaptionTrack // nil: no subtitle rendition
+
+	transcodeAudio bool // set by the Manager: audio the format cannot carry goes out as AAC
 
 	mu         sync.Mutex
 	cmd        *exec.Cmd
@@ -42,6 +47,11 @@ type Packager struct {
 	lastAccess time.Time
 	stopped    bool
 	startErr   error
+
+	events *events.Bus   // set by the Manager before Start; nil emits nothing
+	clock  ClockSource   // set by the Manager before Start; nil keeps ffmpeg's times
+	times  manifestClock // ffmpeg's segment 0 date-time per playlist
+	cues   CueSource     // set by the Manager before Start; nil adds no metadata
 }
 
 // newPackager allocates a packager. It does not start ffmpeg yet — call Start.
@@ -55,6 +65,7 @@ func newPackager(app, name string, format Format, sourceURL, workDir string, opt
 		workDir:   workDir,
 		manifest:  manifest,
 		opts:      opts,
+		captions:  opts.captionTrack(app),
 	}
 }
 
@@ -100,6 +111,7 @@ func (p *Packager) Start(parent context.Context) error {
 	p.cancel = cancel
 	p.startedAt = time.Now()
 	p.lastAccess = p.startedAt
+	p.emit(events.PackagerStarted, nil)
 	go p.reap()
 	return nil
 }
@@ -107,13 +119,27 @@ func (p *Packager) Start(parent context.Context) error {
 // (ffmpegArgs lives in args.go.)
 
 // reap waits for the subprocess and cleans up the work dir.
-// On normal stop (Stop -> cancel) the work dir is removed.
+// On normal stop (Stop -> cancel) the work dir is removed. The exit is
+// emitted with ffmpeg's error unless the packager was stopped on purpose.
 func (p *Packager) reap() {
-	_ = p.cmd.Wait()
+	err := p.cmd.Wait()
 	p.mu.Lock()
 	p.stopped = true
+	if p.cancel == nil {
+		err = nil // Stop killed it
+	}
 	p.mu.Unlock()
 	_ = os.RemoveAll(p.workDir)
+	p.emit(events.PackagerExited, err)
+}
+
+// emit reports a packager lifecycle event; the format goes in Protocol.
+func (p *Packager) emit(typ events.Type, err error) {
+	e := events.Event{Type: typ, App: p.app, Name: p.name, Protocol: string(p.format)}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	p.events.Emit(e)
 }
 
 // WaitReady blocks until the manifest file is non-empty, the context is done,
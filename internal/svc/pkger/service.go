@@ -9,6 +9,8 @@ import (
 
 	"nonchalant/internal/auth"
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
+	"nonchalant/internal/sessions"
 )
 
 // Service exposes HLS and DASH packaging via HTTP.
@@ -21,8 +23,9 @@ type Service struct {
 // Options bundles the optional knobs accepted by NewService so we can grow
 // the configuration surface without breaking callers.
 type Options struct {
-	LowLatency bool         // see HLSConfig.LowLatency
-	Ladder     []LadderRung // empty = single-rendition stream-copy mode
+	LowLatency bool                    // see HLSConfig.LowLatency
+	Ladder     []LadderRung            // empty = single-rendition stream-copy mode
+	Captions   map[string]CaptionTrack // by app, "*" for the rest: WebVTT rendition of decoded captions
 }
 
 // LadderRung is the pkger-local view of a single ABR rendition.
@@ -51,6 +54,20 @@ func NewService(reg *bus.Registry, httpPort int, playKeys *auth.KeySet, opts Opt
 	}, nil
 }
 
+// SetEvents emits packager starts and exits on b. Optional.
+func (s *Service) SetEvents(b *events.Bus) { s.mgr.SetEvents(b) }
+
+// SetClock stamps HLS and DASH manifests with the stream's wall clock from
+// c. Optional.
+func (s *Service) SetClock(c ClockSource) { s.mgr.SetClock(c) }
+
+// SetCues carries timed metadata from c in HLS and DASH segments.
+// Optional.
+func (s *Service) SetCues(c CueSource) { s.mgr.SetCues(c) }
+
+// SetSessionTable registers HLS / DASH viewers in t. Optional.
+func (s *Service) SetSessionTable(t *sessions.Table) { s.handler.SetSessionTable(t) }
+
 // RegisterRoutes mounts /hls/ and /dash/ on the supplied mux.
 // When play keys are configured both prefixes are gated by auth.Gate.
 func (s *Service) RegisterRoutes(mux *http.ServeMux) {
@@ -0,0 +1,231 @@
+// If you are AI: This file marks SCTE-35 ad breaks (see adbreak.go) in
+// HLS and DASH manifests. HLS media playlists get EXT-X-DATERANGE with
+// the SCTE35-OUT / SCTE35-IN bytes plus the EXT-X-CUE-OUT /
+// -CUE-OUT-CONT / -CUE-IN tags, with segments split at the keyframe
+// where a splice lands; DASH periods get an EventStream.
+
+package pkger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// spliceTolerance is how far a segment start or keyframe may sit from a
+// splice point and still be taken as it.
+const spliceTolerance = 100 * time.Millisecond
+
+// scte35Scheme identifies SCTE-35 binary signals in a DASH EventStream.
+const scte35Scheme = "urn:scte:scte35:2014:xml+bin"
+
+// markPlaylist adds ad break tags to an HLS media playlist whose program
+// date-times are wall clock from origin, splitting segments at the video
+// keyframe where a splice lands inside one. keyframes returns a
+// segment's keyframes, the first at the segment's start.
+func markPlaylist(body []byte, origin time.Time, breaks []adBreak, keyframes func(uri string) []tsKeyframe) []byte {
+	all := edges(breaks)
+	if len(all) == 0 {
+		return body
+	}
+	var out bytes.Buffer
+	var pdtLine, extinf string
+	var prev time.Duration
+	first := true
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			pdtLine = line
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			extinf = line
+			continue
+		case line == "" || strings.HasPrefix(line, "#"):
+			out.WriteString(line + "\n")
+			continue
+		}
+		pdt, err := parsePDT(strings.TrimPrefix(pdtLine, "#EXT-X-PROGRAM-DATE-TIME:"))
+		dur := extinfDuration(extinf)
+		if pdtLine == "" || err != nil || dur <= 0 {
+			writeLines(&out, pdtLine, extinf, line)
+		} else {
+			start := pdt.Sub(origin)
+			parts := splitAt(start, start+dur, all, func() []tsKeyframe { return keyframes(line) })
+			for _, part := range parts {
+				writeBreakTags(&out, origin, all, prev, part.start, first)
+				prev, first = part.start, false
+				if len(parts) == 1 {
+					writeLines(&out, pdtLine, extinf, line)
+					continue
+				}
+				writeLines(&out, "#EXT-X-PROGRAM-DATE-TIME:"+origin.Add(part.start).UTC().Format(pdtLayout),
+					"#EXTINF:"+seconds(part.end-part.start)+",", line+part.query())
+			}
+		}
+		pdtLine, extinf = "", ""
+	}
+	return out.Bytes()
+}
+
+// segmentPart is a part of a segment between splice points.
+type segmentPart struct {
+	start, end time.Duration
+	tsPart
+}
+
+// splitAt splits the segment [start, end) at the first keyframe at or
+// after each splice edge inside it.
+func splitAt(start, end time.Duration, all []spliceEdge, load func() []tsKeyframe) []segmentPart {
+	parts := []segmentPart{{start: start, end: end, tsPart: wholeSegment}}
+	var kfs []tsKeyframe
+	for _, e := range all {
+		if e.at <= start+spliceTolerance || e.at >= end-spliceTolerance {
+			continue
+		}
+		if kfs == nil {
+			if kfs = load(); len(kfs) == 0 {
+				return parts
+			}
+		}
+		for _, k := range kfs {
+			at := start + ptsDuration(k.pts-kfs[0].pts)
+			last := &parts[len(parts)-1]
+			if at >= e.at-spliceTolerance && at > last.start+spliceTolerance && at < end-spliceTolerance {
+				last.end, last.to = at, k.pts
+				parts = append(parts, segmentPart{start: at, end: end, tsPart: tsPart{from: k.pts, to: -1}})
+				break
+			}
+		}
+	}
+	return parts
+}
+
+// writeBreakTags writes the ad break tags for a segment (part) starting
+// at start, after one starting at prev unless first: the edges since
+// prev, or else CUE-OUT-CONT inside a break. The first segment of a
+// playlist only takes edges at its start.
+func writeBreakTags(out *bytes.Buffer, origin time.Time, all []spliceEdge, prev, start time.Duration, first bool) {
+	wrote := false
+	for _, e := range all {
+		since := e.at > prev+spliceTolerance
+		if first {
+			since = e.at >= start-spliceTolerance
+		}
+		if !since || e.at > start+spliceTolerance {
+			continue
+		}
+		wrote = true
+		b := e.b
+		id := `ID="splice-` + strconv.FormatUint(uint64(b.id), 10) + "-" + strconv.FormatInt(b.sortKey().Milliseconds(), 10) + `"`
+		date := `START-DATE="` + origin.Add(b.sortKey()).UTC().Format(pdtLayout) + `"`
+		if e.out {
+			attrs := id + "," + date
+			cue := "#EXT-X-CUE-OUT"
+			if b.planned > 0 {
+				attrs += ",PLANNED-DURATION=" + seconds(b.planned)
+				cue += ":DURATION=" + seconds(b.planned)
+			}
+			out.WriteString(fmt.Sprintf("#EXT-X-DATERANGE:%s,SCTE35-OUT=0x%X\n%s\n", attrs, b.outSCTE, cue))
+			continue
+		}
+		if b.inSCTE != nil {
+			attrs := id + "," + date
+			if b.out >= 0 {
+				attrs += `,END-DATE="` + origin.Add(b.in).UTC().Format(pdtLayout) + `"`
+			}
+			out.WriteString(fmt.Sprintf("#EXT-X-DATERANGE:%s,SCTE35-IN=0x%X\n", attrs, b.inSCTE))
+		}
+		out.WriteString("#EXT-X-CUE-IN\n")
+	}
+	if wrote {
+		return
+	}
+	for _, e := range all {
+		b := e.b
+		if e.out && b.out < start && (b.in < 0 || start < b.in-spliceTolerance) {
+			cont := "#EXT-X-CUE-OUT-CONT:ElapsedTime=" + seconds(start-b.out)
+			if b.planned > 0 {
+				cont += ",Duration=" + seconds(b.planned)
+			}
+			out.WriteString(cont + "\n")
+			return
+		}
+	}
+}
+
+// sortKey is the time a break is known by: its out splice, or its in
+// splice when the out is gone from the cue log.
+func (b *adBreak) sortKey() time.Duration {
+	if b.out >= 0 {
+		return b.out
+	}
+	return b.in
+}
+
+// writeLines writes the non-empty lines.
+func writeLines(out *bytes.Buffer, lines ...string) {
+	for _, l := range lines {
+		if l != "" {
+			out.WriteString(l + "\n")
+		}
+	}
+}
+
+// extinfDuration parses an #EXTINF line's duration, 0 if it has none.
+func extinfDuration(line string) time.Duration {
+	v, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+	d, err := time.ParseDuration(v + "s")
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// seconds formats d as decimal seconds with millisecond precision.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// periodStart matches a Period start tag.
+var periodStart = regexp.MustCompile(`<Period[^>]*[^/]>`)
+
+// addEventStream adds the breaks' splices to each DASH period as an
+// SCTE-35 EventStream, timed in milliseconds on the period's timeline.
+func addEventStream(body []byte, breaks []adBreak) []byte {
+	if bytes.Contains(body, []byte(scte35Scheme)) {
+		return body
+	}
+	var events strings.Builder
+	for _, e := range edges(breaks) {
+		raw, dur := e.b.inSCTE, time.Duration(0)
+		if e.out {
+			raw, dur = e.b.outSCTE, e.b.planned
+			if e.b.in >= 0 {
+				dur = e.b.in - e.b.out
+			}
+		}
+		if raw == nil {
+			continue
+		}
+		fmt.Fprintf(&events, `<Event presentationTime="%d"`, e.at.Milliseconds())
+		if dur > 0 {
+			fmt.Fprintf(&events, ` duration="%d"`, dur.Milliseconds())
+		}
+		fmt.Fprintf(&events, ` id="%d"><Signal xmlns="http://www.scte.org/schemas/35/2016"><Binary>%s</Binary></Signal></Event>`,
+			e.at.Milliseconds(), base64.StdEncoding.EncodeToString(raw))
+	}
+	if events.Len() == 0 {
+		return body
+	}
+	stream := `<EventStream schemeIdUri="` + scte35Scheme + `" timescale="1000">` + events.String() + `</EventStream>`
+	return periodStart.ReplaceAllFunc(body, func(tag []byte) []byte {
+		return append(append([]byte(nil), tag...), stream...)
+	})
+}
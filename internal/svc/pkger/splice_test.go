@@ -0,0 +1,152 @@
+// If you are AI: This file unit-tests SCTE-35 ad break marking: pairing
+// splices into breaks, HLS tags with segments split at splice keyframes,
+// cutting TS parts and the DASH EventStream.
+
+package pkger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// spliceInsert builds an immediate splice_insert section.
+func spliceInsert(id uint32, out bool, dur time.Duration) []byte {
+	flags := byte(0x5F) // program_splice_flag, splice_immediate_flag
+	if out {
+		flags |= 0x80
+	}
+	if dur > 0 {
+		flags |= 0x20
+	}
+	cmd := append(binary.BigEndian.AppendUint32(nil, id), 0x7F, flags)
+	if dur > 0 {
+		t := uint64(dur / (time.Second / 1000) * 90)
+		cmd = append(cmd, 0xFE|byte(t>>32), byte(t>>24), byte(t>>16), byte(t>>8), byte(t))
+	}
+	cmd = append(cmd, 0, 0, 0, 0)
+	sec := []byte{0xFC, 0x30, 0, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0xF0, byte(len(cmd)), 0x05}
+	sec = append(append(sec, cmd...), 0, 0, 0, 0, 0, 0) // no descriptors, CRC
+	sec[2] = byte(len(sec) - 3)
+	return sec
+}
+
+// scriptCue returns a cue at ms carrying name and value as script data.
+func scriptCue(ms int, name string, value amf0.Value) Cue {
+	var b bytes.Buffer
+	_ = amf0.Encode(&b, name)
+	_ = amf0.Encode(&b, value)
+	return Cue{ID: uint64(ms), Time: time.Duration(ms) * time.Millisecond, Payload: b.Bytes()}
+}
+
+// b64 encodes a section for a script data field.
+func b64(b []byte) string { return base64.StdEncoding.EncodeToString(b) }
+
+// TestAdBreaks pairs out and in splices from onSCTE35 and onCuePoint.
+func TestAdBreaks(t *testing.T) {
+	cues := []Cue{
+		scriptCue(3000, "onSCTE35", amf0.Object{"data": b64(spliceInsert(1, true, 2*time.Second))}),
+		scriptCue(4000, "onCuePoint", amf0.Object{"name": "question"}),
+		scriptCue(10000, "onCuePoint", amf0.Object{"parameters": amf0.Object{"scte35": b64(spliceInsert(2, true, 0))}}),
+		scriptCue(12500, "onSCTE35", b64(spliceInsert(2, false, 0))),
+		scriptCue(20000, "onSCTE35", amf0.Object{"data": "not scte-35"}),
+	}
+	got := adBreaks(cues)
+	if len(got) != 2 {
+		t.Fatalf("%d breaks, want 2: %+v", len(got), got)
+	}
+	if b := got[0]; b.id != 1 || b.out != 3*time.Second || b.in != 5*time.Second || b.inSCTE != nil {
+		t.Errorf("auto-return break = %+v", b)
+	}
+	if b := got[1]; b.id != 2 || b.out != 10*time.Second || b.in != 12500*time.Millisecond || b.inSCTE == nil {
+		t.Errorf("signalled break = %+v", b)
+	}
+}
+
+// TestMarkPlaylist tags a break from 3s to 5s and splits the segments it
+// starts and ends inside at their keyframes.
+func TestMarkPlaylist(t *testing.T) {
+	origin := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	pdt := func(s int) string {
+		return "#EXT-X-PROGRAM-DATE-TIME:" + origin.Add(time.Duration(s)*time.Second).Format(pdtLayout) + "\n"
+	}
+	body := "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:1\n" +
+		pdt(2) + "#EXTINF:2.000,\nseg_00001.ts\n" +
+		pdt(4) + "#EXTINF:2.000,\nseg_00002.ts\n" +
+		pdt(6) + "#EXTINF:2.000,\nseg_00003.ts\n"
+	out := spliceInsert(1, true, 2*time.Second)
+	breaks := adBreaks([]Cue{scriptCue(3000, "onSCTE35", b64(out))})
+	kfs := map[string][]tsKeyframe{
+		"seg_00001.ts": {{pts: 180000}, {pts: 270000}},
+		"seg_00002.ts": {{pts: 360000}, {pts: 450000}},
+		"seg_00003.ts": {{pts: 540000}},
+	}
+	got := string(markPlaylist([]byte(body), origin, breaks, func(uri string) []tsKeyframe { return kfs[uri] }))
+	want := "#EXTM3U\n#EXT-X-MEDIA-SEQUENCE:1\n" +
+		pdt(2) + "#EXTINF:1.000,\nseg_00001.ts?to=270000\n" +
+		`#EXT-X-DATERANGE:ID="splice-1-3000",START-DATE="2026-10-18T12:00:03.000Z",PLANNED-DURATION=2.000,SCTE35-OUT=0x` +
+		strings.ToUpper(hex.EncodeToString(out)) + "\n#EXT-X-CUE-OUT:DURATION=2.000\n" +
+		pdt(3) + "#EXTINF:1.000,\nseg_00001.ts?from=270000\n" +
+		"#EXT-X-CUE-OUT-CONT:ElapsedTime=1.000,Duration=2.000\n" +
+		pdt(4) + "#EXTINF:1.000,\nseg_00002.ts?to=450000\n" +
+		"#EXT-X-CUE-IN\n" +
+		pdt(5) + "#EXTINF:1.000,\nseg_00002.ts?from=450000\n" +
+		pdt(6) + "#EXTINF:2.000,\nseg_00003.ts\n"
+	if got != want {
+		t.Errorf("playlist:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// keyframePES returns a video PES at pts on PID 0x100 flagged as a
+// random access point.
+func keyframePES(pts int64) []byte {
+	var cc byte
+	pkt := appendPES(nil, pts, []byte{0, 0, 0, 1, 0x65}, &cc)
+	pkt[1], pkt[2] = 0x41, 0x00
+	pkt[5] |= 0x40 // random_access_indicator
+	pkt[4+1+int(pkt[4])+3] = 0xE0
+	return pkt
+}
+
+// TestCutPart serves each side of a split with PAT and PMT up front.
+func TestCutPart(t *testing.T) {
+	seg := tsSegment(90000)[:2*tsPacket]
+	seg = append(append(seg, keyframePES(180000)...), keyframePES(270000)...)
+	if kf := keyframes(seg); len(kf) != 2 || kf[1].pts != 270000 || kf[1].index != 3*tsPacket {
+		t.Fatalf("keyframes = %+v", kf)
+	}
+	head, ok := tsPart{from: -1, to: 270000}.cut(seg)
+	if !ok || !bytes.Equal(head, seg[:3*tsPacket]) {
+		t.Errorf("head: ok %v, %d bytes", ok, len(head))
+	}
+	tail, ok := tsPart{from: 270000, to: -1}.cut(seg)
+	if !ok || !bytes.Equal(tail[:2*tsPacket], seg[:2*tsPacket]) || !bytes.Equal(tail[2*tsPacket:], seg[3*tsPacket:]) {
+		t.Errorf("tail: ok %v, %d bytes", ok, len(tail))
+	}
+	if _, ok := (tsPart{from: 123, to: -1}).cut(seg); ok {
+		t.Error("cut at a missing keyframe succeeded")
+	}
+	if p := partOf(map[string][]string{"from": {"270000"}}); p != (tsPart{from: 270000, to: -1}) || p.query() != "?from=270000" {
+		t.Errorf("partOf = %+v", p)
+	}
+}
+
+// TestAddEventStream puts the break into each period as SCTE-35 binary.
+func TestAddEventStream(t *testing.T) {
+	out := spliceInsert(9, true, 2*time.Second)
+	breaks := adBreaks([]Cue{scriptCue(3000, "onSCTE35", b64(out))})
+	mpd := `<MPD><Period id="0" start="PT0.0S"><AdaptationSet/></Period></MPD>`
+	got := string(addEventStream([]byte(mpd), breaks))
+	want := `<Period id="0" start="PT0.0S"><EventStream schemeIdUri="urn:scte:scte35:2014:xml+bin" timescale="1000">` +
+		`<Event presentationTime="3000" duration="2000" id="3000"><Signal xmlns="http://www.scte.org/schemas/35/2016">` +
+		`<Binary>` + b64(out) + `</Binary></Signal></Event></EventStream><AdaptationSet/>`
+	if !strings.Contains(got, want) {
+		t.Errorf("MPD = %s", got)
+	}
+}
@@ -0,0 +1,141 @@
+// If you are AI: This file splits MPEG-TS segments at ad splice points.
+// ffmpeg cuts segments on its own schedule, so a splice usually lands
+// inside one; the playlist then lists the segment as parts, each named
+// by the PTS of the video keyframes it starts and ends at
+// (seg.ts?from=...&to=...), and each part is cut from the file as served.
+
+package pkger
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// tsPart names a part of a split TS segment by the PTS of the video
+// keyframes it starts and ends at; -1 for the segment's own start or end.
+type tsPart struct {
+	from, to int64
+}
+
+// wholeSegment is the part that is the whole segment.
+var wholeSegment = tsPart{from: -1, to: -1}
+
+// partOf reads a part from a segment URL's query.
+func partOf(q url.Values) tsPart {
+	part := wholeSegment
+	if v, err := strconv.ParseInt(q.Get("from"), 10, 64); err == nil && v >= 0 {
+		part.from = v
+	}
+	if v, err := strconv.ParseInt(q.Get("to"), 10, 64); err == nil && v >= 0 {
+		part.to = v
+	}
+	return part
+}
+
+// query returns the URL query naming the part, "" for the whole segment.
+func (t tsPart) query() string {
+	q := url.Values{}
+	if t.from >= 0 {
+		q.Set("from", strconv.FormatInt(t.from, 10))
+	}
+	if t.to >= 0 {
+		q.Set("to", strconv.FormatInt(t.to, 10))
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	return "?" + q.Encode()
+}
+
+// tsKeyframe is a video keyframe PES in a TS segment.
+type tsKeyframe struct {
+	pts   int64
+	index int // byte offset of the packet the PES starts in
+}
+
+// keyframes returns the video keyframes in seg, in order: PES starts on
+// a video stream in packets with random_access_indicator set.
+func keyframes(seg []byte) []tsKeyframe {
+	var out []tsKeyframe
+	for i := 0; i+tsPacket <= len(seg); i += tsPacket {
+		pkt := seg[i : i+tsPacket]
+		if pkt[0] != 0x47 || pkt[1]&0x40 == 0 || pkt[3]&0x20 == 0 || pkt[4] == 0 || pkt[5]&0x40 == 0 {
+			continue
+		}
+		p := tsPayload(pkt)
+		if len(p) < 4 || p[3]&0xF0 != 0xE0 {
+			continue
+		}
+		if pts, ok := pesPTS(p); ok {
+			out = append(out, tsKeyframe{pts: pts, index: i})
+		}
+	}
+	return out
+}
+
+// cut returns the part of seg, with the segment's PAT and PMT ahead of
+// a part that starts mid-segment. False if a named keyframe is missing.
+func (t tsPart) cut(seg []byte) ([]byte, bool) {
+	start, end := 0, len(seg)
+	for _, k := range keyframes(seg) {
+		if k.pts == t.from {
+			start = k.index
+		}
+		if k.pts == t.to {
+			end = k.index
+		}
+	}
+	if t.from >= 0 && start == 0 || t.to >= 0 && end == len(seg) || start >= end {
+		return nil, false
+	}
+	if start == 0 {
+		return seg[:end], true
+	}
+	var out []byte
+	if pmtPID, ok := findPMT(seg); ok {
+		for i := 0; i < start; i += tsPacket {
+			pid := int(seg[i+1]&0x1F)<<8 | int(seg[i+2])
+			if pid == 0 || pid == pmtPID {
+				out = append(out, seg[i:i+tsPacket]...)
+				if len(out) == 2*tsPacket {
+					break
+				}
+			}
+		}
+	}
+	return append(out, seg[start:end]...), true
+}
+
+// tsWithCues returns part of the TS segment seg (file relative to the
+// work dir) with the cues that fall in it as ID3. False when the whole
+// segment can be served as is.
+func (p *Packager) tsWithCues(file string, seg []byte, part tsPart, cues []Cue) ([]byte, bool) {
+	start, dur, timed := p.segmentTiming(file)
+	end := start + dur
+	if kf := keyframes(seg); len(kf) > 0 {
+		if part.to >= 0 {
+			end = start + ptsDuration(part.to-kf[0].pts)
+		}
+		if part.from >= 0 {
+			start += ptsDuration(part.from - kf[0].pts)
+		}
+	}
+	if part != wholeSegment {
+		var ok bool
+		if seg, ok = part.cut(seg); !ok {
+			return nil, false
+		}
+	}
+	if timed {
+		if out, ok := injectID3(seg, start, cuesIn(cues, start, end)); ok {
+			return out, true
+		}
+	}
+	return seg, part != wholeSegment
+}
+
+// ptsDuration converts a 90 kHz PTS difference to a duration.
+func ptsDuration(d int64) time.Duration {
+	return time.Duration(d) * time.Second / 90000
+}
@@ -0,0 +1,290 @@
+// If you are AI: This file serves a stream's decoded captions (the
+// onCaptionInfo messages of its timed metadata) as a WebVTT subtitle
+// rendition under the virtual subs/ directory. HLS gets a SUBTITLES group
+// in the master playlist and a subtitle playlist that mirrors a video
+// playlist segment for segment; DASH gets a text AdaptationSet with one
+// numbered WebVTT segment per segment duration.
+
+package pkger
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/amf0"
+	"nonchalant/internal/core/protocol/flv"
+)
+
+// CaptionTrack names the subtitle rendition of an app's captions.
+type CaptionTrack struct {
+	Language string // BCP 47 tag
+	Name     string // shown in players' subtitle menus
+}
+
+// captionDir is the virtual directory of the WebVTT rendition.
+const captionDir = "subs"
+
+// captionTrack returns app's caption rendition, falling back to "*", or
+// nil when app has none.
+func (o Options) captionTrack(app string) *CaptionTrack {
+	t, ok := o.Captions[app]
+	if !ok {
+		t, ok = o.Captions["*"]
+	}
+	if !ok {
+		return nil
+	}
+	return &t
+}
+
+// playlistName returns the name ffmpeg gives media playlists. A single
+// rendition stream with captions needs a master playlist, which takes the
+// manifest's name.
+func (p *Packager) playlistName() string {
+	if p.format == FormatHLS && p.captions != nil && len(p.opts.Ladder) == 0 {
+		return "stream.m3u8"
+	}
+	return "index.m3u8"
+}
+
+// segmentDuration returns the target segment duration ffmpeg is given.
+func (p *Packager) segmentDuration() time.Duration {
+	if p.opts.LowLatency {
+		return time.Second
+	}
+	return 2 * time.Second
+}
+
+// captionReference returns the video playlist the subtitle playlist
+// mirrors: the single rendition's, or the first video rung's.
+func (p *Packager) captionReference() string {
+	if len(p.opts.Ladder) == 0 {
+		return p.playlistName()
+	}
+	video, audio := splitLadder(p.opts.Ladder)
+	if len(video) == 0 {
+		video = audio
+	}
+	return path.Join(video[0].Name, p.playlistName())
+}
+
+// addCaptions declares the caption rendition in the HLS master playlist
+// or the DASH MPD.
+func (p *Packager) addCaptions(file string, body []byte) []byte {
+	switch {
+	case p.format == FormatDASH:
+		return addTextSet(body, *p.captions, p.segmentDuration())
+	case file == p.manifest:
+		return addSubtitleGroup(body, *p.captions)
+	}
+	return body
+}
+
+// addSubtitleGroup adds the SUBTITLES group to an HLS master playlist and
+// to each of its variants.
+func addSubtitleGroup(body []byte, t CaptionTrack) []byte {
+	if !bytes.Contains(body, []byte("#EXT-X-STREAM-INF:")) || bytes.Contains(body, []byte("TYPE=SUBTITLES")) {
+		return body
+	}
+	media := fmt.Sprintf(`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="%s",LANGUAGE="%s",DEFAULT=YES,AUTOSELECT=YES,URI="%s/index.m3u8"`,
+		t.Name, t.Language, captionDir)
+	var out bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "#EXT-X-STREAM-INF:") {
+			if media != "" {
+				out.WriteString(media + "\n")
+				media = ""
+			}
+			line += `,SUBTITLES="subs"`
+		}
+		out.WriteString(line + "\n")
+	}
+	return out.Bytes()
+}
+
+// periodEnd matches a Period end tag.
+var periodEnd = regexp.MustCompile(`</Period>`)
+
+// addTextSet adds a WebVTT AdaptationSet to each DASH period, its
+// segments numbered from 0 every dur on the period's timeline.
+func addTextSet(body []byte, t CaptionTrack, dur time.Duration) []byte {
+	if bytes.Contains(body, []byte(`mimeType="text/vtt"`)) {
+		return body
+	}
+	set := fmt.Sprintf(`<AdaptationSet id="100" contentType="text" mimeType="text/vtt" lang="%s">`+
+		`<Role schemeIdUri="urn:mpeg:dash:role:2011" value="caption"/><Label>%s</Label>`+
+		`<SegmentTemplate timescale="1000" duration="%d" startNumber="0" media="%s/$Number$.vtt"/>`+
+		`<Representation id="captions" bandwidth="1000"/></AdaptationSet>`,
+		t.Language, t.Name, dur.Milliseconds(), captionDir)
+	return periodEnd.ReplaceAllLiteral(body, []byte(set+"</Period>"))
+}
+
+// captionFile returns a file of the WebVTT rendition (file relative to
+// the work dir, under captionDir): the HLS subtitle playlist or a WebVTT
+// segment. False when there is no such file.
+func (p *Packager) captionFile(file string) ([]byte, bool) {
+	name := strings.TrimPrefix(file, captionDir+"/")
+	if p.captions == nil || strings.Contains(name, "/") {
+		return nil, false
+	}
+	var cues []Cue
+	if p.cues != nil {
+		cues = p.cues.OutputCues(bus.NewStreamKey(p.app, p.name), p.userAgent())
+	}
+	stem, ok := strings.CutSuffix(name, ".vtt")
+	switch {
+	case p.format == FormatHLS && name == "index.m3u8":
+		ref := p.captionReference()
+		body, err := os.ReadFile(filepath.Join(p.workDir, filepath.FromSlash(ref)))
+		if err != nil {
+			return nil, false
+		}
+		if origin, ok := p.origin(); ok {
+			body, _ = p.datePlaylist(ref, body, origin)
+		}
+		return subtitlePlaylist(body), true
+	case !ok:
+		return nil, false
+	case p.format == FormatDASH:
+		n, err := strconv.Atoi(stem)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		start := time.Duration(n) * p.segmentDuration()
+		return webVTT(captionCues(cues), start, start+p.segmentDuration(), "", -1), true
+	}
+	ext := ".ts"
+	if p.opts.LowLatency && len(p.opts.Ladder) == 0 {
+		ext = ".m4s"
+	}
+	seg := path.Join(path.Dir(p.captionReference()), stem+ext)
+	start, dur, timed := p.segmentTiming(seg)
+	if !timed {
+		return webVTT(nil, 0, 0, "", -1), true
+	}
+	mpegts := int64(start * 90000 / time.Second)
+	if ext == ".ts" {
+		b, err := os.ReadFile(filepath.Join(p.workDir, filepath.FromSlash(seg)))
+		kfs := keyframes(b)
+		if err != nil || len(kfs) == 0 {
+			return webVTT(nil, 0, 0, "", -1), true
+		}
+		mpegts = kfs[0].pts
+	}
+	return webVTT(captionCues(cues), start, start+dur, vttTime(start), mpegts), true
+}
+
+// subtitlePlaylist turns a video media playlist into the subtitle
+// playlist: the same segments, as WebVTT files next to it.
+func subtitlePlaylist(body []byte) []byte {
+	var out bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			continue
+		case line != "" && !strings.HasPrefix(line, "#"):
+			base := path.Base(line)
+			line = strings.TrimSuffix(base, path.Ext(base)) + ".vtt"
+		}
+		out.WriteString(line + "\n")
+	}
+	return out.Bytes()
+}
+
+// captionCue is a caption on screen from start until end; end is -1
+// while it still is.
+type captionCue struct {
+	start, end time.Duration
+	text       string
+}
+
+// captionCues returns the captions the onCaptionInfo messages among cues
+// put on screen: each shows until the next message.
+func captionCues(cues []Cue) []captionCue {
+	var out []captionCue
+	for _, c := range cues {
+		text, ok := captionText(c.Payload)
+		if !ok {
+			continue
+		}
+		if n := len(out); n > 0 && out[n-1].end < 0 {
+			out[n-1].end = c.Time
+		}
+		if text != "" {
+			out = append(out, captionCue{start: c.Time, end: -1, text: text})
+		}
+	}
+	return out
+}
+
+// captionText returns the text of an onCaptionInfo message, false for
+// any other message.
+func captionText(payload []byte) (string, bool) {
+	r := bytes.NewReader(payload)
+	if name, err := amf0.DecodeString(r); err != nil || name != flv.ScriptCaption {
+		return "", false
+	}
+	v, err := amf0.Decode(r)
+	obj, _ := v.(amf0.Object)
+	if err != nil || obj == nil {
+		return "", false
+	}
+	text, _ := obj["text"].(string)
+	return strings.TrimSpace(text), true
+}
+
+// withoutCaptions returns cues less the onCaptionInfo messages, which
+// reach HLS and DASH players as WebVTT rather than ID3.
+func withoutCaptions(cues []Cue) []Cue {
+	out := cues[:0:0]
+	for _, c := range cues {
+		if flv.ScriptName(c.Payload) != flv.ScriptCaption {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// vttEscape escapes caption text for a WebVTT cue.
+var vttEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// webVTT returns a WebVTT file with the captions on screen in
+// [start, end), cut to it. A local time with mpegts >= 0 maps it to that
+// MPEG-TS timestamp for HLS.
+func webVTT(cues []captionCue, start, end time.Duration, local string, mpegts int64) []byte {
+	var out strings.Builder
+	out.WriteString("WEBVTT\n")
+	if mpegts >= 0 {
+		fmt.Fprintf(&out, "X-TIMESTAMP-MAP=MPEGTS:%d,LOCAL:%s\n", mpegts, local)
+	}
+	for _, c := range cues {
+		from, to := max(c.start, start), c.end
+		if to < 0 || to > end {
+			to = end
+		}
+		if from >= to {
+			continue
+		}
+		fmt.Fprintf(&out, "\n%s --> %s\n%s\n", vttTime(from), vttTime(to), vttEscape.Replace(c.text))
+	}
+	return []byte(out.String())
+}
+
+// vttTime formats d as a WebVTT timestamp.
+func vttTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
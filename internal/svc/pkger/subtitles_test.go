@@ -0,0 +1,64 @@
+// If you are AI: This file unit-tests the WebVTT caption rendition: cues
+// from onCaptionInfo messages, WebVTT segments, the HLS master and
+// subtitle playlists and the DASH text AdaptationSet.
+
+package pkger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// captionAt returns an onCaptionInfo cue at ms showing text.
+func captionAt(ms int, text string) Cue {
+	return scriptCue(ms, "onCaptionInfo", amf0.Object{"type": "cea608", "text": text})
+}
+
+// TestWebVTT shows each caption until the next message, cut to the
+// segment, with the segment's MPEG-TS mapping.
+func TestWebVTT(t *testing.T) {
+	cues := []Cue{
+		captionAt(1000, "first <line>"),
+		scriptCue(1500, "onCuePoint", amf0.Object{"name": "q"}),
+		captionAt(3000, "second\nrow"),
+		captionAt(5000, ""),
+		captionAt(7000, "third"),
+	}
+	if got := withoutCaptions(cues); len(got) != 1 {
+		t.Errorf("withoutCaptions left %d cues", len(got))
+	}
+	got := string(webVTT(captionCues(cues), 2*time.Second, 8*time.Second, vttTime(2*time.Second), 306000))
+	want := "WEBVTT\nX-TIMESTAMP-MAP=MPEGTS:306000,LOCAL:00:00:02.000\n" +
+		"\n00:00:02.000 --> 00:00:03.000\nfirst &lt;line&gt;\n" +
+		"\n00:00:03.000 --> 00:00:05.000\nsecond\nrow\n" +
+		"\n00:00:07.000 --> 00:00:08.000\nthird\n"
+	if got != want {
+		t.Errorf("WebVTT:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestSubtitlePlaylists declares the rendition in the master playlist
+// and mirrors the video playlist's segments as WebVTT.
+func TestSubtitlePlaylists(t *testing.T) {
+	track := CaptionTrack{Language: "en", Name: "English"}
+	master := "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nstream.m3u8\n"
+	want := "#EXTM3U\n#EXT-X-VERSION:3\n" +
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English",LANGUAGE="en",DEFAULT=YES,AUTOSELECT=YES,URI="subs/index.m3u8"` + "\n" +
+		`#EXT-X-STREAM-INF:BANDWIDTH=800000,SUBTITLES="subs"` + "\nstream.m3u8\n"
+	if got := string(addSubtitleGroup([]byte(master), track)); got != want {
+		t.Errorf("master:\n%s\nwant:\n%s", got, want)
+	}
+	media := "#EXTM3U\n#EXT-X-MAP:URI=\"init.mp4\"\n#EXTINF:1.000,\nseg_00004.m4s\n"
+	if got := string(subtitlePlaylist([]byte(media))); got != "#EXTM3U\n#EXTINF:1.000,\nseg_00004.vtt\n" {
+		t.Errorf("subtitle playlist:\n%s", got)
+	}
+	mpd := string(addTextSet([]byte(`<MPD><Period id="0"><AdaptationSet/></Period></MPD>`), track, 2*time.Second))
+	if !strings.Contains(mpd, `mimeType="text/vtt" lang="en"`) ||
+		!strings.Contains(mpd, `<SegmentTemplate timescale="1000" duration="2000" startNumber="0" media="subs/$Number$.vtt"/>`) ||
+		!strings.HasSuffix(mpd, "</AdaptationSet></Period></MPD>") {
+		t.Errorf("MPD = %s", mpd)
+	}
+}
@@ -0,0 +1,216 @@
+// If you are AI: This file adds ID3 timed metadata to an MPEG-TS segment
+// the way HLS expects it: a stream of type 0x15 declared in every PMT
+// (with the metadata descriptors Apple's spec asks for) and one PES per
+// tag, stamped on the segment's own PTS timeline.
+
+package pkger
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// tsPacket is the MPEG-TS packet size.
+const tsPacket = 188
+
+// id3PID carries the metadata stream; ffmpeg never allocates it.
+const id3PID = 0x1F00
+
+// Metadata descriptors for ID3 in the PMT: metadata_pointer (program
+// info, before the program number) and metadata (the stream's ES info).
+var (
+	metadataPointer = []byte{0x25, 0x0F, 0xFF, 0xFF, 'I', 'D', '3', ' ', 0xFF, 'I', 'D', '3', ' ', 0x00, 0x1F}
+	metadataDesc    = []byte{0x26, 0x0D, 0xFF, 0xFF, 'I', 'D', '3', ' ', 0xFF, 'I', 'D', '3', ' ', 0x00, 0x0F}
+)
+
+// injectID3 returns seg with tags added, timed from start, the segment's
+// position on the input timeline. False if seg is not a TS segment this
+// can rewrite (or tags is empty).
+func injectID3(seg []byte, start time.Duration, tags []timedTag) ([]byte, bool) {
+	if len(tags) == 0 || len(seg) == 0 || len(seg)%tsPacket != 0 {
+		return nil, false
+	}
+	out := append([]byte(nil), seg...)
+	pmtPID, ok := findPMT(out)
+	if !ok {
+		return nil, false
+	}
+	firstPTS := int64(-1)
+	for i := 0; i < len(out); i += tsPacket {
+		pkt := out[i : i+tsPacket]
+		if pkt[0] != 0x47 {
+			return nil, false
+		}
+		pid := int(pkt[1]&0x1F)<<8 | int(pkt[2])
+		if pid == id3PID {
+			return nil, false
+		}
+		if pkt[1]&0x40 == 0 { // payload_unit_start_indicator
+			continue
+		}
+		payload := tsPayload(pkt)
+		if pid == pmtPID {
+			if !addMetadataStream(pkt, payload) {
+				return nil, false
+			}
+		} else if pts, has := pesPTS(payload); has && (firstPTS < 0 || pts < firstPTS) {
+			firstPTS = pts
+		}
+	}
+	if firstPTS < 0 {
+		return nil, false
+	}
+	cc := byte(0)
+	for _, t := range tags {
+		pts := firstPTS + int64((t.at-start)*90000/time.Second)
+		out = appendPES(out, pts&(1<<33-1), t.id3, &cc)
+	}
+	return out, true
+}
+
+// tsPayload returns a packet's payload, after any adaptation field.
+func tsPayload(pkt []byte) []byte {
+	p := pkt[4:]
+	if pkt[3]&0x20 != 0 {
+		if len(p) == 0 || int(p[0])+1 > len(p) {
+			return nil
+		}
+		p = p[int(p[0])+1:]
+	}
+	if pkt[3]&0x10 == 0 {
+		return nil
+	}
+	return p
+}
+
+// findPMT returns the PID of the first program's PMT from the PAT.
+func findPMT(seg []byte) (int, bool) {
+	for i := 0; i+tsPacket <= len(seg); i += tsPacket {
+		pkt := seg[i : i+tsPacket]
+		if pkt[1]&0x1F != 0 || pkt[2] != 0 || pkt[1]&0x40 == 0 {
+			continue
+		}
+		p := tsPayload(pkt)
+		if len(p) < 1 || int(p[0])+1 > len(p) {
+			return 0, false
+		}
+		sec := p[int(p[0])+1:]
+		if len(sec) < 8 {
+			return 0, false
+		}
+		end := 3 + int(binary.BigEndian.Uint16(sec[1:])&0x0FFF) - 4 // before CRC
+		for j := 8; j+4 <= end && j+4 <= len(sec); j += 4 {
+			if binary.BigEndian.Uint16(sec[j:]) != 0 { // not the network PID
+				return int(binary.BigEndian.Uint16(sec[j+2:]) & 0x1FFF), true
+			}
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// addMetadataStream rewrites the PMT section in payload (inside pkt) to
+// declare the ID3 stream. False if the section spans packets or the new
+// one would not fit.
+func addMetadataStream(pkt, payload []byte) bool {
+	if len(payload) < 1 || int(payload[0])+1 > len(payload) {
+		return false
+	}
+	secStart := int(payload[0]) + 1
+	sec := payload[secStart:]
+	if len(sec) < 12 || sec[0] != 0x02 {
+		return false
+	}
+	total := 3 + int(binary.BigEndian.Uint16(sec[1:])&0x0FFF)
+	if total > len(sec) {
+		return false
+	}
+	infoLen := int(binary.BigEndian.Uint16(sec[10:]) & 0x0FFF)
+	if 12+infoLen > total-4 {
+		return false
+	}
+	esLoop := sec[12+infoLen : total-4]
+
+	news := append([]byte(nil), sec[:10]...)
+	news = binary.BigEndian.AppendUint16(news, 0xF000|uint16(infoLen+len(metadataPointer)))
+	news = append(news, sec[12:12+infoLen]...)
+	news = append(append(news, metadataPointer...), esLoop...)
+	news = append(news, 0x15, 0xE0|id3PID>>8, id3PID&0xFF, 0xF0, byte(len(metadataDesc)))
+	news = append(news, metadataDesc...)
+	length := len(news) - 3 + 4
+	news[1] = sec[1]&0xF0 | byte(length>>8)
+	news[2] = byte(length)
+	news = binary.BigEndian.AppendUint32(news, crc32MPEG(news))
+	if len(news) > len(sec) {
+		return false
+	}
+	n := copy(sec, news)
+	for i := n; i < len(sec); i++ {
+		sec[i] = 0xFF
+	}
+	return true
+}
+
+// pesPTS returns the PTS of a PES packet that starts in payload.
+func pesPTS(p []byte) (int64, bool) {
+	if len(p) < 14 || p[0] != 0 || p[1] != 0 || p[2] != 1 || p[7]&0x80 == 0 {
+		return 0, false
+	}
+	return readTimestamp(p[9:]), true
+}
+
+// readTimestamp decodes a 33-bit PES timestamp.
+func readTimestamp(b []byte) int64 {
+	return int64(b[0]>>1&0x07)<<30 | int64(b[1])<<22 | int64(b[2]>>1)<<15 |
+		int64(b[3])<<7 | int64(b[4]>>1)
+}
+
+// appendPES appends tag as a private-stream PES at pts, split into TS
+// packets on id3PID. cc is the continuity counter.
+func appendPES(out []byte, pts int64, tag []byte, cc *byte) []byte {
+	pes := []byte{0, 0, 1, 0xBD, 0, 0, 0x84, 0x80, 5, // data-aligned, PTS only
+		0x21 | byte(pts>>29)&0x0E, byte(pts >> 22), byte(pts>>14) | 1, byte(pts >> 7), byte(pts<<1) | 1}
+	binary.BigEndian.PutUint16(pes[4:], uint16(len(pes)-6+len(tag)))
+	pes = append(pes, tag...)
+	for first := true; len(pes) > 0; first = false {
+		pkt := make([]byte, tsPacket)
+		pkt[0], pkt[1], pkt[2], pkt[3] = 0x47, id3PID>>8, id3PID&0xFF, 0x10|*cc&0x0F
+		if first {
+			pkt[1] |= 0x40
+		}
+		*cc++
+		room := tsPacket - 4
+		if len(pes) < room { // stuff with an adaptation field
+			pkt[3] |= 0x20
+			stuff := room - len(pes)
+			pkt[4] = byte(stuff - 1)
+			if stuff > 1 {
+				pkt[5] = 0
+				for i := 6; i < 4+stuff; i++ {
+					pkt[i] = 0xFF
+				}
+			}
+			room = len(pes)
+		}
+		copy(pkt[tsPacket-room:], pes[:room])
+		pes = pes[room:]
+		out = append(out, pkt...)
+	}
+	return out
+}
+
+// crc32MPEG is the CRC-32/MPEG-2 of PSI sections.
+func crc32MPEG(b []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, c := range b {
+		crc ^= uint32(c) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,166 @@
+// If you are AI: This file implements source health monitoring for pull
+// relays. A monitor runs alongside each ffmpeg attempt: it declares the
+// source dead when no media reaches the local stream for the stall timeout,
+// and, while on a fallback, probes the primary and requests a switch back
+// once it has been reachable for the whole failback period and ffmpeg can
+// read media from its stream.
+
+package relay
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"os/exec"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+const (
+	// defaultFailback is how long the primary must stay reachable before a
+	// pull relay on a fallback source switches back to it.
+	defaultFailback = 30 * time.Second
+	// defaultStall is how long a source may deliver no media before the
+	// relay fails over.
+	defaultStall = 10 * time.Second
+	// monitorInterval is the health-check tick.
+	monitorInterval = time.Second
+	// probeTimeout bounds the TCP reachability probe of the primary.
+	probeTimeout = time.Second
+	// streamProbeTimeout bounds the media probe run before failing back.
+	streamProbeTimeout = 10 * time.Second
+)
+
+// streamProbe reports whether media can be read from the stream at
+// rawURL. A variable so tests can replace ffmpeg.
+var streamProbe = ffmpegProbe
+
+// failReason says why an attempt ended.
+type failReason int
+
+const (
+	// reasonExit means ffmpeg exited on its own.
+	reasonExit failReason = iota
+	// reasonStall means the monitor killed ffmpeg because no media arrived.
+	reasonStall
+	// reasonFailback means the monitor killed ffmpeg to return to the primary.
+	reasonFailback
+)
+
+// monitor watches one ffmpeg attempt. Its fields are written by watch and
+// read by the task loop after runOnce returns (runOnce waits for watch).
+type monitor struct {
+	task    *PullTask
+	active  int        // index of the source this attempt is pulling
+	reason  failReason // why the attempt ended
+	healthy bool       // media reached the local stream during the attempt
+}
+
+// newMonitor returns the monitor for an attempt on sources[active].
+func (t *PullTask) newMonitor(active int) *monitor {
+	return &monitor{task: t, active: active}
+}
+
+// watch runs until ctx is done, cancelling the attempt on stall or failback.
+func (m *monitor) watch(ctx context.Context, cancel context.CancelFunc) {
+	t := m.task
+	key := bus.NewStreamKey(t.App(), t.Name())
+	lastSeq := publishedCount(t.Registry(), key)
+	lastProgress := time.Now()
+	var reachableSince time.Time
+
+	tick := time.NewTicker(monitorInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+		now := time.Now()
+
+		if seq := publishedCount(t.Registry(), key); seq != lastSeq {
+			lastSeq, lastProgress, m.healthy = seq, now, true
+		} else if now.Sub(lastProgress) >= t.stall {
+			m.reason = reasonStall
+			cancel()
+			return
+		}
+
+		if m.active == 0 {
+			continue
+		}
+		if !probe(t.sources[0]) {
+			reachableSince = time.Time{}
+			continue
+		}
+		if reachableSince.IsZero() {
+			reachableSince = now
+		}
+		if now.Sub(reachableSince) < t.failback {
+			continue
+		}
+		// A listening port is not a stream: a primary that accepts
+		// connections but has nothing to play would take over, fail and
+		// flap back. Only switch once its media can be read.
+		if !streamProbe(ctx, t.sources[0]) {
+			reachableSince = time.Time{}
+			continue
+		}
+		m.reason = reasonFailback
+		cancel()
+		return
+	}
+}
+
+// publishedCount returns the local stream's cumulative message count, or 0
+// when the stream does not exist.
+func publishedCount(reg *bus.Registry, key bus.StreamKey) uint64 {
+	if s := reg.Get(key); s != nil {
+		return s.MessagesPublished()
+	}
+	return 0
+}
+
+// probe reports whether the host behind rawURL accepts TCP connections.
+// This is a cheap liveness signal; the real test is ffmpeg receiving media.
+func probe(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPort(u.Scheme)
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(u.Hostname(), port), probeTimeout)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// ffmpegProbe copies a second of media from rawURL to nowhere and reports
+// whether ffmpeg managed it within streamProbeTimeout.
+func ffmpegProbe(ctx context.Context, rawURL string) bool {
+	ctx, cancel := context.WithTimeout(ctx, streamProbeTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, "ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-i", rawURL, "-map", "0", "-c", "copy", "-t", "1", "-f", "null", "-").Run() == nil
+}
+
+// defaultPort maps a source URL scheme to its well-known port.
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "rtmps", "https":
+		return "443"
+	case "http":
+		return "80"
+	case "srt":
+		return "9000"
+	default:
+		return "1935"
+	}
+}
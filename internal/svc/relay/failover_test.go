@@ -0,0 +1,72 @@
+// If you are AI: This file contains unit tests for pull-relay source health
+// probing used by failover and failback.
+
+package relay
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// TestProbe checks the TCP reachability probe against a live and a closed port.
+func TestProbe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	if !probe("rtmp://" + addr + "/live/a") {
+		t.Error("expected listening source to be reachable")
+	}
+	_ = ln.Close()
+	if probe("rtmp://" + addr + "/live/a") {
+		t.Error("expected closed source to be unreachable")
+	}
+	if probe("not a url") {
+		t.Error("expected malformed URL to be unreachable")
+	}
+}
+
+// TestFailbackNeedsStream keeps a fallback running while the primary's
+// port accepts connections but its stream cannot be read, and fails back
+// once it can.
+func TestFailbackNeedsStream(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	task := NewPullTask(bus.NewRegistry(), "live", "a", "rtmp://"+ln.Addr().String()+"/live/a", false)
+	task.sources = append(task.sources, "rtmp://127.0.0.1:1/live/a")
+	task.failback, task.stall = time.Nanosecond, time.Hour
+	defer func(p func(context.Context, string) bool) { streamProbe = p }(streamProbe)
+
+	for _, playable := range []bool{false, true} {
+		probed := 0
+		streamProbe = func(context.Context, string) bool { probed++; return playable }
+		ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+		m := task.newMonitor(1)
+		m.watch(ctx, cancel)
+		cancel()
+		if probed == 0 {
+			t.Fatalf("playable=%v: stream never probed", playable)
+		}
+		if got := m.reason == reasonFailback; got != playable {
+			t.Errorf("playable=%v: failback %v after %d probes", playable, got, probed)
+		}
+	}
+}
+
+// TestDefaultPort checks scheme-to-port mapping for URLs without a port.
+func TestDefaultPort(t *testing.T) {
+	cases := map[string]string{"rtmp": "1935", "rtmps": "443", "http": "80", "srt": "9000"}
+	for scheme, want := range cases {
+		if got := defaultPort(scheme); got != want {
+			t.Errorf("defaultPort(%q) = %q, want %q", scheme, got, want)
+		}
+	}
+}
@@ -7,24 +7,34 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
+	"time"
 
 	"nonchalant/internal/config"
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
 )
 
-// slot binds one configured relay to its currently-running Task instance and
-// the WaitGroup-tracked goroutine running it. Restart() destroys and recreates
-// the Task and goroutine while keeping the slot's config in place.
+// slot binds one relay destination to its currently-running Task instance
+// and the goroutine running it. A pull relay owns exactly one slot; a push
+// relay owns one slot per target so each destination reconnects, backs off
+// and fails independently. Restart() destroys and recreates the Task while
+// keeping the slot's config in place. A disabled slot has no task.
 type slot struct {
-	cfg  config.RelayConfig
-	task Task
-	done chan struct{} // closed when the goroutine returns
+	cfg    config.RelayConfig // stream-level config (app, name, mode)
+	target config.PushTarget  // destination; ID is "" for pull relays
+	task   Task
+	done   chan struct{} // closed when the goroutine returns
 }
 
-// slotKey is the map key used for restart lookups: app|name uniquely identifies
-// a relay because two relays for the same (app,name) make no sense.
-func slotKey(app, name string) string { return app + "|" + name }
+// slotKey is the map key used for lookups: app|name|target uniquely
+// identifies one relay destination. Pull relays use an empty target.
+func slotKey(app, name, target string) string { return app + "|" + name + "|" + target }
+
+// defaultTargetID names the single destination of a push relay configured
+// with the legacy remote_url shorthand.
+const defaultTargetID = "default"
 
 // Manager manages relay tasks lifecycle.
 type Manager struct {
@@ -40,6 +50,8 @@ type Manager struct {
 	httpPort   int
 	publishKey string
 	playKey    string
+
+	events *events.Bus // relay status changes; nil emits nothing
 }
 
 // NewManager creates a new relay manager.
@@ -67,6 +79,14 @@ func (m *Manager) SetEndpoints(rtmpPort, httpPort int, publishKey, playKey strin
 	m.playKey = playKey
 }
 
+// SetEvents emits every task's status changes on b. Must be called before
+// StartTasks.
+func (m *Manager) SetEvents(b *events.Bus) {
+	m.mu.Lock()
+	m.events = b
+	m.mu.Unlock()
+}
+
 // StartTasks starts all relay tasks from configuration.
 func (m *Manager) StartTasks(cfg *config.Config) error {
 	m.mu.Lock()
@@ -76,68 +96,86 @@ func (m *Manager) StartTasks(cfg *config.Config) error {
 		if err := validateRelay(relayCfg); err != nil {
 			return err
 		}
-		if _, dup := m.slots[slotKey(relayCfg.App, relayCfg.Name)]; dup {
-			return fmt.Errorf("duplicate relay for %s/%s", relayCfg.App, relayCfg.Name)
+		for _, target := range destinations(relayCfg) {
+			key := slotKey(relayCfg.App, relayCfg.Name, target.ID)
+			if _, dup := m.slots[key]; dup {
+				return fmt.Errorf("duplicate relay for %s/%s %s", relayCfg.App, relayCfg.Name, target.ID)
+			}
+			m.slots[key] = m.spawn(relayCfg, target)
 		}
-		m.slots[slotKey(relayCfg.App, relayCfg.Name)] = m.spawn(relayCfg)
 	}
 
 	return nil
 }
 
-// validateRelay enforces required fields and known modes.
-func validateRelay(cfg config.RelayConfig) error {
-	if cfg.App == "" || cfg.Name == "" {
-		return fmt.Errorf("relay config missing app or name")
-	}
-	if cfg.Mode != "pull" && cfg.Mode != "push" {
-		return fmt.Errorf("invalid relay mode: %s (must be 'pull' or 'push')", cfg.Mode)
-	}
-	if cfg.RemoteURL == "" {
-		return fmt.Errorf("relay config missing remote_url")
-	}
-	return nil
-}
-
-// spawn allocates a fresh Task for cfg and runs it in a goroutine. Caller must
+// spawn allocates a fresh Task for one destination and runs it in a
+// goroutine. Disabled targets get an idle slot with no task. Caller must
 // hold m.mu.
-func (m *Manager) spawn(cfg config.RelayConfig) *slot {
+func (m *Manager) spawn(cfg config.RelayConfig, target config.PushTarget) *slot {
+	s := &slot{cfg: cfg, target: target, done: make(chan struct{})}
+	if target.Disabled {
+		close(s.done)
+		return s
+	}
 	var task Task
 	switch cfg.Mode {
 	case "pull":
-		pt := NewPullTask(m.registry, cfg.App, cfg.Name, cfg.RemoteURL, cfg.Reconnect)
+		pt := NewPullTask(m.registry, cfg.App, cfg.Name, target.URL, target.Reconnect)
 		pt.SetEndpoints(m.rtmpPort, m.httpPort, m.publishKey, m.playKey)
+		pt.SetEvents(m.events, target.ID)
+		pt.SetSources(pullSources(cfg))
+		pt.SetFailover(time.Duration(cfg.FailbackSeconds)*time.Second,
+			time.Duration(cfg.StallSeconds)*time.Second)
 		task = pt
 	default: // "push" (validated upstream)
-		pt := NewPushTask(m.registry, cfg.App, cfg.Name, cfg.RemoteURL, cfg.Reconnect)
+		pt := NewPushTask(m.registry, cfg.App, cfg.Name, target.URL, target.Reconnect)
 		pt.SetEndpoints(m.rtmpPort, m.httpPort, m.publishKey, m.playKey)
+		pt.SetEvents(m.events, target.ID)
+		pt.SetMaxBackoff(time.Duration(target.MaxBackoffSeconds) * time.Second)
 		task = pt
 	}
-	s := &slot{cfg: cfg, task: task, done: make(chan struct{})}
+	s.task = task
 	go func() {
 		defer close(s.done)
 		if err := task.Start(m.ctx); err != nil && m.ctx.Err() == nil {
-			log.Printf("relay task %s/%s exited: %v", cfg.App, cfg.Name, err)
+			log.Printf("relay task %s/%s %s exited: %v", cfg.App, cfg.Name, target.ID, err)
 		}
 	}()
 	return s
 }
 
-// Restart stops the relay for app/name and starts a fresh instance with the
-// same configuration. Returns an error if the relay is not configured.
+// halt stops the slot's task (if any) and waits for its goroutine.
+// Caller must hold m.mu.
+func (s *slot) halt() {
+	if s.task != nil {
+		if err := s.task.Stop(); err != nil {
+			log.Printf("relay task stop: %v", err)
+		}
+	}
+	<-s.done
+}
+
+// Restart stops every enabled destination of the relay for app/name and
+// starts fresh instances with the same configuration. Returns an error if
+// the relay is not configured.
 func (m *Manager) Restart(app, name string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	key := slotKey(app, name)
-	s, ok := m.slots[key]
-	if !ok {
-		return fmt.Errorf("no relay configured for %s/%s", app, name)
+	found := false
+	for key, s := range m.slots {
+		if s.cfg.App != app || s.cfg.Name != name {
+			continue
+		}
+		found = true
+		if s.target.Disabled {
+			continue
+		}
+		s.halt()
+		m.slots[key] = m.spawn(s.cfg, s.target)
 	}
-	if err := s.task.Stop(); err != nil {
-		log.Printf("relay task stop during restart: %v", err)
+	if !found {
+		return fmt.Errorf("no relay configured for %s/%s", app, name)
 	}
-	<-s.done
-	m.slots[key] = m.spawn(s.cfg)
 	return nil
 }
 
@@ -150,8 +188,10 @@ func (m *Manager) Stop() error {
 
 	m.cancel()
 	for _, s := range m.slots {
-		if err := s.task.Stop(); err != nil {
-			log.Printf("relay task stop: %v", err)
+		if s.task != nil {
+			if err := s.task.Stop(); err != nil {
+				log.Printf("relay task stop: %v", err)
+			}
 		}
 	}
 	for _, s := range m.slots {
@@ -160,14 +200,15 @@ func (m *Manager) Stop() error {
 	return nil
 }
 
-// TaskCount returns the number of active relay tasks.
+// TaskCount returns the number of configured relay destinations.
 func (m *Manager) TaskCount() int {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	return len(m.slots)
 }
 
-// GetTasks returns information about all relay tasks.
+// GetTasks returns information about all relay destinations, sorted by
+// app, name and target for stable output.
 // Used by API for introspection.
 func (m *Manager) GetTasks() []TaskInfo {
 	m.mu.Lock()
@@ -175,29 +216,54 @@ func (m *Manager) GetTasks() []TaskInfo {
 
 	infos := make([]TaskInfo, 0, len(m.slots))
 	for _, s := range m.slots {
-		var running bool
-		switch t := s.task.(type) {
-		case *PullTask:
-			running = t.IsRunning()
-		case *PushTask:
-			running = t.IsRunning()
-		}
-		infos = append(infos, TaskInfo{
+		info := TaskInfo{
 			App:       s.cfg.App,
 			Name:      s.cfg.Name,
 			Mode:      s.cfg.Mode,
-			RemoteURL: s.cfg.RemoteURL,
-			Running:   running,
-		})
+			Target:    s.target.ID,
+			RemoteURL: s.target.URL,
+			Source:    s.target.URL,
+			Enabled:   !s.target.Disabled,
+			Status:    StatusDisabled,
+		}
+		if s.task != nil {
+			snap := s.task.Snapshot()
+			info.Running = s.task.IsRunning()
+			info.Status = snap.Status
+			info.LastError = snap.LastError
+			info.Attempts = snap.Attempts
+			info.Source = snap.Source
+		}
+		if s.cfg.Mode == "pull" {
+			info.Sources = pullSources(s.cfg)
+		}
+		infos = append(infos, info)
 	}
+	sort.Slice(infos, func(i, j int) bool {
+		a, b := infos[i], infos[j]
+		if a.App != b.App {
+			return a.App < b.App
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Target < b.Target
+	})
 	return infos
 }
 
-// TaskInfo represents information about a relay task.
+// TaskInfo represents information about a relay destination.
 type TaskInfo struct {
 	App       string
 	Name      string
 	Mode      string
+	Target    string // push target ID; "" for pull relays
 	RemoteURL string
+	Sources   []string // pull only: ordered source list, primary first
+	Source    string   // URL currently in use
+	Enabled   bool
 	Running   bool
+	Status    Status
+	LastError string
+	Attempts  int
 }
@@ -153,3 +153,111 @@ func TestManagerRestart(t *testing.T) {
 		t.Errorf("after restart TaskCount = %d, want 1", got)
 	}
 }
+
+// TestManagerPushTargets verifies that a push relay with several targets gets
+// one slot per destination and that targets can be toggled, added and removed
+// individually without touching their siblings.
+func TestManagerPushTargets(t *testing.T) {
+	registry := bus.NewRegistry()
+	manager := NewManager(registry)
+	defer manager.Stop()
+
+	cfg := &config.Config{
+		Relays: []config.RelayConfig{{
+			App: "live", Name: "sim", Mode: "push",
+			Targets: []config.PushTarget{
+				{ID: "yt", URL: "rtmp://127.0.0.1:1/a/x", Reconnect: true},
+				{ID: "tw", URL: "rtmp://127.0.0.1:1/b/x", Disabled: true},
+			},
+		}},
+	}
+	if err := manager.StartTasks(cfg); err != nil {
+		t.Fatalf("StartTasks: %v", err)
+	}
+	if got := manager.TaskCount(); got != 2 {
+		t.Fatalf("TaskCount = %d, want 2", got)
+	}
+
+	tasks := manager.GetTasks()
+	if tasks[0].Target != "tw" || tasks[0].Enabled || tasks[0].Status != StatusDisabled {
+		t.Errorf("tw target = %+v, want disabled", tasks[0])
+	}
+	if tasks[1].Target != "yt" || !tasks[1].Enabled {
+		t.Errorf("yt target = %+v, want enabled", tasks[1])
+	}
+
+	if err := manager.SetTargetEnabled("live", "sim", "tw", true); err != nil {
+		t.Fatalf("enable tw: %v", err)
+	}
+	if err := manager.AddTarget("live", "sim", config.PushTarget{ID: "yt", URL: "rtmp://x/y/z"}); err == nil {
+		t.Error("AddTarget with duplicate id should fail")
+	}
+	if err := manager.AddTarget("live", "sim", config.PushTarget{ID: "fb", URL: "rtmp://127.0.0.1:1/c/x"}); err != nil {
+		t.Fatalf("AddTarget: %v", err)
+	}
+	if err := manager.RemoveTarget("live", "sim", "yt"); err != nil {
+		t.Fatalf("RemoveTarget: %v", err)
+	}
+	if err := manager.RemoveTarget("live", "sim", "yt"); err == nil {
+		t.Error("RemoveTarget of removed target should fail")
+	}
+
+	tasks = manager.GetTasks()
+	if len(tasks) != 2 || tasks[0].Target != "fb" || tasks[1].Target != "tw" || !tasks[1].Enabled {
+		t.Errorf("unexpected tasks after edits: %+v", tasks)
+	}
+}
+
+// TestManagerTargetValidation covers the push-target config checks.
+func TestManagerTargetValidation(t *testing.T) {
+	cases := []config.RelayConfig{
+		{App: "live", Name: "x", Mode: "pull", Targets: []config.PushTarget{{ID: "a", URL: "rtmp://h/a/b"}}},
+		{App: "live", Name: "x", Mode: "push", Targets: []config.PushTarget{{URL: "rtmp://h/a/b"}}},
+		{App: "live", Name: "x", Mode: "push", Targets: []config.PushTarget{{ID: "a"}}},
+		{App: "live", Name: "x", Mode: "push", Targets: []config.PushTarget{
+			{ID: "a", URL: "rtmp://h/a/b"}, {ID: "a", URL: "rtmp://h/a/c"},
+		}},
+	}
+	for i, rc := range cases {
+		manager := NewManager(bus.NewRegistry())
+		if err := manager.StartTasks(&config.Config{Relays: []config.RelayConfig{rc}}); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+		manager.Stop()
+	}
+}
+
+// TestManagerPullSources verifies remote_urls validation and that the
+// ordered source list is reported with the primary as the active source.
+func TestManagerPullSources(t *testing.T) {
+	bad := []config.RelayConfig{
+		{App: "live", Name: "x", Mode: "push", RemoteURLs: []string{"rtmp://h/a/b"}},
+		{App: "live", Name: "x", Mode: "pull", RemoteURL: "rtmp://h/a/b", RemoteURLs: []string{"rtmp://h/a/c"}},
+		{App: "live", Name: "x", Mode: "pull", RemoteURLs: []string{"rtmp://h/a/b", ""}},
+		{App: "live", Name: "x", Mode: "pull", RemoteURLs: []string{"rtmp://h/a/b"}, StallSeconds: -1},
+	}
+	for i, rc := range bad {
+		manager := NewManager(bus.NewRegistry())
+		if err := manager.StartTasks(&config.Config{Relays: []config.RelayConfig{rc}}); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+		manager.Stop()
+	}
+
+	manager := NewManager(bus.NewRegistry())
+	defer manager.Stop()
+	urls := []string{"rtmp://primary/live/a", "rtmp://backup/live/a"}
+	cfg := &config.Config{Relays: []config.RelayConfig{
+		{App: "live", Name: "a", Mode: "pull", RemoteURLs: urls, FailbackSeconds: 5},
+	}}
+	if err := manager.StartTasks(cfg); err != nil {
+		t.Fatalf("StartTasks: %v", err)
+	}
+	tasks := manager.GetTasks()
+	if len(tasks) != 1 || len(tasks[0].Sources) != 2 || tasks[0].RemoteURL != urls[0] {
+		t.Fatalf("unexpected tasks: %+v", tasks)
+	}
+	if tasks[0].Source != urls[0] {
+		t.Errorf("active source = %q, want primary", tasks[0].Source)
+	}
+}
@@ -1,13 +1,16 @@
 // If you are AI: This file implements pull relay functionality.
 // Pull relay shells out to ffmpeg to fetch a remote RTMP stream and republish
 // it to our own RTMP ingest. ffmpeg handles the full RTMP client protocol so
-// we don't have to.
+// we don't have to. An ordered source list gives health-based failover with
+// automatic return to the primary (see failover.go).
 
 package relay
 
 import (
 	"context"
 	"fmt"
+	"log"
+	"time"
 
 	"nonchalant/internal/core/bus"
 )
@@ -15,29 +18,112 @@ import (
 // PullTask implements pull relay (connect to remote, play, republish locally).
 type PullTask struct {
 	*BaseTask
+	sources  []string      // ordered; sources[0] is the primary
+	failback time.Duration // primary must stay reachable this long before we return
+	stall    time.Duration // no local media for this long counts as a dead source
 }
 
-// NewPullTask creates a new pull relay task.
+// NewPullTask creates a new pull relay task with a single source.
 func NewPullTask(registry *bus.Registry, app, name, remoteURL string, reconnect bool) *PullTask {
 	return &PullTask{
 		BaseTask: NewBaseTask(registry, app, name, remoteURL, reconnect),
+		sources:  []string{remoteURL},
+		failback: defaultFailback,
+		stall:    defaultStall,
 	}
 }
 
-// Start runs an ffmpeg subprocess that pulls from the remote RTMP server and
-// republishes to our local RTMP ingest. On exit it retries with exponential
-// backoff while reconnect is enabled. Returns when ctx is cancelled or the
-// task is Stop()'d.
+// SetSources replaces the ordered source list. The first entry is the
+// primary. Must be called before Start; an empty list is ignored.
+func (t *PullTask) SetSources(urls []string) {
+	if len(urls) == 0 {
+		return
+	}
+	t.sources = append([]string(nil), urls...)
+}
+
+// SetFailover tunes the failback stable period and the stall timeout.
+// Values <= 0 keep the defaults. Must be called before Start.
+func (t *PullTask) SetFailover(failback, stall time.Duration) {
+	if failback > 0 {
+		t.failback = failback
+	}
+	if stall > 0 {
+		t.stall = stall
+	}
+}
+
+// Start runs an ffmpeg subprocess that pulls from the active source and
+// republishes to our local RTMP ingest. When the source exits or stalls the
+// task moves to the next source immediately; only after every source has
+// failed does it back off. While on a fallback it watches the primary and
+// switches back once it has been reachable for the failback period. Returns
+// when ctx is cancelled or the task is Stop()'d.
 func (t *PullTask) Start(ctx context.Context) error {
 	t.SetRunning(true)
 	defer t.SetRunning(false)
 
-	args := []string{
+	label := fmt.Sprintf("pull %s/%s", t.App(), t.Name())
+	if err := t.requireFFmpeg(label); err != nil {
+		return err
+	}
+	log.Printf("relay %s: starting with %d source(s)", label, len(t.sources))
+	defer t.markStopped()
+
+	active := 0
+	backoff := minBackoff
+	for {
+		if t.shouldExit(ctx) {
+			return nil
+		}
+		src := t.sources[active]
+		t.setSource(src)
+		t.setStatus(StatusRunning, nil)
+
+		mon := t.newMonitor(active)
+		stopped, err := t.runOnce(ctx, pullArgs(src, t.localRTMPTarget()), mon.watch)
+		if stopped {
+			return nil
+		}
+
+		if mon.reason == reasonFailback {
+			log.Printf("relay %s: primary stable for %s, switching back", label, t.failback)
+			active, backoff = 0, minBackoff
+			continue
+		}
+		if mon.reason == reasonStall {
+			err = fmt.Errorf("no media for %s", t.stall)
+		}
+		if mon.healthy {
+			backoff = minBackoff
+		}
+
+		next := (active + 1) % len(t.sources)
+		wrapped := next == 0
+		if wrapped && !t.reconnect {
+			t.setStatus(StatusFailed, err)
+			return err
+		}
+		log.Printf("relay %s: source %d (%s) failed (%v)", label, active, src, err)
+		active = next
+		if !wrapped {
+			continue // fail over immediately
+		}
+		t.setStatus(StatusBackoff, err)
+		if !t.sleep(ctx, backoff) {
+			return nil
+		}
+		backoff = t.nextBackoff(backoff)
+	}
+}
+
+// pullArgs builds the ffmpeg command line for pulling src into target.
+func pullArgs(src, target string) []string {
+	return []string{
 		"-hide_banner", "-loglevel", "warning",
-		"-i", t.RemoteURL(),
+		"-i", src,
 		"-c", "copy",
 		"-f", "flv",
-		t.localRTMPTarget(),
+		target,
 	}
-	return t.runFFmpegLoop(ctx, fmt.Sprintf("pull %s/%s", t.App(), t.Name()), args)
 }
@@ -10,6 +10,7 @@ import (
 	"fmt"
 
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
 )
 
 // PushTask implements push relay (subscribe local, publish remote).
@@ -36,6 +37,8 @@ func (t *PushTask) Start(ctx context.Context) error {
 		// No -re: the source is already live-paced (our own HTTP-FLV).
 		// -fflags +nobuffer avoids initial demuxer buffering.
 		"-fflags", "+nobuffer",
+		// Labels our pull as "relay" in the sessions API and latency metrics.
+		"-user_agent", sessions.UserAgentRelay,
 		"-i", t.localFLVSource(),
 		"-c", "copy",
 		"-f", "flv",
@@ -0,0 +1,126 @@
+// If you are AI: This file tracks the observable state of a relay task
+// (status string, last error, attempt counter) so the API can report on each
+// destination independently.
+
+package relay
+
+import (
+	"time"
+
+	"nonchalant/internal/events"
+)
+
+// Status is the coarse lifecycle state of a relay task.
+type Status string
+
+const (
+	// StatusStarting means the task was spawned but ffmpeg has not run yet.
+	StatusStarting Status = "starting"
+	// StatusRunning means an ffmpeg subprocess is currently attached.
+	StatusRunning Status = "running"
+	// StatusBackoff means ffmpeg exited and the task is waiting to retry.
+	StatusBackoff Status = "backoff"
+	// StatusFailed means ffmpeg exited and reconnect is disabled.
+	StatusFailed Status = "failed"
+	// StatusStopped means the task was stopped by the manager.
+	StatusStopped Status = "stopped"
+	// StatusDisabled means the destination is configured but switched off.
+	StatusDisabled Status = "disabled"
+)
+
+// defaultMaxBackoff caps the retry delay between ffmpeg attempts unless a
+// target overrides it.
+const defaultMaxBackoff = 5 * time.Second
+
+// Snapshot is a point-in-time copy of a task's observable state.
+type Snapshot struct {
+	Status    Status
+	LastError string
+	Attempts  int       // ffmpeg launches since the task started
+	Since     time.Time // when Status last changed
+	Source    string    // URL currently being pulled or pushed to
+}
+
+// Snapshot returns the task's current state.
+func (t *BaseTask) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Snapshot{
+		Status:    t.status,
+		LastError: t.lastErr,
+		Attempts:  t.attempts,
+		Since:     t.since,
+		Source:    t.source,
+	}
+}
+
+// setSource records the URL the current attempt is using.
+func (t *BaseTask) setSource(url string) {
+	t.mu.Lock()
+	t.source = url
+	t.mu.Unlock()
+}
+
+// SetMaxBackoff overrides the retry backoff ceiling. Values <= 0 keep the
+// default.
+func (t *BaseTask) SetMaxBackoff(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	t.mu.Lock()
+	t.maxBackoff = d
+	t.mu.Unlock()
+}
+
+// setStatus records a state transition and, when err is non-nil, the error
+// that caused it. Transitions are emitted as RelayStateChanged.
+func (t *BaseTask) setStatus(s Status, err error) {
+	t.mu.Lock()
+	changed := t.status != s
+	if changed {
+		t.since = time.Now()
+	}
+	t.status = s
+	if err != nil {
+		t.lastErr = err.Error()
+	}
+	if s == StatusRunning {
+		t.attempts++
+	}
+	ev, target := t.events, t.target
+	t.mu.Unlock()
+
+	if changed {
+		e := events.Event{Type: events.RelayStateChanged, App: t.app, Name: t.name, Target: target, State: string(s)}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		ev.Emit(e)
+	}
+}
+
+// SetEvents emits the task's status changes on b, labelled with the push
+// target ID ("" for pull relays).
+func (t *BaseTask) SetEvents(b *events.Bus, target string) {
+	t.mu.Lock()
+	t.events, t.target = b, target
+	t.mu.Unlock()
+}
+
+// markStopped moves the task to StatusStopped unless it already ended in
+// StatusFailed, whose error is more useful to the operator.
+func (t *BaseTask) markStopped() {
+	t.mu.Lock()
+	failed := t.status == StatusFailed
+	t.mu.Unlock()
+	if !failed {
+		t.setStatus(StatusStopped, nil)
+	}
+}
+
+// backoffCeiling returns the configured retry backoff ceiling.
+func (t *BaseTask) backoffCeiling() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.maxBackoff
+}
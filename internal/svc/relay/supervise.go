@@ -0,0 +1,106 @@
+// If you are AI: This file holds the ffmpeg supervision primitives shared by
+// relay tasks: one-shot subprocess runs with an optional watcher, stop
+// detection and backoff timing.
+
+package relay
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// minBackoff is the first retry delay after an ffmpeg exit.
+const minBackoff = 500 * time.Millisecond
+
+// requireFFmpeg fails the task with a clear error when ffmpeg is missing.
+func (t *BaseTask) requireFFmpeg(label string) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		err = fmt.Errorf("relay %s: ffmpeg not on PATH", label)
+		t.setStatus(StatusFailed, err)
+		return err
+	}
+	return nil
+}
+
+// runOnce runs a single ffmpeg attempt. watch, if non-nil, runs alongside
+// the subprocess and may cancel it early (failover, stall detection); it
+// must return once ctx is done. stopped reports that the attempt ended
+// because of shutdown or Stop, in which case err is not meaningful.
+func (t *BaseTask) runOnce(parent context.Context, args []string, watch func(ctx context.Context, cancel context.CancelFunc)) (stopped bool, err error) {
+	ctx, cancel := context.WithCancel(parent)
+	stopWatch := watchStop(ctx, cancel, t.StopChan())
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		if watch != nil {
+			watch(ctx, cancel)
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	// Cancel the per-attempt context so the helper goroutines return even
+	// on a clean ffmpeg exit (Run does not cancel ctx by itself).
+	cancel()
+	<-stopWatch
+	<-watchDone
+
+	return t.shouldExit(parent), err
+}
+
+// shouldExit reports whether the parent context is done or Stop was called.
+func (t *BaseTask) shouldExit(parent context.Context) bool {
+	return parent.Err() != nil || ctxStopped(t.StopChan())
+}
+
+// sleep waits for d, returning false early if the task should exit.
+func (t *BaseTask) sleep(parent context.Context, d time.Duration) bool {
+	select {
+	case <-time.After(d):
+		return true
+	case <-parent.Done():
+		return false
+	case <-t.StopChan():
+		return false
+	}
+}
+
+// nextBackoff doubles the retry delay up to the task's ceiling.
+func (t *BaseTask) nextBackoff(cur time.Duration) time.Duration {
+	maxBackoff := t.backoffCeiling()
+	cur *= 2
+	if cur > maxBackoff {
+		cur = maxBackoff
+	}
+	return cur
+}
+
+// watchStop bridges the StopChan into the per-attempt context so a Stop()
+// during ffmpeg execution promptly terminates the subprocess.
+func watchStop(ctx context.Context, cancel context.CancelFunc, stop <-chan struct{}) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-ctx.Done():
+		case <-stop:
+			cancel()
+		}
+	}()
+	return done
+}
+
+// ctxStopped is true if the stop channel has been closed.
+func ctxStopped(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,78 @@
+// If you are AI: This file implements runtime management of push-relay
+// destinations (add, remove, enable, disable) used by the HTTP API.
+// Each operation touches exactly one slot so sibling targets keep running.
+
+package relay
+
+import (
+	"fmt"
+
+	"nonchalant/internal/config"
+	"nonchalant/internal/core/bus"
+)
+
+// AddTarget adds a push destination for app/name and starts it unless it is
+// disabled. A stream with no push relay yet gets one implicitly. Returns an
+// error if the target is invalid or its ID is already in use.
+func (m *Manager) AddTarget(app, name string, target config.PushTarget) error {
+	if app == "" || name == "" {
+		return fmt.Errorf("app and name are required")
+	}
+	if err := bus.NewStreamKey(app, name).Validate(); err != nil {
+		return err
+	}
+	if err := validateTarget(target); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := slotKey(app, name, target.ID)
+	if _, dup := m.slots[key]; dup {
+		return fmt.Errorf("target %q already exists for %s/%s", target.ID, app, name)
+	}
+	cfg := config.RelayConfig{App: app, Name: name, Mode: "push"}
+	m.slots[key] = m.spawn(cfg, target)
+	return nil
+}
+
+// RemoveTarget stops and forgets one push destination of app/name.
+func (m *Manager) RemoveTarget(app, name, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, key, err := m.pushSlot(app, name, id)
+	if err != nil {
+		return err
+	}
+	s.halt()
+	delete(m.slots, key)
+	return nil
+}
+
+// SetTargetEnabled switches one push destination on or off. Disabling stops
+// its ffmpeg; enabling starts a fresh task. No-op if already in that state.
+func (m *Manager) SetTargetEnabled(app, name, id string, enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, key, err := m.pushSlot(app, name, id)
+	if err != nil {
+		return err
+	}
+	if s.target.Disabled == !enabled {
+		return nil
+	}
+	s.halt()
+	target := s.target
+	target.Disabled = !enabled
+	m.slots[key] = m.spawn(s.cfg, target)
+	return nil
+}
+
+// pushSlot looks up the slot for a push destination. Caller must hold m.mu.
+func (m *Manager) pushSlot(app, name, id string) (*slot, string, error) {
+	key := slotKey(app, name, id)
+	s, ok := m.slots[key]
+	if !ok || s.cfg.Mode != "push" {
+		return nil, "", fmt.Errorf("no push target %q configured for %s/%s", id, app, name)
+	}
+	return s, key, nil
+}
@@ -8,12 +8,11 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
-	"os/exec"
 	"sync"
 	"time"
 
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/events"
 )
 
 // Task represents a relay task (pull or push).
@@ -22,6 +21,7 @@ type Task interface {
 	Start(ctx context.Context) error
 	Stop() error
 	IsRunning() bool
+	Snapshot() Snapshot
 }
 
 // BaseTask provides common functionality for relay tasks.
@@ -39,6 +39,16 @@ type BaseTask struct {
 	stopChan chan struct{}
 	stopped  bool
 
+	// Observable state for the API (see status.go).
+	status     Status
+	lastErr    string
+	attempts   int
+	since      time.Time
+	maxBackoff time.Duration
+	source     string
+	events     *events.Bus // nil emits nothing
+	target     string      // push target ID for events
+
 	// Local endpoint configuration. Populated by the relay Manager so pull
 	// targets can reach our RTMP ingest and push sources can reach our HTTP-FLV.
 	rtmpPort int
@@ -52,14 +62,18 @@ type BaseTask struct {
 // from the live config before Start() runs.
 func NewBaseTask(registry *bus.Registry, app, name, remoteURL string, reconnect bool) *BaseTask {
 	return &BaseTask{
-		registry:  registry,
-		app:       app,
-		name:      name,
-		remoteURL: remoteURL,
-		reconnect: reconnect,
-		stopChan:  make(chan struct{}),
-		rtmpPort:  1935,
-		httpPort:  8081,
+		registry:   registry,
+		app:        app,
+		name:       name,
+		remoteURL:  remoteURL,
+		reconnect:  reconnect,
+		stopChan:   make(chan struct{}),
+		rtmpPort:   1935,
+		httpPort:   8081,
+		status:     StatusStarting,
+		since:      time.Now(),
+		maxBackoff: defaultMaxBackoff,
+		source:     remoteURL,
 	}
 }
 
@@ -167,80 +181,32 @@ func (t *BaseTask) localFLVSource() string {
 // It restarts on subprocess exit while reconnect is true, with bounded
 // exponential backoff. Returns nil on context cancellation or Stop.
 func (t *BaseTask) runFFmpegLoop(parent context.Context, label string, args []string) error {
-	if _, err := exec.LookPath("ffmpeg"); err != nil {
-		return fmt.Errorf("relay %s: ffmpeg not on PATH", label)
+	if err := t.requireFFmpeg(label); err != nil {
+		return err
 	}
 	log.Printf("relay %s: starting", label)
-	const minBackoff = 500 * time.Millisecond
-	const maxBackoff = 5 * time.Second
+	defer t.markStopped()
 	backoff := minBackoff
 	for {
-		select {
-		case <-parent.Done():
-			return nil
-		case <-t.StopChan():
+		if t.shouldExit(parent) {
 			return nil
-		default:
 		}
-
-		ctx, cancel := context.WithCancel(parent)
-		stopped := watchStop(ctx, cancel, t.StopChan())
-
-		cmd := exec.CommandContext(ctx, "ffmpeg", args...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err := cmd.Run()
-		// Cancel the per-attempt context so the watchStop goroutine returns
-		// even on a clean ffmpeg exit (Run does not cancel ctx by itself).
-		cancel()
-		<-stopped
-
-		if parent.Err() != nil || ctxStopped(t.StopChan()) {
+		t.setStatus(StatusRunning, nil)
+		stopped, err := t.runOnce(parent, args, nil)
+		if stopped {
 			// Subprocess was killed because we're shutting down. The ffmpeg
 			// exit error is the intentional cause of the kill, so swallow it.
-			return nil //nolint:nilerr // shutdown is the cause of err
+			return nil
 		}
 		if !t.reconnect {
+			t.setStatus(StatusFailed, err)
 			return err
 		}
+		t.setStatus(StatusBackoff, err)
 		log.Printf("relay %s: ffmpeg exited (%v); retry in %s", label, err, backoff)
-		select {
-		case <-time.After(backoff):
-		case <-parent.Done():
-			return nil
-		case <-t.StopChan():
+		if !t.sleep(parent, backoff) {
 			return nil
 		}
-		if backoff < maxBackoff {
-			backoff *= 2
-			if backoff > maxBackoff {
-				backoff = maxBackoff
-			}
-		}
-	}
-}
-
-// watchStop bridges the StopChan into the per-attempt context so a Stop()
-// during ffmpeg execution promptly terminates the subprocess.
-func watchStop(ctx context.Context, cancel context.CancelFunc, stop <-chan struct{}) <-chan struct{} {
-	done := make(chan struct{})
-	go func() {
-		defer close(done)
-		select {
-		case <-ctx.Done():
-		case <-stop:
-			cancel()
-		}
-	}()
-	return done
-}
-
-// ctxStopped is true if the stop channel has been closed.
-func ctxStopped(c <-chan struct{}) bool {
-	select {
-	case <-c:
-		return true
-	default:
-		return false
+		backoff = t.nextBackoff(backoff)
 	}
 }
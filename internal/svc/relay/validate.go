@@ -0,0 +1,96 @@
+// If you are AI: This file validates relay configuration and expands it into
+// per-destination targets (one slot per pull relay, one per push target).
+
+package relay
+
+import (
+	"fmt"
+
+	"nonchalant/internal/config"
+	"nonchalant/internal/core/bus"
+)
+
+// validateRelay enforces required fields, safe stream names (they end up
+// in ffmpeg URLs) and known modes.
+func validateRelay(cfg config.RelayConfig) error {
+	if cfg.App == "" || cfg.Name == "" {
+		return fmt.Errorf("relay config missing app or name")
+	}
+	if err := bus.NewStreamKey(cfg.App, cfg.Name).Validate(); err != nil {
+		return fmt.Errorf("relay: %w", err)
+	}
+	if cfg.Mode != "pull" && cfg.Mode != "push" {
+		return fmt.Errorf("invalid relay mode: %s (must be 'pull' or 'push')", cfg.Mode)
+	}
+	if len(cfg.Targets) > 0 {
+		if cfg.Mode != "push" {
+			return fmt.Errorf("relay %s/%s: targets are only valid for push relays", cfg.App, cfg.Name)
+		}
+		seen := make(map[string]struct{}, len(cfg.Targets))
+		for i, t := range cfg.Targets {
+			if err := validateTarget(t); err != nil {
+				return fmt.Errorf("relay %s/%s targets[%d]: %w", cfg.App, cfg.Name, i, err)
+			}
+			if _, dup := seen[t.ID]; dup {
+				return fmt.Errorf("relay %s/%s: duplicate target id %q", cfg.App, cfg.Name, t.ID)
+			}
+			seen[t.ID] = struct{}{}
+		}
+		return nil
+	}
+	if len(cfg.RemoteURLs) > 0 {
+		if cfg.Mode != "pull" {
+			return fmt.Errorf("relay %s/%s: remote_urls are only valid for pull relays", cfg.App, cfg.Name)
+		}
+		if cfg.RemoteURL != "" {
+			return fmt.Errorf("relay %s/%s: set either remote_url or remote_urls, not both", cfg.App, cfg.Name)
+		}
+		for i, u := range cfg.RemoteURLs {
+			if u == "" {
+				return fmt.Errorf("relay %s/%s: remote_urls[%d] is empty", cfg.App, cfg.Name, i)
+			}
+		}
+	} else if cfg.RemoteURL == "" {
+		return fmt.Errorf("relay config missing remote_url")
+	}
+	if cfg.FailbackSeconds < 0 || cfg.StallSeconds < 0 {
+		return fmt.Errorf("relay %s/%s: failback_seconds and stall_seconds must not be negative", cfg.App, cfg.Name)
+	}
+	return nil
+}
+
+// validateTarget enforces the required fields of one push destination.
+func validateTarget(t config.PushTarget) error {
+	if t.ID == "" {
+		return fmt.Errorf("target id is required")
+	}
+	if t.URL == "" {
+		return fmt.Errorf("target %q missing url", t.ID)
+	}
+	if t.MaxBackoffSeconds < 0 {
+		return fmt.Errorf("target %q: max_backoff_seconds must not be negative", t.ID)
+	}
+	return nil
+}
+
+// destinations expands a relay config into its per-slot targets. Pull relays
+// and single-URL push relays yield exactly one entry built from RemoteURL.
+func destinations(cfg config.RelayConfig) []config.PushTarget {
+	if cfg.Mode == "push" && len(cfg.Targets) > 0 {
+		return cfg.Targets
+	}
+	id := ""
+	if cfg.Mode == "push" {
+		id = defaultTargetID
+	}
+	return []config.PushTarget{{ID: id, URL: pullSources(cfg)[0], Reconnect: cfg.Reconnect}}
+}
+
+// pullSources returns the ordered source list of a relay: RemoteURLs when
+// set, otherwise the single RemoteURL.
+func pullSources(cfg config.RelayConfig) []string {
+	if len(cfg.RemoteURLs) > 0 {
+		return cfg.RemoteURLs
+	}
+	return []string{cfg.RemoteURL}
+}
@@ -0,0 +1,77 @@
+// If you are AI: This file keeps the server-wide tally of ingest watchdog
+// alerts for /metrics: totals by reason and the conditions active now.
+
+package rtmp
+
+import (
+	"sort"
+	"sync"
+
+	"nonchalant/internal/core/bus"
+)
+
+// alertBoard counts raised alerts and tracks active ones. Safe for
+// concurrent use.
+type alertBoard struct {
+	mu     sync.Mutex
+	total  map[string]uint64
+	active map[bus.StreamKey]map[string]struct{}
+}
+
+// newAlertBoard returns an empty board.
+func newAlertBoard() *alertBoard {
+	return &alertBoard{total: make(map[string]uint64), active: make(map[bus.StreamKey]map[string]struct{})}
+}
+
+// count records a one-off alert.
+func (b *alertBoard) count(reason string) {
+	b.mu.Lock()
+	b.total[reason]++
+	b.mu.Unlock()
+}
+
+// raise counts reason and marks it active on key.
+func (b *alertBoard) raise(key bus.StreamKey, reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total[reason]++
+	if b.active[key] == nil {
+		b.active[key] = make(map[string]struct{})
+	}
+	b.active[key][reason] = struct{}{}
+}
+
+// clear marks reason no longer active on key.
+func (b *alertBoard) clear(key bus.StreamKey, reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.active[key], reason)
+	if len(b.active[key]) == 0 {
+		delete(b.active, key)
+	}
+}
+
+// totals returns a copy of the counts by reason.
+func (b *alertBoard) totals() map[string]uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make(map[string]uint64, len(b.total))
+	for r, n := range b.total {
+		out[r] = n
+	}
+	return out
+}
+
+// list returns the active reasons per stream, sorted.
+func (b *alertBoard) list() map[bus.StreamKey][]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make(map[bus.StreamKey][]string, len(b.active))
+	for key, reasons := range b.active {
+		for r := range reasons {
+			out[key] = append(out[key], r)
+		}
+		sort.Strings(out[key])
+	}
+	return out
+}
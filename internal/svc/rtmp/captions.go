@@ -0,0 +1,74 @@
+// If you are AI: This file decodes closed captions (CEA-608 / CEA-708 in
+// H.264 SEI) from a publisher's video and publishes each change of the
+// caption text as an onCaptionInfo script message, which FLV viewers
+// receive as is and the HLS / DASH packager turns into WebVTT.
+
+package rtmp
+
+import (
+	"bytes"
+
+	"nonchalant/internal/core/protocol/amf0"
+	"nonchalant/internal/core/protocol/avc"
+	"nonchalant/internal/core/protocol/captions"
+	"nonchalant/internal/core/protocol/flv"
+)
+
+// Captions sets how one app's publisher captions are decoded.
+type Captions struct {
+	Language string // BCP 47 tag sent with each caption; "" omits it
+}
+
+// SetCaptions decodes publisher captions by app name; the "*" entry covers
+// apps without their own. A nil map decodes none.
+func (s *Server) SetCaptions(c map[string]*Captions) { s.captions = c }
+
+// captionDecoder decodes one publish's captions. Owned by the publishing
+// goroutine; nil when the app has no captions.
+type captionDecoder struct {
+	dec      captions.Decoder
+	nalLen   int
+	language string
+}
+
+// newCaptionDecoder returns a decoder for c, nil for nil.
+func newCaptionDecoder(c *Captions) *captionDecoder {
+	if c == nil {
+		return nil
+	}
+	return &captionDecoder{language: c.Language}
+}
+
+// frame returns the onCaptionInfo payloads for the caption changes due
+// at a video message: an AVC sequence header sets the NAL length size,
+// and each frame's captions are decoded in presentation order.
+func (d *captionDecoder) frame(ts uint32, payload []byte) [][]byte {
+	if d == nil || len(payload) < 5 || payload[0]&0x0F != 7 {
+		return nil
+	}
+	if payload[1] == 0 && len(payload) >= 10 {
+		d.nalLen = int(payload[9]&0x03) + 1
+	}
+	if payload[1] != 1 || d.nalLen == 0 {
+		return nil
+	}
+	cc := avc.FrameCaptions(payload[5:], d.nalLen)
+	var out [][]byte
+	for _, u := range d.dec.Frame(int64(ts), int64(ts)+int64(compositionTime(payload)), cc) {
+		info := amf0.Object{"type": u.Format, "text": u.Text}
+		if d.language != "" {
+			info["language"] = d.language
+		}
+		var b bytes.Buffer
+		_ = amf0.Encode(&b, flv.ScriptCaption)
+		_ = amf0.Encode(&b, info)
+		out = append(out, b.Bytes())
+	}
+	return out
+}
+
+// compositionTime returns an AVC video message's signed composition time
+// offset in milliseconds.
+func compositionTime(payload []byte) int32 {
+	return int32(uint32(payload[2])<<24|uint32(payload[3])<<16|uint32(payload[4])<<8) >> 8
+}
@@ -0,0 +1,51 @@
+// If you are AI: This file unit-tests publishing decoded captions as
+// onCaptionInfo script messages.
+
+package rtmp
+
+import (
+	"bytes"
+	"testing"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// captionFrame returns an AVC keyframe whose SEI carries 608 field 1
+// byte pairs as GA94 cc_data.
+func captionFrame(pairs ...byte) []byte {
+	sei := []byte{0xB5, 0x00, 0x31, 'G', 'A', '9', '4', 0x03, 0x40 | byte(len(pairs)/2), 0xFF}
+	for i := 0; i < len(pairs); i += 2 {
+		sei = append(sei, 0xFC, pairs[i], pairs[i+1])
+	}
+	sei = append(sei, 0xFF)
+	nal := append([]byte{0x06, 4, byte(len(sei))}, sei...)
+	nal = append(nal, 0x80)
+	frame := []byte{0x17, 1, 0, 0, 0, 0, 0, 0, byte(len(nal))}
+	return append(frame, nal...)
+}
+
+// TestPublisherCaptions publishes a pop-on caption when it is shown, as
+// script data at the frame's timestamp.
+func TestPublisherCaptions(t *testing.T) {
+	stream := bus.NewStream(bus.NewStreamKey("live", "cc"))
+	p := NewPublisher(nil, stream, 1)
+	p.captions = newCaptionDecoder(&Captions{Language: "en"})
+	p.PublishVideo(0, []byte{0x17, 0, 0, 0, 0, 1, 0x64, 0, 0x28, 0xFF})
+	p.PublishVideo(1000, captionFrame(0x14, 0x20, 0x14, 0x40, 'H', 'i'))
+	if cues := stream.Cues(); len(cues) != 0 {
+		t.Fatalf("caption published before end of caption: %d cues", len(cues))
+	}
+	p.PublishVideo(1040, captionFrame(0x14, 0x2F))
+	cues := stream.Cues()
+	if len(cues) != 1 || cues[0].Timestamp != 1040 {
+		t.Fatalf("cues = %+v", cues)
+	}
+	r := bytes.NewReader(cues[0].Payload)
+	name, _ := amf0.DecodeString(r)
+	v, _ := amf0.Decode(r)
+	obj, _ := v.(amf0.Object)
+	if name != "onCaptionInfo" || obj["type"] != "cea608" || obj["text"] != "Hi" || obj["language"] != "en" {
+		t.Errorf("message %q %v", name, v)
+	}
+}
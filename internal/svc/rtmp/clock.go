@@ -0,0 +1,84 @@
+// If you are AI: This file reads wall-clock time from what the encoder
+// sends — onFI script data and H.264 picture timing SEI — and anchors the
+// stream's timeline to it (bus.Clock).
+
+package rtmp
+
+import (
+	"bytes"
+	"time"
+
+	"nonchalant/internal/core/protocol/amf0"
+	"nonchalant/internal/core/protocol/avc"
+)
+
+// encoderClock holds what reading picture timing SEI needs from the
+// current AVC sequence header. Owned by the publishing goroutine.
+type encoderClock struct {
+	timing avc.PicTiming
+	nalLen int
+}
+
+// sequenceHeader learns SEI timing from an AVC sequence header.
+func (c *encoderClock) sequenceHeader(payload []byte) {
+	c.timing = avc.PicTiming{}
+	if len(payload) < 10 || payload[0]&0x0F != 7 {
+		return
+	}
+	if sps, err := avc.ParseDecoderConfig(payload[5:]); err == nil {
+		c.timing, c.nalLen = sps.Timing, int(payload[9]&0x03)+1
+	}
+}
+
+// frame returns the presentation timestamp and wall-clock time of an AVC
+// frame carrying picture timing SEI.
+func (c *encoderClock) frame(ts uint32, payload []byte) (uint32, time.Time, bool) {
+	if c.timing.TimeScale == 0 || len(payload) < 5 || payload[0]&0x0F != 7 || payload[1] != 1 {
+		return 0, time.Time{}, false
+	}
+	tod, ok := avc.FrameClock(payload[5:], c.nalLen, c.timing)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	return ts + uint32(compositionTime(payload)), timeOfDay(time.Now(), tod), true
+}
+
+// timeOfDay places a UTC time of day on the day that puts it nearest now.
+// Picture timing carries no date.
+func timeOfDay(now time.Time, tod time.Duration) time.Time {
+	now = now.UTC()
+	t := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(tod)
+	switch {
+	case t.Sub(now) > 12*time.Hour:
+		t = t.AddDate(0, 0, -1)
+	case now.Sub(t) > 12*time.Hour:
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// onFIDates are the sd formats encoders use.
+var onFIDates = []string{"02-01-2006", "02-01-06", "2006-01-02"}
+
+// parseOnFI returns the time in an onFI data message (sd date, st time of
+// day, taken as UTC), and false for any other message.
+func parseOnFI(payload []byte) (time.Time, bool) {
+	r := bytes.NewReader(payload)
+	if name, err := amf0.DecodeString(r); err != nil || name != "onFI" {
+		return time.Time{}, false
+	}
+	v, err := amf0.Decode(r)
+	obj, _ := v.(amf0.Object)
+	sd, _ := obj["sd"].(string)
+	st, _ := obj["st"].(string)
+	if err != nil || sd == "" || st == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range onFIDates {
+		// Fractional seconds after 05 are accepted when parsing.
+		if t, err := time.Parse(layout+" 15:04:05", sd+" "+st); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
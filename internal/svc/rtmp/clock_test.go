@@ -0,0 +1,86 @@
+// If you are AI: This file unit-tests reading the encoder's wall clock
+// from onFI data and anchoring the stream to it.
+
+package rtmp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// onFI encodes an onFI data message.
+func onFI(sd, st string) []byte {
+	var b bytes.Buffer
+	_ = amf0.Encode(&b, "onFI")
+	_ = amf0.Encode(&b, amf0.Object{"sd": sd, "st": st})
+	return b.Bytes()
+}
+
+// TestParseOnFI accepts the date layouts encoders use.
+func TestParseOnFI(t *testing.T) {
+	want := time.Date(2026, 3, 14, 9, 26, 53, 500e6, time.UTC)
+	for _, sd := range []string{"14-03-2026", "14-03-26", "2026-03-14"} {
+		if got, ok := parseOnFI(onFI(sd, "09:26:53.500")); !ok || !got.Equal(want) {
+			t.Errorf("sd %q: got %v, %v; want %v", sd, got, ok, want)
+		}
+	}
+	if _, ok := parseOnFI(onFI("", "09:26:53")); ok {
+		t.Error("onFI without a date parsed")
+	}
+	var b bytes.Buffer
+	_ = amf0.Encode(&b, "onMetaData")
+	_ = amf0.Encode(&b, amf0.Object{"sd": "14-03-2026", "st": "09:26:53"})
+	if _, ok := parseOnFI(b.Bytes()); ok {
+		t.Error("onMetaData parsed as onFI")
+	}
+}
+
+// TestTimeOfDay picks the day that puts the time nearest now.
+func TestTimeOfDay(t *testing.T) {
+	now := time.Date(2026, 3, 14, 0, 10, 0, 0, time.UTC)
+	cases := []struct {
+		tod  time.Duration
+		want time.Time
+	}{
+		{5 * time.Minute, time.Date(2026, 3, 14, 0, 5, 0, 0, time.UTC)},
+		{23*time.Hour + 55*time.Minute, time.Date(2026, 3, 13, 23, 55, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		if got := timeOfDay(now, c.tod); !got.Equal(c.want) {
+			t.Errorf("timeOfDay(%v) = %v, want %v", c.tod, got, c.want)
+		}
+	}
+	late := time.Date(2026, 3, 14, 23, 58, 0, 0, time.UTC)
+	if got, want := timeOfDay(late, time.Minute), time.Date(2026, 3, 15, 0, 1, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("after midnight = %v, want %v", got, want)
+	}
+}
+
+// TestPublisherOnFIAnchorsClock: onFI replaces the server anchor and is
+// not published.
+func TestPublisherOnFIAnchorsClock(t *testing.T) {
+	stream := bus.NewStream(bus.NewStreamKey("live", "x"))
+	p := NewPublisher(nil, stream, 1)
+	p.PublishVideo(1000, []byte{0x17, 1, 0, 0, 0})
+	if c, ok := stream.Clock(); !ok || c.Source != bus.ClockServer || c.Timestamp != 1000 {
+		t.Fatalf("clock after first frame = %+v, %v", c, ok)
+	}
+	published := stream.MessagesPublished()
+
+	p.PublishMetadata(2000, onFI("14-03-2026", "09:26:53.000"))
+	c, ok := stream.Clock()
+	if !ok || c.Source != bus.ClockOnFI || c.Timestamp != 2000 {
+		t.Fatalf("clock after onFI = %+v, %v", c, ok)
+	}
+	want := time.Date(2026, 3, 14, 9, 26, 52, 0, time.UTC)
+	if got := c.At(1000); !got.Equal(want) {
+		t.Errorf("At(1000) = %v, want %v", got, want)
+	}
+	if stream.MessagesPublished() != published {
+		t.Error("onFI was published")
+	}
+}
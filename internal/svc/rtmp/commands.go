@@ -1,14 +1,18 @@
 // If you are AI: This file handles RTMP command messages after connect.
-// Implements releaseStream, FCPublish, createStream, publish, deleteStream.
+// Dispatches and implements releaseStream, FCPublish, createStream,
+// publish and deleteStream.
 
 package rtmp
 
 import (
+	"bytes"
 	"fmt"
 	"log"
+	"nonchalant/internal/access"
 	"nonchalant/internal/core/bus"
 	"nonchalant/internal/core/protocol/amf0"
 	rtmpprotocol "nonchalant/internal/core/protocol/rtmp"
+	"nonchalant/internal/sessions"
 )
 
 // HandleReleaseStream handles the releaseStream command.
@@ -66,6 +70,10 @@ func (s *ServiceSession) HandleCreateStream(command amf0.Array) error {
 // Sends StreamBegin + onStatus NetStream.Publish.Start on success.
 // If publish-key authentication is configured, the stream name must include
 // "?key=<secret>"; otherwise the publish is rejected with NetStream.Publish.Failed.
+// Names that fail the name policy get NetStream.Publish.BadName.
+// Addresses refused by the access rules, and publishes over the stream
+// capacity, get NetStream.Publish.Rejected; so do publishes that later break
+// the app's publish policy (see HandleMediaMessage).
 func (s *ServiceSession) HandlePublish(command amf0.Array, streamID uint32) error {
 	// publish format: ["publish", txnID, null, streamName, publishType]
 	rawName := extractStreamName(command)
@@ -79,7 +87,7 @@ func (s *ServiceSession) HandlePublish(command amf0.Array, streamID uint32) erro
 	}
 
 	if !s.auth.Allow(key) {
-		log.Printf("Publish rejected: invalid or missing auth key for %s", streamName)
+		log.Printf("Publish rejected: invalid or missing auth key for %s from %s", streamName, s.remoteAddr)
 		// Notify client via onStatus, then return error to close the session.
 		_ = s.sendOnStatus(streamID, "error",
 			"NetStream.Publish.Failed", "Authentication failed")
@@ -90,8 +98,35 @@ func (s *ServiceSession) HandlePublish(command amf0.Array, streamID uint32) erro
 	if app == "" {
 		return fmt.Errorf("app not set")
 	}
-
 	streamKey := bus.NewStreamKey(app, streamName)
+	if err := s.names.Check(streamKey); err != nil {
+		log.Printf("Publish rejected: %v (from %s)", err, s.remoteAddr)
+		s.guard.Reject(access.ReasonBadName)
+		s.rejected(streamKey, access.ReasonBadName, err.Error())
+		_ = s.sendOnStatus(streamID, "error", "NetStream.Publish.BadName", "Invalid stream name")
+		return err
+	}
+	if !s.guard.Allow(app, access.Publish, s.remoteAddr) {
+		log.Printf("Publish rejected: %s/%s from %s is not allowed", app, streamName, s.remoteAddr)
+		s.rejected(streamKey, access.ReasonPublishDenied, "")
+		_ = s.sendOnStatus(streamID, "error",
+			"NetStream.Publish.Rejected", "Address not allowed")
+		return fmt.Errorf("publish from %s denied by access rules", s.remoteAddr)
+	}
+	if s.limiter != nil {
+		release, ok := s.limiter.ReservePublish(app)
+		if !ok {
+			log.Printf("Publish rejected: %s/%s, server at stream capacity", app, streamName)
+			s.guard.Reject(access.ReasonCapacity)
+			s.rejected(streamKey, access.ReasonCapacity, "")
+			_ = s.sendOnStatus(streamID, "error",
+				"NetStream.Publish.Rejected", "Server at capacity")
+			return fmt.Errorf("publish of %s/%s over capacity", app, streamName)
+		}
+		// The attached publisher takes over the slot; see ReservePublish.
+		defer release()
+	}
+
 	stream, created := s.registry.GetOrCreate(streamKey)
 	if !created {
 		log.Printf("Stream %s already exists", streamKey)
@@ -103,7 +138,19 @@ func (s *ServiceSession) HandlePublish(command amf0.Array, streamID uint32) erro
 		return fmt.Errorf("stream already has a publisher")
 	}
 
+	stream.SetPublisherAddr(s.remoteAddr)
+	log.Printf("Publish started: %s from %s", streamKey, s.remoteAddr)
+
 	s.publisher = NewPublisher(s.Session, stream, publisherID)
+	s.publisher.sanitize = newSanitizer(policyFor(s.sanitizers, app), s.fixes.counter(streamKey))
+	s.publisher.captions = newCaptionDecoder(policyFor(s.captions, app))
+	s.policy = newPolicyCheck(policyFor(s.policies, app))
+	s.watchdog = newWatchdog(policyFor(s.watchdogs, app), streamKey, s.remoteAddr, s.events, s.alerts,
+		func() { _ = s.conn.Close() })
+	s.watchdog.start()
+	s.streamID = streamID
+	s.tracked = s.table.Open(sessions.ProtoRTMP, sessions.RolePublisher, streamKey,
+		s.remoteAddr, s.flashVer, func() { _ = s.conn.Close() })
 	s.SetStreamName(streamName)
 	s.SetState(rtmpprotocol.StatePublishing)
 
@@ -151,3 +198,61 @@ func extractStreamName(command amf0.Array) string {
 	}
 	return ""
 }
+
+// handleCommand handles AMF0 command messages.
+// streamID is the stream ID from the message header.
+func (s *Server) handleCommand(session *ServiceSession, body []byte, streamID uint32) error {
+	command, err := amf0.DecodeCommand(bytes.NewReader(body))
+	if err != nil {
+		logDecodeError(body, err)
+		return err
+	}
+	if len(command) == 0 {
+		return nil
+	}
+
+	cmdName, ok := command[0].(string)
+	if !ok {
+		return nil
+	}
+
+	switch cmdName {
+	case "connect":
+		log.Printf("Command: connect")
+		return session.HandleConnect(command)
+	case "releaseStream":
+		log.Printf("Command: releaseStream")
+		return session.HandleReleaseStream(command)
+	case "FCPublish":
+		log.Printf("Command: FCPublish")
+		return session.HandleFCPublish(command)
+	case "createStream":
+		log.Printf("Command: createStream")
+		return session.HandleCreateStream(command)
+	case "publish":
+		log.Printf("Command: publish (streamID=%d)", streamID)
+		return session.HandlePublish(command, streamID)
+	case "deleteStream", "closeStream":
+		log.Printf("Command: %s", cmdName)
+		session.Close()
+		return nil
+	case "FCUnpublish":
+		log.Printf("Command: FCUnpublish (ignored)")
+		return nil
+	default:
+		log.Printf("Command: %s (unhandled)", cmdName)
+		return nil
+	}
+}
+
+// logDecodeError logs diagnostic info for failed AMF0 decoding.
+func logDecodeError(body []byte, err error) {
+	hex := ""
+	if len(body) > 0 {
+		hex = fmt.Sprintf(" first_byte=0x%02x", body[0])
+		if len(body) > 15 {
+			hex += fmt.Sprintf(" hex=%x", body[:16])
+		}
+	}
+	log.Printf("Failed to decode command: %v (len=%d%s)", err, len(body), hex)
+}
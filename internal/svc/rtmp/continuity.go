@@ -0,0 +1,79 @@
+// If you are AI: This file keeps a stream's timeline continuous when a new
+// publisher replaces a previous one (relay failover, encoder reconnect).
+// Viewers already attached to the bus.Stream keep playing: the new source is
+// held back until its first video keyframe and its timestamps are rebased to
+// continue from the last timestamp the old source produced.
+
+package rtmp
+
+import (
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/flv"
+)
+
+// handoverGap is added between the previous publisher's last timestamp and
+// the new publisher's first frame so the timeline stays strictly increasing.
+const handoverGap = 33 // ms, roughly one frame at 30 fps
+
+// continuity rebases timestamps for a publisher that takes over a stream
+// which already carried media. The zero value is a pass-through used for
+// the first publisher of a stream.
+type continuity struct {
+	active       bool   // a previous publisher existed; rebase + gate
+	base         uint32 // output timestamp of the first forwarded frame
+	first        uint32 // input timestamp of the first forwarded frame
+	anchored     bool   // first has been captured
+	gated        bool   // still waiting for the first video keyframe
+	sawVideoInit bool   // new source announced video (AVC sequence header)
+}
+
+// newContinuity inspects the stream's previous timeline and returns the
+// rebaser for a publisher that has just attached.
+func newContinuity(stream *bus.Stream) continuity {
+	last, ok := stream.LastTimestamp()
+	if !ok {
+		return continuity{}
+	}
+	return continuity{active: true, base: last + handoverGap, gated: true}
+}
+
+// apply returns the timestamp to publish for a message and whether it should
+// be forwarded at all. Init messages always pass. Until the first video
+// keyframe only audio from a source that has not announced video passes, so
+// the switch is keyframe-aligned for viewers.
+func (c *continuity) apply(typ bus.MessageType, ts uint32, payload []byte, isInit bool) (uint32, bool) {
+	if !c.active {
+		return ts, true
+	}
+	if isInit {
+		if typ == bus.MessageTypeVideo {
+			c.sawVideoInit = true
+		}
+		return c.rebased(ts), true
+	}
+	if c.gated {
+		switch {
+		case typ == bus.MessageTypeVideo && flv.IsVideoKeyframe(payload):
+			c.gated = false
+		case typ == bus.MessageTypeAudio && !c.sawVideoInit:
+			// Audio-only source: nothing to align to.
+		default:
+			return 0, false
+		}
+	}
+	if !c.anchored {
+		c.first = ts
+		c.anchored = true
+	}
+	return c.rebased(ts), true
+}
+
+// rebased maps an input timestamp onto the continued timeline. Timestamps
+// before the anchor (B-frame reordering, early init) clamp to the base;
+// differences are modulo 2^32 so a wrapping timeline stays continuous.
+func (c *continuity) rebased(ts uint32) uint32 {
+	if !c.anchored || int32(ts-c.first) < 0 {
+		return c.base
+	}
+	return c.base + (ts - c.first)
+}
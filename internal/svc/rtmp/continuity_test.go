@@ -0,0 +1,42 @@
+// If you are AI: This file unit-tests timeline continuation across publisher handovers.
+
+package rtmp
+
+import (
+	"testing"
+
+	"nonchalant/internal/core/bus"
+)
+
+// TestContinuityFirstPublisherPassThrough: a fresh stream is not rebased.
+func TestContinuityFirstPublisherPassThrough(t *testing.T) {
+	c := newContinuity(bus.NewStream(bus.NewStreamKey("live", "x")))
+	if ts, ok := c.apply(bus.MessageTypeVideo, 500, []byte{0x27, 1}, false); !ok || ts != 500 {
+		t.Errorf("apply = (%d,%v), want (500,true)", ts, ok)
+	}
+}
+
+// TestContinuityHandover: the replacement publisher is gated on a keyframe
+// and continues from the previous timeline.
+func TestContinuityHandover(t *testing.T) {
+	stream := bus.NewStream(bus.NewStreamKey("live", "x"))
+	stream.Publish(&bus.MediaMessage{Type: bus.MessageTypeVideo, Timestamp: 9000})
+	c := newContinuity(stream)
+
+	if _, ok := c.apply(bus.MessageTypeVideo, 0, []byte{0x17, 0}, true); !ok {
+		t.Fatal("init messages must always pass")
+	}
+	if _, ok := c.apply(bus.MessageTypeVideo, 40, []byte{0x27, 1}, false); ok {
+		t.Error("inter frame before first keyframe should be dropped")
+	}
+	if _, ok := c.apply(bus.MessageTypeAudio, 45, []byte{0xAF, 1}, false); ok {
+		t.Error("audio before first keyframe should be dropped once video is announced")
+	}
+	ts, ok := c.apply(bus.MessageTypeVideo, 80, []byte{0x17, 1}, false)
+	if !ok || ts != 9000+handoverGap {
+		t.Errorf("keyframe = (%d,%v), want (%d,true)", ts, ok, 9000+handoverGap)
+	}
+	if ts, _ := c.apply(bus.MessageTypeAudio, 100, []byte{0xAF, 1}, false); ts != 9000+handoverGap+20 {
+		t.Errorf("follow-up audio ts = %d, want %d", ts, 9000+handoverGap+20)
+	}
+}
@@ -0,0 +1,179 @@
+// If you are AI: This file enforces per-app publish policies: allowed codecs,
+// the resolution and frame rate from the AVC sequence header and onMetaData,
+// and ingest bitrate over a sliding window with a grace period.
+
+package rtmp
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"nonchalant/internal/core/protocol/amf0"
+	"nonchalant/internal/core/protocol/avc"
+	"nonchalant/internal/core/protocol/flv"
+	rtmpprotocol "nonchalant/internal/core/protocol/rtmp"
+)
+
+// PublishPolicy restricts what a publisher may send. Zero or empty fields
+// are not checked.
+type PublishPolicy struct {
+	VideoCodecs    []string // flv codec names, e.g. "h264"
+	AudioCodecs    []string // flv codec names, e.g. "aac"
+	MaxWidth       int
+	MaxHeight      int
+	MaxFPS         float64
+	MaxBitrateKbps int
+	BitrateWindow  time.Duration // averaging window; default 5s
+	BitrateGrace   time.Duration // how long the rate may stay over; default 10s
+}
+
+// Bitrate defaults, used when the policy leaves them zero.
+const (
+	defaultBitrateWindow = 5 * time.Second
+	defaultBitrateGrace  = 10 * time.Second
+)
+
+// SetPublishPolicies applies policies by app name; the "*" entry covers
+// apps without their own. A nil map disables policy checks.
+func (s *Server) SetPublishPolicies(p map[string]*PublishPolicy) { s.policies = p }
+
+// policyFor returns the per-app setting for app, falling back to "*", or
+// nil.
+func policyFor[T any](policies map[string]*T, app string) *T {
+	if p, ok := policies[app]; ok {
+		return p
+	}
+	return policies["*"]
+}
+
+// policyCheck tracks one publish against its policy.
+type policyCheck struct {
+	policy    *PublishPolicy
+	rate      *rateWindow // nil without a bitrate cap
+	overSince time.Time   // zero while within the bitrate cap
+	now       func() time.Time
+}
+
+// newPolicyCheck starts checking a publish against p (nil when p is nil).
+func newPolicyCheck(p *PublishPolicy) *policyCheck {
+	if p == nil {
+		return nil
+	}
+	c := &policyCheck{policy: p, now: time.Now}
+	if p.MaxBitrateKbps > 0 {
+		window := p.BitrateWindow
+		if window <= 0 {
+			window = defaultBitrateWindow
+		}
+		c.rate = newRateWindow(window, c.now())
+	}
+	return c
+}
+
+// check returns why a media message breaks the policy, or "".
+func (c *policyCheck) check(msgType byte, payload []byte) string {
+	if c == nil {
+		return ""
+	}
+	if reason := c.bitrate(len(payload)); reason != "" {
+		return reason
+	}
+	switch msgType {
+	case rtmpprotocol.MessageTypeVideo:
+		if codec := flv.VideoCodec(payload); !allowed(c.policy.VideoCodecs, codec) {
+			return fmt.Sprintf("video codec %s not allowed", orUnknown(codec))
+		}
+		if isAVCSequenceHeader(payload) && len(payload) > 5 {
+			sps, err := avc.ParseDecoderConfig(payload[5:])
+			if err != nil {
+				return fmt.Sprintf("unreadable AVC sequence header: %v", err)
+			}
+			return c.frame(sps.Width, sps.Height, sps.FPS)
+		}
+	case rtmpprotocol.MessageTypeAudio:
+		if codec := flv.AudioCodec(payload); !allowed(c.policy.AudioCodecs, codec) {
+			return fmt.Sprintf("audio codec %s not allowed", orUnknown(codec))
+		}
+	case rtmpprotocol.MessageTypeDataAMF0:
+		return c.metadata(stripSetDataFrame(payload))
+	}
+	return ""
+}
+
+// metadata checks the codecs, size and frame rate an onMetaData declares.
+func (c *policyCheck) metadata(payload []byte) string {
+	data, err := amf0.DecodeCommand(bytes.NewReader(payload))
+	if err != nil || len(data) < 2 || data[0] != "onMetaData" {
+		return ""
+	}
+	meta := toObject(data[1])
+	if codec := flv.MetadataVideoCodec(meta["videocodecid"]); codec != "" && !allowed(c.policy.VideoCodecs, codec) {
+		return fmt.Sprintf("video codec %s not allowed", codec)
+	}
+	if codec := flv.MetadataAudioCodec(meta["audiocodecid"]); codec != "" && !allowed(c.policy.AudioCodecs, codec) {
+		return fmt.Sprintf("audio codec %s not allowed", codec)
+	}
+	width, _ := meta["width"].(float64)
+	height, _ := meta["height"].(float64)
+	fps, _ := meta["framerate"].(float64)
+	return c.frame(int(width), int(height), fps)
+}
+
+// frame checks a resolution and frame rate; zero values are unknown.
+func (c *policyCheck) frame(width, height int, fps float64) string {
+	p := c.policy
+	if (p.MaxWidth > 0 && width > p.MaxWidth) || (p.MaxHeight > 0 && height > p.MaxHeight) {
+		return fmt.Sprintf("resolution %dx%d exceeds %dx%d", width, height, p.MaxWidth, p.MaxHeight)
+	}
+	if p.MaxFPS > 0 && fps > p.MaxFPS+0.01 {
+		return fmt.Sprintf("frame rate %.2f exceeds %g", fps, p.MaxFPS)
+	}
+	return ""
+}
+
+// bitrate adds n bytes to the window and reports a rate that has stayed
+// over the cap for longer than the grace period.
+func (c *policyCheck) bitrate(n int) string {
+	if c.rate == nil {
+		return ""
+	}
+	now := c.now()
+	kbps, full := c.rate.add(now, n)
+	if !full || kbps <= c.policy.MaxBitrateKbps {
+		c.overSince = time.Time{}
+		return ""
+	}
+	if c.overSince.IsZero() {
+		c.overSince = now
+	}
+	grace := c.policy.BitrateGrace
+	if grace <= 0 {
+		grace = defaultBitrateGrace
+	}
+	if now.Sub(c.overSince) < grace {
+		return ""
+	}
+	return fmt.Sprintf("bitrate %d kbps over %d kbps for %s", kbps, c.policy.MaxBitrateKbps, grace)
+}
+
+// allowed reports whether codec is in list; an empty list allows anything.
+func allowed(list []string, codec string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, c := range list {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
+
+// orUnknown names an unrecognised codec in reasons.
+func orUnknown(codec string) string {
+	if codec == "" {
+		return "unknown"
+	}
+	return codec
+}
@@ -0,0 +1,92 @@
+// If you are AI: This file unit-tests publish policy checks: codecs,
+// onMetaData limits and the bitrate window with its grace period.
+
+package rtmp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/protocol/amf0"
+	rtmpprotocol "nonchalant/internal/core/protocol/rtmp"
+)
+
+// premium is the policy the tests check against.
+var premium = &PublishPolicy{
+	VideoCodecs: []string{"h264"}, AudioCodecs: []string{"aac"},
+	MaxWidth: 1920, MaxHeight: 1080, MaxFPS: 30,
+}
+
+// TestPolicyCodecs: HEVC (legacy id 12 and Enhanced RTMP hvc1) and MP3 are
+// refused; H.264 and AAC pass.
+func TestPolicyCodecs(t *testing.T) {
+	c := newPolicyCheck(premium)
+	if r := c.check(rtmpprotocol.MessageTypeVideo, []byte{0x17, 1, 0, 0, 0}); r != "" {
+		t.Errorf("h264 refused: %s", r)
+	}
+	if r := c.check(rtmpprotocol.MessageTypeAudio, []byte{0xAF, 1}); r != "" {
+		t.Errorf("aac refused: %s", r)
+	}
+	if r := c.check(rtmpprotocol.MessageTypeVideo, []byte{0x1C, 1}); !strings.Contains(r, "hevc") {
+		t.Errorf("legacy hevc: %q", r)
+	}
+	if r := c.check(rtmpprotocol.MessageTypeVideo, []byte{0x91, 'h', 'v', 'c', '1'}); !strings.Contains(r, "hevc") {
+		t.Errorf("enhanced hevc: %q", r)
+	}
+	if r := c.check(rtmpprotocol.MessageTypeAudio, []byte{0x2F, 1}); !strings.Contains(r, "mp3") {
+		t.Errorf("mp3: %q", r)
+	}
+	if newPolicyCheck(nil).check(rtmpprotocol.MessageTypeVideo, []byte{0x1C, 1}) != "" {
+		t.Error("nil policy refused a message")
+	}
+}
+
+// TestPolicyMetadata: onMetaData over the size or frame rate cap is refused.
+func TestPolicyMetadata(t *testing.T) {
+	meta := func(w, h, fps float64) []byte {
+		b, _ := amf0.EncodeCommand(amf0.Array{"@setDataFrame", "onMetaData",
+			amf0.Object{"width": w, "height": h, "framerate": fps, "videocodecid": float64(7)}})
+		return b
+	}
+	c := newPolicyCheck(premium)
+	if r := c.check(rtmpprotocol.MessageTypeDataAMF0, meta(1920, 1080, 29.97)); r != "" {
+		t.Errorf("1080p30 refused: %s", r)
+	}
+	if r := c.check(rtmpprotocol.MessageTypeDataAMF0, meta(3840, 2160, 30)); !strings.Contains(r, "3840x2160") {
+		t.Errorf("4k: %q", r)
+	}
+	if r := c.check(rtmpprotocol.MessageTypeDataAMF0, meta(1280, 720, 60)); !strings.Contains(r, "frame rate") {
+		t.Errorf("60fps: %q", r)
+	}
+}
+
+// TestPolicyBitrate: a rate over the cap is tolerated for the grace period,
+// then refused; dropping back under resets the grace.
+func TestPolicyBitrate(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := newPolicyCheck(&PublishPolicy{MaxBitrateKbps: 1000, BitrateWindow: 2 * time.Second, BitrateGrace: 3 * time.Second})
+	c.now = func() time.Time { return now }
+	c.rate.start = now
+	send := func(bytesPerSec int, seconds int) string {
+		for i := 0; i < seconds; i++ {
+			if r := c.check(rtmpprotocol.MessageTypeVideo, make([]byte, bytesPerSec)); r != "" {
+				return r
+			}
+			now = now.Add(time.Second)
+		}
+		return ""
+	}
+	if r := send(100_000, 10); r != "" { // 800 kbps
+		t.Fatalf("under the cap: %s", r)
+	}
+	if r := send(200_000, 2); r != "" { // 1600 kbps, within grace
+		t.Fatalf("within grace: %s", r)
+	}
+	if r := send(100_000, 4); r != "" {
+		t.Fatalf("back under the cap: %s", r)
+	}
+	if r := send(200_000, 10); !strings.Contains(r, "1600 kbps") {
+		t.Errorf("sustained overrun: %q", r)
+	}
+}
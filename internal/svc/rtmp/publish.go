@@ -6,6 +6,7 @@ package rtmp
 import (
 	"log"
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/flv"
 	rtmpprotocol "nonchalant/internal/core/protocol/rtmp"
 )
 
@@ -16,6 +17,11 @@ type Publisher struct {
 	stream      *bus.Stream
 	streamKey   bus.StreamKey
 	publisherID uint64
+	cont        continuity // timeline continuation after a publisher handover
+	sanitize    *sanitizer // nil publishes timestamps as sent
+	clock       encoderClock
+	captions    *captionDecoder // nil unless the app decodes captions
+	audio       bus.AudioCodec  // last descriptor set on the stream
 }
 
 // NewPublisher creates a new publisher for a stream.
@@ -25,62 +31,100 @@ func NewPublisher(session *rtmpprotocol.Session, stream *bus.Stream, publisherID
 		stream:      stream,
 		streamKey:   stream.Key(),
 		publisherID: publisherID,
+		cont:        newContinuity(stream),
 	}
 }
 
 // PublishAudio publishes an audio message to the stream.
-// Detects AAC sequence headers and marks them as init data for late-joining subscribers.
+// Detects audio sequence headers (AAC, Enhanced RTMP) and marks them as init data for late-joining subscribers.
 func (p *Publisher) PublishAudio(timestamp uint32, payload []byte) {
-	msg := p.stream.AcquireMessage()
-	msg.Type = bus.MessageTypeAudio
-	msg.Timestamp = timestamp
-	msg.IsInit = isAACSequenceHeader(payload)
-
-	buf := p.stream.AcquirePayload(len(payload))
-	msg.Payload = append(buf, payload...)
-
-	if msg.IsInit {
-		log.Printf("Cached AAC sequence header (%d bytes)", len(payload))
-	}
-
-	p.stream.Publish(msg)
+	p.sanitize.push(bus.MessageTypeAudio, timestamp, payload, flv.IsAudioSequenceHeader(payload), p.forward)
 }
 
 // PublishVideo publishes a video message to the stream.
 // Detects AVC sequence headers and marks them as init data for late-joining subscribers.
 func (p *Publisher) PublishVideo(timestamp uint32, payload []byte) {
-	msg := p.stream.AcquireMessage()
-	msg.Type = bus.MessageTypeVideo
-	msg.Timestamp = timestamp
-	msg.IsInit = isAVCSequenceHeader(payload)
-
-	buf := p.stream.AcquirePayload(len(payload))
-	msg.Payload = append(buf, payload...)
-
-	if msg.IsInit {
-		log.Printf("Cached AVC sequence header (%d bytes)", len(payload))
-	}
-
-	p.stream.Publish(msg)
+	p.sanitize.push(bus.MessageTypeVideo, timestamp, payload, isAVCSequenceHeader(payload), p.forward)
 }
 
 // PublishMetadata publishes a metadata message to the stream.
-// Metadata (@setDataFrame / onMetaData) is always treated as init data.
+// Only onMetaData (sent as @setDataFrame) is treated as init data; all
+// other script data (onCuePoint, onTextData, onSCTE35, custom names)
+// passes through on the timeline.
 // The RTMP @setDataFrame prefix is stripped so the FLV script tag starts with "onMetaData".
 func (p *Publisher) PublishMetadata(timestamp uint32, payload []byte) {
 	payload = stripSetDataFrame(payload)
+	isInit := flv.ScriptName(payload) == flv.ScriptMetaData
+	p.sanitize.push(bus.MessageTypeMetadata, timestamp, payload, isInit, p.forward)
+}
 
+// forward copies a sanitized message into the stream. After a publisher
+// handover, frames are rebased onto the previous timeline and nothing but
+// init data passes until the first keyframe. Encoder clock data anchors
+// the stream's wall clock; onFI itself is not passed on. Captions decoded
+// from a video frame go out just ahead of it; audio keeps the stream's
+// codec descriptor current.
+func (p *Publisher) forward(typ bus.MessageType, timestamp uint32, payload []byte, isInit bool) {
+	timestamp, ok := p.cont.apply(typ, timestamp, payload, isInit)
+	if !ok {
+		return
+	}
+	if p.anchor(typ, timestamp, payload, isInit) {
+		return
+	}
+	switch typ {
+	case bus.MessageTypeAudio:
+		p.noteAudio(payload)
+	case bus.MessageTypeVideo:
+		for _, c := range p.captions.frame(timestamp, payload) {
+			p.publish(bus.MessageTypeMetadata, timestamp, c, false)
+		}
+	}
+	p.publish(typ, timestamp, payload, isInit)
+}
+
+// publish copies a message into the stream.
+func (p *Publisher) publish(typ bus.MessageType, timestamp uint32, payload []byte, isInit bool) {
 	msg := p.stream.AcquireMessage()
-	msg.Type = bus.MessageTypeMetadata
+	msg.Type = typ
 	msg.Timestamp = timestamp
-	msg.IsInit = true // Metadata is always init data
+	msg.IsInit = isInit
 
 	buf := p.stream.AcquirePayload(len(payload))
 	msg.Payload = append(buf, payload...)
 
+	switch {
+	case !isInit:
+	case typ == bus.MessageTypeAudio:
+		log.Printf("Cached %s sequence header (%d bytes)", p.audio.Name, len(payload))
+	case typ == bus.MessageTypeVideo:
+		log.Printf("Cached AVC sequence header (%d bytes)", len(payload))
+	}
+
 	p.stream.Publish(msg)
 }
 
+// anchor sets the stream's clock from an onFI message or a keyframe's
+// picture timing SEI, and reports whether the message was onFI.
+func (p *Publisher) anchor(typ bus.MessageType, timestamp uint32, payload []byte, isInit bool) bool {
+	switch {
+	case typ == bus.MessageTypeMetadata:
+		wall, ok := parseOnFI(payload)
+		if ok {
+			p.stream.SetClock(bus.Clock{Timestamp: timestamp, Wall: wall, Source: bus.ClockOnFI})
+		}
+		return ok
+	case typ != bus.MessageTypeVideo:
+	case isInit:
+		p.clock.sequenceHeader(payload)
+	case flv.IsVideoKeyframe(payload):
+		if pts, wall, ok := p.clock.frame(timestamp, payload); ok {
+			p.stream.SetClock(bus.Clock{Timestamp: pts, Wall: wall, Source: bus.ClockSEI})
+		}
+	}
+	return false
+}
+
 // stripSetDataFrame removes the RTMP-specific "@setDataFrame" AMF0 string prefix.
 // RTMP data messages contain: "@setDataFrame" + "onMetaData" + metadata_object.
 // FLV script tags expect:                      "onMetaData" + metadata_object.
@@ -111,15 +155,35 @@ func isAVCSequenceHeader(payload []byte) bool {
 	return len(payload) >= 2 && (payload[0]&0x0F) == 7 && payload[1] == 0
 }
 
-// isAACSequenceHeader detects an AAC AudioSpecificConfig.
-// In RTMP/FLV audio format: byte[0] upper nibble = sound format (10=AAC), byte[1] = packet type (0=seq header).
-func isAACSequenceHeader(payload []byte) bool {
-	return len(payload) >= 2 && (payload[0]>>4) == 10 && payload[1] == 0
+// noteAudio updates the stream's audio codec descriptor from an audio
+// tag. Fields a tag leaves unsaid keep their values while the codec
+// stays the same, so AAC frames do not undo their AudioSpecificConfig.
+func (p *Publisher) noteAudio(payload []byte) {
+	f := flv.ParseAudioFormat(payload)
+	if f.Codec == "" {
+		return
+	}
+	c := p.audio
+	if c.Name != f.Codec {
+		c = bus.AudioCodec{Name: f.Codec, NeedsInit: f.NeedsInit}
+	}
+	if f.SampleRate > 0 {
+		c.SampleRate = f.SampleRate
+	}
+	if f.Channels > 0 {
+		c.Channels = f.Channels
+	}
+	if c != p.audio {
+		p.audio = c
+		p.stream.SetAudioCodec(c)
+	}
 }
 
-// Detach detaches the publisher from the stream.
+// Detach publishes whatever the re-interleave window still holds and
+// detaches the publisher from the stream.
 func (p *Publisher) Detach() {
 	if p.stream != nil {
+		p.sanitize.flush(p.forward)
 		p.stream.DetachPublisher()
 	}
 }
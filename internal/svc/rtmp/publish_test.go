@@ -0,0 +1,60 @@
+// If you are AI: This file unit-tests the audio codec descriptor the
+// publisher keeps on its stream and how it classifies script data.
+
+package rtmp
+
+import (
+	"testing"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/amf0"
+)
+
+// TestPublisherAudioCodec describes G.711 from its first frame and AAC
+// from its sequence header, which later frames do not undo.
+func TestPublisherAudioCodec(t *testing.T) {
+	stream := bus.NewStream(bus.NewStreamKey("live", "cam"))
+	p := NewPublisher(nil, stream, 1)
+	p.PublishAudio(0, []byte{0x72, 0xD5, 0xD5})
+	if c, ok := stream.AudioCodec(); !ok || c != (bus.AudioCodec{Name: "pcma", SampleRate: 8000, Channels: 1}) {
+		t.Fatalf("codec = %+v, %v", c, ok)
+	}
+	if !stream.HasAudio() {
+		t.Fatal("G.711 stream has no audio")
+	}
+
+	p.PublishAudio(20, []byte{0xAF, 0, 0x11, 0x90})
+	p.PublishAudio(40, []byte{0xAF, 1, 0x21})
+	want := bus.AudioCodec{Name: "aac", SampleRate: 48000, Channels: 2, NeedsInit: true}
+	if c, _ := stream.AudioCodec(); c != want {
+		t.Fatalf("codec = %+v, want %+v", c, want)
+	}
+	if !stream.HasAudioInit() || !stream.HasAudio() {
+		t.Fatal("AAC sequence header not cached")
+	}
+}
+
+// TestPublisherScriptData caches only onMetaData; script data under any
+// other name passes through on the timeline without replacing it.
+func TestPublisherScriptData(t *testing.T) {
+	stream := bus.NewStream(bus.NewStreamKey("live", "cam"))
+	p := NewPublisher(nil, stream, 1)
+	script := func(items ...amf0.Value) []byte {
+		b, err := amf0.EncodeCommand(items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	meta := script("onMetaData", amf0.Object{"width": 1280.0})
+	p.PublishMetadata(0, script("@setDataFrame", "onMetaData", amf0.Object{"width": 1280.0}))
+	p.PublishMetadata(40, script("onUserData", amf0.Object{"n": 1.0}))
+
+	if _, _, m := stream.InitMessages(); m == nil || string(m.Payload) != string(meta) {
+		t.Fatalf("cached metadata = %v", m)
+	}
+	cues := stream.Cues()
+	if len(cues) != 1 || cues[0].Timestamp != 40 {
+		t.Fatalf("cues = %+v", cues)
+	}
+}
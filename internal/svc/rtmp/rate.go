@@ -0,0 +1,48 @@
+// If you are AI: This file measures ingest bitrate over a sliding window of
+// whole seconds, for the publish policy cap and the ingest watchdog.
+
+package rtmp
+
+import "time"
+
+// rateWindow sums bytes per second over a sliding window.
+type rateWindow struct {
+	buckets []int // bytes per second: the window plus the current second
+	start   time.Time
+	second  int64 // index of the newest bucket
+}
+
+// newRateWindow returns a window of the given length (rounded up to whole
+// seconds) starting at now.
+func newRateWindow(window time.Duration, now time.Time) *rateWindow {
+	return &rateWindow{
+		buckets: make([]int, int((window+time.Second-1)/time.Second)+1),
+		start:   now,
+	}
+}
+
+// add counts n bytes at now and returns the average rate over the
+// window's completed seconds. full is false until the window has elapsed
+// once. add(now, 0) just reads the rate.
+func (w *rateWindow) add(now time.Time, n int) (kbps int, full bool) {
+	sec := int64(now.Sub(w.start) / time.Second)
+	for ; w.second < sec; w.second++ {
+		if sec-w.second > int64(len(w.buckets)) {
+			w.second = sec - int64(len(w.buckets))
+		}
+		w.buckets[(w.second+1)%int64(len(w.buckets))] = 0
+	}
+	current := int(sec % int64(len(w.buckets)))
+	w.buckets[current] += n
+	window := len(w.buckets) - 1
+	if sec < int64(window) {
+		return 0, false
+	}
+	total := 0
+	for i, b := range w.buckets {
+		if i != current {
+			total += b
+		}
+	}
+	return total * 8 / 1000 / window, true
+}
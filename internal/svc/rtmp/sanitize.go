@@ -0,0 +1,241 @@
+// If you are AI: This file normalizes a publisher's timestamps before they
+// reach the bus: monotonic DTS per track, 32-bit wrap treated as forward
+// time, large jumps (encoder restarts) smoothed into a small gap, and an
+// optional re-interleave window that puts audio and video in order.
+
+package rtmp
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// Sanitizer sets how one app's publisher timestamps are normalized.
+type Sanitizer struct {
+	MaxJump    time.Duration // larger steps on a track are smoothed; default 3s
+	Interleave time.Duration // re-interleave window; 0 publishes in arrival order
+}
+
+// Timestamp correction kinds, reported as the metric label.
+const (
+	FixWrap      = "wrap"      // 32-bit wrap, passed on as forward time
+	FixBackwards = "backwards" // small step back, held at the previous DTS
+	FixJump      = "jump"      // step over MaxJump either way, smoothed
+	FixReorder   = "reorder"   // moved by the re-interleave window
+)
+
+// defaultMaxJump is the largest timestamp step taken as is.
+const defaultMaxJump = 3 * time.Second
+
+// SetSanitizers normalizes publisher timestamps by app name; the "*" entry
+// covers apps without their own. A nil map publishes timestamps as sent.
+func (s *Server) SetSanitizers(z map[string]*Sanitizer) { s.sanitizers = z }
+
+// TimestampCorrections returns the corrections made so far, by stream and
+// kind.
+func (s *Server) TimestampCorrections() map[bus.StreamKey]map[string]uint64 {
+	return s.fixes.snapshot()
+}
+
+// Track indices in sanitizer state.
+const (
+	trackAudio = iota
+	trackVideo
+)
+
+// queued is a message held by the re-interleave window. The payload is a
+// private copy: the connection reuses its buffer after each message.
+type queued struct {
+	typ     bus.MessageType
+	ts      uint32
+	payload []byte
+	isInit  bool
+}
+
+// sanitizer normalizes one publish. It runs on the connection goroutine
+// only. Times are uint32 milliseconds compared modulo 2^32.
+type sanitizer struct {
+	maxJump    uint32
+	interleave uint32
+	count      func(kind string)
+
+	last   [2]uint32 // last input timestamp per track
+	out    [2]uint32 // last output timestamp per track
+	offset [2]uint32 // output = input + offset
+	seen   [2]bool
+	head   uint32 // highest output timestamp on any track
+	headOK bool   // head has been set
+	queue  []queued
+}
+
+// newSanitizer returns a sanitizer for cfg, or nil when cfg is nil. count
+// records each correction.
+func newSanitizer(cfg *Sanitizer, count func(kind string)) *sanitizer {
+	if cfg == nil {
+		return nil
+	}
+	maxJump := cfg.MaxJump
+	if maxJump <= 0 {
+		maxJump = defaultMaxJump
+	}
+	return &sanitizer{
+		maxJump:    uint32(maxJump / time.Millisecond),
+		interleave: uint32(cfg.Interleave / time.Millisecond),
+		count:      count,
+	}
+}
+
+// push normalizes one message and hands it, or whatever the window
+// releases, to emit. A nil sanitizer passes the message straight on.
+func (z *sanitizer) push(typ bus.MessageType, ts uint32, payload []byte, isInit bool,
+	emit func(bus.MessageType, uint32, []byte, bool)) {
+	if z == nil {
+		emit(typ, ts, payload, isInit)
+		return
+	}
+	ts = z.fix(typ, ts, isInit)
+	if z.interleave == 0 {
+		emit(typ, ts, payload, isInit)
+		return
+	}
+	i := sort.Search(len(z.queue), func(i int) bool { return int32(z.queue[i].ts-ts) > 0 })
+	if i < len(z.queue) {
+		z.count(FixReorder)
+	}
+	z.queue = append(z.queue, queued{})
+	copy(z.queue[i+1:], z.queue[i:])
+	z.queue[i] = queued{typ: typ, ts: ts, payload: append([]byte(nil), payload...), isInit: isInit}
+	n := 0
+	for n < len(z.queue) && int32(z.head-z.queue[n].ts) >= int32(z.interleave) {
+		n++
+	}
+	z.release(n, emit)
+}
+
+// flush emits everything the window still holds.
+func (z *sanitizer) flush(emit func(bus.MessageType, uint32, []byte, bool)) {
+	if z != nil {
+		z.release(len(z.queue), emit)
+	}
+}
+
+// release emits the first n queued messages.
+func (z *sanitizer) release(n int, emit func(bus.MessageType, uint32, []byte, bool)) {
+	for _, q := range z.queue[:n] {
+		emit(q.typ, q.ts, q.payload, q.isInit)
+	}
+	z.queue = append(z.queue[:0], z.queue[n:]...)
+}
+
+// fix returns the output timestamp for one message. Init messages and
+// metadata sit at their track's current position and change no state.
+func (z *sanitizer) fix(typ bus.MessageType, ts uint32, isInit bool) uint32 {
+	track := trackAudio
+	switch typ {
+	case bus.MessageTypeVideo:
+		track = trackVideo
+	case bus.MessageTypeAudio:
+	default:
+		if z.headOK {
+			return z.head
+		}
+		return ts
+	}
+	if !z.seen[track] {
+		if isInit {
+			return ts
+		}
+		z.seen[track] = true
+		z.last[track], z.out[track] = ts, ts
+		z.advance(ts)
+		return ts
+	}
+	if isInit {
+		return z.out[track]
+	}
+
+	step := int32(ts - z.last[track])
+	if step > 0 && ts < z.last[track] {
+		z.count(FixWrap)
+	}
+	z.last[track] = ts
+	if step > int32(z.maxJump) || -step > int32(z.maxJump) {
+		z.count(FixJump)
+		z.reanchor(track, ts)
+	}
+	out := ts + z.offset[track]
+	if int32(out-z.out[track]) < 0 {
+		z.count(FixBackwards)
+		out = z.out[track]
+	}
+	z.out[track] = out
+	z.advance(out)
+	return out
+}
+
+// reanchor moves a track that jumped next to the timeline. If the
+// other track already jumped the same way (an encoder restart), the track
+// takes the other's offset so the two stay in sync; otherwise it resumes
+// just after the newest output.
+func (z *sanitizer) reanchor(track int, ts uint32) {
+	other := 1 - track
+	if z.seen[other] {
+		if gap := int32(ts + z.offset[other] - z.out[track]); gap >= 0 && gap <= int32(z.maxJump) {
+			z.offset[track] = z.offset[other]
+			return
+		}
+	}
+	z.offset[track] = z.head + handoverGap - ts
+}
+
+// advance raises head to ts.
+func (z *sanitizer) advance(ts uint32) {
+	if !z.headOK || int32(ts-z.head) > 0 {
+		z.head, z.headOK = ts, true
+	}
+}
+
+// fixBoard counts timestamp corrections per stream for /metrics. Safe for
+// concurrent use.
+type fixBoard struct {
+	mu     sync.Mutex
+	counts map[bus.StreamKey]map[string]uint64
+}
+
+// newFixBoard returns an empty board.
+func newFixBoard() *fixBoard {
+	return &fixBoard{counts: make(map[bus.StreamKey]map[string]uint64)}
+}
+
+// counter returns a function that counts corrections on key. A nil board
+// counts nothing.
+func (b *fixBoard) counter(key bus.StreamKey) func(kind string) {
+	if b == nil {
+		return func(string) {}
+	}
+	return func(kind string) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.counts[key] == nil {
+			b.counts[key] = make(map[string]uint64)
+		}
+		b.counts[key][kind]++
+	}
+}
+
+// snapshot returns a copy of the counts.
+func (b *fixBoard) snapshot() map[bus.StreamKey]map[string]uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make(map[bus.StreamKey]map[string]uint64, len(b.counts))
+	for key, kinds := range b.counts {
+		out[key] = make(map[string]uint64, len(kinds))
+		for kind, n := range kinds {
+			out[key][kind] = n
+		}
+	}
+	return out
+}
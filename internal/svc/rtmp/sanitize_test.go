@@ -0,0 +1,102 @@
+// If you are AI: This file unit-tests the timestamp sanitizer: clamping,
+// 32-bit wrap, encoder restarts and the re-interleave window.
+
+package rtmp
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+)
+
+// sanitized is one message as the sanitizer emitted it.
+type sanitized struct {
+	typ bus.MessageType
+	ts  uint32
+}
+
+// runSanitizer feeds msgs through a sanitizer for cfg and returns what it
+// emitted, flushed, and the corrections it counted.
+func runSanitizer(cfg *Sanitizer, msgs []sanitized) ([]sanitized, map[string]int) {
+	fixes := make(map[string]int)
+	z := newSanitizer(cfg, func(kind string) { fixes[kind]++ })
+	var out []sanitized
+	emit := func(typ bus.MessageType, ts uint32, _ []byte, _ bool) {
+		out = append(out, sanitized{typ, ts})
+	}
+	for _, m := range msgs {
+		z.push(m.typ, m.ts, []byte{0}, false, emit)
+	}
+	z.flush(emit)
+	return out, fixes
+}
+
+// timestamps lists the emitted timestamps.
+func timestamps(msgs []sanitized) []uint32 {
+	out := make([]uint32, len(msgs))
+	for i, m := range msgs {
+		out[i] = m.ts
+	}
+	return out
+}
+
+// TestSanitizerMonotonic: a small step back is held at the previous DTS.
+func TestSanitizerMonotonic(t *testing.T) {
+	v := bus.MessageTypeVideo
+	out, fixes := runSanitizer(&Sanitizer{}, []sanitized{{v, 1000}, {v, 1040}, {v, 1020}, {v, 1080}})
+	want := []uint32{1000, 1040, 1040, 1080}
+	if got := timestamps(out); !slices.Equal(got, want) {
+		t.Errorf("timestamps = %v, want %v", got, want)
+	}
+	if fixes[FixBackwards] != 1 {
+		t.Errorf("fixes = %v, want one backwards", fixes)
+	}
+}
+
+// TestSanitizerWrap: the 32-bit wrap is forward time, not a jump.
+func TestSanitizerWrap(t *testing.T) {
+	a := bus.MessageTypeAudio
+	out, fixes := runSanitizer(&Sanitizer{}, []sanitized{{a, 0xFFFFFFEC}, {a, 3}, {a, 26}})
+	want := []uint32{0xFFFFFFEC, 3, 26}
+	if got := timestamps(out); !slices.Equal(got, want) {
+		t.Errorf("timestamps = %v, want %v", got, want)
+	}
+	if fixes[FixWrap] != 1 || fixes[FixJump] != 0 {
+		t.Errorf("fixes = %v, want one wrap", fixes)
+	}
+}
+
+// TestSanitizerRestart: both tracks restarting from zero continue just
+// after the old timeline and stay in sync.
+func TestSanitizerRestart(t *testing.T) {
+	a, v := bus.MessageTypeAudio, bus.MessageTypeVideo
+	out, fixes := runSanitizer(&Sanitizer{MaxJump: time.Second}, []sanitized{
+		{v, 60000}, {a, 60010}, {v, 60040},
+		{v, 0}, {a, 10}, {v, 40}, {a, 33},
+	})
+	want := []uint32{60000, 60010, 60040, 60073, 60083, 60113, 60106}
+	if got := timestamps(out); !slices.Equal(got, want) {
+		t.Errorf("timestamps = %v, want %v", got, want)
+	}
+	if fixes[FixJump] != 2 {
+		t.Errorf("fixes = %v, want two jumps", fixes)
+	}
+}
+
+// TestSanitizerInterleave: the window publishes audio and video in
+// timestamp order.
+func TestSanitizerInterleave(t *testing.T) {
+	a, v := bus.MessageTypeAudio, bus.MessageTypeVideo
+	out, fixes := runSanitizer(&Sanitizer{Interleave: 100 * time.Millisecond}, []sanitized{
+		{v, 0}, {v, 40}, {v, 80}, {a, 20}, {a, 60}, {v, 120}, {v, 160}, {a, 100},
+	})
+	want := []uint32{0, 20, 40, 60, 80, 100, 120, 160}
+	if got := timestamps(out); !slices.Equal(got, want) {
+		t.Errorf("timestamps = %v, want %v", got, want)
+	}
+	if fixes[FixReorder] != 3 {
+		t.Errorf("fixes = %v, want three reorders", fixes)
+	}
+}
@@ -4,45 +4,128 @@
 package rtmp
 
 import (
-	"bytes"
-	"fmt"
+	"context"
+	"crypto/tls"
+	"errors"
 	"io"
 	"log"
 	"net"
+	"time"
+
+	"nonchalant/internal/access"
 	"nonchalant/internal/core/bus"
-	"nonchalant/internal/core/protocol/amf0"
 	rtmpprotocol "nonchalant/internal/core/protocol/rtmp"
+	"nonchalant/internal/events"
+	"nonchalant/internal/proxyproto"
+	"nonchalant/internal/sessions"
 )
 
 // Server represents an RTMP server.
 type Server struct {
-	registry *bus.Registry
-	listener net.Listener
-	auth     *Authenticator // nil means anonymous publishing is allowed
+	registry    *bus.Registry
+	listener    net.Listener
+	tlsListener net.Listener              // RTMPS; nil unless ListenTLS was called
+	auth        *Authenticator            // nil means anonymous publishing is allowed
+	proxy       *proxyproto.Policy        // nil means PROXY protocol is off
+	guard       *access.Guard             // nil admits every client
+	limiter     PublishLimiter            // nil means no stream cap
+	handshake   time.Duration             // TLS + RTMP handshake + connect deadline
+	policies    map[string]*PublishPolicy // by app, "*" for the rest
+	names       *bus.NamePolicy           // nil applies the built-in rules
+	sessions    *sessions.Table           // nil leaves publishers unlisted
+	events      *events.Bus               // publish rejections; nil emits nothing
+	watchdogs   map[string]*Watchdog      // by app, "*" for the rest
+	alerts      *alertBoard               // watchdog alerts, for metrics
+	sanitizers  map[string]*Sanitizer     // by app, "*" for the rest
+	fixes       *fixBoard                 // timestamp corrections, for metrics
+	captions    map[string]*Captions      // by app, "*" for the rest
 }
 
+// defaultHandshakeTimeout bounds the time from accept to a completed
+// connect command, so idle or trickling clients do not pin a goroutine.
+const defaultHandshakeTimeout = 10 * time.Second
+
 // NewServer creates a new RTMP server.
 // Pass a nil Authenticator (or one returned for an empty key list) to allow
 // anonymous publishing; otherwise publishers must include "?key=<secret>"
 // in the stream name on the publish command.
 func NewServer(registry *bus.Registry, auth *Authenticator) *Server {
-	return &Server{registry: registry, auth: auth}
+	return &Server{registry: registry, auth: auth, handshake: defaultHandshakeTimeout, alerts: newAlertBoard(), fixes: newFixBoard()}
 }
 
+// SetGuard applies g's connection limits to both listeners and its publish
+// ACL to publish commands. Must be called before Listen / ListenTLS.
+func (s *Server) SetGuard(g *access.Guard) { s.guard = g }
+
+// PublishLimiter caps how many streams may publish. ReservePublish holds a
+// slot until release is called.
+type PublishLimiter interface {
+	ReservePublish(app string) (release func(), ok bool)
+}
+
+// SetPublishLimiter refuses publishes that l does not allow with
+// NetStream.Publish.Rejected.
+func (s *Server) SetPublishLimiter(l PublishLimiter) { s.limiter = l }
+
+// SetHandshakeTimeout sets how long a client has from accept to a
+// completed connect command. d <= 0 keeps the default.
+func (s *Server) SetHandshakeTimeout(d time.Duration) {
+	if d > 0 {
+		s.handshake = d
+	}
+}
+
+// SetNamePolicy checks published stream keys against p in addition to the
+// built-in rules; refused publishes get NetStream.Publish.BadName.
+func (s *Server) SetNamePolicy(p *bus.NamePolicy) { s.names = p }
+
+// SetSessionTable registers every publisher in t so it can be listed and
+// disconnected through the API.
+func (s *Server) SetSessionTable(t *sessions.Table) { s.sessions = t }
+
+// SetEvents emits PolicyRejected on b for refused or stopped publishes.
+func (s *Server) SetEvents(b *events.Bus) { s.events = b }
+
+// SetProxyPolicy accepts PROXY protocol headers from the policy's trusted
+// peers on both listeners. Must be called before Listen / ListenTLS.
+func (s *Server) SetProxyPolicy(p *proxyproto.Policy) { s.proxy = p }
+
 // Listen starts listening on the specified address.
 func (s *Server) Listen(addr string) error {
-	var err error
-	s.listener, err = net.Listen("tcp", addr)
+	ln, err := net.Listen("tcp", addr)
 	if err != nil {
 		return err
 	}
+	s.listener = s.guard.Wrap(s.proxy.Wrap(ln))
+	return nil
+}
+
+// ListenTLS starts an RTMPS listener on addr. Once the TLS handshake is
+// done, connections are handled exactly like plain RTMP. A PROXY header
+// precedes the TLS handshake.
+func (s *Server) ListenTLS(addr string, cfg *tls.Config) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.tlsListener = tls.NewListener(s.guard.Wrap(s.proxy.Wrap(ln)), cfg)
 	return nil
 }
 
 // Accept accepts connections and handles them in goroutines.
 func (s *Server) Accept() error {
+	return s.serve(s.listener)
+}
+
+// AcceptTLS is Accept for the RTMPS listener.
+func (s *Server) AcceptTLS() error {
+	return s.serve(s.tlsListener)
+}
+
+// serve runs the accept loop for ln.
+func (s *Server) serve(ln net.Listener) error {
 	for {
-		conn, err := s.listener.Accept()
+		conn, err := ln.Accept()
 		if err != nil {
 			return err
 		}
@@ -65,23 +148,62 @@ func (s *Server) handleConnection(conn net.Conn) {
 		}
 	}()
 
+	// One deadline covers the TLS and RTMP handshakes and the connect
+	// command; it is lifted once the client has connected.
+	deadline := time.Now().Add(s.handshake)
+	_ = conn.SetDeadline(deadline)
+	connected := false
+	timedOut := func() bool {
+		if connected || time.Now().Before(deadline) {
+			return false
+		}
+		s.guard.Reject(access.ReasonHandshakeTimeout)
+		log.Printf("Dropped %s: no connect within %s", conn.RemoteAddr(), s.handshake)
+		return true
+	}
+
+	if tc, ok := conn.(*tls.Conn); ok {
+		if err := tc.HandshakeContext(context.Background()); err != nil {
+			if !timedOut() && !errors.Is(err, access.ErrRefused) {
+				log.Printf("RTMPS handshake from %s failed: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+
 	sc := &sessionConn{Conn: conn}
 	session := NewServiceSession(sc, s.registry, s.auth)
+	session.guard = s.guard
+	session.limiter = s.limiter
+	session.policies = s.policies
+	session.names = s.names
+	session.table = s.sessions
+	session.events = s.events
+	session.watchdogs, session.alerts = s.watchdogs, s.alerts
+	session.sanitizers, session.fixes = s.sanitizers, s.fixes
+	session.captions = s.captions
 	defer session.Close()
 
 	if err := session.PerformHandshake(); err != nil {
+		if timedOut() || errors.Is(err, access.ErrRefused) {
+			return // counted in metrics
+		}
 		if err.Error() == "invalid RTMP version" {
 			return // Silently close non-RTMP connections
 		}
-		log.Printf("Handshake failed: %v", err)
+		log.Printf("Handshake from %s failed: %v", conn.RemoteAddr(), err)
 		return
 	}
 
 	// Main message loop
 	for {
+		if !connected && session.GetApp() != "" {
+			connected = true
+			_ = conn.SetDeadline(time.Time{})
+		}
 		csID, err := session.ReadChunk()
 		if err != nil {
-			if err != io.EOF {
+			if err != io.EOF && !timedOut() {
 				log.Printf("Read chunk error: %v", err)
 			}
 			return
@@ -99,6 +221,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 		if !complete {
 			continue
 		}
+		session.tracked.AddReceived(len(body))
 
 		switch msgType {
 		case rtmpprotocol.MessageTypeSetChunkSize:
@@ -126,7 +249,10 @@ func (s *Server) handleConnection(conn net.Conn) {
 
 		case rtmpprotocol.MessageTypeAudio, rtmpprotocol.MessageTypeVideo,
 			rtmpprotocol.MessageTypeDataAMF0:
-			session.HandleMediaMessage(msgType, timestamp, body)
+			if err := session.HandleMediaMessage(msgType, timestamp, body); err != nil {
+				s.guard.Reject(access.ReasonPublishPolicy)
+				return
+			}
 
 		default:
 			// NOTE: Other message types (abort, ack, peer bandwidth) are ignored
@@ -134,66 +260,11 @@ func (s *Server) handleConnection(conn net.Conn) {
 	}
 }
 
-// handleCommand handles AMF0 command messages.
-// streamID is the stream ID from the message header.
-func (s *Server) handleCommand(session *ServiceSession, body []byte, streamID uint32) error {
-	command, err := amf0.DecodeCommand(bytes.NewReader(body))
-	if err != nil {
-		logDecodeError(body, err)
-		return err
-	}
-	if len(command) == 0 {
-		return nil
-	}
-
-	cmdName, ok := command[0].(string)
-	if !ok {
-		return nil
-	}
-
-	switch cmdName {
-	case "connect":
-		log.Printf("Command: connect")
-		return session.HandleConnect(command)
-	case "releaseStream":
-		log.Printf("Command: releaseStream")
-		return session.HandleReleaseStream(command)
-	case "FCPublish":
-		log.Printf("Command: FCPublish")
-		return session.HandleFCPublish(command)
-	case "createStream":
-		log.Printf("Command: createStream")
-		return session.HandleCreateStream(command)
-	case "publish":
-		log.Printf("Command: publish (streamID=%d)", streamID)
-		return session.HandlePublish(command, streamID)
-	case "deleteStream", "closeStream":
-		log.Printf("Command: %s", cmdName)
-		session.Close()
-		return nil
-	case "FCUnpublish":
-		log.Printf("Command: FCUnpublish (ignored)")
-		return nil
-	default:
-		log.Printf("Command: %s (unhandled)", cmdName)
-		return nil
-	}
-}
-
-// logDecodeError logs diagnostic info for failed AMF0 decoding.
-func logDecodeError(body []byte, err error) {
-	hex := ""
-	if len(body) > 0 {
-		hex = fmt.Sprintf(" first_byte=0x%02x", body[0])
-		if len(body) > 15 {
-			hex += fmt.Sprintf(" hex=%x", body[:16])
-		}
-	}
-	log.Printf("Failed to decode command: %v (len=%d%s)", err, len(body), hex)
-}
-
 // Close closes the server.
 func (s *Server) Close() error {
+	if s.tlsListener != nil {
+		_ = s.tlsListener.Close()
+	}
 	if s.listener != nil {
 		return s.listener.Close()
 	}
@@ -5,10 +5,14 @@ package rtmp
 
 import (
 	"fmt"
+	"io"
 	"log"
+	"nonchalant/internal/access"
 	"nonchalant/internal/core/bus"
 	"nonchalant/internal/core/protocol/amf0"
 	rtmpprotocol "nonchalant/internal/core/protocol/rtmp"
+	"nonchalant/internal/events"
+	"nonchalant/internal/sessions"
 )
 
 // ServiceSession wraps RTMP protocol session with service logic.
@@ -18,6 +22,24 @@ type ServiceSession struct {
 	auth         *Authenticator
 	publisher    *Publisher
 	nextStreamID uint32
+	remoteAddr   string                    // client address, after PROXY protocol
+	guard        *access.Guard             // publish ACL; nil allows everyone
+	limiter      PublishLimiter            // stream cap; nil is unlimited
+	policies     map[string]*PublishPolicy // by app, "*" for the rest
+	policy       *policyCheck              // nil unless the app has a publish policy
+	streamID     uint32                    // message stream of the publish
+	names        *bus.NamePolicy           // stream name rules; nil = built-in only
+	conn         io.Closer                 // closed to kick the client
+	flashVer     string                    // client software, from connect
+	table        *sessions.Table           // nil leaves the publisher unlisted
+	tracked      *sessions.Session         // publisher's entry in table
+	events       *events.Bus               // PolicyRejected; nil emits nothing
+	watchdogs    map[string]*Watchdog      // by app, "*" for the rest
+	alerts       *alertBoard               // shared with the server
+	watchdog     *watchdog                 // nil unless the app is watched
+	sanitizers   map[string]*Sanitizer     // by app, "*" for the rest
+	fixes        *fixBoard                 // shared with the server
+	captions     map[string]*Captions      // by app, "*" for the rest
 }
 
 // NewServiceSession creates a new service session.
@@ -28,6 +50,8 @@ func NewServiceSession(conn *sessionConn, registry *bus.Registry, auth *Authenti
 		registry:     registry,
 		auth:         auth,
 		nextStreamID: 1,
+		remoteAddr:   conn.RemoteAddr().String(),
+		conn:         conn,
 	}
 }
 
@@ -55,6 +79,9 @@ func (s *ServiceSession) HandleConnect(command amf0.Array) error {
 			if encVal, ok := cmdObj["objectEncoding"].(float64); ok {
 				objectEncoding = encVal
 			}
+			if ver, ok := cmdObj["flashVer"].(string); ok {
+				s.flashVer = ver
+			}
 		}
 	}
 
@@ -114,11 +141,21 @@ func (s *ServiceSession) sendConnectResult(transID interface{}, objectEncoding f
 	return s.WriteMessage(3, rtmpprotocol.MessageTypeCommandAMF0, 0, 0, body)
 }
 
-// HandleMediaMessage handles audio/video/data messages.
-func (s *ServiceSession) HandleMediaMessage(msgType byte, timestamp uint32, body []byte) {
+// HandleMediaMessage handles audio/video/data messages. A message that
+// breaks the app's publish policy is dropped, the client gets
+// NetStream.Publish.Rejected, and the error tells the caller to disconnect.
+func (s *ServiceSession) HandleMediaMessage(msgType byte, timestamp uint32, body []byte) error {
 	if s.publisher == nil {
-		return // Not publishing
+		return nil // Not publishing
+	}
+	if reason := s.policy.check(msgType, body); reason != "" {
+		log.Printf("Publish rejected: %s from %s breaks the publish policy: %s",
+			s.publisher.StreamKey(), s.remoteAddr, reason)
+		s.rejected(s.publisher.StreamKey(), access.ReasonPublishPolicy, reason)
+		_ = s.sendOnStatus(s.streamID, "error", "NetStream.Publish.Rejected", reason)
+		return fmt.Errorf("%s from %s: %s", s.publisher.StreamKey(), s.remoteAddr, reason)
 	}
+	s.watchdog.observe(msgType, timestamp, body)
 
 	switch msgType {
 	case rtmpprotocol.MessageTypeAudio:
@@ -130,10 +167,27 @@ func (s *ServiceSession) HandleMediaMessage(msgType byte, timestamp uint32, body
 	default:
 		// NOTE: Other message types are ignored
 	}
+	return nil
+}
+
+// rejected emits PolicyRejected for a refused or stopped publish of key.
+// detail, if any, explains the reason code and goes in the event's Error.
+func (s *ServiceSession) rejected(key bus.StreamKey, reason, detail string) {
+	s.events.Emit(events.Event{
+		Type:       events.PolicyRejected,
+		App:        key.App,
+		Name:       key.Name,
+		Protocol:   sessions.ProtoRTMP,
+		RemoteAddr: s.remoteAddr,
+		Reason:     reason,
+		Error:      detail,
+	})
 }
 
 // Close closes the session and detaches publisher.
 func (s *ServiceSession) Close() {
+	s.tracked.Close()
+	s.watchdog.close()
 	if s.publisher != nil {
 		s.publisher.Detach()
 		if s.publisher.stream != nil {
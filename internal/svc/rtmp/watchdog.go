@@ -0,0 +1,266 @@
+// If you are AI: This file implements the ingest watchdog. It watches each
+// publish for stalls, missing keyframes, video dropping out while audio
+// continues, timestamp anomalies and bitrate collapse, raises events and
+// metrics, and can disconnect the publisher so the encoder reconnects.
+
+package rtmp
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	"nonchalant/internal/core/protocol/flv"
+	rtmpprotocol "nonchalant/internal/core/protocol/rtmp"
+	"nonchalant/internal/events"
+	"nonchalant/internal/sessions"
+)
+
+// Watchdog sets ingest health thresholds for one app. Zero fields are not
+// checked.
+type Watchdog struct {
+	NoMedia        time.Duration // neither audio nor video
+	NoKeyframe     time.Duration // video arrives but no keyframe
+	NoVideo        time.Duration // video stopped while audio continues
+	TimestampJump  time.Duration // forward timestamp step on one track
+	MinBitrateKbps int
+	BitrateWindow  time.Duration // averaging window; default 5s
+	Disconnect     bool          // close the publisher on any alert
+}
+
+// Alert reasons, reported as the event reason and metric label.
+const (
+	AlertNoMedia            = "no_media"
+	AlertNoKeyframe         = "no_keyframe"
+	AlertAudioOnly          = "audio_only"
+	AlertLowBitrate         = "low_bitrate"
+	AlertTimestampBackwards = "timestamp_backwards"
+	AlertTimestampJump      = "timestamp_jump"
+)
+
+// watchdogTick is how often stall conditions are evaluated.
+const watchdogTick = time.Second
+
+// SetWatchdogs watches publishes by app name; the "*" entry covers apps
+// without their own. A nil map disables the watchdog.
+func (s *Server) SetWatchdogs(w map[string]*Watchdog) { s.watchdogs = w }
+
+// IngestAlertTotals returns how many alerts were raised, by reason.
+func (s *Server) IngestAlertTotals() map[string]uint64 { return s.alerts.totals() }
+
+// ActiveIngestAlerts returns the conditions currently raised, by stream.
+// Timestamp anomalies are one-off and never active.
+func (s *Server) ActiveIngestAlerts() map[bus.StreamKey][]string { return s.alerts.list() }
+
+// watchdog watches one publish. observe runs on the connection goroutine
+// and tick on the watchdog's own, so state is guarded by mu.
+type watchdog struct {
+	cfg        *Watchdog
+	key        bus.StreamKey
+	remoteAddr string
+	events     *events.Bus
+	board      *alertBoard
+	kick       func()
+	now        func() time.Time
+	stop       chan struct{}
+	closeOnce  sync.Once // the session closes twice after deleteStream
+
+	mu        sync.Mutex
+	lastMedia time.Time
+	lastAudio time.Time
+	lastVideo time.Time // zero until the first video frame
+	lastKey   time.Time
+	lastTS    [2]uint32 // audio, video
+	hasTS     [2]bool
+	rate      *rateWindow // nil without MinBitrateKbps
+	active    map[string]bool
+}
+
+// newWatchdog returns a watchdog for the publish of key, or nil when cfg
+// is nil. kick disconnects the publisher.
+func newWatchdog(cfg *Watchdog, key bus.StreamKey, remoteAddr string, ev *events.Bus, board *alertBoard, kick func()) *watchdog {
+	if cfg == nil {
+		return nil
+	}
+	w := &watchdog{
+		cfg: cfg, key: key, remoteAddr: remoteAddr, events: ev, board: board, kick: kick,
+		now: time.Now, stop: make(chan struct{}), active: make(map[string]bool),
+	}
+	w.lastMedia, w.lastAudio, w.lastKey = w.now(), w.now(), w.now()
+	if cfg.MinBitrateKbps > 0 {
+		window := cfg.BitrateWindow
+		if window <= 0 {
+			window = defaultBitrateWindow
+		}
+		w.rate = newRateWindow(window, w.now())
+	}
+	return w
+}
+
+// start evaluates the stall conditions every watchdogTick until close.
+func (w *watchdog) start() {
+	if w == nil {
+		return
+	}
+	go func() {
+		t := time.NewTicker(watchdogTick)
+		defer t.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-t.C:
+				w.tick()
+			}
+		}
+	}()
+}
+
+// close stops the watchdog and drops its active alerts. Calls after the
+// first do nothing.
+func (w *watchdog) close() {
+	if w == nil {
+		return
+	}
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for reason := range w.active {
+			w.board.clear(w.key, reason)
+		}
+	})
+}
+
+// observe records one audio or video message.
+func (w *watchdog) observe(msgType byte, timestamp uint32, payload []byte) {
+	if w == nil || (msgType != rtmpprotocol.MessageTypeAudio && msgType != rtmpprotocol.MessageTypeVideo) {
+		return
+	}
+	w.mu.Lock()
+	now := w.now()
+	w.lastMedia = now
+	if w.rate != nil {
+		w.rate.add(now, len(payload))
+	}
+	var alerts []alert
+	track := 0
+	if msgType == rtmpprotocol.MessageTypeVideo {
+		track = 1
+		w.lastVideo = now
+		if flv.IsVideoKeyframe(payload) {
+			w.lastKey = now
+		}
+	} else {
+		w.lastAudio = now
+	}
+	if !isAVCSequenceHeader(payload) && !flv.IsAudioSequenceHeader(payload) {
+		alerts = w.timestamp(track, timestamp)
+	}
+	alerts = append(alerts, w.evaluateLocked(now)...)
+	w.mu.Unlock()
+	w.raise(alerts)
+}
+
+// timestamp checks one track's timestamp against the previous one.
+// Caller must hold mu.
+func (w *watchdog) timestamp(track int, ts uint32) []alert {
+	prev, had := w.lastTS[track], w.hasTS[track]
+	w.lastTS[track], w.hasTS[track] = ts, true
+	switch {
+	case !had:
+		return nil
+	case int32(ts-prev) < 0: // a 32-bit wrap counts as forward
+		return []alert{{reason: AlertTimestampBackwards, on: true, oneOff: true,
+			detail: fmt.Sprintf("%s timestamp went back from %d to %d ms", trackName(track), prev, ts)}}
+	case w.cfg.TimestampJump > 0 && time.Duration(ts-prev)*time.Millisecond > w.cfg.TimestampJump:
+		return []alert{{reason: AlertTimestampJump, on: true, oneOff: true,
+			detail: fmt.Sprintf("%s timestamp jumped %d ms", trackName(track), ts-prev)}}
+	}
+	return nil
+}
+
+// tick evaluates the stall conditions.
+func (w *watchdog) tick() {
+	w.mu.Lock()
+	alerts := w.evaluateLocked(w.now())
+	w.mu.Unlock()
+	w.raise(alerts)
+}
+
+// evaluateLocked returns the stall conditions that changed state. Caller
+// must hold mu.
+func (w *watchdog) evaluateLocked(now time.Time) []alert {
+	c := w.cfg
+	var out []alert
+	set := func(reason string, on bool, detail string) {
+		if on != w.active[reason] {
+			w.active[reason] = on
+			out = append(out, alert{reason: reason, on: on, detail: detail})
+		}
+	}
+	if c.NoMedia > 0 {
+		set(AlertNoMedia, now.Sub(w.lastMedia) > c.NoMedia, fmt.Sprintf("no media for %s", c.NoMedia))
+	}
+	videoFlowing := !w.lastVideo.IsZero() && now.Sub(w.lastVideo) <= 2*watchdogTick
+	if c.NoKeyframe > 0 {
+		set(AlertNoKeyframe, videoFlowing && now.Sub(w.lastKey) > c.NoKeyframe, fmt.Sprintf("no keyframe for %s", c.NoKeyframe))
+	}
+	if c.NoVideo > 0 {
+		lost := !w.lastVideo.IsZero() && now.Sub(w.lastVideo) > c.NoVideo && now.Sub(w.lastAudio) <= c.NoVideo
+		set(AlertAudioOnly, lost, fmt.Sprintf("audio only for %s", c.NoVideo))
+	}
+	if w.rate != nil {
+		kbps, full := w.rate.add(now, 0)
+		set(AlertLowBitrate, full && kbps < c.MinBitrateKbps, fmt.Sprintf("bitrate %d kbps under %d kbps", kbps, c.MinBitrateKbps))
+	}
+	return out
+}
+
+// alert is one change to report.
+type alert struct {
+	reason string
+	on     bool // raised, or recovered when false
+	oneOff bool // never active, so never recovers
+	detail string
+}
+
+// raise reports alerts on the board and the event bus, and disconnects
+// the publisher on a raised one when configured.
+func (w *watchdog) raise(alerts []alert) {
+	kick := false
+	for _, a := range alerts {
+		typ := events.IngestRecovered
+		switch {
+		case a.on && a.oneOff:
+			w.board.count(a.reason)
+			typ = events.IngestAlert
+		case a.on:
+			w.board.raise(w.key, a.reason)
+			typ = events.IngestAlert
+		default:
+			w.board.clear(w.key, a.reason)
+		}
+		ev := events.Event{Type: typ, App: w.key.App, Name: w.key.Name, Protocol: sessions.ProtoRTMP,
+			RemoteAddr: w.remoteAddr, Reason: a.reason}
+		if a.on {
+			ev.Error = a.detail
+			log.Printf("Ingest watchdog: %s from %s: %s", w.key, w.remoteAddr, a.detail)
+			kick = kick || w.cfg.Disconnect
+		}
+		w.events.Emit(ev)
+	}
+	if kick {
+		w.kick()
+	}
+}
+
+// trackName names a watchdog track index.
+func trackName(track int) string {
+	if track == 1 {
+		return "video"
+	}
+	return "audio"
+}
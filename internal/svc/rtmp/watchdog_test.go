@@ -0,0 +1,131 @@
+// If you are AI: Unit tests for the ingest watchdog: each alert is raised
+// and recovers, and Disconnect kicks the publisher.
+
+package rtmp
+
+import (
+	"testing"
+	"time"
+
+	"nonchalant/internal/core/bus"
+	rtmpprotocol "nonchalant/internal/core/protocol/rtmp"
+	"nonchalant/internal/events"
+)
+
+// watchdogHarness drives a watchdog on a fake clock.
+type watchdogHarness struct {
+	t      *testing.T
+	w      *watchdog
+	now    time.Time
+	ev     *events.Bus
+	board  *alertBoard
+	kicked int
+	seen   int // events already checked
+}
+
+// newWatchdogHarness builds a watchdog for cfg on live/cam.
+func newWatchdogHarness(t *testing.T, cfg *Watchdog) *watchdogHarness {
+	h := &watchdogHarness{t: t, now: time.Unix(1000, 0), ev: events.NewBus(), board: newAlertBoard()}
+	w := newWatchdog(cfg, bus.NewStreamKey("live", "cam"), "10.0.0.1:5000", h.ev, h.board, func() { h.kicked++ })
+	w.now = func() time.Time { return h.now }
+	w.lastMedia, w.lastAudio, w.lastKey = h.now, h.now, h.now
+	if w.rate != nil {
+		w.rate.start = h.now
+	}
+	h.w = w
+	return h
+}
+
+// media sends one audio or video frame every 100ms for d.
+func (h *watchdogHarness) media(d time.Duration, video, key bool) {
+	for end := h.now.Add(d); h.now.Before(end); h.now = h.now.Add(100 * time.Millisecond) {
+		ts := uint32(h.now.Sub(time.Unix(1000, 0)) / time.Millisecond)
+		if video {
+			frame := byte(0x27)
+			if key {
+				frame = 0x17
+			}
+			h.w.observe(rtmpprotocol.MessageTypeVideo, ts, []byte{frame, 1, 0, 0, 0})
+		} else {
+			h.w.observe(rtmpprotocol.MessageTypeAudio, ts, []byte{0xAF, 1, 0})
+		}
+		h.w.tick()
+	}
+}
+
+// expect checks the events emitted since the last call.
+func (h *watchdogHarness) expect(want ...string) {
+	h.t.Helper()
+	backlog, _, cancel := h.ev.Subscribe(0)
+	cancel()
+	got := backlog[h.seen:]
+	h.seen = len(backlog)
+	if len(got) != len(want) {
+		h.t.Fatalf("events = %+v, want %v", got, want)
+	}
+	for i, e := range got {
+		if string(e.Type)+":"+e.Reason != want[i] {
+			h.t.Errorf("event %d = %s:%s, want %s", i, e.Type, e.Reason, want[i])
+		}
+	}
+}
+
+// TestWatchdogStalls raises and recovers the stall conditions.
+func TestWatchdogStalls(t *testing.T) {
+	h := newWatchdogHarness(t, &Watchdog{NoMedia: 3 * time.Second, NoKeyframe: 2 * time.Second, NoVideo: 2 * time.Second})
+	h.media(time.Second, true, true)
+	h.expect()
+
+	h.media(3*time.Second, true, false) // video without keyframes
+	h.expect("ingest_alert:no_keyframe")
+	if got := h.board.list()[bus.NewStreamKey("live", "cam")]; len(got) != 1 || got[0] != AlertNoKeyframe {
+		t.Fatalf("active = %v", got)
+	}
+	h.media(time.Second, true, true)
+	h.expect("ingest_recovered:no_keyframe")
+
+	h.media(3*time.Second, false, false) // video gone, audio continues
+	h.expect("ingest_alert:audio_only")
+
+	h.now = h.now.Add(4 * time.Second)
+	h.w.tick()
+	h.expect("ingest_alert:no_media", "ingest_recovered:audio_only")
+	h.media(time.Second, true, true)
+	h.expect("ingest_recovered:no_media")
+	if h.kicked != 0 || h.board.totals()[AlertNoMedia] != 1 {
+		t.Fatalf("kicked %d, totals %v", h.kicked, h.board.totals())
+	}
+	h.w.close()
+	if len(h.board.list()) != 0 {
+		t.Fatal("close left active alerts")
+	}
+}
+
+// TestWatchdogTimestamps flags backwards and jumping timestamps once each.
+func TestWatchdogTimestamps(t *testing.T) {
+	h := newWatchdogHarness(t, &Watchdog{TimestampJump: 2 * time.Second, Disconnect: true})
+	video := byte(rtmpprotocol.MessageTypeVideo)
+	h.w.observe(video, 1000, []byte{0x17, 1})
+	h.w.observe(video, 1040, []byte{0x27, 1})
+	h.w.observe(video, 1000, []byte{0x27, 1})
+	h.w.observe(video, 9000, []byte{0x27, 1})
+	h.w.observe(video, 0, []byte{0x17, 0}) // sequence header: not checked
+	h.expect("ingest_alert:timestamp_backwards", "ingest_alert:timestamp_jump")
+	if h.kicked != 2 || len(h.board.list()) != 0 {
+		t.Fatalf("kicked %d, active %v", h.kicked, h.board.list())
+	}
+}
+
+// TestWatchdogBitrate raises low_bitrate once the window shows a collapse.
+func TestWatchdogBitrate(t *testing.T) {
+	h := newWatchdogHarness(t, &Watchdog{MinBitrateKbps: 100, BitrateWindow: 2 * time.Second})
+	big := make([]byte, 5000) // 10 frames/s: 400 kbps
+	for i := 0; i < 30; i++ {
+		h.w.observe(rtmpprotocol.MessageTypeVideo, uint32(i*100), big)
+		h.w.tick()
+		h.now = h.now.Add(100 * time.Millisecond)
+	}
+	h.expect()
+	h.media(3*time.Second, true, true) // 5-byte frames
+	h.expect("ingest_alert:low_bitrate")
+}
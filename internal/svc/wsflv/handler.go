@@ -9,18 +9,20 @@ import (
 	"strings"
 	"time"
 
+	"nonchalant/internal/auth"
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
 
 	"github.com/gorilla/websocket"
 )
 
-// waitForStreams polls the stream's cached init data and returns the
+// waitForStreams polls the stream's audio codec and cached init data and returns the
 // (hasAudio, hasVideo) flags suitable for the FLV header. Mirrors the
 // httpflv version — see that file for rationale.
 func waitForStreams(ctx context.Context, stream *bus.Stream, timeout time.Duration) (bool, bool) {
 	deadline := time.Now().Add(timeout)
 	for {
-		hasAudio := stream.HasAudioInit()
+		hasAudio := stream.HasAudio()
 		hasVideo := stream.HasVideoInit()
 		if (hasAudio && hasVideo) || time.Now().After(deadline) {
 			return hasAudio, hasVideo
@@ -37,6 +39,7 @@ func waitForStreams(ctx context.Context, stream *bus.Stream, timeout time.Durati
 type Handler struct {
 	registry *bus.Registry
 	upgrader websocket.Upgrader
+	table    *sessions.Table // nil unless SetSessionTable was called
 }
 
 // NewHandler creates a new WebSocket-FLV handler.
@@ -78,17 +81,15 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	app := parts[0]
 	name := parts[1]
-
-	// Get stream from registry
-	streamKey := bus.NewStreamKey(app, name)
-	stream := h.registry.Get(streamKey)
-	if stream == nil {
-		w.WriteHeader(http.StatusNotFound)
+	if bus.NewStreamKey(app, name).Validate() != nil {
+		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	// Check if stream has a publisher
-	if !stream.HasPublisher() {
+	// Resolve a live stream. On an edge this may pull it from the origin
+	// first; concurrent first viewers share that one pull.
+	stream := h.registry.Live(r.Context(), bus.NewStreamKey(app, name))
+	if stream == nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
@@ -100,8 +101,21 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Register the viewer; a kick cancels ctx, which closes the socket.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	sess := h.table.OpenAs(sessions.ProtoWSFLV, sessions.RoleViewer,
+		bus.NewStreamKey(app, name), r.RemoteAddr, r.UserAgent(), auth.Subject(r), cancel)
+	defer sess.Close()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
 	// Create subscriber
-	sub := NewSubscriber(conn, stream)
+	var ws WebSocketConn = conn
+	if sess != nil {
+		ws = &countingConn{WebSocketConn: conn, sess: sess}
+	}
+	sub := NewSubscriber(ws, stream)
 	defer func() {
 		sub.Detach()
 		_ = conn.Close()
@@ -109,22 +123,43 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Attach to stream
 	sub.Attach()
+	sess.SetSubscriber(sub.BusSubscriber())
+	sub.SetSession(sess)
 
 	// Wait briefly for codec init data so the FLV header reflects only the
 	// streams that actually exist; otherwise ffmpeg/flv.js can hang waiting
 	// for an audio packet that never arrives.
-	hasAudio, hasVideo := waitForStreams(r.Context(), stream, 2*time.Second)
+	hasAudio, hasVideo := waitForStreams(ctx, stream, 2*time.Second)
 	if err := sub.WriteHeader(hasAudio, hasVideo); err != nil {
 		return
 	}
 
-	// Process messages until the request context is cancelled (server shutdown
-	// or client disconnect) or a write error fires.
-	if err := sub.ProcessMessages(r.Context()); err != nil {
+	// Process messages until the request context is cancelled (server shutdown,
+	// client disconnect or kick) or a write error fires.
+	if err := sub.ProcessMessages(ctx); err != nil {
 		return
 	}
 }
 
+// SetSessionTable registers every viewer in t so it can be listed and
+// kicked through the API.
+func (h *Handler) SetSessionTable(t *sessions.Table) { h.table = t }
+
+// countingConn counts the bytes a viewer is sent on its session.
+type countingConn struct {
+	WebSocketConn
+	sess *sessions.Session
+}
+
+// WriteMessage writes one frame and counts its payload.
+func (c *countingConn) WriteMessage(messageType int, data []byte) error {
+	err := c.WebSocketConn.WriteMessage(messageType, data)
+	if err == nil {
+		c.sess.AddSent(len(data))
+	}
+	return err
+}
+
 // RegisterRoutes registers WebSocket-FLV routes on the given mux.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/ws/", h.ServeHTTP)
@@ -8,6 +8,7 @@ import (
 
 	"nonchalant/internal/auth"
 	"nonchalant/internal/core/bus"
+	"nonchalant/internal/sessions"
 )
 
 // Service provides WebSocket-FLV streaming functionality.
@@ -25,6 +26,9 @@ func NewService(registry *bus.Registry, playKeys *auth.KeySet) *Service {
 	}
 }
 
+// SetSessionTable registers every WebSocket-FLV viewer in t. Optional.
+func (s *Service) SetSessionTable(t *sessions.Table) { s.handler.SetSessionTable(t) }
+
 // RegisterRoutes registers WebSocket-FLV routes on the provided mux.
 // When play keys are configured, the /ws/ prefix is gated by auth.Gate.
 func (s *Service) RegisterRoutes(mux *http.ServeMux) {
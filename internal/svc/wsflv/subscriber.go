@@ -9,6 +9,7 @@ import (
 
 	"nonchalant/internal/core/bus"
 	"nonchalant/internal/core/protocol/flv"
+	"nonchalant/internal/sessions"
 )
 
 // Subscriber represents a WebSocket-FLV client subscriber.
@@ -19,9 +20,9 @@ type Subscriber struct {
 	stream        *bus.Stream
 	subscriberID  uint64
 	headerWritten bool
-	gotKeyframe   bool   // True after first video keyframe received
-	tsOffset      uint32 // First non-init timestamp, subtracted from all subsequent
-	tsBaseSet     bool   // True after tsOffset is captured
+	gotKeyframe   bool              // True after first video keyframe received
+	rebase        flv.Rebaser       // starts the viewer's timeline at zero
+	session       *sessions.Session // publish-to-write latency; nil records nothing
 }
 
 // WebSocketConn defines the interface for WebSocket operations.
@@ -116,37 +117,24 @@ func (s *Subscriber) ProcessMessages(ctx context.Context) error {
 		if !ok {
 			continue
 		}
-		tagBuf := bus.AcquirePayload()
-		tagBuf = flv.AppendTag(tagBuf, tagType, s.rebaseTimestamp(msg), msg.Payload)
+		ts := s.rebase.Rebase(msg)
+		tagBuf := flv.AppendClock(bus.AcquirePayload(), s.stream, msg, ts)
+		tagBuf = flv.AppendTag(tagBuf, tagType, ts, msg.Payload)
 
-		// Write tag as binary WebSocket frame (each FLV tag = one frame).
+		// Write tag as binary WebSocket frame (each FLV tag = one frame; a
+		// keyframe shares its frame with the onFI tag stamping it).
 		// The per-write deadline bounds how long a slow client can block us.
+		received := msg.Received
 		_ = s.conn.SetWriteDeadline(time.Now().Add(writeDeadline))
 		werr := s.conn.WriteMessage(2, tagBuf)
 		bus.ReleasePayload(tagBuf)
 		if werr != nil {
 			return werr
 		}
+		s.session.ObserveDelivery(received)
 	}
 }
 
-// rebaseTimestamp adjusts a message timestamp so the subscriber's stream starts at ts=0.
-// Init messages always return ts=0. The first non-init timestamp becomes the offset
-// that is subtracted from all subsequent timestamps.
-func (s *Subscriber) rebaseTimestamp(msg *bus.MediaMessage) uint32 {
-	if msg.IsInit {
-		return 0
-	}
-	if !s.tsBaseSet {
-		s.tsOffset = msg.Timestamp
-		s.tsBaseSet = true
-	}
-	if msg.Timestamp < s.tsOffset {
-		return 0 // Guard against underflow
-	}
-	return msg.Timestamp - s.tsOffset
-}
-
 // Attach attaches the subscriber to the stream.
 // Returns the subscriber ID for later detach.
 // Backpressure strategy: DropOldest - same as HTTP-FLV to ensure consistency.
@@ -160,6 +148,13 @@ func (s *Subscriber) Attach() uint64 {
 	return id
 }
 
+// SetSession records each write's publish-to-write latency, and where
+// the output timeline starts, on sess.
+func (s *Subscriber) SetSession(sess *sessions.Session) {
+	s.session = sess
+	s.rebase.OnStart = sess.SetOrigin
+}
+
 // Detach detaches the subscriber from the stream.
 func (s *Subscriber) Detach() {
 	if s.stream != nil && s.subscriberID != 0 {
@@ -168,3 +163,8 @@ func (s *Subscriber) Detach() {
 		s.busSubscriber = nil
 	}
 }
+
+// BusSubscriber returns the underlying bus subscriber, or nil if not yet attached.
+func (s *Subscriber) BusSubscriber() *bus.Subscriber {
+	return s.busSubscriber
+}
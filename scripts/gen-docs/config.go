@@ -0,0 +1,264 @@
+// If you are AI: This file holds the CONFIG.md template. It lives apart from
+// main.go so the schema reference can grow without breaking the 300-line limit.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeConfig writes the CONFIG.md document describing the YAML schema.
+func writeConfig(dir string) error {
+	body := `<!--
+If you are AI: This file describes the configuration schema for nonchalant.
+It is generated by scripts/gen-docs.go and should be updated when config structure changes.
+-->
+
+# Configuration
+
+## Format
+
+Configuration is YAML-only. Unknown fields are rejected.
+
+## Schema
+
+` + "```yaml" + `
+server:
+  health_port: 8080  # Admin listener port in the split layout (1-65535)
+  http_port:   8081  # Port for HTTP-FLV, WS-FLV, HLS, DASH (+ API, /metrics when combined)
+  rtmp_port:   1935  # Port for RTMP ingest
+  layout: combined   # "combined" (default) or "split": admin surface on health_port
+  admin_bind: ""     # Split layout: admin listener address, e.g. 127.0.0.1
+
+auth:                 # Optional. Omit for anonymous publishing/playback.
+  publish_keys:       # Pre-shared secrets accepted on RTMP publish.
+    - changeme        # rtmp://host/live/foo?key=changeme
+  play_keys:          # Pre-shared secrets accepted on FLV/WS/HLS/DASH playback.
+    - watch-secret    # http://host/live/foo.flv?key=watch-secret
+
+hls:                  # Optional HLS / DASH packager tuning.
+  low_latency: false  # When true: 1s fMP4 segments, LL-HLS friendly.
+  ladder:             # Optional ABR (multi-bitrate) renditions.
+    - {name: 720p,  width: 1280, height: 720, video_bitrate: 2500}
+    - {name: 480p,  width: 854,  height: 480, video_bitrate: 1100}
+    - {name: 240p,  width: 426,  height: 240, video_bitrate: 400}
+    - {name: audio, audio_only: true, audio_bitrate: 64}
+
+relays:               # Optional. Each entry runs as a managed task.
+  - app: live
+    name: mystream
+    mode: pull        # "pull" (remote → local) or "push" (local → remote)
+    remote_url: rtmp://remote-server:1935/live/source
+    reconnect: true
+  - app: live
+    name: resilient
+    mode: pull        # Failover: sources are tried in order, primary first.
+    remote_urls:
+      - rtmp://primary:1935/live/source
+      - rtmp://backup:1935/live/source
+    failback_seconds: 30  # primary must stay reachable this long before switching back
+    stall_seconds: 10     # no media for this long counts as a dead source
+    reconnect: true
+  - app: live
+    name: mystream
+    mode: push        # Simulcast: one ffmpeg per target, independent retries.
+    targets:
+      - {id: youtube, url: "rtmp://a.rtmp.youtube.com/live2/KEY", reconnect: true}
+      - {id: twitch,  url: "rtmp://live.twitch.tv/app/KEY", reconnect: true, max_backoff_seconds: 30}
+      - {id: backup,  url: "rtmp://backup:1935/live/mystream", disabled: true}
+
+edge:                 # Optional. Pull streams from an origin on first viewer request.
+  origin: "http://origin:8081/{app}/{name}.flv"  # URL template; {app}/{name} substituted
+  origins:            # Optional per-app overrides.
+    sports: "http://sports-origin:8081/{app}/{name}.flv"
+  idle_ttl_seconds: 30  # keep the pull this long after the last viewer leaves
+  wait_seconds: 10      # how long a first viewer waits for the origin
+
+cluster:              # Optional. Static peer list + heartbeat stream directory.
+  node_id: node-a
+  advertise_url: "http://10.0.0.1:8081"  # how peers and redirected viewers reach us
+  peers: ["http://10.0.0.2:8081", "http://10.0.0.3:8081"]  # heartbeat URLs (admin port if split)
+  secret: cluster-secret  # required; must match on every node
+  mode: redirect          # "redirect" (302 to owner) or "proxy" (pull from owner)
+  heartbeat_seconds: 2
+  peer_timeout_seconds: 6
+
+admin:                # Optional. Omit to leave /api and /debug/pprof open.
+  tokens:
+    - {name: grafana, token: "read-secret",  role: read}
+    - {name: oncall,  token: "ops-secret",   role: operate}
+    - {name: root,    token: "admin-secret", role: admin}
+  metrics_token: "scrape-secret"  # optional bearer token for /metrics
+
+tls:                  # Optional. HTTPS and RTMPS next to the plain listeners.
+  cert_file: /etc/nonchalant/tls.crt
+  key_file:  /etc/nonchalant/tls.key
+  min_version: "1.2"  # "1.2" (default) or "1.3"
+  sni:                # Optional per-server-name certificates ("*.domain" allowed)
+    live.example.com: {cert_file: /etc/nonchalant/live.crt, key_file: /etc/nonchalant/live.key}
+  https_port: 8443    # same routes as http_port, over TLS
+  rtmps_port: 1936    # RTMP ingest over TLS
+  http2: true         # offer HTTP/2 on https_port
+  http3_port: 8443    # HTTP/3 over QUIC (UDP); advertised via Alt-Svc
+  reload_seconds: 10  # poll interval for certificate file changes
+
+proxy_protocol:         # Optional. HAProxy PROXY protocol v1/v2 from load balancers.
+  rtmp: true            # accept headers on rtmp_port and rtmps_port
+  http: true            # accept headers on http_port and https_port
+  trusted_cidrs: ["10.0.0.0/8"]  # only these peers may send a header
+  header_timeout_seconds: 5      # time a trusted peer has to send it
+
+access:                 # Optional. Loopback clients are always exempt.
+  max_conns_per_ip: 20  # concurrent connections per client IP (0 = unlimited)
+  connect_rate: 5       # new connections per second per IP (0 = unlimited)
+  connect_burst: 20     # token bucket size (default connect_rate)
+  handshake_timeout_seconds: 10  # RTMP handshake + connect, HTTP request headers
+  rules:
+    - app: live         # "*" or omitted = every app
+      publish_allow: ["10.0.0.0/8"]
+      publish_deny:  ["10.9.0.0/16"]
+      play_allow:    []
+      play_deny:     ["203.0.113.0/24"]
+
+capacity:               # Optional. 0 or omitted = unlimited.
+  max_streams: 100                # streams with a publisher
+  max_subscribers_per_stream: 1000  # HTTP-FLV / WS-FLV viewers of one stream
+  max_subscribers: 5000           # HTTP-FLV / WS-FLV viewers in total
+  max_egress_kbps: 900000         # media sent to viewers, all protocols
+  retry_after_seconds: 10         # Retry-After on 503 (default 10)
+  apps:                           # extra caps on one app's share
+    premium: {max_streams: 5, max_subscribers_per_stream: 5000}
+
+publish_policy:         # Optional, per app ("*" = apps without an entry)
+  premium:
+    video_codecs: [h264]          # h264, hevc, av1, vp9, ...
+    audio_codecs: [aac]           # aac, mp3, opus, pcma, pcmu, ...
+    max_width: 1920               # from the AVC SPS and onMetaData
+    max_height: 1080
+    max_fps: 30
+    max_bitrate_kbps: 8000        # ingest, averaged over the window
+    bitrate_window_seconds: 5     # default 5
+    bitrate_grace_seconds: 10     # time allowed over the cap (default 10)
+
+stream_names:           # Optional: per-app name patterns ("*" = the rest)
+  premium: "[a-z0-9]{8,32}"       # must match the whole stream name
+
+ingest_watchdog:        # Optional: per-app publisher health ("*" = the rest)
+  live:
+    no_media_seconds: 5           # no audio or video
+    no_keyframe_seconds: 10       # video without a keyframe
+    no_video_seconds: 5           # video gone, audio continues
+    timestamp_jump_seconds: 5     # forward jump on one track
+    min_bitrate_kbps: 200         # averaged over the window
+    bitrate_window_seconds: 5     # default 5
+    disconnect: true              # drop the publisher on an alert
+
+timestamps:             # Optional: per-app timestamp sanitizer ("*" = the rest)
+  "*":
+    max_jump_ms: 3000             # larger steps are smoothed (default 3000)
+    interleave_ms: 0              # re-interleave window, 0 = off (max 2000)
+
+captions:               # Optional: per-app CEA-608/708 decoding ("*" = the rest)
+  "*":
+    language: en                  # BCP 47 tag (default en)
+    name: English                 # subtitle rendition name (default Captions)
+
+analytics:              # Optional: export a viewer report per broadcast
+  export_dir: /var/lib/nonchalant/reports  # JSON + CSV on unpublish
+` + "```" + `
+
+## Validation Rules
+
+- All ports must be between 1 and 65535.
+- All ports must be unique across ` + "`health_port`" + `, ` + "`http_port`" + `, and ` + "`rtmp_port`" + `.
+- Default values are applied when a section is omitted.
+- ` + "`layout`" + ` is ` + "`combined`" + ` or ` + "`split`" + `. In the split layout ` + "`/healthz`" + `,
+  ` + "`/readyz`" + `, ` + "`/api/*`" + `, ` + "`/metrics`" + ` and ` + "`/debug/pprof/*`" + ` are served on
+  ` + "`admin_bind:health_port`" + ` and the public port keeps only media and ` + "`/healthz`" + `.
+  Cluster ` + "`peers`" + ` must then list the peers' admin listeners, since heartbeats
+  go to ` + "`/api/cluster/heartbeat`" + `.
+- Each relay requires ` + "`app`" + `, ` + "`name`" + `, ` + "`mode`" + `, and either ` + "`remote_url`" + `
+  or (push only) a non-empty ` + "`targets`" + ` list.
+- Push ` + "`targets`" + ` need a unique ` + "`id`" + ` per stream and a ` + "`url`" + `. A failing target
+  backs off on its own (` + "`max_backoff_seconds`" + `, default 5) without affecting
+  sibling targets or local viewers. Targets can be added, removed, enabled and
+  disabled at runtime via ` + "`/api/relay/targets`" + `.
+- Pull relays may set ` + "`remote_urls`" + ` (ordered, primary first) instead of
+  ` + "`remote_url`" + `. A source that exits or delivers no media for ` + "`stall_seconds`" + `
+  (default 10) is replaced by the next one immediately; backoff applies only
+  after every source has failed. While on a fallback the primary is probed and
+  resumed once reachable for ` + "`failback_seconds`" + ` (default 30) and ffmpeg can
+  read a second of media from its stream; a port that accepts connections but
+  has no stream restarts the wait. Switches wait
+  for a keyframe and continue the existing timeline, so viewers stay attached.
+- ` + "`edge`" + ` requires ` + "`origin`" + ` or at least one ` + "`origins`" + ` entry. An HTTP-FLV,
+  WS-FLV, HLS or DASH request for a stream that is not live locally starts one
+  pull from the origin (concurrent first viewers share it) and returns 404 only
+  if the origin has produced nothing after ` + "`wait_seconds`" + `. The pull stops
+  once the stream has had no viewers for ` + "`idle_ttl_seconds`" + `. To pull from
+  another nonchalant instance use its HTTP-FLV URL, since RTMP here is
+  ingest-only.
+- ` + "`cluster`" + ` requires ` + "`node_id`" + `, ` + "`advertise_url`" + ` and ` + "`secret`" + `; ` + "`peers`" + `
+  must be http(s) base URLs; ` + "`mode`" + ` is ` + "`redirect`" + ` or ` + "`proxy`" + `. See
+  OPERATIONS.md for behaviour.
+- ` + "`tls`" + ` requires ` + "`cert_file`" + `, ` + "`key_file`" + ` and at least one of
+  ` + "`https_port`" + ` / ` + "`rtmps_port`" + `, which must not collide with the other
+  listeners. Certificates are loaded at startup (a bad pair fails startup)
+  and re-read when their files change; a renewal that fails to parse keeps
+  the previous certificate. HTTPS negotiates HTTP/1.1 by default, so HTTP-FLV is
+  served over the same hijacked connection as on the plain port.
+  ` + "`http2`" + ` and ` + "`http3_port`" + ` require ` + "`https_port`" + `; HTTP/2 and HTTP/3
+  viewers get HTTP-FLV through response flushes instead of a hijack.
+- ` + "`proxy_protocol`" + ` requires ` + "`rtmp`" + ` and/or ` + "`http`" + ` and a non-empty list of valid
+  ` + "`trusted_cidrs`" + `. A header is optional from trusted peers (so direct health
+  checks keep working) and never honoured from anyone else.
+- ` + "`access`" + ` limits and ` + "`handshake_timeout_seconds`" + ` must not be negative and
+  every rule CIDR must parse. Deny entries win over allow entries; a
+  non-empty allow list refuses every address it does not match.
+- ` + "`capacity`" + ` limits must not be negative. An app's ` + "`max_subscribers_per_stream`" + `
+  replaces the global one; its other limits cap the app's share in addition
+  to the global limits.
+- ` + "`publish_policy`" + ` limits must not be negative and codec names must be ones
+  nonchalant recognises. Codec, size or frame-rate violations are rejected at
+  once; a bitrate over the cap for longer than the grace period disconnects
+  the publisher.
+- App and stream names are always limited to letters, digits, ` + "`_`" + `, ` + "`-`" + ` and
+  ` + "`.`" + `, 1 to 128 bytes, with no ` + "`..`" + `. ` + "`stream_names`" + ` patterns must compile and
+  narrow this further for an app's stream names.
+- ` + "`ingest_watchdog`" + ` thresholds must not be negative; zero disables a check.
+- ` + "`timestamps`" + ` values must not be negative and ` + "`interleave_ms`" + ` is at most 2000.
+- ` + "`captions`" + ` ` + "`language`" + ` must be a BCP 47 tag; ` + "`name`" + ` must not contain quotes, markup or line breaks.
+- An ` + "`analytics`" + ` section needs ` + "`export_dir`" + `; it is created on first export.
+- ` + "`admin.tokens`" + ` entries need a unique ` + "`name`" + `, a ` + "`token`" + ` and a ` + "`role`" + ` of
+  ` + "`read`" + `, ` + "`operate`" + ` or ` + "`admin`" + `.
+- ` + "`auth.publish_keys`" + ` is optional. When present and non-empty, every publisher
+  must include ` + "`?key=<secret>`" + ` in the RTMP stream name.
+- Each ` + "`hls.ladder`" + ` rung needs a unique alphanumeric ` + "`name`" + ` (no slashes / dots).
+  Video rungs require ` + "`width`" + `, ` + "`height`" + `, and ` + "`video_bitrate`" + ` (kbit/s).
+  Audio-only rungs set ` + "`audio_only: true`" + ` and may set ` + "`audio_bitrate`" + `.
+
+## ABR / multi-bitrate notes
+
+- An empty ` + "`hls.ladder`" + ` runs the packager in stream-copy mode: one ffmpeg
+  per stream, no transcoding, ~zero CPU. The default.
+- A non-empty ladder runs ` + "`libx264`" + ` per video rung. CPU is roughly
+  ` + "`Σ rungs`" + ` × bitrate-dependent. Consider hardware acceleration if you
+  configure many rungs.
+- Per-rendition URLs follow ` + "`/hls/{app}/{name}/{rung}/index.m3u8`" + ` and
+  ` + "`/hls/{app}/{name}/{rung}/seg_NNNNN.ts`" + `.
+- DASH's MPD lists each video rung as a Representation under one
+  AdaptationSet automatically.
+
+## Loading
+
+Configuration is loaded via the ` + "`--config`" + ` flag:
+
+` + "```" + `
+./nonchalant --config configs/nonchalant.example.yaml
+` + "```" + `
+
+Default path is ` + "`configs/nonchalant.example.yaml`" + `.
+`
+	return os.WriteFile(filepath.Join(dir, "CONFIG.md"), []byte(body), 0644)
+}
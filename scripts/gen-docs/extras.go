@@ -54,6 +54,25 @@ Coverage today (` + "`internal/itest/`" + `):
 - ` + "`wsflv_test.go`" + ` - publish via RTMP, consume via WebSocket-FLV
 - ` + "`hls_test.go`" + ` / ` + "`dash_test.go`" + ` - FLV → external ffmpeg HLS / DASH (legacy)
 - ` + "`native_hls_test.go`" + ` - the built-in /hls/* and /dash/* endpoints
+- ` + "`tls_test.go`" + ` - HTTPS and RTMPS listeners, certificate reload
+- ` + "`http3_test.go`" + ` - HTTP/2 negotiation, Alt-Svc and HTTP/3 on the QUIC listener; HTTP/3 refused over ` + "`max_conns_per_ip`" + `
+- ` + "`access_test.go`" + ` - publish / play ACLs, per-IP cap, handshake deadline, rejection metrics
+- ` + "`policy_test.go`" + ` - publish policy: H.264 publisher stays live, HEVC is rejected and counted
+- ` + "`names_test.go`" + ` - off-pattern publish disconnected; unsafe HTTP-FLV / WS-FLV / HLS names get 400
+- ` + "`media_test.go`" + ` - AVC / AAC sequence headers reported in ` + "`/api/streams`" + ` and ` + "`/metrics`" + `
+- ` + "`sessions_test.go`" + ` - publisher and HTTP-FLV viewer listed per stream, each kicked by ID
+- ` + "`latency_test.go`" + ` - HTTP-FLV viewer latency p50 / p99 in the sessions API and ` + "`/metrics`" + `
+- ` + "`traffic_test.go`" + ` - ingress / egress bytes, session gauges, TTFB and duration in ` + "`/metrics`" + `
+- ` + "`analytics_test.go`" + ` - live viewer report at ` + "`/api/analytics`" + `, JSON + CSV export on unpublish
+- ` + "`watchdog_test.go`" + ` - stalled publisher alerted in ` + "`/metrics`" + ` and disconnected; deleteStream then disconnect on a watched publish
+- ` + "`timestamps_test.go`" + ` - encoder restart smoothed for an HTTP-FLV viewer and counted in ` + "`/metrics`" + `
+- ` + "`clock_test.go`" + ` - encoder onFI reaches an HTTP-FLV viewer before keyframes and ` + "`/api/streams`" + `
+- ` + "`metadata_test.go`" + ` - API-injected and publisher onCuePoint / onSCTE35 reach an HTTP-FLV viewer at the live point
+- ` + "`captions_test.go`" + ` - CEA-608 in publisher SEI reaches an HTTP-FLV viewer as onCaptionInfo
+- ` + "`audio_test.go`" + ` - G.711 publisher flagged as audio in the HTTP-FLV header, frames passed through
+- ` + "`events_test.go`" + ` - publish start / stop over ` + "`/api/events`" + `, replay after Last-Event-ID
+- ` + "`capacity_test.go`" + ` - stream and per-stream viewer caps, 503 + Retry-After, usage in ` + "`/api/server`" + `
+- ` + "`proxyproto_test.go`" + ` - PROXY headers on RTMP and HTTP, real address in ` + "`/api/streams`" + `
 
 Tests that need ` + "`ffmpeg`" + ` skip themselves when it is not on PATH.
 
@@ -87,87 +106,3 @@ separate CI jobs because they are slower and require external tooling.
 `
 	return os.WriteFile(filepath.Join(dir, "TESTING.md"), []byte(body), 0644)
 }
-
-// writeOps writes OPERATIONS.md describing the metrics endpoint and ops surface.
-func writeOps(dir string) error {
-	body := `<!--
-If you are AI: This file documents the operational endpoints — health, metrics, and API.
-Generated by scripts/gen-docs.
--->
-
-# Operations
-
-## Endpoints
-
-| Path                          | Purpose                                                  |
-| ----------------------------- | -------------------------------------------------------- |
-| ` + "`/healthz`" + `                    | Liveness probe (200 if process is up).                  |
-| ` + "`/metrics`" + `                    | Prometheus text-format metrics (process, Go, custom).   |
-| ` + "`/api/server`" + `                 | Server version, uptime, enabled services.               |
-| ` + "`/api/streams`" + `                | Per-stream publisher / subscriber / drop counters.      |
-| ` + "`/api/relay`" + `                  | Configured relay tasks and running flag.                |
-| ` + "`/api/relay/restart`" + `          | POST {app, name} to restart a relay task.               |
-| ` + "`/{app}/{name}.flv`" + `           | HTTP-FLV live playback.                                 |
-| ` + "`/ws/{app}/{name}`" + `            | WebSocket-FLV live playback.                            |
-| ` + "`/hls/{app}/{name}.m3u8`" + `      | Native HLS playlist + .ts segments under the prefix.    |
-| ` + "`/dash/{app}/{name}.mpd`" + `      | Native MPEG-DASH manifest + .m4s chunks under prefix.   |
-
-## Metrics
-
-The ` + "`/metrics`" + ` endpoint emits Prometheus text format. Custom metrics:
-
-- ` + "`nonchalant_streams`" + ` (gauge): registered streams.
-- ` + "`nonchalant_publishers`" + ` (gauge): streams with a publisher.
-- ` + "`nonchalant_subscribers{app,name}`" + ` (gauge): subscribers per stream.
-- ` + "`nonchalant_messages_published_total{app,name}`" + ` (counter).
-- ` + "`nonchalant_messages_dropped_total{app,name}`" + ` (counter — backpressure drops).
-- ` + "`nonchalant_relay_tasks`" + ` (gauge).
-
-Standard ` + "`go_*`" + ` and ` + "`process_*`" + ` collectors are also exposed.
-
-## Authentication
-
-Set ` + "`auth.publish_keys`" + ` to require a pre-shared secret on every RTMP
-publish; ` + "`auth.play_keys`" + ` does the same for HTTP-FLV / WS-FLV / HLS / DASH
-subscribers. Both pass the secret as ` + "`?key=<secret>`" + `:
-
-` + "```" + `
-ffmpeg ... -f flv 'rtmp://host:1935/live/mystream?key=changeme'
-ffplay 'http://host:8081/live/mystream.flv?key=watch-secret'
-` + "```" + `
-
-Either field may be omitted to allow anonymous access in that direction.
-
-## Native HLS / DASH
-
-The packager spawns one ` + "`ffmpeg`" + ` subprocess per (stream, format) on first
-request. It pulls the server's own HTTP-FLV output and writes segments to a
-temp directory. Subprocesses are GC'd 60 s after their last access; SIGKILL
-is sent on server shutdown. ` + "`ffmpeg`" + ` must be on the server's PATH; if
-absent the endpoints return 503.
-
-### Single rendition (default)
-
-When ` + "`hls.ladder`" + ` is empty the packager runs in stream-copy mode (no
-transcoding, ~zero CPU). Output URLs:
-
-- ` + "`/hls/{app}/{name}.m3u8`" + ` — playlist
-- ` + "`/hls/{app}/{name}/seg_NNNNN.ts`" + ` — TS segments
-- ` + "`/dash/{app}/{name}.mpd`" + ` — DASH manifest
-
-### ABR (multi-bitrate)
-
-When ` + "`hls.ladder`" + ` is non-empty the packager transcodes one rendition per
-rung with ` + "`libx264`" + `. Output URLs:
-
-- ` + "`/hls/{app}/{name}.m3u8`" + ` — master playlist (lists all rungs)
-- ` + "`/hls/{app}/{name}/{rung}/index.m3u8`" + ` — per-rendition media playlist
-- ` + "`/hls/{app}/{name}/{rung}/seg_NNNNN.ts`" + ` — per-rendition TS segments
-- ` + "`/dash/{app}/{name}.mpd`" + ` — DASH manifest with one Representation per
-  video rung in a single AdaptationSet
-
-ABR cost is dominated by H.264 encoding. Pick ladder rungs with care; use
-hardware acceleration if you need many rungs at high resolution.
-`
-	return os.WriteFile(filepath.Join(dir, "OPERATIONS.md"), []byte(body), 0644)
-}
@@ -65,16 +65,30 @@ just a subscriber.
 - ` + "`cmd/nonchalant/`" + ` - Main entrypoint: configuration, server startup, signal handling
 - ` + "`internal/config/`" + ` - YAML configuration loading and validation
 - ` + "`internal/server/`" + ` - Top-level server lifecycle and graceful shutdown
+- ` + "`internal/certs/`" + ` - TLS certificate store: SNI selection and reload on file change
+- ` + "`internal/access/`" + ` - Per-app CIDR rules, per-IP connection and connect-rate limits
+- ` + "`internal/capacity/`" + ` - Stream, viewer and egress caps; usage for ` + "`/api/server`" + `
+- ` + "`internal/analytics/`" + ` - Per-broadcast viewer reports: peak, unique viewers, watch time
+- ` + "`internal/events/`" + ` - In-process lifecycle event bus with resumable history for ` + "`/api/events`" + `
+- ` + "`internal/sessions/`" + ` - Shared table of publisher and viewer sessions for listing, kicks and traffic / latency metrics
+- ` + "`internal/proxyproto/`" + ` - PROXY protocol v1/v2 listener wrapper for trusted load balancers
 - ` + "`internal/core/bus/`" + ` - Stream registry, ring-buffered subscribers, fan-out
 - ` + "`internal/core/protocol/amf0/`" + ` - AMF0 encode/decode for RTMP commands
-- ` + "`internal/core/protocol/flv/`" + ` - FLV header / tag muxing
+- ` + "`internal/core/protocol/flv/`" + ` - FLV header / tag muxing, codec names, audio track description
+- ` + "`internal/core/protocol/avc/`" + ` - H.264 SPS parsing (profile, level, size, frame rate), SEI clock and captions
+- ` + "`internal/core/protocol/aac/`" + ` - AAC AudioSpecificConfig parsing
+- ` + "`internal/core/protocol/scte35/`" + ` - SCTE-35 splice_insert / time_signal parsing
+- ` + "`internal/core/protocol/captions/`" + ` - CEA-608 / CEA-708 caption decoding
+- ` + "`internal/core/mediainfo/`" + ` - Per-stream codec description (RFC 6381 strings) and ingest stats
 - ` + "`internal/core/protocol/rtmp/`" + ` - RTMP chunk, message, handshake
-- ` + "`internal/svc/health/`" + ` - ` + "`/healthz`" + ` endpoint
+- ` + "`internal/svc/health/`" + ` - ` + "`/healthz`" + ` and ` + "`/readyz`" + ` endpoints
 - ` + "`internal/svc/rtmp/`" + ` - RTMP ingest with optional publish-key authentication
 - ` + "`internal/svc/httpflv/`" + ` - HTTP-FLV output
 - ` + "`internal/svc/wsflv/`" + ` - WebSocket-FLV output
 - ` + "`internal/svc/pkger/`" + ` - HLS / DASH packager (spawns ffmpeg subprocesses)
 - ` + "`internal/svc/relay/`" + ` - RTMP pull / push relay tasks
+- ` + "`internal/svc/edge/`" + ` - Edge mode: on-demand pulls from an origin
+- ` + "`internal/svc/cluster/`" + ` - Multi-node stream directory, redirects, ` + "`/api/cluster`" + `
 - ` + "`internal/svc/api/`" + ` - HTTP API
 - ` + "`internal/svc/metrics/`" + ` - Prometheus ` + "`/metrics`" + ` endpoint
 - ` + "`internal/svc/transcode/`" + ` - Optional transcode pipeline (build tag ` + "`ffmpeg`" + `)
@@ -126,85 +140,3 @@ Prometheus metrics, lint config, and CI.
 `
 	return os.WriteFile(filepath.Join(dir, "ARCHITECTURE.md"), []byte(body), 0644)
 }
-
-// writeConfig writes the CONFIG.md document describing the YAML schema.
-func writeConfig(dir string) error {
-	body := `<!--
-If you are AI: This file describes the configuration schema for nonchalant.
-It is generated by scripts/gen-docs.go and should be updated when config structure changes.
--->
-
-# Configuration
-
-## Format
-
-Configuration is YAML-only. Unknown fields are rejected.
-
-## Schema
-
-` + "```yaml" + `
-server:
-  health_port: 8080  # Port for /healthz endpoint (1-65535)
-  http_port:   8081  # Port for HTTP-FLV, WS-FLV, HLS, DASH, API, /metrics
-  rtmp_port:   1935  # Port for RTMP ingest
-
-auth:                 # Optional. Omit for anonymous publishing/playback.
-  publish_keys:       # Pre-shared secrets accepted on RTMP publish.
-    - changeme        # rtmp://host/live/foo?key=changeme
-  play_keys:          # Pre-shared secrets accepted on FLV/WS/HLS/DASH playback.
-    - watch-secret    # http://host/live/foo.flv?key=watch-secret
-
-hls:                  # Optional HLS / DASH packager tuning.
-  low_latency: false  # When true: 1s fMP4 segments, LL-HLS friendly.
-  ladder:             # Optional ABR (multi-bitrate) renditions.
-    - {name: 720p,  width: 1280, height: 720, video_bitrate: 2500}
-    - {name: 480p,  width: 854,  height: 480, video_bitrate: 1100}
-    - {name: 240p,  width: 426,  height: 240, video_bitrate: 400}
-    - {name: audio, audio_only: true, audio_bitrate: 64}
-
-relays:               # Optional. Each entry runs as a managed task.
-  - app: live
-    name: mystream
-    mode: pull        # "pull" (remote → local) or "push" (local → remote)
-    remote_url: rtmp://remote-server:1935/live/source
-    reconnect: true
-    max_retries: 5
-    retry_delay_seconds: 5
-` + "```" + `
-
-## Validation Rules
-
-- All ports must be between 1 and 65535.
-- All ports must be unique across ` + "`health_port`" + `, ` + "`http_port`" + `, and ` + "`rtmp_port`" + `.
-- Default values are applied when a section is omitted.
-- Each relay requires ` + "`app`" + `, ` + "`name`" + `, ` + "`mode`" + `, and ` + "`remote_url`" + `.
-- ` + "`auth.publish_keys`" + ` is optional. When present and non-empty, every publisher
-  must include ` + "`?key=<secret>`" + ` in the RTMP stream name.
-- Each ` + "`hls.ladder`" + ` rung needs a unique alphanumeric ` + "`name`" + ` (no slashes / dots).
-  Video rungs require ` + "`width`" + `, ` + "`height`" + `, and ` + "`video_bitrate`" + ` (kbit/s).
-  Audio-only rungs set ` + "`audio_only: true`" + ` and may set ` + "`audio_bitrate`" + `.
-
-## ABR / multi-bitrate notes
-
-- An empty ` + "`hls.ladder`" + ` runs the packager in stream-copy mode: one ffmpeg
-  per stream, no transcoding, ~zero CPU. The default.
-- A non-empty ladder runs ` + "`libx264`" + ` per video rung. CPU is roughly
-  ` + "`Σ rungs`" + ` × bitrate-dependent. Consider hardware acceleration if you
-  configure many rungs.
-- Per-rendition URLs follow ` + "`/hls/{app}/{name}/{rung}/index.m3u8`" + ` and
-  ` + "`/hls/{app}/{name}/{rung}/seg_NNNNN.ts`" + `.
-- DASH's MPD lists each video rung as a Representation under one
-  AdaptationSet automatically.
-
-## Loading
-
-Configuration is loaded via the ` + "`--config`" + ` flag:
-
-` + "```" + `
-./nonchalant --config configs/nonchalant.example.yaml
-` + "```" + `
-
-Default path is ` + "`configs/nonchalant.example.yaml`" + `.
-`
-	return os.WriteFile(filepath.Join(dir, "CONFIG.md"), []byte(body), 0644)
-}
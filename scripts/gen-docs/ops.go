@@ -0,0 +1,294 @@
+// If you are AI: This file holds the OPERATIONS.md template describing the
+// HTTP endpoints, metrics and operational behaviour.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeOps writes OPERATIONS.md describing the metrics endpoint and ops surface.
+func writeOps(dir string) error {
+	body := `<!--
+If you are AI: This file documents the operational endpoints — health, metrics, and API.
+Generated by scripts/gen-docs.
+-->
+
+# Operations
+
+## Endpoints
+
+| Path                          | Purpose                                                  |
+| ----------------------------- | -------------------------------------------------------- |
+| ` + "`/healthz`" + `                    | Liveness probe (200 if process is up).                  |
+| ` + "`/readyz`" + `                     | Readiness: 200 once listeners are bound, 503 during shutdown. |
+| ` + "`/metrics`" + `                    | Prometheus text-format metrics (process, Go, custom).   |
+| ` + "`/api/server`" + `                 | Server version, uptime, enabled services, capacity usage. |
+| ` + "`/api/streams`" + `                | Per-stream publisher / subscriber / drop counters; codecs and ingest quality. |
+| ` + "`/api/streams/{app}/{name}/sessions`" + ` | The stream's publisher and viewers: protocol, address, user agent, bytes, drops, lag. |
+| ` + "`/api/streams/{app}/{name}/metadata`" + ` | POST {type, data} injects an onCuePoint / onTextData / onSCTE35 / onCaptionInfo at the live point. |
+| ` + "`/api/sessions/{id}`" + `          | GET one session; DELETE disconnects that viewer or publisher. |
+| ` + "`/api/events`" + `                 | Server-Sent Events: publish, viewer, relay, packager and rejection events. |
+| ` + "`/api/analytics/{app}/{name}`" + ` | Viewer report of the live or last broadcast (JSON, or ` + "`?format=csv`" + `). |
+| ` + "`/api/relay`" + `                  | Relay tasks (one row per push target), status and active pull source. |
+| ` + "`/api/relay/restart`" + `          | POST {app, name} to restart a relay task.               |
+| ` + "`/api/relay/targets`" + `          | POST {app, name, target} adds, DELETE {app, name, id} removes a push target. |
+| ` + "`/api/relay/targets/enable`" + `   | POST {app, name, id} starts a disabled push target.     |
+| ` + "`/api/relay/targets/disable`" + `  | POST {app, name, id} stops a push target, keeps config. |
+| ` + "`/api/cluster`" + `                | Cluster members, liveness and stream → node map.        |
+| ` + "`/api/cluster/heartbeat`" + `      | POST; node-to-node heartbeat (cluster secret).          |
+| ` + "`/{app}/{name}.flv`" + `           | HTTP-FLV live playback.                                 |
+| ` + "`/ws/{app}/{name}`" + `            | WebSocket-FLV live playback.                            |
+| ` + "`/hls/{app}/{name}.m3u8`" + `      | Native HLS playlist + .ts segments under the prefix.    |
+| ` + "`/dash/{app}/{name}.mpd`" + `      | Native MPEG-DASH manifest + .m4s chunks under prefix.   |
+
+In the default ` + "`combined`" + ` layout every path above is served on
+` + "`http_port`" + `. With ` + "`server.layout: split`" + ` the health, API, cluster, metrics and
+pprof paths move to a separate listener on ` + "`admin_bind:health_port`" + ` (for
+example ` + "`127.0.0.1`" + ` or an internal VLAN address), and ` + "`http_port`" + ` serves
+playback plus ` + "`/healthz`" + ` only.
+
+## Metrics
+
+The ` + "`/metrics`" + ` endpoint emits Prometheus text format. Custom metrics:
+
+- ` + "`nonchalant_streams`" + ` (gauge): registered streams.
+- ` + "`nonchalant_publishers`" + ` (gauge): streams with a publisher.
+- ` + "`nonchalant_subscribers{app,name}`" + ` (gauge): subscribers per stream.
+- ` + "`nonchalant_messages_published_total{app,name}`" + ` (counter).
+- ` + "`nonchalant_messages_dropped_total{app,name}`" + ` (counter — backpressure drops).
+- ` + "`nonchalant_relay_tasks`" + ` (gauge): configured relay destinations.
+- ` + "`nonchalant_httpflv_sessions{proto}`" + ` (gauge): active HTTP-FLV sessions by
+  ` + "`http/1.1`" + `, ` + "`h2`" + ` or ` + "`h3`" + ` (also in ` + "`/api/server`" + ` as ` + "`flv_sessions`" + `).
+- ` + "`nonchalant_rejections_total{reason}`" + ` (counter): refused clients by
+  ` + "`publish_denied`" + `, ` + "`play_denied`" + `, ` + "`conn_limit`" + `, ` + "`rate_limit`" + `,
+  ` + "`handshake_timeout`" + `, ` + "`publish_policy`" + `, ` + "`bad_name`" + ` or ` + "`capacity`" + `.
+- ` + "`nonchalant_stream_video_info{app,name,codec,profile,level,codec_string}`" + ` and
+  ` + "`nonchalant_stream_audio_info{app,name,codec,profile,codec_string}`" + ` (gauge, 1)
+  with ` + "`nonchalant_stream_video_width_pixels`" + `, ` + "`_video_height_pixels`" + `,
+  ` + "`_audio_sample_rate_hz`" + ` and ` + "`_audio_channels`" + `: what each published stream
+  carries, parsed from its sequence headers and ` + "`onMetaData`" + `.
+- ` + "`nonchalant_stream_ingest_bitrate_kbps`" + `, ` + "`_ingest_fps`" + `, ` + "`_gop_frames`" + `,
+  ` + "`_gop_seconds`" + ` and ` + "`_av_drift_seconds`" + ` (gauges, ` + "`{app,name}`" + `): live ingest
+  quality, measured over the last second of media time. The same values are
+  in ` + "`/api/streams`" + ` under ` + "`media`" + `.
+- ` + "`nonchalant_delivery_latency_seconds{protocol}`" + ` (histogram): time from a
+  message being accepted by the stream to an output writing it to its
+  socket, for ` + "`httpflv`" + `, ` + "`wsflv`" + `, ` + "`packager`" + ` (the HLS / DASH ffmpeg input)
+  and ` + "`relay`" + ` (push relays).
+- ` + "`nonchalant_ingress_bytes_total{app,name}`" + ` and
+  ` + "`nonchalant_egress_bytes_total{app,name,protocol}`" + ` (counters): bytes read
+  from publishers and written to clients. Pull relays count as RTMP ingress;
+  push relays and the packager as ` + "`relay`" + ` / ` + "`packager`" + ` egress (their
+  loopback HTTP-FLV read). A total is kept for 10 minutes after the last
+  session on it ends.
+- ` + "`nonchalant_sessions{protocol,role}`" + ` (gauge): live sessions, as in the
+  sessions API.
+- ` + "`nonchalant_session_duration_seconds{protocol}`" + ` and
+  ` + "`nonchalant_session_ttfb_seconds{protocol}`" + ` (histograms): lifetime of
+  ended sessions, and time from a session opening to its first byte written
+  (response headers included). HLS / DASH sessions end at their last request.
+- ` + "`nonchalant_ingest_alerts_total{reason}`" + ` (counter) and
+  ` + "`nonchalant_ingest_alert_active{app,name,reason}`" + ` (gauge, 1): ingest
+  watchdog alerts raised, and the conditions still active on a publisher.
+- ` + "`nonchalant_timestamp_corrections_total{app,name,kind}`" + ` (counter): publisher
+  timestamps fixed by the sanitizer (` + "`wrap`" + `, ` + "`backwards`" + `, ` + "`jump`" + `, ` + "`reorder`" + `).
+
+Standard ` + "`go_*`" + ` and ` + "`process_*`" + ` collectors are also exposed.
+
+## Authentication
+
+Set ` + "`auth.publish_keys`" + ` to require a pre-shared secret on every RTMP
+publish; ` + "`auth.play_keys`" + ` does the same for HTTP-FLV / WS-FLV / HLS / DASH
+subscribers. Both pass the secret as ` + "`?key=<secret>`" + `:
+
+` + "```" + `
+ffmpeg ... -f flv 'rtmp://host:1935/live/mystream?key=changeme'
+ffplay 'http://host:8081/live/mystream.flv?key=watch-secret'
+` + "```" + `
+
+Either field may be omitted to allow anonymous access in that direction.
+
+### Admin API
+
+When ` + "`admin.tokens`" + ` is set, ` + "`/api/*`" + ` and ` + "`/debug/pprof/*`" + ` require
+` + "`Authorization: Bearer <token>`" + `. Missing or unknown tokens get 401; a
+token whose role is too small gets 403.
+
+| Role      | Allows                                                          |
+| --------- | --------------------------------------------------------------- |
+| ` + "`read`" + `    | Every GET endpoint (` + "`/api/server`" + `, ` + "`/api/streams`" + `, ` + "`/api/relay`" + `, ` + "`/api/cluster`" + `). |
+| ` + "`operate`" + ` | Also relay restart, target enable / disable, session kicks and metadata injection. |
+| ` + "`admin`" + `   | Also adding / removing relay targets and ` + "`/debug/pprof/*`" + `.     |
+
+Every mutating call (any method other than GET / HEAD) logs an audit line,
+with or without tokens configured:
+
+` + "```" + `
+audit: user=oncall role=operate POST /api/relay/restart from 10.0.0.7:51234 -> 200
+` + "```" + `
+
+` + "`admin.metrics_token`" + ` independently protects ` + "`/metrics`" + `; configure
+Prometheus with ` + "`authorization: {credentials: <token>}`" + `.
+
+## Clustering
+
+With a ` + "`cluster`" + ` section each node heartbeats its locally published
+streams to every peer (the peer answers with its own list). A viewer asking
+node B for a stream published on node A is either redirected with a 302 to
+the same URL on A (` + "`mode: redirect`" + `, default) or served by B through an
+internal HTTP-FLV pull from A that is dropped once B's viewers leave
+(` + "`mode: proxy`" + `, same idle rules as edge mode). RTMP is ingest-only, so
+redirects apply to HTTP-FLV, WS-FLV, HLS and DASH playback. Peers that miss
+heartbeats for ` + "`peer_timeout_seconds`" + ` are shown as down and no longer
+receive traffic.
+
+Heartbeats must carry the shared ` + "`secret`" + ` (401 otherwise). The URL a
+heartbeat advertises becomes a redirect target and a pull source, so its
+host must be the host of a configured peer (any port or scheme, for split
+admin listeners); other senders get 403.
+
+## TLS
+
+With a ` + "`tls`" + ` section the server adds an HTTPS listener on ` + "`https_port`" + `
+(every route of ` + "`http_port`" + `, so ` + "`https://`" + ` FLV, ` + "`wss://`" + ` WS-FLV, HLS and
+DASH work from HTTPS pages) and an RTMPS ingest listener on ` + "`rtmps_port`" + `
+(` + "`rtmps://host:1936/live/name`" + `). The plain listeners keep running: relays,
+the packager and edge pulls loop back through them. The server picks a
+certificate by SNI (exact name, then ` + "`*.domain`" + `, then the default pair) and
+polls the files every ` + "`reload_seconds`" + `; renewed certificates apply to new
+handshakes without a restart and a ` + "`tls: reloaded <file>`" + ` line is logged.
+
+` + "`http2: true`" + ` offers HTTP/2 on ` + "`https_port`" + ` and ` + "`http3_port`" + ` adds an
+HTTP/3 (QUIC, UDP) listener that HTTPS responses advertise with ` + "`Alt-Svc`" + `.
+HLS and DASH segment fetches then share one multiplexed connection per
+player. HTTP-FLV on HTTP/1.1 keeps the hijacked one-write-per-tag path; on
+HTTP/2 and HTTP/3 it streams through the response writer with a flush per
+tag, which costs more CPU per viewer. WS-FLV always uses HTTP/1.1.
+
+## PROXY protocol
+
+Behind a TCP load balancer every connection appears to come from the
+balancer. With ` + "`proxy_protocol`" + ` enabled, peers in ` + "`trusted_cidrs`" + ` may prefix
+the connection with a PROXY v1 (text) or v2 (binary) header; the address it
+names replaces the peer address everywhere: RTMP logs, ` + "`publisher_addr`" + ` in
+` + "`/api/streams`" + `, the admin audit log and ` + "`r.RemoteAddr`" + `. Configure the balancer
+to send the header (HAProxy ` + "`send-proxy`" + ` / ` + "`send-proxy-v2`" + `, AWS NLB proxy
+protocol v2). Headers from untrusted peers are not parsed, so clients cannot
+spoof their address. The header is read on the connection's first use and
+must arrive within ` + "`header_timeout_seconds`" + `.
+
+## Access control
+
+The ` + "`access`" + ` section applies to every public listener (RTMP, RTMPS, HTTP,
+HTTPS, HTTP/3). Per-app rules refuse publishers with
+` + "`NetStream.Publish.Rejected`" + ` and viewers with 403. ` + "`max_conns_per_ip`" + ` caps
+concurrent connections and ` + "`connect_rate`" + ` / ` + "`connect_burst`" + ` is a token
+bucket on new connections, both per client IP, with each QUIC connection
+counted like a TCP one; refused connections are closed before the
+handshake (after it for HTTP/3, with ` + "`H3_EXCESSIVE_LOAD`" + `). An RTMP client must finish the handshake and
+` + "`connect`" + ` within ` + "`handshake_timeout_seconds`" + ` and an HTTP client must send
+its request headers within it, so slowloris-style clients are dropped.
+Limits use the address after PROXY protocol. Loopback is exempt because
+relays, the packager and edge pulls connect through the public listeners.
+Every refusal is counted in ` + "`nonchalant_rejections_total{reason}`" + `.
+
+## Capacity limits
+
+The ` + "`capacity`" + ` section caps load globally and per app. A publish over
+` + "`max_streams`" + ` is refused with ` + "`NetStream.Publish.Rejected`" + ` and counted as
+` + "`nonchalant_rejections_total{reason=\"capacity\"}`" + `; concurrent publishes
+reserve their slot, so they cannot overshoot the cap together. A viewer over
+` + "`max_subscribers_per_stream`" + ` or ` + "`max_subscribers`" + ` (HTTP-FLV and WS-FLV
+viewers), or any playback request (including HLS / DASH segments) while
+egress is over ` + "`max_egress_kbps`" + `, gets 503 with ` + "`Retry-After`" + `. Egress is
+measured from the bytes written to viewers and sampled every second.
+The server's own pulls (loopback requests with the packager's or a push
+relay's user agent) are neither limited nor metered, so they never count
+against viewers; other loopback clients, such as a same-host reverse proxy
+without PROXY protocol, are limited like anyone else. ` + "`/api/server`" + ` reports ` + "`capacity`" + `: current
+streams, subscribers, busiest stream and egress next to each limit, for the
+server and per app.
+
+## Publish policy
+
+` + "`publish_policy`" + ` restricts what each app accepts. Video and audio codecs are
+read from every tag (legacy codec IDs and Enhanced RTMP FourCCs), resolution
+and frame rate from the AVC sequence header (SPS) and from ` + "`onMetaData`" + `.
+A violation sends ` + "`NetStream.Publish.Rejected`" + ` with the reason as its
+description, logs ` + "`Publish policy: app/name from addr: reason`" + `, and closes the
+connection. Ingest bitrate is averaged over ` + "`bitrate_window_seconds`" + `; a
+publisher may stay over ` + "`max_bitrate_kbps`" + ` for ` + "`bitrate_grace_seconds`" + `
+before it is disconnected the same way. Each disconnect counts as
+` + "`nonchalant_rejections_total{reason=\"publish_policy\"}`" + `.
+
+## Ingest watchdog
+
+` + "`ingest_watchdog`" + ` watches RTMP publishers per app (` + "`*`" + ` for the rest) and
+raises an alert when one is up but unhealthy: ` + "`no_media`" + ` (no audio or
+video for ` + "`no_media_seconds`" + `), ` + "`no_keyframe`" + ` (video flowing without a
+keyframe), ` + "`audio_only`" + ` (video stopped while audio continues),
+` + "`low_bitrate`" + ` (under ` + "`min_bitrate_kbps`" + ` over ` + "`bitrate_window_seconds`" + `),
+` + "`timestamp_backwards`" + ` and ` + "`timestamp_jump`" + ` (per track, sequence headers
+excepted). Checks run on every message and once a second. Each alert is
+logged, emitted as an ` + "`ingest_alert`" + ` event and counted in
+` + "`nonchalant_ingest_alerts_total{reason}`" + `; the four stall conditions stay in
+` + "`nonchalant_ingest_alert_active`" + ` until they clear, which emits
+` + "`ingest_recovered`" + `. With ` + "`disconnect: true`" + ` the publisher is also dropped
+so its encoder reconnects.
+
+` + timingDoc + `## Stream names
+
+App and stream names reach file paths, URLs and ffmpeg arguments, so every
+key is checked in one place (` + "`bus.StreamKey.Validate`" + `): 1 to 128 bytes of
+letters, digits, ` + "`_`" + `, ` + "`-`" + ` and ` + "`.`" + `, and no ` + "`..`" + `. ` + "`stream_names`" + ` adds a
+per-app regexp for stream names. An RTMP publish that fails gets
+` + "`NetStream.Publish.BadName`" + ` and is disconnected; an HTTP-FLV, WS-FLV, HLS or
+DASH request gets 400. Both count as
+` + "`nonchalant_rejections_total{reason=\"bad_name\"}`" + `. Relay names are
+checked the same way when relays are configured or added.
+
+` + sessionsDoc + `## Native HLS / DASH
+
+The packager spawns one ` + "`ffmpeg`" + ` subprocess per (stream, format) on first
+request. It pulls the server's own HTTP-FLV output and writes segments to a
+temp directory. Subprocesses are GC'd 60 s after their last access; SIGKILL
+is sent on server shutdown. ` + "`ffmpeg`" + ` must be on the server's PATH; if
+absent the endpoints return 503.
+
+### Single rendition (default)
+
+When ` + "`hls.ladder`" + ` is empty the packager runs in stream-copy mode (no
+transcoding, ~zero CPU). Output URLs:
+
+- ` + "`/hls/{app}/{name}.m3u8`" + ` — playlist
+- ` + "`/hls/{app}/{name}/seg_NNNNN.ts`" + ` — TS segments
+- ` + "`/dash/{app}/{name}.mpd`" + ` — DASH manifest
+
+Audio is copied when the segment container carries it: AAC, MP3, AC-3 and
+E-AC-3 in TS; those plus Opus and FLAC in fMP4 (DASH, low-latency HLS).
+Anything else (G.711 ` + "`pcma`" + ` / ` + "`pcmu`" + `, Nellymoser, Opus in TS) is
+transcoded to 128 kbit/s AAC while video is still copied. Speex has no
+native ffmpeg decoder; its streams get 501 with
+` + "`unsupported audio codec: speex cannot be packaged for hls`" + `. The
+first request for a stream waits up to 2 s for its first audio tag before
+choosing; a stream with no audio by then is packaged video-only.
+
+### ABR (multi-bitrate)
+
+When ` + "`hls.ladder`" + ` is non-empty the packager transcodes one rendition per
+rung with ` + "`libx264`" + `. Output URLs:
+
+- ` + "`/hls/{app}/{name}.m3u8`" + ` — master playlist (lists all rungs)
+- ` + "`/hls/{app}/{name}/{rung}/index.m3u8`" + ` — per-rendition media playlist
+- ` + "`/hls/{app}/{name}/{rung}/seg_NNNNN.ts`" + ` — per-rendition TS segments
+- ` + "`/dash/{app}/{name}.mpd`" + ` — DASH manifest with one Representation per
+  video rung in a single AdaptationSet
+
+Audio is always transcoded to AAC, so any codec but Speex works.
+ABR cost is dominated by H.264 encoding. Pick ladder rungs with care; use
+hardware acceleration if you need many rungs at high resolution.
+`
+	return os.WriteFile(filepath.Join(dir, "OPERATIONS.md"), []byte(body), 0644)
+}
@@ -0,0 +1,72 @@
+// If you are AI: This file holds the OPERATIONS.md sections on sessions,
+// events and viewer analytics, kept apart so ops.go stays under the
+// 300-line limit.
+
+package main
+
+// sessionsDoc documents the session table and what is built on it.
+const sessionsDoc = `## Sessions
+
+Every RTMP publisher and every HTTP-FLV, WS-FLV, HLS and DASH viewer is
+registered in one session table. ` + "`GET /api/streams/{app}/{name}/sessions`" + `
+lists them with a random ID, protocol, role, remote address, user agent,
+start time, bytes sent / received and, for FLV viewers, bus drops and lag
+(published messages not yet read). FLV viewers also report
+` + "`latency_p50_ms`" + ` / ` + "`latency_p99_ms`" + `: publish-to-write latency over their
+last 256 writes, not counting the GOP replayed when they joined. The
+packager and push relays read over loopback HTTP-FLV and are listed with
+protocol ` + "`packager`" + ` or ` + "`relay`" + `. ` + "`DELETE /api/sessions/{id}`" + ` closes the
+connection. HLS and DASH have no connection to close: their requests are
+grouped by client IP and user agent, the session ends after 30 s without a
+request (it stays listed until the next sweep, every 5 s), and a kicked
+client gets 403 for a minute.
+
+## Events
+
+` + "`GET /api/events`" + ` is a Server-Sent Events stream of server lifecycle
+events, so dashboards need not poll ` + "`/api/streams`" + `. Each message has an
+` + "`id`" + ` (monotonic for the life of the process), an ` + "`event`" + ` type and a JSON
+` + "`data`" + ` body with ` + "`app`" + `, ` + "`name`" + ` and the fields that apply:
+
+| Event                 | Emitted when                                             |
+| --------------------- | -------------------------------------------------------- |
+| ` + "`publish_started`" + `     | An RTMP publisher goes live (` + "`session_id`" + `, ` + "`remote_addr`" + `). |
+| ` + "`publish_stopped`" + `     | The publisher disconnects or is kicked.                  |
+| ` + "`subscriber_joined`" + `   | A viewer connects on any protocol (` + "`protocol`" + `, ` + "`session_id`" + `). |
+| ` + "`subscriber_left`" + `     | The viewer disconnects, is kicked or, for HLS / DASH, goes idle. |
+| ` + "`relay_state_changed`" + ` | A relay destination changes ` + "`state`" + ` (with ` + "`target`" + ` and ` + "`error`" + `). |
+| ` + "`packager_started`" + `    | An HLS / DASH packager starts (format in ` + "`protocol`" + `).   |
+| ` + "`packager_exited`" + `     | Its ffmpeg exits; ` + "`error`" + ` is set unless it was stopped. |
+| ` + "`policy_rejected`" + `     | A publish or playback is refused or stopped (` + "`reason`" + `; details in ` + "`error`" + `). |
+| ` + "`ingest_alert`" + `        | The ingest watchdog trips (` + "`reason`" + `; details in ` + "`error`" + `). |
+| ` + "`ingest_recovered`" + `    | A watchdog stall condition clears (` + "`reason`" + `).          |
+
+` + "`?app=`" + ` and ` + "`?name=`" + ` filter the stream. The last 1024 events are kept:
+a client that reconnects with ` + "`Last-Event-ID`" + ` (browsers send it
+automatically; ` + "`?last_event_id=`" + ` also works) first receives the events it
+missed. A client more than 256 events behind is disconnected and resumes
+the same way.
+
+` + "```" + `
+curl -N 'http://localhost:8081/api/events?app=live'
+` + "```" + `
+
+## Analytics
+
+Viewer sessions are rolled up per broadcast, from publish to unpublish.
+` + "`GET /api/analytics/{app}/{name}`" + ` returns the live broadcast, or the last
+one for 24 hours after the stream goes offline: ` + "`peak_viewers`" + ` (with ` + "`peak_at`" + `),
+` + "`unique_viewers`" + `, ` + "`sessions`" + `, ` + "`watch_minutes`" + ` and ` + "`avg_watch_minutes`" + `
+(per session), overall and under ` + "`protocols`" + `. Viewers are told apart by
+the play key they were admitted with, so a key per subscriber gives exact
+counts across devices and shared addresses; without play keys, by client
+IP and user agent. Only a hash is kept. Viewers connected before the publisher count from the
+moment it goes live. The packager and push relays are not viewers.
+` + "`?format=csv`" + ` returns the same report as CSV: an ` + "`all`" + ` row, then one row
+per protocol.
+
+With ` + "`analytics.export_dir`" + ` set, every finished broadcast is also written
+there as ` + "`{app}_{name}_{start}.json`" + ` and ` + "`.csv`" + `, ` + "`{start}`" + ` being the UTC
+publish time (` + "`20060102T150405Z`" + `).
+
+`
@@ -0,0 +1,116 @@
+// If you are AI: This file holds the OPERATIONS.md sections on stream
+// timing: the timestamp sanitizer, the wall clock, timed metadata, ad
+// markers and closed captions, kept apart so ops.go stays under the 300-line limit.
+
+package main
+
+// timingDoc documents timestamp normalization, wall-clock time, timed
+// metadata, ad markers and captions.
+const timingDoc = `## Timestamp sanitizer
+
+` + "`timestamps`" + ` normalizes RTMP publisher timestamps per app (` + "`*`" + ` for the
+rest) before they reach the bus, so every output gets the same clean
+timeline. Each track's DTS never goes backwards: a small step back is held
+at the previous value. The 32-bit wrap after ~49 days is forward time. A
+step over ` + "`max_jump_ms`" + ` either way (an encoder restart) is smoothed to one
+33 ms frame after the newest timestamp; when the other track jumps the
+same way it keeps the same offset, so audio and video stay in sync. With
+` + "`interleave_ms`" + `, messages are held that long and released in timestamp
+order, which delays every frame by the window. Corrections count in
+` + "`nonchalant_timestamp_corrections_total`" + `.
+
+## Wall clock
+
+Each stream's timeline is anchored to wall-clock time. By default the
+anchor is when the first frame arrived. An encoder that sends ` + "`onFI`" + `
+data (` + "`sd`" + ` date, ` + "`st`" + ` time of day, taken as UTC) or H.264 picture timing
+SEI with clock timestamps replaces it; SEI carries no date, so the day
+nearest the server's is used. ` + "`onFI`" + ` is consumed, not passed on.
+` + "`GET /api/streams`" + ` reports the anchor as ` + "`clock`" + ` with its ` + "`source`" + `
+(` + "`server`" + `, ` + "`onfi`" + ` or ` + "`sei`" + `).
+
+Outputs carry the anchor so players can line up streams by absolute time:
+HTTP-FLV and WS-FLV viewers get an ` + "`onFI`" + ` tag before every keyframe, HLS
+playlists carry ` + "`EXT-X-PROGRAM-DATE-TIME`" + ` on each segment, and DASH
+manifests set ` + "`availabilityStartTime`" + ` from it and add a
+` + "`UTCTiming`" + ` element (` + "`urn:mpeg:dash:utc:direct:2014`" + `) for client
+clock sync.
+
+## Timed metadata
+
+` + "`POST /api/streams/{app}/{name}/metadata`" + ` with
+` + "`{\"type\": \"onCuePoint\", \"data\": {\"name\": \"question\", \"parameters\": {\"n\": 3}}}`" + `
+(or ` + "`onTextData`" + `) publishes an AMF0 script message into the live stream. It
+is queued and goes out with the publisher's next frame, at that frame's
+timestamp; 202 means queued, 404 that the stream has no publisher.
+Script data an RTMP publisher sends passes through the same way, whatever
+its name; only ` + "`onMetaData`" + ` is cached for late joiners.
+
+HTTP-FLV and WS-FLV viewers get script tags. HLS and DASH segments get
+them as they are served, as an ID3 tag with one ` + "`TXXX`" + ` frame: the
+message name and its data as JSON. Messages whose tag would exceed 65000
+bytes (the limit of one PES packet) reach FLV viewers only. MPEG-TS segments carry a timed ID3
+stream (type 0x15, declared in the PMT); fMP4 segments (DASH and
+low-latency HLS) carry ` + "`emsg`" + ` boxes with scheme
+` + "`https://aomedia.org/emsg/ID3`" + `, declared in the MPD as an
+` + "`InbandEventStream`" + `. The last 64 messages of each name per stream, 256
+in all, are kept for the packager.
+
+## Ad markers (SCTE-35)
+
+An ` + "`onSCTE35`" + ` message whose value is a base64 SCTE-35
+splice_info_section (or an object with it in ` + "`data`" + ` or ` + "`scte35`" + `), or an
+` + "`onCuePoint`" + ` with a ` + "`scte35`" + ` field (top level or in ` + "`parameters`" + `), is a
+splice at the message's timestamp. Send it from the encoder at the splice
+point, or POST it to the metadata endpoint. ` + "`splice_insert`" + ` and
+` + "`time_signal`" + ` with a segmentation descriptor (break, provider /
+distributor advertisement and placement opportunity start and end) are
+understood; splice times in the section are not used. An out splice with
+a break duration returns automatically; an in splice closes the break
+with its event ID, and a cancel drops it.
+
+HLS media playlists get, at the splice point:
+
+- out: ` + "`#EXT-X-DATERANGE`" + ` with ` + "`SCTE35-OUT`" + ` and ` + "`PLANNED-DURATION`" + `, then
+  ` + "`#EXT-X-CUE-OUT:DURATION=...`" + `
+- inside the break: ` + "`#EXT-X-CUE-OUT-CONT:ElapsedTime=...,Duration=...`" + `
+- in: ` + "`#EXT-X-DATERANGE`" + ` with ` + "`SCTE35-IN`" + ` (signalled returns), then
+  ` + "`#EXT-X-CUE-IN`" + `
+
+ffmpeg cuts segments on its own schedule, so a splice inside an MPEG-TS
+segment splits it at the first keyframe at or after the splice: the
+playlist lists the parts as ` + "`seg_00007.ts?to=<pts>`" + ` and
+` + "`seg_00007.ts?from=<pts>`" + `, cut from the file as served. The encoder should
+put a keyframe at the splice point (most do); without one in the segment
+the tags move to the next segment. Low-latency (fMP4) segments are not
+split. DASH periods get an ` + "`EventStream`" + ` with scheme
+` + "`urn:scte:scte35:2014:xml+bin`" + ` carrying each splice's binary. Markers
+need the wall clock; a break leaves the playlist once its splices are
+older than the stream's last 64 ` + "`onSCTE35`" + ` (or ` + "`onCuePoint`" + `) messages.
+
+## Closed captions
+
+` + "`captions`" + ` decodes CEA-608 / CEA-708 captions that encoders put in H.264
+SEI (` + "`user_data_registered_itu_t_t35`" + `, ATSC A/53 ` + "`GA94`" + ` cc_data) for each
+configured app (` + "`*`" + ` for the rest). CEA-608 CC1 is read (pop-on, roll-up
+and paint-on); a stream without 608 data falls back to CEA-708 service 1.
+Each change of the text on screen is published at the frame that shows
+it as ` + "`onCaptionInfo`" + ` script data,
+` + "`{\"type\": \"cea608\", \"text\": \"...\", \"language\": \"en\"}`" + `; an empty ` + "`text`" + `
+clears the screen. HTTP-FLV and WS-FLV viewers get it as a script tag.
+Captions can also be POSTed to the metadata endpoint as ` + "`onCaptionInfo`" + `
+with a ` + "`text`" + ` field.
+
+HLS and DASH players get a WebVTT subtitle rendition instead of ID3.
+HLS master playlists get
+` + "`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"subs\",...,URI=\"subs/index.m3u8\"`" + ` and
+` + "`SUBTITLES=\"subs\"`" + ` on each variant; the subtitle playlist lists a WebVTT
+file per video segment (` + "`subs/seg_00007.vtt`" + `), mapped to the segment's
+timestamps with ` + "`X-TIMESTAMP-MAP`" + `. A single-rendition stream with
+captions gets a master playlist at ` + "`index.m3u8`" + ` and its media playlist at
+` + "`stream.m3u8`" + `. DASH MPDs get a ` + "`text/vtt`" + ` AdaptationSet with role
+` + "`caption`" + ` and numbered segments (` + "`subs/$Number$.vtt`" + `), one per segment
+duration from the period start. Captions show until the next message;
+` + "`language`" + ` and ` + "`name`" + ` set the rendition's language tag and menu name.
+
+`